@@ -17,17 +17,179 @@ CREATE TABLE IF NOT EXISTS `users` (
     UNIQUE KEY `email` (`email`)
     ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
+CREATE TABLE IF NOT EXISTS `plans` (
+                                       `name` varchar(50) NOT NULL,
+    `cpu` int(11) NOT NULL,
+    `memory_mb` int(11) NOT NULL,
+    `disk_gb` int(11) NOT NULL,
+    `max_snapshots` int(11) NOT NULL DEFAULT 1,
+    `features` varchar(255) NOT NULL DEFAULT '{}', -- DomainFeatures JSON (uefi, virtio_rng, cpu_quota)
+    `node_labels` varchar(255) NOT NULL DEFAULT '{}', -- 이 플랜의 VM을 배치할 노드의 레이블 (모두 일치해야 함)
+    PRIMARY KEY (`name`)
+    ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+INSERT IGNORE INTO `plans` (`name`, `cpu`, `memory_mb`, `disk_gb`, `max_snapshots`) VALUES
+    ('small', 1, 1024, 10, 1),
+    ('medium', 2, 2048, 20, 3),
+    ('large', 4, 4096, 40, 5);
+
+-- 이미지는 scripts/ubuntu-image-download.sh가 출력하는 값으로 POST /images 로 등록한다
+CREATE TABLE IF NOT EXISTS `images` (
+                                        `name` varchar(50) NOT NULL,
+    `os_family` varchar(50) NOT NULL,
+    `version` varchar(50) NOT NULL DEFAULT '',
+    `path` varchar(255) NOT NULL,
+    `sha256` char(64) NOT NULL,
+    `default_user` varchar(50) NOT NULL,
+    `seed_format` enum('iso','vfat') NOT NULL DEFAULT 'iso',
+    `features` varchar(255) NOT NULL DEFAULT '{}', -- DomainFeatures JSON (uefi, virtio_rng)
+    `status` enum('active','retired') NOT NULL DEFAULT 'active',
+    `verified_at` timestamp NULL DEFAULT NULL,
+    `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (`name`)
+    ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- compute 노드. 하나도 없으면 API 서버의 LibvirtAgentAddr(또는 로컬 libvirt) 하나로 동작한다
+CREATE TABLE IF NOT EXISTS `nodes` (
+                                       `name` varchar(50) NOT NULL,
+    `address` varchar(255) NOT NULL, -- agent://host:port 또는 libvirt URI (qemu+tcp://host/system 등)
+    `vcpus` int(11) NOT NULL, -- 할당 가능한 vCPU (오버커밋 포함)
+    `memory_mb` int(11) NOT NULL,
+    `disk_gb` int(11) NOT NULL,
+    `labels` varchar(255) NOT NULL DEFAULT '{}',
+    `schedulable` tinyint(1) NOT NULL DEFAULT 1,
+    `shared_storage` tinyint(1) NOT NULL DEFAULT 0, -- 인스턴스 디렉토리가 공유 저장소에 있음 (이전 시 디스크를 복사하지 않는다)
+    `migration_uri` varchar(255) NOT NULL DEFAULT '', -- live migration 대상 libvirt URI (''이면 address에서 만든다)
+    `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (`name`)
+    ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
 CREATE TABLE IF NOT EXISTS `hostings` (
                                           `id` bigint(20) NOT NULL AUTO_INCREMENT,
     `user_id` bigint(20) NOT NULL,
+    `name` varchar(32) NOT NULL,
     `vm_name` varchar(100) NOT NULL,
     `ip_address` varchar(100) NOT NULL,
     `ssh_port` int(11) NOT NULL,
     `proxy_path` varchar(100) NOT NULL,
     `disk_path` text NOT NULL,
-    `status` enum('running','stopped','deleted','error') NOT NULL DEFAULT 'running',
+    `plan` varchar(50) NOT NULL DEFAULT 'small',
+    `image` varchar(50) NOT NULL DEFAULT '',
+    `vnc_password` varchar(8) NOT NULL DEFAULT '',
+    `status` enum('provisioning','running','stopped','paused','migrating','deleted','error') NOT NULL DEFAULT 'running',
+    -- 사용자가 마지막으로 요청한 전원 상태, compute 노드 재부팅 후 running인 VM을 다시 부팅한다
+    `desired_state` enum('running','stopped') NOT NULL DEFAULT 'running',
+    -- VM이 배치된 노드 (nodes.name, 노드를 등록하기 전에 만든 VM은 '')
+    `node_name` varchar(50) NOT NULL DEFAULT '',
     `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+    -- 삭제되지 않은 VM끼리만 IP/SSH 포트가 겹치지 않게 한다 (삭제된 행은 NULL이라 UNIQUE에 걸리지 않는다)
+    `active_ip` varchar(100) GENERATED ALWAYS AS (IF(`status` = 'deleted', NULL, `ip_address`)) VIRTUAL,
+    `active_ssh_port` int(11) GENERATED ALWAYS AS (IF(`status` = 'deleted', NULL, `ssh_port`)) VIRTUAL,
     PRIMARY KEY (`id`),
-    KEY `user_id` (`user_id`),
+    UNIQUE KEY `vm_name` (`vm_name`),
+    UNIQUE KEY `active_ip` (`active_ip`),
+    UNIQUE KEY `active_ssh_port` (`active_ssh_port`),
+    KEY `user_id_name` (`user_id`, `name`),
     CONSTRAINT `hostings_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
-    ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
\ No newline at end of file
+    ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `ssh_keys` (
+                                          `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `user_id` bigint(20) NOT NULL,
+    `name` varchar(100) NOT NULL,
+    `public_key` text NOT NULL,
+    `fingerprint` varchar(100) NOT NULL,
+    `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (`id`),
+    KEY `user_id` (`user_id`),
+    CONSTRAINT `ssh_keys_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+    ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `snapshots` (
+                                           `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `hosting_id` bigint(20) NOT NULL,
+    `name` varchar(32) NOT NULL,
+    `description` varchar(255) NOT NULL DEFAULT '',
+    `vm_status` varchar(50) NOT NULL,
+    `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `hosting_id_name` (`hosting_id`, `name`),
+    CONSTRAINT `snapshots_ibfk_1` FOREIGN KEY (`hosting_id`) REFERENCES `hostings` (`id`) ON DELETE CASCADE
+    ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- VM을 삭제해도 백업은 남는다 (hostings 행은 status = 'deleted'로만 바뀐다)
+CREATE TABLE IF NOT EXISTS `backups` (
+                                         `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `user_id` bigint(20) NOT NULL,
+    `hosting_id` bigint(20) NOT NULL,
+    `name` varchar(100) NOT NULL,
+    `kind` enum('manual','scheduled') NOT NULL DEFAULT 'manual',
+    `plan` varchar(50) NOT NULL,
+    `path` text NOT NULL,
+    `size_bytes` bigint(20) NOT NULL DEFAULT 0,
+    -- 백업 파일이 있는 노드 (노드마다 백업 디렉토리가 따로 있다, 노드를 등록하기 전의 백업은 '')
+    `node_name` varchar(50) NOT NULL DEFAULT '',
+    `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (`id`),
+    KEY `user_id` (`user_id`),
+    KEY `hosting_id` (`hosting_id`),
+    CONSTRAINT `backups_ibfk_1` FOREIGN KEY (`hosting_id`) REFERENCES `hostings` (`id`) ON DELETE CASCADE
+    ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `backup_schedules` (
+                                                  `hosting_id` bigint(20) NOT NULL,
+    `cron` varchar(100) NOT NULL,
+    `keep_daily` int(11) NOT NULL DEFAULT 7,
+    `keep_weekly` int(11) NOT NULL DEFAULT 4,
+    `last_run_at` timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (`hosting_id`),
+    CONSTRAINT `backup_schedules_ibfk_1` FOREIGN KEY (`hosting_id`) REFERENCES `hostings` (`id`) ON DELETE CASCADE
+    ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- VM 사용량 시계열. resolution(초)이 60이면 1분 값(1일 보관), 3600이면 1시간 평균(30일 보관)
+CREATE TABLE IF NOT EXISTS `vm_metrics` (
+                                            `hosting_id` bigint(20) NOT NULL,
+    `resolution` int(11) NOT NULL,
+    `ts` timestamp NOT NULL DEFAULT current_timestamp(),
+    `cpu_percent` double NOT NULL,
+    `memory_kb` bigint(20) unsigned NOT NULL,
+    `memory_used_kb` bigint(20) unsigned NOT NULL,
+    `disk_read_bps` double NOT NULL,
+    `disk_write_bps` double NOT NULL,
+    `net_rx_bps` double NOT NULL,
+    `net_tx_bps` double NOT NULL,
+    PRIMARY KEY (`hosting_id`, `resolution`, `ts`),
+    KEY `resolution_ts` (`resolution`, `ts`),
+    CONSTRAINT `vm_metrics_ibfk_1` FOREIGN KEY (`hosting_id`) REFERENCES `hostings` (`id`) ON DELETE CASCADE
+    ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- user_id = 0 행은 기본 할당량
+CREATE TABLE IF NOT EXISTS `quotas` (
+                                        `user_id` bigint(20) NOT NULL,
+    `max_vms` int(11) NOT NULL,
+    `max_vcpus` int(11) NOT NULL,
+    `max_memory_mb` int(11) NOT NULL,
+    `max_disk_gb` int(11) NOT NULL,
+    PRIMARY KEY (`user_id`)
+    ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+INSERT IGNORE INTO `quotas` (`user_id`, `max_vms`, `max_vcpus`, `max_memory_mb`, `max_disk_gb`) VALUES
+    (0, 3, 8, 8192, 100);
+
+CREATE TABLE IF NOT EXISTS `operations` (
+                                            `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `user_id` bigint(20) NOT NULL,
+    `kind` varchar(50) NOT NULL,
+    `target` varchar(255) NOT NULL,
+    `vm_name` varchar(100) NOT NULL,
+    `status` varchar(50) NOT NULL,
+    `step` varchar(50) NOT NULL DEFAULT '',
+    `error` text NOT NULL,
+    `params` text NOT NULL,
+    `result` text NOT NULL,
+    `rollback` text NOT NULL,
+    `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+    `updated_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp(),
+    PRIMARY KEY (`id`),
+    KEY `status` (`status`)
+    ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
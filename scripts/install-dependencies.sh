@@ -1,7 +1,7 @@
 #!/bin/bash
 
 sudo apt update
-sudo apt install -y libvirt-daemon-system libvirt-clients virtinst qemu-kvm genisoimage
+sudo apt install -y libvirt-daemon-system libvirt-clients virtinst qemu-kvm ovmf
 
 sudo usermod -aG libvirt $(whoami)
 
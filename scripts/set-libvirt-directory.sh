@@ -4,43 +4,15 @@ set -e
 
 # 기본 경로
 BASE_DIR="/var/lib/libvirt/images"
-CLOUD_INIT_SHARE="$BASE_DIR/cloud-init/share"
 TEMPLATES_DIR="$BASE_DIR/templates"
-USER_DATA="$CLOUD_INIT_SHARE/user-data"
 UBUNTU_IMG="$TEMPLATES_DIR/ubuntu-22.04-server-cloudimg-amd64.img"
 UBUNTU_IMG_URL="https://cloud-images.ubuntu.com/releases/22.04/release/ubuntu-22.04-server-cloudimg-amd64.img"
 
 echo "🔧 디렉토리 생성..."
-mkdir -p "$CLOUD_INIT_SHARE"
 mkdir -p "$BASE_DIR/instances"
 mkdir -p "$TEMPLATES_DIR"
 
-echo "📄 user-data 작성..."
-cat > "$USER_DATA" <<EOF
-#cloud-config
-
-package_update: true
-package_upgrade: true
-
-packages:
-  - nginx
-
-users:
-  - name: ubuntu
-    plain_text_passwd: 'ubuntu'
-    lock_passwd: false
-    shell: /bin/bash
-    sudo: ["ALL=(ALL) NOPASSWD:ALL"]
-chpasswd:
-  expire: false
-ssh_pwauth: true
-
-runcmd:
-  - systemctl enable nginx
-  - systemctl start nginx
-  - ln -s /var/www/html /home/ubuntu/www
-  - chown -R ubuntu:ubuntu /var/www/html
-EOF
+# user-data는 VM마다 관리 서버가 생성하므로 공용 파일을 만들지 않는다
 
 echo "📥 Ubuntu Cloud Image 다운로드..."
 if [ ! -f "$UBUNTU_IMG" ]; then
@@ -51,36 +23,7 @@ fi
 
 echo "✅ 초기화 완료!"
 
-sudo mkdir -p /etc/libvirt/templates
-
-sudo tee "/etc/libvirt/templates/domain_template.xml" > /dev/null <<EOF
-<domain type='kvm'>
-    <name>{{.Name}}</name>
-    <memory unit='MiB'>{{.MemoryMB}}</memory>
-    <vcpu>{{.VCPUs}}</vcpu>
-    <os>
-        <type arch='x86_64'>hvm</type>
-        <boot dev='hd'/>
-    </os>
-    <devices>
-        <disk type='file' device='disk'>
-            <driver name='qemu' type='qcow2'/>
-            <source file='{{.DiskPath}}'/>
-            <target dev='vda' bus='virtio'/>
-        </disk>
-        <disk type='file' device='cdrom'>
-            <driver name='qemu' type='raw'/>
-            <source file='{{.ISOPath}}'/>
-            <target dev='sda' bus='sata'/>
-            <readonly/>
-        </disk>
-        <interface type='network'>
-            <source network='default'/>
-            <model type='virtio'/>
-        </interface>
-    </devices>
-</domain>
-EOF
+# 도메인 XML은 libvirt-agent가 VM마다 직접 만든다 (pkg/libvirt/domainxml.go)
 
 # 현재 사용자
 CURRENT_USER=$(whoami)
@@ -1,12 +1,38 @@
 #!/bin/bash
 set -e
 
-# 변수 설정
-UBUNTU_VERSION="22.04"
-IMAGE_NAME="ubuntu-${UBUNTU_VERSION}-server-cloudimg-amd64.img"
-IMAGE_URL="https://cloud-images.ubuntu.com/releases/${UBUNTU_VERSION}/release/${IMAGE_NAME}"
+# 사용법: sudo ./ubuntu-image-download.sh [이미지 이름]  (기본: ubuntu-22.04)
+# 받은 파일은 배포처의 체크섬으로 확인한 뒤, POST /images 에 쓸 등록 정보를 출력한다.
+IMAGE="${1:-ubuntu-22.04}"
 TEMPLATE_DIR="/var/lib/libvirt/images/templates"
 
+# 이미지 카탈로그: OS 종류, 버전, 파일 이름, 다운로드 위치, 체크섬 파일, 체크섬 명령, 기본 사용자
+case "${IMAGE}" in
+    ubuntu-22.04|ubuntu-24.04)
+        OS_FAMILY="ubuntu"
+        VERSION="${IMAGE#ubuntu-}"
+        IMAGE_NAME="ubuntu-${VERSION}-server-cloudimg-amd64.img"
+        BASE_URL="https://cloud-images.ubuntu.com/releases/${VERSION}/release"
+        SUMS_FILE="SHA256SUMS"
+        SUM_CMD="sha256sum"
+        DEFAULT_USER="ubuntu"
+        ;;
+    debian-12)
+        OS_FAMILY="debian"
+        VERSION="12"
+        IMAGE_NAME="debian-12-generic-amd64.qcow2"
+        BASE_URL="https://cloud.debian.org/images/cloud/bookworm/latest"
+        SUMS_FILE="SHA512SUMS"
+        SUM_CMD="sha512sum"
+        DEFAULT_USER="debian"
+        ;;
+    *)
+        echo "[오류] 알 수 없는 이미지입니다: ${IMAGE}"
+        echo "      사용 가능: ubuntu-22.04, ubuntu-24.04, debian-12"
+        exit 1
+        ;;
+esac
+
 # libvirt 설치 확인 (virsh 명령 확인)
 if ! command -v virsh &> /dev/null; then
     echo "[오류] libvirt 또는 virsh 명령이 존재하지 않습니다."
@@ -27,13 +53,34 @@ mkdir -p "${TEMPLATE_DIR}"
 if [ -f "${TEMPLATE_DIR}/${IMAGE_NAME}" ]; then
     echo "[정보] 이미지가 이미 존재합니다: ${TEMPLATE_DIR}/${IMAGE_NAME}"
 else
-    echo "[다운로드] 이미지 받는 중: ${IMAGE_URL}"
-    curl -o "${TEMPLATE_DIR}/${IMAGE_NAME}" -L "${IMAGE_URL}"
+    echo "[다운로드] 이미지 받는 중: ${BASE_URL}/${IMAGE_NAME}"
+    curl -fL -o "${TEMPLATE_DIR}/${IMAGE_NAME}.part" "${BASE_URL}/${IMAGE_NAME}"
+    mv "${TEMPLATE_DIR}/${IMAGE_NAME}.part" "${TEMPLATE_DIR}/${IMAGE_NAME}"
     echo "[완료] 다운로드 완료: ${TEMPLATE_DIR}/${IMAGE_NAME}"
 fi
 
+# 배포처 체크섬으로 확인 (덮어쓴 파일이나 중간에 끊긴 다운로드를 걸러낸다)
+EXPECTED=$(curl -fsSL "${BASE_URL}/${SUMS_FILE}" | awk -v f="${IMAGE_NAME}" '$2 == f || $2 == "*"f {print $1}')
+if [ -z "${EXPECTED}" ]; then
+    echo "[오류] ${SUMS_FILE}에서 ${IMAGE_NAME}의 체크섬을 찾을 수 없습니다."
+    exit 1
+fi
+ACTUAL=$(${SUM_CMD} "${TEMPLATE_DIR}/${IMAGE_NAME}" | awk '{print $1}')
+if [ "${EXPECTED}" != "${ACTUAL}" ]; then
+    echo "[오류] 체크섬이 일치하지 않습니다. 파일을 지우고 다시 받으세요: ${TEMPLATE_DIR}/${IMAGE_NAME}"
+    exit 1
+fi
+echo "[확인] ${SUMS_FILE} 체크섬 일치"
+
 # 권한 확인 및 소유권 설정
 chown root:kvm "${TEMPLATE_DIR}/${IMAGE_NAME}"
 chmod 644 "${TEMPLATE_DIR}/${IMAGE_NAME}"
 
-echo "[완료] Ubuntu 클라우드 이미지가 성공적으로 준비되었습니다."
\ No newline at end of file
+# 관리 서버는 sha256으로 확인하므로 항상 sha256을 출력한다
+SHA256=$(sha256sum "${TEMPLATE_DIR}/${IMAGE_NAME}" | awk '{print $1}')
+
+echo "[완료] 클라우드 이미지가 성공적으로 준비되었습니다."
+echo "[등록] 관리자 토큰으로 다음 내용을 POST /images 에 보내세요:"
+cat <<EOF
+{"name": "${IMAGE}", "os_family": "${OS_FAMILY}", "version": "${VERSION}", "path": "${IMAGE_NAME}", "sha256": "${SHA256}", "default_user": "${DEFAULT_USER}", "seed_format": "iso"}
+EOF
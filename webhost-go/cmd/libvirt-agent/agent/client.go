@@ -0,0 +1,459 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"webhost-go/webhost-go/pkg/libvirt"
+
+	"golang.org/x/net/websocket"
+)
+
+// Client - compute 노드의 libvirt-agent HTTP API를 호출하는 클라이언트
+// LibvirtManager와 같은 메서드를 제공하므로 관리 서버에서 대신 사용할 수 있다.
+type Client struct {
+	addr   string
+	token  string
+	http   *http.Client
+	stream *http.Client // 이벤트 스트림용 (타임아웃 없음, ctx로 종료)
+}
+
+// NewClient - token은 agent의 -token-file(또는 LIBVIRT_AGENT_TOKEN)과 같은 값이어야 한다
+func NewClient(addr, token string) *Client {
+	tr := &tokenTransport{token: token, base: http.DefaultTransport}
+	return &Client{
+		addr:  addr,
+		token: token,
+		// 디스크 복사 + ISO 생성 + 부팅까지 기다려야 하므로 넉넉하게 잡는다
+		http:   &http.Client{Timeout: 5 * time.Minute, Transport: tr},
+		stream: &http.Client{Transport: tr},
+	}
+}
+
+// tokenTransport - 모든 요청에 Authorization: Bearer 헤더를 붙인다
+type tokenTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(req)
+}
+
+// StartUbuntuVMWithStaticIP - agent가 스트리밍하는 진행 단계를 progress로 전달한다
+func (c *Client) StartUbuntuVMWithStaticIP(cfg libvirt.VMConfig, staticIP net.IP, progress func(step string)) error {
+	data, err := json.Marshal(CreateRequest{
+		Name:        cfg.Name,
+		IP:          staticIP.String(),
+		VCPUs:       cfg.VCPUs,
+		MemoryMB:    cfg.MemoryMB,
+		DiskGB:      cfg.DiskGB,
+		Template:    cfg.Template,
+		SourceDisk:  cfg.SourceDisk,
+		CloudInit:   cfg.CloudInit,
+		UEFI:        cfg.UEFI,
+		VirtioRNG:   cfg.VirtioRNG,
+		CPUQuota:    cfg.CPUQuota,
+		VNCPassword: cfg.VNCPassword,
+	})
+	if err != nil {
+		return fmt.Errorf("libvirt-agent 전송 실패: JSON 변환 오류: %w", err)
+	}
+
+	resp, err := c.http.Post(fmt.Sprintf("http://%s/api/libvirt/create", c.addr), "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("libvirt-agent 요청 실패: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("libvirt-agent 오류 응답: %s", string(data))
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var ev ProgressEvent
+		if err := dec.Decode(&ev); err != nil {
+			return fmt.Errorf("libvirt-agent 응답이 중간에 끊겼습니다: %w", err)
+		}
+		switch {
+		case ev.Error != "":
+			return fmt.Errorf("libvirt-agent 오류 응답: %s", ev.Error)
+		case ev.Done:
+			return nil
+		case progress != nil:
+			progress(ev.Step)
+		}
+	}
+}
+
+// SubscribeLifecycle - agent의 이벤트 스트림(NDJSON)을 채널로 전달한다.
+// ctx가 끝나거나 연결이 끊기면 채널이 닫힌다.
+func (c *Client) SubscribeLifecycle(ctx context.Context) (<-chan libvirt.LifecycleEvent, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://%s/api/libvirt/events", c.addr), nil)
+	if err != nil {
+		return nil, fmt.Errorf("요청 생성 실패: %w", err)
+	}
+	resp, err := c.stream.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("libvirt-agent 요청 실패: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("libvirt-agent 오류 응답: %s", string(data))
+	}
+
+	ch := make(chan libvirt.LifecycleEvent, 64)
+	go func() {
+		defer close(ch)
+		defer resp.Body.Close()
+		dec := json.NewDecoder(resp.Body)
+		for {
+			var ev libvirt.LifecycleEvent
+			if err := dec.Decode(&ev); err != nil {
+				return
+			}
+			select {
+			case ch <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// OpenConsole - agent의 콘솔 WebSocket에 연결한다 (바이너리 프레임으로 주고받는다)
+func (c *Client) OpenConsole(name string) (io.ReadWriteCloser, error) {
+	return c.dialStream("/api/libvirt/console/" + url.PathEscape(name))
+}
+
+// OpenVNC - agent가 중계하는 도메인 VNC 서버에 연결한다 (RFB 그대로)
+func (c *Client) OpenVNC(name string) (io.ReadWriteCloser, error) {
+	return c.dialStream("/api/libvirt/vnc/" + url.PathEscape(name))
+}
+
+func (c *Client) dialStream(path string) (io.ReadWriteCloser, error) {
+	cfg, err := websocket.NewConfig(fmt.Sprintf("ws://%s%s", c.addr, path), fmt.Sprintf("http://%s/", c.addr))
+	if err != nil {
+		return nil, fmt.Errorf("libvirt-agent 스트림 연결 실패: %w", err)
+	}
+	cfg.Header.Set("Authorization", "Bearer "+c.token)
+	ws, err := websocket.DialConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("libvirt-agent 스트림 연결 실패: %w", err)
+	}
+	ws.PayloadType = websocket.BinaryFrame
+	return ws, nil
+}
+
+func (c *Client) DeleteDomain(name string, withDisks bool) error {
+	path := fmt.Sprintf("/api/libvirt/destroy/%s?disks=%s", url.PathEscape(name), strconv.FormatBool(withDisks))
+	return c.do(http.MethodDelete, path, nil, nil)
+}
+
+func (c *Client) Start(name string) error {
+	return c.do(http.MethodPost, "/api/libvirt/start/"+url.PathEscape(name), nil, nil)
+}
+
+func (c *Client) Shutdown(name string) error {
+	return c.do(http.MethodPost, "/api/libvirt/stop/"+url.PathEscape(name), nil, nil)
+}
+
+func (c *Client) Destroy(name string) error {
+	return c.do(http.MethodPost, "/api/libvirt/poweroff/"+url.PathEscape(name), nil, nil)
+}
+
+func (c *Client) Reboot(name string) error {
+	return c.do(http.MethodPost, "/api/libvirt/reboot/"+url.PathEscape(name), nil, nil)
+}
+
+func (c *Client) Reset(name string) error {
+	return c.do(http.MethodPost, "/api/libvirt/reset/"+url.PathEscape(name), nil, nil)
+}
+
+func (c *Client) Suspend(name string) error {
+	return c.do(http.MethodPost, "/api/libvirt/suspend/"+url.PathEscape(name), nil, nil)
+}
+
+func (c *Client) SetAutostart(name string, enabled bool) error {
+	return c.do(http.MethodPost, "/api/libvirt/autostart/"+url.PathEscape(name), AutostartRequest{Enabled: enabled}, nil)
+}
+
+func (c *Client) Resume(domainName string) error {
+	return c.do(http.MethodPost, "/api/libvirt/resume/"+url.PathEscape(domainName), nil, nil)
+}
+
+func (c *Client) ResizeDomain(name string, vcpus, memoryMB, diskGB int) (bool, error) {
+	req := ResizeRequest{VCPUs: vcpus, MemoryMB: memoryMB, DiskGB: diskGB}
+	var resp ResizeResponse
+	if err := c.do(http.MethodPost, "/api/libvirt/resize/"+url.PathEscape(name), req, &resp); err != nil {
+		return false, err
+	}
+	return resp.Live, nil
+}
+
+func (c *Client) DomainIsActive(name string) (bool, error) {
+	var resp StatusResponse
+	if err := c.do(http.MethodGet, "/api/libvirt/status/"+url.PathEscape(name), nil, &resp); err != nil {
+		return false, err
+	}
+	return resp.Active, nil
+}
+
+func (c *Client) GetDomainInfoByName(name string) (*libvirt.DomainInfo, error) {
+	var info libvirt.DomainInfo
+	if err := c.do(http.MethodGet, "/api/libvirt/info/"+url.PathEscape(name), nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (c *Client) ListDomains() ([]libvirt.DomainSummary, error) {
+	var list []libvirt.DomainSummary
+	if err := c.do(http.MethodGet, "/api/libvirt/domains", nil, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (c *Client) GetAllDomainStats() ([]libvirt.DomainStats, error) {
+	var list []libvirt.DomainStats
+	if err := c.do(http.MethodGet, "/api/libvirt/stats", nil, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (c *Client) GuestInterfaceAddresses(name string) ([]libvirt.GuestInterface, error) {
+	var list []libvirt.GuestInterface
+	if err := c.do(http.MethodGet, "/api/libvirt/guest/"+url.PathEscape(name)+"/interfaces", nil, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (c *Client) GetGuestInfo(name string) (*libvirt.GuestInfo, error) {
+	var info libvirt.GuestInfo
+	if err := c.do(http.MethodGet, "/api/libvirt/guest/"+url.PathEscape(name)+"/info", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (c *Client) SetUserPassword(name, user, password string) error {
+	req := GuestPasswordRequest{User: user, Password: password}
+	return c.do(http.MethodPost, "/api/libvirt/guest/"+url.PathEscape(name)+"/password", req, nil)
+}
+
+func (c *Client) SetGuestNetwork(name string, ip net.IP) error {
+	req := GuestNetworkRequest{IP: ip.String()}
+	return c.do(http.MethodPost, "/api/libvirt/guest/"+url.PathEscape(name)+"/network", req, nil)
+}
+
+func (c *Client) CreateSnapshot(domainName, snapshotName, description string) error {
+	req := SnapshotRequest{Name: snapshotName, Description: description}
+	return c.do(http.MethodPost, "/api/libvirt/snapshots/"+url.PathEscape(domainName), req, nil)
+}
+
+func (c *Client) ListSnapshots(domainName string) ([]libvirt.SnapshotSummary, error) {
+	var list []libvirt.SnapshotSummary
+	if err := c.do(http.MethodGet, "/api/libvirt/snapshots/"+url.PathEscape(domainName), nil, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (c *Client) RevertSnapshot(domainName, snapshotName string) error {
+	path := fmt.Sprintf("/api/libvirt/snapshots/%s/%s/revert", url.PathEscape(domainName), url.PathEscape(snapshotName))
+	return c.do(http.MethodPost, path, nil, nil)
+}
+
+func (c *Client) DeleteSnapshot(domainName, snapshotName string) error {
+	path := fmt.Sprintf("/api/libvirt/snapshots/%s/%s", url.PathEscape(domainName), url.PathEscape(snapshotName))
+	return c.do(http.MethodDelete, path, nil, nil)
+}
+
+func (c *Client) BackupDisk(domainName, backupName string) (string, int64, error) {
+	var resp BackupResponse
+	if err := c.do(http.MethodPost, "/api/libvirt/backup/"+url.PathEscape(domainName), BackupRequest{Name: backupName}, &resp); err != nil {
+		return "", 0, err
+	}
+	return resp.Path, resp.SizeBytes, nil
+}
+
+func (c *Client) RestoreDisk(domainName, backupPath string) error {
+	return c.do(http.MethodPost, "/api/libvirt/restore/"+url.PathEscape(domainName), RestoreRequest{Path: backupPath}, nil)
+}
+
+func (c *Client) DeleteBackup(backupPath string) error {
+	return c.do(http.MethodDelete, "/api/libvirt/backups?path="+url.QueryEscape(backupPath), nil, nil)
+}
+
+func (c *Client) FlattenDisk(domainName string) error {
+	return c.do(http.MethodPost, "/api/libvirt/flatten/"+url.PathEscape(domainName), nil, nil)
+}
+
+// ExportInstance - agent가 만든 인스턴스 아카이브(tar)를 스트림으로 받는다 (디스크 크기만큼 걸리므로 타임아웃 없음)
+func (c *Client) ExportInstance(name, content string) (io.ReadCloser, error) {
+	resp, err := c.stream.Get(fmt.Sprintf("http://%s/api/libvirt/export/%s?content=%s", c.addr, url.PathEscape(name), url.QueryEscape(content)))
+	if err != nil {
+		return nil, fmt.Errorf("libvirt-agent 요청 실패: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("libvirt-agent 오류 응답: %s", string(data))
+	}
+	return resp.Body, nil
+}
+
+// ImportInstance - 인스턴스 아카이브를 agent로 보낸다 (타임아웃 없음).
+// 도메인 정의에 쓸 cfg는 본문이 아니라 ImportConfigHeader로 보낸다
+func (c *Client) ImportInstance(cfg libvirt.VMConfig, r io.Reader, define bool) error {
+	data, err := json.Marshal(ImportRequest{
+		VCPUs:       cfg.VCPUs,
+		MemoryMB:    cfg.MemoryMB,
+		SeedFormat:  cfg.CloudInit.SeedFormat,
+		UEFI:        cfg.UEFI,
+		VirtioRNG:   cfg.VirtioRNG,
+		CPUQuota:    cfg.CPUQuota,
+		VNCPassword: cfg.VNCPassword,
+	})
+	if err != nil {
+		return fmt.Errorf("libvirt-agent 전송 실패: JSON 변환 오류: %w", err)
+	}
+	path := fmt.Sprintf("/api/libvirt/import/%s?define=%s", url.PathEscape(cfg.Name), strconv.FormatBool(define))
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s%s", c.addr, path), r)
+	if err != nil {
+		return fmt.Errorf("요청 생성 실패: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-tar")
+	req.Header.Set(ImportConfigHeader, string(data))
+	return c.send(c.stream, req, nil)
+}
+
+// MigrateDomain - 메모리와 디스크를 복사하는 동안 기다리므로 타임아웃 없이 보낸다
+func (c *Client) MigrateDomain(name, destURI string, copyStorage bool) error {
+	data, err := json.Marshal(MigrateRequest{DestURI: destURI, CopyStorage: copyStorage})
+	if err != nil {
+		return fmt.Errorf("libvirt-agent 전송 실패: JSON 변환 오류: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s/api/libvirt/migrate/%s", c.addr, url.PathEscape(name)), bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("요청 생성 실패: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.send(c.stream, req, nil)
+}
+
+func (c *Client) DiscardInstance(name string, withFiles bool) error {
+	return c.do(http.MethodPost, "/api/libvirt/discard/"+url.PathEscape(name), DiscardRequest{WithFiles: withFiles}, nil)
+}
+
+func (c *Client) ListTemplates() ([]libvirt.TemplateSummary, error) {
+	var list []libvirt.TemplateSummary
+	if err := c.do(http.MethodGet, "/api/libvirt/templates", nil, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (c *Client) TemplateChecksum(name string) (string, error) {
+	var resp ChecksumResponse
+	if err := c.do(http.MethodGet, "/api/libvirt/templates/"+url.PathEscape(name)+"/sha256", nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.SHA256, nil
+}
+
+func (c *Client) DeleteTemplate(name string) error {
+	return c.do(http.MethodDelete, "/api/libvirt/templates/"+url.PathEscape(name), nil, nil)
+}
+
+func (c *Client) GetUsableIPs(used []net.IP) ([]net.IP, error) {
+	req := UsableIPsRequest{}
+	for _, ip := range used {
+		req.Used = append(req.Used, ip.String())
+	}
+
+	var resp UsableIPsResponse
+	if err := c.do(http.MethodPost, "/api/libvirt/usable-ips", req, &resp); err != nil {
+		return nil, err
+	}
+
+	var ips []net.IP
+	for _, s := range resp.IPs {
+		if ip := net.ParseIP(s); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
+
+// do - JSON 요청을 보내고 200이 아니면 응답 본문을 에러로 돌려준다.
+// 503은 게스트 에이전트 없음(libvirt.ErrGuestAgentUnavailable)으로,
+// 422는 스냅샷을 지원하지 않는 도메인(libvirt.ErrSnapshotUnsupported)으로 돌려준다.
+func (c *Client) do(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("libvirt-agent 전송 실패: JSON 변환 오류: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, fmt.Sprintf("http://%s%s", c.addr, path), body)
+	if err != nil {
+		return fmt.Errorf("요청 생성 실패: %w", err)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.send(c.http, req, out)
+}
+
+// send - do와 같지만 요청과 클라이언트를 직접 받는다 (스트림 본문, 오래 걸리는 요청)
+func (c *Client) send(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("libvirt-agent 요청 실패: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusServiceUnavailable {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%w: %s", libvirt.ErrGuestAgentUnavailable, string(data))
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%w: %s", libvirt.ErrDomainNotFound, string(data))
+	}
+	if resp.StatusCode == http.StatusUnprocessableEntity {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%w: %s", libvirt.ErrSnapshotUnsupported, string(data))
+	}
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("libvirt-agent 오류 응답: %s", string(data))
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("libvirt-agent 응답 파싱 실패: %w", err)
+		}
+	}
+	return nil
+}
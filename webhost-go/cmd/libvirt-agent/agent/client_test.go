@@ -0,0 +1,269 @@
+package agent_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"webhost-go/webhost-go/cmd/libvirt-agent/agent"
+	"webhost-go/webhost-go/pkg/libvirt"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestClient_CreateAndUsableIPs(t *testing.T) {
+	var created agent.CreateRequest
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/libvirt/create", func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+			t.Errorf("decode create request: %v", err)
+		}
+		enc := json.NewEncoder(w)
+		_ = enc.Encode(agent.ProgressEvent{Step: "copying_disk"})
+		_ = enc.Encode(agent.ProgressEvent{Step: "booting"})
+		_ = enc.Encode(agent.ProgressEvent{Done: true})
+	})
+	mux.HandleFunc("/api/libvirt/usable-ips", func(w http.ResponseWriter, r *http.Request) {
+		var req agent.UsableIPsRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Used) != 1 || req.Used[0] != "192.168.122.2" {
+			t.Errorf("unexpected used list: %v", req.Used)
+		}
+		_ = json.NewEncoder(w).Encode(agent.UsableIPsResponse{IPs: []string{"192.168.122.3"}})
+	})
+	mux.HandleFunc("/api/libvirt/status/vm1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error":"domain not found"}`))
+	})
+	mux.HandleFunc("/api/libvirt/info/vm2", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":"domain not found: vm2"}`))
+	})
+	mux.HandleFunc("/api/libvirt/info/vm3", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error":"domain info failed: connection reset"}`))
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client := agent.NewClient(strings.TrimPrefix(srv.URL, "http://"), testToken)
+
+	// 1. create
+	var steps []string
+	cfg := libvirt.VMConfig{Name: "vm1", VCPUs: 2, MemoryMB: 2048, DiskGB: 20}
+	if err := client.StartUbuntuVMWithStaticIP(cfg, net.ParseIP("192.168.122.3"), func(step string) {
+		steps = append(steps, step)
+	}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if created.Name != "vm1" || created.IP != "192.168.122.3" || created.VCPUs != 2 || created.MemoryMB != 2048 || created.DiskGB != 20 {
+		t.Errorf("unexpected create request: %+v", created)
+	}
+	if strings.Join(steps, ",") != "copying_disk,booting" {
+		t.Errorf("unexpected progress steps: %v", steps)
+	}
+
+	// 2. usable ips
+	ips, err := client.GetUsableIPs([]net.IP{net.ParseIP("192.168.122.2")})
+	if err != nil {
+		t.Fatalf("usable ips failed: %v", err)
+	}
+	if len(ips) != 1 || !ips[0].Equal(net.ParseIP("192.168.122.3")) {
+		t.Errorf("unexpected usable ips: %v", ips)
+	}
+
+	// 3. error response is surfaced
+	if _, err := client.DomainIsActive("vm1"); err == nil || !strings.Contains(err.Error(), "domain not found") {
+		t.Errorf("expected agent error, got %v", err)
+	}
+
+	// 4. 404만 ErrDomainNotFound이다 (다른 오류로 도메인이 없다고 판단하면 안 된다)
+	if _, err := client.GetDomainInfoByName("vm2"); !errors.Is(err, libvirt.ErrDomainNotFound) {
+		t.Errorf("expected ErrDomainNotFound, got %v", err)
+	}
+	if _, err := client.GetDomainInfoByName("vm3"); err == nil || errors.Is(err, libvirt.ErrDomainNotFound) {
+		t.Errorf("expected a plain agent error, got %v", err)
+	}
+}
+
+func TestClient_GuestAgent(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/libvirt/guest/vm1/interfaces", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]libvirt.GuestInterface{
+			{Name: "enp1s0", MAC: "52:54:00:12:34:56", Addresses: []string{"192.168.122.3/24"}},
+		})
+	})
+	mux.HandleFunc("/api/libvirt/guest/vm2/info", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"error":"guest agent is not connected"}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client := agent.NewClient(strings.TrimPrefix(srv.URL, "http://"), testToken)
+
+	ifaces, err := client.GuestInterfaceAddresses("vm1")
+	if err != nil {
+		t.Fatalf("guest interfaces failed: %v", err)
+	}
+	if len(ifaces) != 1 || ifaces[0].Addresses[0] != "192.168.122.3/24" {
+		t.Errorf("unexpected interfaces: %+v", ifaces)
+	}
+
+	// 503은 ErrGuestAgentUnavailable로 돌아와야 호출한 쪽이 대체 경로로 간다
+	if _, err := client.GetGuestInfo("vm2"); !errors.Is(err, libvirt.ErrGuestAgentUnavailable) {
+		t.Errorf("expected ErrGuestAgentUnavailable, got %v", err)
+	}
+}
+
+func TestClient_OpenConsole(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/api/libvirt/console/vm1", websocket.Server{Handler: func(ws *websocket.Conn) {
+		ws.PayloadType = websocket.BinaryFrame
+		_, _ = io.Copy(ws, ws) // echo
+	}})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client := agent.NewClient(strings.TrimPrefix(srv.URL, "http://"), testToken)
+	con, err := client.OpenConsole("vm1")
+	if err != nil {
+		t.Fatalf("open console failed: %v", err)
+	}
+	defer con.Close()
+
+	if _, err := con.Write([]byte("\x1b[Ahello\r")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	buf := make([]byte, 64)
+	n, err := con.Read(buf)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if got := string(buf[:n]); got != "\x1b[Ahello\r" {
+		t.Errorf("unexpected echo: %q", got)
+	}
+
+	if _, err := client.OpenConsole("missing"); err == nil {
+		t.Error("expected error for unknown console")
+	}
+}
+
+func TestClient_ExportImport(t *testing.T) {
+	var imported, define string
+	var importReq agent.ImportRequest
+	var migrate agent.MigrateRequest
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/libvirt/export/vm1", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("content") != libvirt.ArchiveSkeleton {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"error":"unexpected content"}`))
+			return
+		}
+		_, _ = w.Write([]byte("archive"))
+	})
+	mux.HandleFunc("/api/libvirt/import/vm1", func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		imported, define = string(data), r.URL.Query().Get("define")
+		_ = json.Unmarshal([]byte(r.Header.Get(agent.ImportConfigHeader)), &importReq)
+		_, _ = w.Write([]byte(`{"message":"domain imported"}`))
+	})
+	mux.HandleFunc("/api/libvirt/migrate/vm1", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&migrate)
+		_, _ = w.Write([]byte(`{"message":"domain migrated"}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client := agent.NewClient(strings.TrimPrefix(srv.URL, "http://"), testToken)
+
+	// 1. export 스트림을 그대로 import 본문으로 보낸다
+	r, err := client.ExportInstance("vm1", libvirt.ArchiveSkeleton)
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	cfg := libvirt.VMConfig{Name: "vm1", VCPUs: 2, MemoryMB: 2048, CloudInit: libvirt.CloudInit{SeedFormat: "vfat"}, UEFI: true, VNCPassword: "Xk3pQ9wz"}
+	if err := client.ImportInstance(cfg, r, false); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	r.Close()
+	if imported != "archive" || define != "false" {
+		t.Errorf("unexpected import: %q define=%q", imported, define)
+	}
+	// 도메인 정의에 쓸 사양은 본문이 아니라 헤더로 간다
+	want := agent.ImportRequest{VCPUs: 2, MemoryMB: 2048, SeedFormat: "vfat", UEFI: true, VNCPassword: "Xk3pQ9wz"}
+	if importReq != want {
+		t.Errorf("unexpected import config: %+v", importReq)
+	}
+
+	// 2. 오류 응답은 스트림 대신 에러로 돌아온다
+	if _, err := client.ExportInstance("vm1", libvirt.ArchiveFull); err == nil || !strings.Contains(err.Error(), "unexpected content") {
+		t.Errorf("expected export error, got %v", err)
+	}
+
+	// 3. migrate
+	if err := client.MigrateDomain("vm1", "qemu+tcp://10.0.0.3/system", true); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	if migrate.DestURI != "qemu+tcp://10.0.0.3/system" || !migrate.CopyStorage {
+		t.Errorf("unexpected migrate request: %+v", migrate)
+	}
+}
+
+const testToken = "0123456789abcdef"
+
+func TestClient_Token(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/libvirt/domains", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[]`))
+	})
+	mux.HandleFunc("/api/libvirt/export/vm1", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("archive"))
+	})
+	mux.Handle("/api/libvirt/console/vm1", websocket.Server{Handler: func(ws *websocket.Conn) {}})
+	// agent의 requireToken과 같은 검사
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"missing or invalid token"}`))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	// 1. JSON 요청, 스트림, WebSocket 모두 토큰을 보낸다
+	client := agent.NewClient(addr, testToken)
+	if _, err := client.ListDomains(); err != nil {
+		t.Errorf("list domains failed: %v", err)
+	}
+	r, err := client.ExportInstance("vm1", libvirt.ArchiveFull)
+	if err != nil {
+		t.Errorf("export failed: %v", err)
+	} else {
+		r.Close()
+	}
+	con, err := client.OpenConsole("vm1")
+	if err != nil {
+		t.Errorf("open console failed: %v", err)
+	} else {
+		con.Close()
+	}
+
+	// 2. 토큰이 다르면 거절된다
+	wrong := agent.NewClient(addr, "wrong-token-value")
+	if _, err := wrong.ListDomains(); err == nil || !strings.Contains(err.Error(), "invalid token") {
+		t.Errorf("expected token error, got %v", err)
+	}
+	if _, err := wrong.OpenConsole("vm1"); err == nil {
+		t.Error("expected console to be rejected")
+	}
+}
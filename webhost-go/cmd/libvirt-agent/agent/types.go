@@ -0,0 +1,129 @@
+package agent
+
+import "webhost-go/webhost-go/pkg/libvirt"
+
+// CreateRequest - VM 생성 요청 (POST /api/libvirt/create)
+type CreateRequest struct {
+	Name     string `json:"name" binding:"required"`
+	IP       string `json:"ip" binding:"required"`
+	VCPUs    int    `json:"vcpus" binding:"required,min=1"`
+	MemoryMB int    `json:"memory_mb" binding:"required,min=1"`
+	DiskGB   int    `json:"disk_gb" binding:"required,min=1"`
+	// Template - 템플릿 디렉토리 안의 이미지 파일 (비어 있으면 기본 템플릿)
+	Template string `json:"template,omitempty"`
+	// SourceDisk - 템플릿 대신 복사할 백업 파일 (agent의 백업 디렉토리 안)
+	SourceDisk string `json:"source_disk,omitempty"`
+	// CloudInit - VM 전용 user-data 입력 (계정, SSH 키, 사용자 cloud-config)
+	CloudInit libvirt.CloudInit `json:"cloud_init"`
+	// 플랜/이미지가 켜는 선택 기능
+	UEFI      bool `json:"uefi,omitempty"`
+	VirtioRNG bool `json:"virtio_rng,omitempty"`
+	CPUQuota  int  `json:"cpu_quota,omitempty" binding:"min=0,max=100"`
+	// VNCPassword - localhost VNC 장치의 비밀번호 (비어 있으면 VNC 없음)
+	VNCPassword string `json:"vnc_password,omitempty"`
+}
+
+// ResizeRequest - vCPU/메모리/디스크 변경 요청 (POST /api/libvirt/resize/:name)
+type ResizeRequest struct {
+	VCPUs    int `json:"vcpus" binding:"required,min=1"`
+	MemoryMB int `json:"memory_mb" binding:"required,min=1"`
+	DiskGB   int `json:"disk_gb" binding:"required,min=1"`
+}
+
+// ResizeResponse - Live가 false면 다음 부팅부터 적용된다
+type ResizeResponse struct {
+	Live bool `json:"live"`
+}
+
+// AutostartRequest - 호스트 부팅 시 자동 시작 여부 (POST /api/libvirt/autostart/:name)
+type AutostartRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// SnapshotRequest - 스냅샷 생성 요청 (POST /api/libvirt/snapshots/:name)
+type SnapshotRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+// BackupRequest - 루트 디스크 백업 요청 (POST /api/libvirt/backup/:name)
+type BackupRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type BackupResponse struct {
+	Path      string `json:"path"`
+	SizeBytes int64  `json:"size_bytes"`
+}
+
+// RestoreRequest - 중지된 도메인의 디스크를 백업으로 교체 (POST /api/libvirt/restore/:name)
+type RestoreRequest struct {
+	Path string `json:"path" binding:"required"`
+}
+
+// MigrateRequest - 다른 노드의 libvirtd로 live migration (POST /api/libvirt/migrate/:name)
+// 인스턴스 파일은 GET /api/libvirt/export/:name?content=... 의 tar 스트림을
+// POST /api/libvirt/import/:name?define=... 로 보내 옮긴다 (도메인 정의는 ImportConfigHeader의 ImportRequest로 만든다)
+type MigrateRequest struct {
+	DestURI     string `json:"dest_uri" binding:"required"`
+	CopyStorage bool   `json:"copy_storage"`
+}
+
+// ImportConfigHeader - import 요청 본문은 tar 스트림이므로 ImportRequest(JSON)는 이 헤더로 보낸다
+const ImportConfigHeader = "X-Import-Config"
+
+// ImportRequest - 옮겨 온 도메인을 정의할 때 쓰는 사양 (아카이브의 domain.xml은 MAC만 쓴다)
+type ImportRequest struct {
+	VCPUs    int `json:"vcpus" binding:"required,min=1"`
+	MemoryMB int `json:"memory_mb" binding:"required,min=1"`
+	// SeedFormat - 이미지의 seed 형식 ("iso", "vfat")
+	SeedFormat  string `json:"seed_format,omitempty"`
+	UEFI        bool   `json:"uefi,omitempty"`
+	VirtioRNG   bool   `json:"virtio_rng,omitempty"`
+	CPUQuota    int    `json:"cpu_quota,omitempty" binding:"min=0,max=100"`
+	VNCPassword string `json:"vnc_password,omitempty"`
+}
+
+// DiscardRequest - 꺼진 도메인의 정의와 인스턴스 파일 정리 (POST /api/libvirt/discard/:name)
+type DiscardRequest struct {
+	WithFiles bool `json:"with_files"`
+}
+
+// GuestPasswordRequest - 게스트 에이전트로 계정 비밀번호 변경 (POST /api/libvirt/guest/:name/password)
+type GuestPasswordRequest struct {
+	User     string `json:"user" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// GuestNetworkRequest - 게스트 에이전트로 고정 IP 변경 (POST /api/libvirt/guest/:name/network)
+type GuestNetworkRequest struct {
+	IP string `json:"ip" binding:"required,ip"`
+}
+
+// StatusResponse - 도메인 실행 여부 (GET /api/libvirt/status/:name)
+type StatusResponse struct {
+	Name   string `json:"name"`
+	Active bool   `json:"active"`
+}
+
+// UsableIPsRequest - 이미 사용 중인 IP 목록 (POST /api/libvirt/usable-ips)
+type UsableIPsRequest struct {
+	Used []string `json:"used"`
+}
+
+type UsableIPsResponse struct {
+	IPs []string `json:"ips"`
+}
+
+// ProgressEvent - create 응답으로 한 줄씩 전송되는 진행 상황 (NDJSON)
+// 마지막 줄은 Done 또는 Error가 채워진다.
+type ProgressEvent struct {
+	Step  string `json:"step,omitempty"`
+	Done  bool   `json:"done,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// ChecksumResponse - 템플릿 파일 체크섬 (GET /api/libvirt/templates/:name/sha256)
+type ChecksumResponse struct {
+	SHA256 string `json:"sha256"`
+}
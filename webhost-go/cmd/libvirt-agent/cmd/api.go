@@ -0,0 +1,613 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"webhost-go/webhost-go/cmd/libvirt-agent/agent"
+	"webhost-go/webhost-go/pkg/libvirt"
+
+	"golang.org/x/net/websocket"
+)
+
+type Server struct {
+	Manager *libvirt.LibvirtManager
+	// Token - every request must carry "Authorization: Bearer <Token>"
+	Token string
+}
+
+func (s *Server) RegisterRoutes(router *gin.Engine) {
+	router.Use(s.requireToken)
+	router.POST("/api/libvirt/create", s.createDomain)
+	router.POST("/api/libvirt/start/:name", s.startDomain)
+	router.POST("/api/libvirt/stop/:name", s.stopDomain)
+	router.POST("/api/libvirt/poweroff/:name", s.powerOffDomain)
+	router.POST("/api/libvirt/reboot/:name", s.rebootDomain)
+	router.POST("/api/libvirt/reset/:name", s.resetDomain)
+	router.POST("/api/libvirt/suspend/:name", s.suspendDomain)
+	router.POST("/api/libvirt/resume/:name", s.resumeDomain)
+	router.POST("/api/libvirt/autostart/:name", s.autostartDomain)
+	router.POST("/api/libvirt/resize/:name", s.resizeDomain)
+	router.DELETE("/api/libvirt/destroy/:name", s.destroyDomain)
+	router.GET("/api/libvirt/status/:name", s.domainStatus)
+	router.GET("/api/libvirt/info/:name", s.domainInfo)
+	router.GET("/api/libvirt/domains", s.listDomains)
+	router.GET("/api/libvirt/stats", s.domainStats)
+	router.GET("/api/libvirt/events", s.streamEvents)
+	router.GET("/api/libvirt/console/:name", s.console)
+	router.GET("/api/libvirt/vnc/:name", s.vnc)
+	router.GET("/api/libvirt/guest/:name/interfaces", s.guestInterfaces)
+	router.GET("/api/libvirt/guest/:name/info", s.guestInfo)
+	router.POST("/api/libvirt/guest/:name/password", s.guestPassword)
+	router.POST("/api/libvirt/guest/:name/network", s.guestNetwork)
+	router.GET("/api/libvirt/snapshots/:name", s.listSnapshots)
+	router.POST("/api/libvirt/snapshots/:name", s.createSnapshot)
+	router.POST("/api/libvirt/snapshots/:name/:snapshot/revert", s.revertSnapshot)
+	router.DELETE("/api/libvirt/snapshots/:name/:snapshot", s.deleteSnapshot)
+	router.POST("/api/libvirt/backup/:name", s.backupDomain)
+	router.POST("/api/libvirt/restore/:name", s.restoreDomain)
+	router.DELETE("/api/libvirt/backups", s.deleteBackup)
+	router.POST("/api/libvirt/flatten/:name", s.flattenDomain)
+	router.GET("/api/libvirt/export/:name", s.exportDomain)
+	router.POST("/api/libvirt/import/:name", s.importDomain)
+	router.POST("/api/libvirt/migrate/:name", s.migrateDomain)
+	router.POST("/api/libvirt/discard/:name", s.discardDomain)
+	router.GET("/api/libvirt/templates", s.listTemplates)
+	router.GET("/api/libvirt/templates/:name/sha256", s.templateChecksum)
+	router.DELETE("/api/libvirt/templates/:name", s.deleteTemplate)
+	router.POST("/api/libvirt/usable-ips", s.usableIPs)
+}
+
+// requireToken - the agent can define domains and write to consoles, so nothing is served without the shared token
+func (s *Server) requireToken(c *gin.Context) {
+	got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(s.Token)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing or invalid token"})
+		return
+	}
+	c.Next()
+}
+
+func (s *Server) createDomain(c *gin.Context) {
+	var req agent.CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ip := net.ParseIP(req.IP)
+	if ip == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ip: " + req.IP})
+		return
+	}
+
+	// progress is streamed as NDJSON, so the status code is always 200
+	// and a failure is reported in the last line.
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	enc := json.NewEncoder(c.Writer)
+	send := func(ev agent.ProgressEvent) {
+		_ = enc.Encode(ev)
+		c.Writer.Flush()
+	}
+
+	cfg := libvirt.VMConfig{
+		Name:        req.Name,
+		VCPUs:       req.VCPUs,
+		MemoryMB:    req.MemoryMB,
+		DiskGB:      req.DiskGB,
+		Template:    req.Template,
+		SourceDisk:  req.SourceDisk,
+		CloudInit:   req.CloudInit,
+		UEFI:        req.UEFI,
+		VirtioRNG:   req.VirtioRNG,
+		CPUQuota:    req.CPUQuota,
+		VNCPassword: req.VNCPassword,
+	}
+	err := s.Manager.StartUbuntuVMWithStaticIP(cfg, ip, func(step string) {
+		send(agent.ProgressEvent{Step: step})
+	})
+	if err != nil {
+		log.Errorf("domain create failed (%s): %v", req.Name, err)
+		send(agent.ProgressEvent{Error: "domain create failed: " + err.Error()})
+		return
+	}
+	send(agent.ProgressEvent{Done: true})
+}
+
+// startDomain - boots a shut-off domain, or resumes a paused one
+func (s *Server) startDomain(c *gin.Context) {
+	if err := s.Manager.Start(c.Param("name")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "domain start failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "domain started"})
+}
+
+// stopDomain - only requests a guest shutdown; the caller waits for shutoff
+func (s *Server) stopDomain(c *gin.Context) {
+	if err := s.Manager.Shutdown(c.Param("name")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "domain shutdown failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "domain shutdown requested"})
+}
+
+func (s *Server) powerOffDomain(c *gin.Context) {
+	if err := s.Manager.Destroy(c.Param("name")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "domain power off failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "domain powered off"})
+}
+
+func (s *Server) rebootDomain(c *gin.Context) {
+	if err := s.Manager.Reboot(c.Param("name")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "domain reboot failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "domain reboot requested"})
+}
+
+func (s *Server) resetDomain(c *gin.Context) {
+	if err := s.Manager.Reset(c.Param("name")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "domain reset failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "domain reset"})
+}
+
+func (s *Server) suspendDomain(c *gin.Context) {
+	if err := s.Manager.Suspend(c.Param("name")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "domain suspend failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "domain suspended"})
+}
+
+func (s *Server) resumeDomain(c *gin.Context) {
+	if err := s.Manager.Resume(c.Param("name")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "domain resume failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "domain resumed"})
+}
+
+func (s *Server) autostartDomain(c *gin.Context) {
+	var req agent.AutostartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.Manager.SetAutostart(c.Param("name"), req.Enabled); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "domain autostart failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "domain autostart updated"})
+}
+
+func (s *Server) resizeDomain(c *gin.Context) {
+	var req agent.ResizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	live, err := s.Manager.ResizeDomain(c.Param("name"), req.VCPUs, req.MemoryMB, req.DiskGB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "domain resize failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, agent.ResizeResponse{Live: live})
+}
+
+func (s *Server) destroyDomain(c *gin.Context) {
+	withDisks, err := strconv.ParseBool(c.DefaultQuery("disks", "true"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid disks flag: " + err.Error()})
+		return
+	}
+
+	if err := s.Manager.DeleteDomain(c.Param("name"), withDisks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "domain delete failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "domain deleted"})
+}
+
+func (s *Server) domainStatus(c *gin.Context) {
+	name := c.Param("name")
+	active, err := s.Manager.DomainIsActive(name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "domain status failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, agent.StatusResponse{Name: name, Active: active})
+}
+
+func (s *Server) domainInfo(c *gin.Context) {
+	info, err := s.Manager.GetDomainInfoByName(c.Param("name"))
+	if errors.Is(err, libvirt.ErrDomainNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "domain not found: " + err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "domain info failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+func (s *Server) listDomains(c *gin.Context) {
+	list, err := s.Manager.ListDomains()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "domain list failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// domainStats - cumulative CPU, memory, block and interface counters of every running domain
+func (s *Server) domainStats(c *gin.Context) {
+	list, err := s.Manager.GetAllDomainStats()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "domain stats failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// streamEvents - lifecycle events are streamed as NDJSON until the client disconnects
+func (s *Server) streamEvents(c *gin.Context) {
+	events, err := s.Manager.SubscribeLifecycle(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "event subscribe failed: " + err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+	enc := json.NewEncoder(c.Writer)
+	for ev := range events {
+		if err := enc.Encode(ev); err != nil {
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// console - relays the serial console over a WebSocket until either side closes
+func (s *Server) console(c *gin.Context) {
+	con, err := s.Manager.OpenConsole(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "console open failed: " + err.Error()})
+		return
+	}
+	relay(c, "console", con)
+}
+
+// vnc - relays the domain's localhost VNC server (raw RFB) over a WebSocket
+func (s *Server) vnc(c *gin.Context) {
+	conn, err := s.Manager.OpenVNC(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "vnc open failed: " + err.Error()})
+		return
+	}
+	relay(c, "vnc", conn)
+}
+
+// relay - upgrades the request and copies binary frames both ways, then closes stream
+func relay(c *gin.Context, kind string, stream io.ReadWriteCloser) {
+	defer stream.Close()
+	name := c.Param("name")
+
+	websocket.Server{Handler: func(ws *websocket.Conn) {
+		ws.PayloadType = websocket.BinaryFrame
+		done := make(chan struct{}, 2)
+		go func() {
+			_, _ = io.Copy(ws, stream)
+			done <- struct{}{}
+		}()
+		go func() {
+			_, _ = io.Copy(stream, ws)
+			done <- struct{}{}
+		}()
+		<-done
+		log.Infof("%s closed (%s)", kind, name)
+	}}.ServeHTTP(c.Writer, c.Request)
+}
+
+// guestInterfaces - interface addresses reported by the qemu-guest-agent
+func (s *Server) guestInterfaces(c *gin.Context) {
+	list, err := s.Manager.GuestInterfaceAddresses(c.Param("name"))
+	if err != nil {
+		c.JSON(guestStatus(err), gin.H{"error": "guest interfaces failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// guestInfo - hostname and OS release reported by the qemu-guest-agent
+func (s *Server) guestInfo(c *gin.Context) {
+	info, err := s.Manager.GetGuestInfo(c.Param("name"))
+	if err != nil {
+		c.JSON(guestStatus(err), gin.H{"error": "guest info failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+func (s *Server) guestPassword(c *gin.Context) {
+	var req agent.GuestPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.Manager.SetUserPassword(c.Param("name"), req.User, req.Password); err != nil {
+		c.JSON(guestStatus(err), gin.H{"error": "guest password failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "guest password set"})
+}
+
+func (s *Server) guestNetwork(c *gin.Context) {
+	var req agent.GuestNetworkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.Manager.SetGuestNetwork(c.Param("name"), net.ParseIP(req.IP)); err != nil {
+		c.JSON(guestStatus(err), gin.H{"error": "guest network failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "guest network set"})
+}
+
+// guestStatus - 503 tells the client the guest agent is missing, so callers can fall back
+func guestStatus(err error) int {
+	if errors.Is(err, libvirt.ErrGuestAgentUnavailable) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+func (s *Server) listSnapshots(c *gin.Context) {
+	list, err := s.Manager.ListSnapshots(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "snapshot list failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func (s *Server) createSnapshot(c *gin.Context) {
+	var req agent.SnapshotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.Manager.CreateSnapshot(c.Param("name"), req.Name, req.Description); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, libvirt.ErrSnapshotUnsupported) {
+			// 422 tells the client this domain can never be snapshotted (not a transient failure)
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, gin.H{"error": "snapshot create failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "snapshot created"})
+}
+
+func (s *Server) revertSnapshot(c *gin.Context) {
+	if err := s.Manager.RevertSnapshot(c.Param("name"), c.Param("snapshot")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "snapshot revert failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "snapshot reverted"})
+}
+
+func (s *Server) deleteSnapshot(c *gin.Context) {
+	if err := s.Manager.DeleteSnapshot(c.Param("name"), c.Param("snapshot")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "snapshot delete failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "snapshot deleted"})
+}
+
+func (s *Server) backupDomain(c *gin.Context) {
+	var req agent.BackupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	path, size, err := s.Manager.BackupDisk(c.Param("name"), req.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "domain backup failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, agent.BackupResponse{Path: path, SizeBytes: size})
+}
+
+func (s *Server) restoreDomain(c *gin.Context) {
+	var req agent.RestoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.Manager.RestoreDisk(c.Param("name"), req.Path); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "domain restore failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "domain restored"})
+}
+
+func (s *Server) deleteBackup(c *gin.Context) {
+	path := c.Query("path")
+	if path == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "path is required"})
+		return
+	}
+
+	if err := s.Manager.DeleteBackup(path); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "backup delete failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "backup deleted"})
+}
+
+func (s *Server) flattenDomain(c *gin.Context) {
+	if err := s.Manager.FlattenDisk(c.Param("name")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "domain flatten failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "domain disk flattened"})
+}
+
+func (s *Server) exportDomain(c *gin.Context) {
+	r, err := s.Manager.ExportInstance(c.Param("name"), c.DefaultQuery("content", libvirt.ArchiveFull))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "domain export failed: " + err.Error()})
+		return
+	}
+	defer r.Close()
+
+	c.Header("Content-Type", "application/x-tar")
+	c.Status(http.StatusOK)
+	if _, err := io.Copy(c.Writer, r); err != nil {
+		// 이미 200을 보냈으므로 연결을 끊어 받는 쪽이 잘린 아카이브를 쓰지 않게 한다
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func (s *Server) importDomain(c *gin.Context) {
+	define, err := strconv.ParseBool(c.DefaultQuery("define", "true"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid define flag: " + err.Error()})
+		return
+	}
+
+	var req agent.ImportRequest
+	if err := json.Unmarshal([]byte(c.GetHeader(agent.ImportConfigHeader)), &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + agent.ImportConfigHeader + " header: " + err.Error()})
+		return
+	}
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cfg := libvirt.VMConfig{
+		Name:        c.Param("name"),
+		VCPUs:       req.VCPUs,
+		MemoryMB:    req.MemoryMB,
+		CloudInit:   libvirt.CloudInit{SeedFormat: req.SeedFormat},
+		UEFI:        req.UEFI,
+		VirtioRNG:   req.VirtioRNG,
+		CPUQuota:    req.CPUQuota,
+		VNCPassword: req.VNCPassword,
+	}
+	if err := s.Manager.ImportInstance(cfg, c.Request.Body, define); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "domain import failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "domain imported"})
+}
+
+func (s *Server) migrateDomain(c *gin.Context) {
+	var req agent.MigrateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.Manager.MigrateDomain(c.Param("name"), req.DestURI, req.CopyStorage); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "domain migrate failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "domain migrated"})
+}
+
+func (s *Server) discardDomain(c *gin.Context) {
+	var req agent.DiscardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.Manager.DiscardInstance(c.Param("name"), req.WithFiles); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "domain discard failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "domain discarded"})
+}
+
+func (s *Server) listTemplates(c *gin.Context) {
+	list, err := s.Manager.ListTemplates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "template list failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func (s *Server) templateChecksum(c *gin.Context) {
+	sum, err := s.Manager.TemplateChecksum(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "template checksum failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, agent.ChecksumResponse{SHA256: sum})
+}
+
+func (s *Server) deleteTemplate(c *gin.Context) {
+	if err := s.Manager.DeleteTemplate(c.Param("name")); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, libvirt.ErrTemplateInUse) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": "template delete failed: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "template deleted"})
+}
+
+func (s *Server) usableIPs(c *gin.Context) {
+	var req agent.UsableIPsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var used []net.IP
+	for _, str := range req.Used {
+		if ip := net.ParseIP(str); ip != nil {
+			used = append(used, ip)
+		}
+	}
+
+	ips, err := s.Manager.GetUsableIPs(used)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "usable ip lookup failed: " + err.Error()})
+		return
+	}
+
+	resp := agent.UsableIPsResponse{IPs: []string{}}
+	for _, ip := range ips {
+		resp.IPs = append(resp.IPs, ip.String())
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"github.com/gin-gonic/gin"
+	"github.com/op/go-logging"
+	"os"
+	"strings"
+	"webhost-go/webhost-go/pkg/libvirt"
+)
+
+var (
+	log    = logging.MustGetLogger("api")
+	format = logging.MustStringFormatter(
+		`%{color}[%{level:.4s}] %{time:2006/01/02 - 15:04:05}%{color:reset} ▶ %{message}`,
+	)
+)
+
+func initLogger() {
+	backend1 := logging.NewLogBackend(os.Stderr, "", 0)
+	backend2 := logging.NewLogBackend(os.Stderr, "", 0)
+
+	backend2Formatter := logging.NewBackendFormatter(backend2, format)
+
+	// Only errors and more severe messages should be sent to backend1
+	backend1Leveled := logging.AddModuleLevel(backend1)
+	backend1Leveled.SetLevel(logging.ERROR, "")
+
+	logging.SetBackend(backend1Leveled, backend2Formatter)
+}
+
+func main() {
+	backupDir := flag.String("backup-dir", libvirt.DefaultBackupDir, "directory for VM disk backups")
+	listen := flag.String("listen", ":5004", "address to listen on (e.g. 10.0.0.2:5004 to serve only the management network)")
+	tokenFile := flag.String("token-file", "", "file holding the shared API token (default: $LIBVIRT_AGENT_TOKEN)")
+	flag.Parse()
+
+	// Initialize logger.
+	initLogger()
+	log.Info("Successfully initialized logger")
+
+	// Disk images and cloud-init ISOs live under /var/lib/libvirt. We need root permission.
+	if os.Geteuid() != 0 {
+		log.Error("Please run this program as root")
+		return
+	}
+
+	// The agent runs as root and can define arbitrary domains, so refuse to start without a token.
+	token, err := loadToken(*tokenFile)
+	if err != nil {
+		log.Fatalf("Failed to load token: %v", err)
+	}
+
+	// connect to the local libvirt daemon
+	manager, err := libvirt.NewLibvirtManager()
+	if err != nil {
+		log.Fatalf("Failed to connect to libvirt: %v", err)
+	}
+	manager.BackupDir = *backupDir
+
+	router := gin.Default()
+
+	server := &Server{Manager: manager, Token: token}
+	server.RegisterRoutes(router)
+
+	if err := router.Run(*listen); err != nil {
+		log.Fatalf("Failed to run server: %v", err)
+	}
+}
+
+// loadToken - reads the token from path, or from $LIBVIRT_AGENT_TOKEN when path is empty
+func loadToken(path string) (string, error) {
+	token := os.Getenv("LIBVIRT_AGENT_TOKEN")
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		token = string(data)
+	}
+	token = strings.TrimSpace(token)
+	if len(token) < 16 {
+		return "", errors.New("token must be at least 16 characters (set -token-file or LIBVIRT_AGENT_TOKEN)")
+	}
+	return token, nil
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	_ "github.com/go-sql-driver/mysql"
 	"log"
+	"os"
 	"time"
 	"webhost-go/webhost-go/internal/dependency_injector"
 )
@@ -23,6 +24,9 @@ func main() {
 		},
 		JWTSecret: "outcider112@dankook.ac.kr",
 		TokenTTL:  30 * time.Minute,
+
+		LibvirtAgentAddr:  "localhost:5004",
+		LibvirtAgentToken: os.Getenv("LIBVIRT_AGENT_TOKEN"),
 	}
 
 	// 2. DI 컨테이너 생성
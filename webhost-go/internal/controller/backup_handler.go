@@ -0,0 +1,198 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+	"webhost-go/webhost-go/internal/services/user_service"
+)
+
+type BackupHandler struct {
+	HostingService hosting_service.Service
+	UserService    user_service.Service
+}
+
+// CreateBackupRequest - vm은 VM ID 또는 이름
+type CreateBackupRequest struct {
+	VM string `json:"vm" binding:"required"`
+}
+
+// CloneBackupRequest - plan이 없으면 백업 시점의 플랜을 사용한다
+type CloneBackupRequest struct {
+	Name string `json:"name" binding:"required"`
+	Plan string `json:"plan"`
+}
+
+type BackupScheduleRequest struct {
+	Cron       string `json:"cron" binding:"required"`
+	KeepDaily  int    `json:"keep_daily"`
+	KeepWeekly int    `json:"keep_weekly"`
+}
+
+func NewBackupHandler(h hosting_service.Service, u user_service.Service) *BackupHandler {
+	return &BackupHandler{HostingService: h, UserService: u}
+}
+
+// GET /hosting/:username/backups?vm=<ID 또는 이름>
+func (h *BackupHandler) ListBackups(c *gin.Context) {
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	backups, err := h.HostingService.ListBackups(user.ID, c.Query("vm"))
+	if err != nil {
+		hostingError(c, "백업 목록 조회 실패", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"backups": backups})
+}
+
+// POST /hosting/:username/backups
+func (h *BackupHandler) CreateBackup(c *gin.Context) {
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	var req CreateBackupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 요청 형식입니다: " + err.Error()})
+		return
+	}
+
+	op, err := h.HostingService.CreateBackup(user.ID, req.VM)
+	if err != nil {
+		hostingError(c, "백업 생성 실패", err)
+		return
+	}
+	accepted(c, "백업 요청 접수", op)
+}
+
+// POST /hosting/:username/backups/:backupID/restore - 원래 VM의 디스크를 백업으로 교체
+func (h *BackupHandler) RestoreBackup(c *gin.Context) {
+	user, id, ok := h.targetBackup(c)
+	if !ok {
+		return
+	}
+
+	op, err := h.HostingService.RestoreBackup(user.ID, id)
+	if err != nil {
+		hostingError(c, "백업 복원 실패", err)
+		return
+	}
+	accepted(c, "백업 복원 요청 접수", op)
+}
+
+// POST /hosting/:username/backups/:backupID/clone - 백업으로 새 VM 생성
+func (h *BackupHandler) CloneBackup(c *gin.Context) {
+	user, id, ok := h.targetBackup(c)
+	if !ok {
+		return
+	}
+
+	var req CloneBackupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 요청 형식입니다: " + err.Error()})
+		return
+	}
+
+	op, err := h.HostingService.CloneBackup(user.ID, id, req.Name, req.Plan)
+	if err != nil {
+		hostingError(c, "백업으로 VM 생성 실패", err)
+		return
+	}
+	accepted(c, "백업으로 VM 생성 요청 접수", op)
+}
+
+// DELETE /hosting/:username/backups/:backupID
+func (h *BackupHandler) DeleteBackup(c *gin.Context) {
+	user, id, ok := h.targetBackup(c)
+	if !ok {
+		return
+	}
+
+	op, err := h.HostingService.DeleteBackup(user.ID, id)
+	if err != nil {
+		hostingError(c, "백업 삭제 실패", err)
+		return
+	}
+	accepted(c, "백업 삭제 요청 접수", op)
+}
+
+// GET /hosting/:username/vms/:vmID/backup-schedule
+func (h *BackupHandler) GetSchedule(c *gin.Context) {
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	sched, err := h.HostingService.GetBackupSchedule(user.ID, c.Param("vmID"))
+	if err != nil {
+		hostingError(c, "백업 일정 조회 실패", err)
+		return
+	}
+	c.JSON(http.StatusOK, sched)
+}
+
+// PUT /hosting/:username/vms/:vmID/backup-schedule
+func (h *BackupHandler) SetSchedule(c *gin.Context) {
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	var req BackupScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 요청 형식입니다: " + err.Error()})
+		return
+	}
+
+	sched := &hosting_service.BackupSchedule{
+		Cron:       req.Cron,
+		KeepDaily:  req.KeepDaily,
+		KeepWeekly: req.KeepWeekly,
+	}
+	if err := h.HostingService.SetBackupSchedule(user.ID, c.Param("vmID"), sched); err != nil {
+		hostingError(c, "백업 일정 설정 실패", err)
+		return
+	}
+	c.JSON(http.StatusOK, sched)
+}
+
+// DELETE /hosting/:username/vms/:vmID/backup-schedule
+func (h *BackupHandler) DeleteSchedule(c *gin.Context) {
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.HostingService.DeleteBackupSchedule(user.ID, c.Param("vmID")); err != nil {
+		hostingError(c, "백업 일정 삭제 실패", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "백업 일정 삭제 완료"})
+}
+
+func (h *BackupHandler) targetUser(c *gin.Context) (*user_service.User, bool) {
+	user, err := h.UserService.GetUserByEmail(c.Param("username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "유저 정보를 불러올 수 없습니다: " + err.Error()})
+		return nil, false
+	}
+	return user, true
+}
+
+func (h *BackupHandler) targetBackup(c *gin.Context) (*user_service.User, int64, bool) {
+	id, err := strconv.ParseInt(c.Param("backupID"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 백업 ID입니다"})
+		return nil, 0, false
+	}
+	user, ok := h.targetUser(c)
+	if !ok {
+		return nil, 0, false
+	}
+	return user, id, true
+}
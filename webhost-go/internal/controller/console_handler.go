@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+	"webhost-go/webhost-go/internal/services/user_service"
+
+	"golang.org/x/net/websocket"
+)
+
+type ConsoleHandler struct {
+	HostingService hosting_service.Service
+	UserService    user_service.Service
+}
+
+// ConsoleTicketRequest - read_only면 출력만 볼 수 있는 티켓을 받는다 (본문 생략 가능)
+type ConsoleTicketRequest struct {
+	ReadOnly bool `json:"read_only"`
+}
+
+// VNCTicketRequest - VM은 숫자 ID 또는 이름
+type VNCTicketRequest struct {
+	VM string `json:"vm" binding:"required"`
+}
+
+func NewConsoleHandler(h hosting_service.Service, u user_service.Service) *ConsoleHandler {
+	return &ConsoleHandler{HostingService: h, UserService: u}
+}
+
+// POST /hosting/:username/vms/:vmID/console/ticket
+func (h *ConsoleHandler) IssueTicket(c *gin.Context) {
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	var req ConsoleTicketRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 요청 형식입니다: " + err.Error()})
+			return
+		}
+	}
+
+	ticket, err := h.HostingService.IssueConsoleTicket(user.ID, c.Param("vmID"), !req.ReadOnly)
+	if err != nil {
+		hostingError(c, "콘솔 티켓 발급 실패", err)
+		return
+	}
+	c.JSON(http.StatusCreated, ticket)
+}
+
+// GET /hosting/:username/console?ticket=... - 시리얼 콘솔 WebSocket (xterm.js 등)
+// JWT 대신 티켓으로 인증하며, 콘솔 출력은 바이너리 프레임으로 보낸다.
+// 읽기 전용 접속자가 보낸 입력은 버린다.
+func (h *ConsoleHandler) Console(c *gin.Context) {
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	client, err := h.HostingService.AttachConsole(user.ID, c.Query("ticket"))
+	if err != nil {
+		hostingError(c, "콘솔 연결 실패", err)
+		return
+	}
+	defer client.Close()
+
+	websocket.Server{Handler: func(ws *websocket.Conn) {
+		ws.PayloadType = websocket.BinaryFrame
+		go func() {
+			defer client.Close()
+			buf := make([]byte, 4096)
+			for {
+				n, err := ws.Read(buf)
+				if err != nil {
+					return
+				}
+				if !client.Writable() {
+					continue
+				}
+				if _, err := client.Write(buf[:n]); err != nil {
+					return
+				}
+			}
+		}()
+		for chunk := range client.Output() {
+			if _, err := ws.Write(chunk); err != nil {
+				return
+			}
+		}
+	}}.ServeHTTP(c.Writer, c.Request)
+}
+
+// POST /hosting/:username/vnc-ticket
+func (h *ConsoleHandler) IssueVNCTicket(c *gin.Context) {
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	var req VNCTicketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 요청 형식입니다: " + err.Error()})
+		return
+	}
+
+	ticket, err := h.HostingService.IssueVNCTicket(user.ID, req.VM)
+	if err != nil {
+		hostingError(c, "VNC 티켓 발급 실패", err)
+		return
+	}
+	c.JSON(http.StatusCreated, ticket)
+}
+
+// GET /hosting/:username/vnc?ticket=... - noVNC용 WebSocket-VNC 프록시
+// RFB를 바이너리 프레임으로 그대로 중계하며, VNC 인증은 noVNC가 티켓의 비밀번호로 한다.
+func (h *ConsoleHandler) VNC(c *gin.Context) {
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	conn, err := h.HostingService.ConnectVNC(user.ID, c.Query("ticket"))
+	if err != nil {
+		hostingError(c, "VNC 연결 실패", err)
+		return
+	}
+	defer conn.Close()
+
+	websocket.Server{
+		Handshake: vncHandshake,
+		Handler: func(ws *websocket.Conn) {
+			ws.PayloadType = websocket.BinaryFrame
+			done := make(chan struct{}, 2)
+			go func() {
+				_, _ = io.Copy(ws, conn)
+				done <- struct{}{}
+			}()
+			go func() {
+				_, _ = io.Copy(conn, ws)
+				done <- struct{}{}
+			}()
+			<-done
+		},
+	}.ServeHTTP(c.Writer, c.Request)
+}
+
+// vncHandshake - noVNC가 서브프로토콜을 제안하면 "binary"를 고른다 (여러 개를 돌려주면 브라우저가 연결을 끊는다).
+// 티켓으로 인증하므로 Origin은 확인하지 않는다.
+func vncHandshake(config *websocket.Config, _ *http.Request) error {
+	offered := config.Protocol
+	config.Protocol = nil
+	for _, p := range offered {
+		if p == "binary" {
+			config.Protocol = []string{p}
+			break
+		}
+	}
+	return nil
+}
+
+func (h *ConsoleHandler) targetUser(c *gin.Context) (*user_service.User, bool) {
+	user, err := h.UserService.GetUserByEmail(c.Param("username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "유저 정보를 불러올 수 없습니다: " + err.Error()})
+		return nil, false
+	}
+	return user, true
+}
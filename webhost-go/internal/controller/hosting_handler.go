@@ -1,8 +1,12 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"io"
 	"net/http"
+	"time"
 	"webhost-go/webhost-go/internal/services/hosting_service"
 	"webhost-go/webhost-go/internal/services/user_service"
 )
@@ -12,58 +16,99 @@ type HostingHandler struct {
 	UserService    user_service.Service
 }
 
+// sseKeepAlive - 이벤트가 없을 때 프록시가 연결을 끊지 않도록 보내는 ping 주기
+const sseKeepAlive = 30 * time.Second
+
+// CreateHostingRequest - plan/image가 없으면 기본 플랜/이미지를 사용한다.
+// user_data는 #cloud-config 문서로, 기본 설정 위에 병합된다.
 type CreateHostingRequest struct {
-	UserID   int64  `json:"user_id" binding:"required"`
-	Username string `json:"username" binding:"required"`
+	Name     string `json:"name" binding:"required"`
+	Plan     string `json:"plan"`
+	Image    string `json:"image"`
+	UserData string `json:"user_data"`
+}
+
+type ResizeVMRequest struct {
+	Plan string `json:"plan" binding:"required"`
+}
+
+// StopVMRequest - timeout_seconds가 없으면 기본 대기 시간 뒤 강제로 끈다
+type StopVMRequest struct {
+	TimeoutSeconds int `json:"timeout_seconds"`
 }
 
 func NewHostingHandler(h hosting_service.Service, u user_service.Service) *HostingHandler {
 	return &HostingHandler{HostingService: h, UserService: u}
 }
 
+// POST /hosting/:username/vms
 func (h *HostingHandler) CreateHosting(c *gin.Context) {
-	targetEmail := c.Param("username")
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
 
-	user, err := h.UserService.GetUserByEmail(targetEmail)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "유저 정보를 불러올 수 없습니다: " + err.Error()})
+	var req CreateHostingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 요청 형식입니다: " + err.Error()})
 		return
 	}
 
-	// VM 생성 및 DB 등록
-	hosting, err := h.HostingService.CreateHosting(user.ID, user.Email)
+	// VM 생성 작업 등록 (실제 생성은 워커가 처리)
+	op, err := h.HostingService.CreateHosting(user.ID, req.Name, hosting_service.CreateOptions{
+		Plan:     req.Plan,
+		Image:    req.Image,
+		UserData: req.UserData,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "호스팅 생성 실패: " + err.Error()})
+		hostingError(c, "호스팅 생성 실패", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":  "VM 생성 완료",
-		"hostname": hosting.VMName,
-		"ip":       hosting.IPAddress,
-		"ssh_port": hosting.SSHPort,
-		"proxy":    hosting.ProxyPath,
-	})
+	accepted(c, "VM 생성 요청 접수", op)
+}
+
+// GET /hosting/:username/vms
+func (h *HostingHandler) ListVMs(c *gin.Context) {
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	vms, err := h.HostingService.ListVMs(user.ID)
+	if err != nil {
+		hostingError(c, "VM 목록 조회 실패", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"vms": vms})
 }
 
+// GET /hosting/:username/vms/:vmID/status
 func (h *HostingHandler) GetVMStatus(c *gin.Context) {
-	email := c.Param("username")
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
 
-	active, err := h.HostingService.GetVMStatus(email)
+	active, err := h.HostingService.GetVMStatus(user.ID, c.Param("vmID"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "상태 조회 실패: " + err.Error()})
+		hostingError(c, "상태 조회 실패", err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"name": active.VMName, "running": active.Active})
 }
 
+// GET /hosting/:username/vms/:vmID
 func (h *HostingHandler) GetVMDetail(c *gin.Context) {
-	email := c.Param("username")
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
 
-	hosting, info, err := h.HostingService.GetVMDetail(email)
+	hosting, info, err := h.HostingService.GetVMDetail(user.ID, c.Param("vmID"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "상세 정보 조회 실패: " + err.Error()})
+		hostingError(c, "상세 정보 조회 실패", err)
 		return
 	}
 
@@ -73,29 +118,249 @@ func (h *HostingHandler) GetVMDetail(c *gin.Context) {
 	})
 }
 
+// POST /hosting/:username/vms/:vmID/password - 게스트 에이전트로 기본 계정 비밀번호 재설정
+// 새 비밀번호는 이 응답에서만 볼 수 있다.
+func (h *HostingHandler) ResetGuestPassword(c *gin.Context) {
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	password, err := h.HostingService.ResetGuestPassword(user.ID, c.Param("vmID"))
+	if err != nil {
+		hostingError(c, "비밀번호 재설정 실패", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "비밀번호 재설정 완료",
+		"password": password,
+	})
+}
+
+// POST /hosting/:username/vms/:vmID/resize
+func (h *HostingHandler) ResizeVM(c *gin.Context) {
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	var req ResizeVMRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 요청 형식입니다"})
+		return
+	}
+
+	op, err := h.HostingService.ResizeVM(user.ID, c.Param("vmID"), req.Plan)
+	if err != nil {
+		hostingError(c, "VM 사양 변경 실패", err)
+		return
+	}
+	accepted(c, "VM 사양 변경 요청 접수", op)
+}
+
+// POST /hosting/:username/vms/:vmID/start
 func (h *HostingHandler) StartVM(c *gin.Context) {
-	email := c.Param("username")
-	if err := h.HostingService.StartVM(email); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "VM 시작 실패: " + err.Error()})
+	user, ok := h.targetUser(c)
+	if !ok {
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "VM 시작 완료"})
+
+	op, err := h.HostingService.StartVM(user.ID, c.Param("vmID"))
+	if err != nil {
+		hostingError(c, "VM 시작 실패", err)
+		return
+	}
+	accepted(c, "VM 시작 요청 접수", op)
 }
 
+// POST /hosting/:username/vms/:vmID/stop
 func (h *HostingHandler) StopVM(c *gin.Context) {
-	email := c.Param("username")
-	if err := h.HostingService.StopVM(email); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "VM 중지 실패: " + err.Error()})
+	user, ok := h.targetUser(c)
+	if !ok {
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "VM 중지 완료"})
+
+	var req StopVMRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 요청 형식입니다: " + err.Error()})
+			return
+		}
+	}
+
+	op, err := h.HostingService.StopVM(user.ID, c.Param("vmID"), time.Duration(req.TimeoutSeconds)*time.Second)
+	if err != nil {
+		hostingError(c, "VM 중지 실패", err)
+		return
+	}
+	accepted(c, "VM 중지 요청 접수", op)
+}
+
+// POST /hosting/:username/vms/:vmID/reboot
+func (h *HostingHandler) RebootVM(c *gin.Context) {
+	h.powerAction(c, h.HostingService.RebootVM, "VM 재부팅")
+}
+
+// POST /hosting/:username/vms/:vmID/reset
+func (h *HostingHandler) ResetVM(c *gin.Context) {
+	h.powerAction(c, h.HostingService.ResetVM, "VM 리셋")
+}
+
+// POST /hosting/:username/vms/:vmID/pause
+func (h *HostingHandler) PauseVM(c *gin.Context) {
+	h.powerAction(c, h.HostingService.PauseVM, "VM 일시 정지")
+}
+
+// POST /hosting/:username/vms/:vmID/resume
+func (h *HostingHandler) ResumeVM(c *gin.Context) {
+	h.powerAction(c, h.HostingService.ResumeVM, "VM 재개")
+}
+
+// powerAction - 인자 없는 전원 작업 공통 처리
+func (h *HostingHandler) powerAction(c *gin.Context, action func(userID int64, ref string) (*hosting_service.Operation, error), name string) {
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	op, err := action(user.ID, c.Param("vmID"))
+	if err != nil {
+		hostingError(c, name+" 실패", err)
+		return
+	}
+	accepted(c, name+" 요청 접수", op)
 }
 
+// POST /hosting/:username/vms/:vmID/flatten
+func (h *HostingHandler) FlattenVM(c *gin.Context) {
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	op, err := h.HostingService.FlattenVM(user.ID, c.Param("vmID"))
+	if err != nil {
+		hostingError(c, "디스크 평탄화 실패", err)
+		return
+	}
+	accepted(c, "디스크 평탄화 요청 접수", op)
+}
+
+// DELETE /hosting/:username/vms/:vmID
 func (h *HostingHandler) DeleteVM(c *gin.Context) {
-	email := c.Param("username")
-	if err := h.HostingService.DeleteVM(email); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "VM 삭제 실패: " + err.Error()})
+	user, ok := h.targetUser(c)
+	if !ok {
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "VM 삭제 완료"})
+
+	op, err := h.HostingService.DeleteVM(user.ID, c.Param("vmID"))
+	if err != nil {
+		hostingError(c, "VM 삭제 실패", err)
+		return
+	}
+	accepted(c, "VM 삭제 요청 접수", op)
+}
+
+// GET /hosting/:username/events - VM 라이프사이클 이벤트를 Server-Sent Events로 전달
+func (h *HostingHandler) StreamEvents(c *gin.Context) {
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	events, cancel := h.HostingService.SubscribeEvents(user.ID)
+	defer cancel()
+
+	ping := time.NewTicker(sseKeepAlive)
+	defer ping.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // nginx 프록시 버퍼링 해제
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent("lifecycle", ev)
+			return true
+		case <-ping.C:
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// targetUser - 경로의 :username(이메일)에 해당하는 사용자 (권한 확인은 RequireSelfOrAdmin)
+func (h *HostingHandler) targetUser(c *gin.Context) (*user_service.User, bool) {
+	user, err := h.UserService.GetUserByEmail(c.Param("username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "유저 정보를 불러올 수 없습니다: " + err.Error()})
+		return nil, false
+	}
+	return user, true
+}
+
+// hostingError - 서비스 에러를 HTTP 상태 코드로 변환해 응답한다
+func hostingError(c *gin.Context, message string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, hosting_service.ErrVMNotFound),
+		errors.Is(err, hosting_service.ErrSnapshotNotFound),
+		errors.Is(err, hosting_service.ErrBackupNotFound),
+		errors.Is(err, hosting_service.ErrScheduleNotFound),
+		errors.Is(err, hosting_service.ErrSSHKeyNotFound),
+		errors.Is(err, hosting_service.ErrNodeNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, hosting_service.ErrPlanNotFound),
+		errors.Is(err, hosting_service.ErrImageNotFound),
+		errors.Is(err, hosting_service.ErrImageRetired),
+		errors.Is(err, hosting_service.ErrDiskShrink),
+		errors.Is(err, hosting_service.ErrInvalidVMName),
+		errors.Is(err, hosting_service.ErrInvalidSnapshotName),
+		errors.Is(err, hosting_service.ErrInvalidSchedule),
+		errors.Is(err, hosting_service.ErrInvalidSSHKey),
+		errors.Is(err, hosting_service.ErrInvalidUserData),
+		errors.Is(err, hosting_service.ErrInvalidMetricsQuery),
+		errors.Is(err, hosting_service.ErrInvalidStopTimeout),
+		errors.Is(err, hosting_service.ErrInvalidMigrationMode):
+		status = http.StatusBadRequest
+	case errors.Is(err, hosting_service.ErrVMNameTaken),
+		errors.Is(err, hosting_service.ErrQuotaExceeded),
+		errors.Is(err, hosting_service.ErrNoNodeAvailable),
+		errors.Is(err, hosting_service.ErrNodeCapacity),
+		errors.Is(err, hosting_service.ErrMigrationSameNode),
+		errors.Is(err, hosting_service.ErrMigrationSnapshots),
+		errors.Is(err, hosting_service.ErrSnapshotNameTaken),
+		errors.Is(err, hosting_service.ErrSnapshotLimit),
+		errors.Is(err, hosting_service.ErrSnapshotUnsupported),
+		errors.Is(err, hosting_service.ErrVMRunning),
+		errors.Is(err, hosting_service.ErrVMNotRunning),
+		errors.Is(err, hosting_service.ErrVMNotPaused),
+		errors.Is(err, hosting_service.ErrConsoleBusy),
+		errors.Is(err, hosting_service.ErrConsoleUnavailable),
+		errors.Is(err, hosting_service.ErrNoVNC),
+		errors.Is(err, hosting_service.ErrGuestAgentUnavailable):
+		status = http.StatusConflict
+	case errors.Is(err, hosting_service.ErrConsoleTicket),
+		errors.Is(err, hosting_service.ErrVNCTicket):
+		status = http.StatusUnauthorized
+	}
+	c.JSON(status, gin.H{"error": message + ": " + err.Error()})
+}
+
+// accepted - 작업이 큐에 들어갔음을 202로 알린다.
+// VM 생성 작업의 초기 비밀번호는 이 응답에서만 볼 수 있다.
+func accepted(c *gin.Context, message string, op *hosting_service.Operation) {
+	c.Header("Location", fmt.Sprintf("/operations/%d", op.ID))
+	res := gin.H{
+		"message":      message,
+		"operation_id": op.ID,
+	}
+	if op.Password != "" {
+		res["password"] = op.Password
+	}
+	c.JSON(http.StatusAccepted, res)
 }
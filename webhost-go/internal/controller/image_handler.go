@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+)
+
+type ImageHandler struct {
+	HostingService hosting_service.Service
+}
+
+func NewImageHandler(h hosting_service.Service) *ImageHandler {
+	return &ImageHandler{HostingService: h}
+}
+
+// GET /images
+func (h *ImageHandler) ListImages(c *gin.Context) {
+	images, err := h.HostingService.ListImages()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "이미지 목록 조회 실패: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"images": images})
+}
+
+// POST /images
+func (h *ImageHandler) RegisterImage(c *gin.Context) {
+	var img hosting_service.Image
+	if err := c.ShouldBindJSON(&img); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 요청 형식입니다"})
+		return
+	}
+	if err := img.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.HostingService.RegisterImage(&img); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "이미지 등록 실패: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, img)
+}
+
+// POST /images/:name/verify
+func (h *ImageHandler) VerifyImage(c *gin.Context) {
+	img, err := h.HostingService.VerifyImage(c.Param("name"))
+	switch {
+	case errors.Is(err, hosting_service.ErrImageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, hosting_service.ErrImageChecksum):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "이미지 확인 실패: " + err.Error()})
+	default:
+		c.JSON(http.StatusOK, img)
+	}
+}
+
+// DELETE /images/:name - 새 VM에 쓰지 않도록 retired로 바꾼다
+func (h *ImageHandler) RetireImage(c *gin.Context) {
+	err := h.HostingService.RetireImage(c.Param("name"))
+	switch {
+	case errors.Is(err, hosting_service.ErrImageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "이미지 사용 중지 실패: " + err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "이미지 사용 중지 완료"})
+	}
+}
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"time"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+	"webhost-go/webhost-go/internal/services/user_service"
+)
+
+// defaultMetricsRange - from을 생략하면 to 이전 1시간
+const defaultMetricsRange = time.Hour
+
+type MetricsHandler struct {
+	HostingService hosting_service.Service
+	UserService    user_service.Service
+}
+
+func NewMetricsHandler(h hosting_service.Service, u user_service.Service) *MetricsHandler {
+	return &MetricsHandler{HostingService: h, UserService: u}
+}
+
+// GET /hosting/:username/metrics?from=&to=&step=&vm=
+// from/to는 RFC3339 또는 유닉스 초, step은 "5m" 같은 기간 또는 초 (생략하면 자동)
+func (h *MetricsHandler) GetMetrics(c *gin.Context) {
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	q := hosting_service.MetricsQuery{VM: c.Query("vm"), To: time.Now()}
+	var err error
+	if v := c.Query("to"); v != "" {
+		if q.To, err = parseMetricsTime(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 to 값입니다: " + err.Error()})
+			return
+		}
+	}
+	q.From = q.To.Add(-defaultMetricsRange)
+	if v := c.Query("from"); v != "" {
+		if q.From, err = parseMetricsTime(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 from 값입니다: " + err.Error()})
+			return
+		}
+	}
+	if v := c.Query("step"); v != "" {
+		if q.Step, err = parseMetricsStep(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 step 값입니다: " + err.Error()})
+			return
+		}
+	}
+
+	metrics, err := h.HostingService.GetMetrics(user.ID, q)
+	if err != nil {
+		hostingError(c, "사용량 조회 실패", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"from": q.From,
+		"to":   q.To,
+		"vms":  metrics,
+	})
+}
+
+func parseMetricsTime(v string) (time.Time, error) {
+	if sec, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+func parseMetricsStep(v string) (time.Duration, error) {
+	if sec, err := strconv.Atoi(v); err == nil {
+		v = strconv.Itoa(sec) + "s"
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("양수여야 합니다: %s", v)
+	}
+	return d, nil
+}
+
+func (h *MetricsHandler) targetUser(c *gin.Context) (*user_service.User, bool) {
+	user, err := h.UserService.GetUserByEmail(c.Param("username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "유저 정보를 불러올 수 없습니다: " + err.Error()})
+		return nil, false
+	}
+	return user, true
+}
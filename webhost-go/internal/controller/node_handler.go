@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+)
+
+type NodeHandler struct {
+	HostingService hosting_service.Service
+}
+
+func NewNodeHandler(h hosting_service.Service) *NodeHandler {
+	return &NodeHandler{HostingService: h}
+}
+
+// GET /nodes
+func (h *NodeHandler) ListNodes(c *gin.Context) {
+	nodes, err := h.HostingService.ListNodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "노드 목록 조회 실패: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"nodes": nodes})
+}
+
+// POST /nodes
+func (h *NodeHandler) CreateNode(c *gin.Context) {
+	n := hosting_service.Node{Schedulable: true}
+	if err := c.ShouldBindJSON(&n); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 요청 형식입니다"})
+		return
+	}
+	if err := n.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.HostingService.CreateNode(&n); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "노드 등록 실패: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, n)
+}
+
+// PUT /nodes/:name
+func (h *NodeHandler) UpdateNode(c *gin.Context) {
+	n := hosting_service.Node{Schedulable: true}
+	if err := c.ShouldBindJSON(&n); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 요청 형식입니다"})
+		return
+	}
+	n.Name = c.Param("name")
+	if err := n.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.HostingService.UpdateNode(&n)
+	if errors.Is(err, hosting_service.ErrNodeNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "노드 수정 실패: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, n)
+}
+
+// DELETE /nodes/:name
+func (h *NodeHandler) DeleteNode(c *gin.Context) {
+	err := h.HostingService.DeleteNode(c.Param("name"))
+	switch {
+	case errors.Is(err, hosting_service.ErrNodeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, hosting_service.ErrNodeInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "노드 삭제 실패: " + err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "노드 삭제 완료"})
+	}
+}
+
+// POST /admin/hostings/:id/migrate {"node": "...", "mode": "live|cold"} (둘 다 생략 가능)
+func (h *NodeHandler) MigrateHosting(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 VM ID입니다"})
+		return
+	}
+
+	var opts hosting_service.MigrateOptions
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&opts); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 요청 형식입니다: " + err.Error()})
+			return
+		}
+	}
+
+	op, err := h.HostingService.MigrateVM(id, opts)
+	if err != nil {
+		hostingError(c, "VM 이전 실패", err)
+		return
+	}
+	accepted(c, "VM 이전 요청 접수", op)
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+	"webhost-go/webhost-go/internal/services/user_service"
+	"webhost-go/webhost-go/internal/services/user_service/authn/token"
+)
+
+type OperationHandler struct {
+	HostingService hosting_service.Service
+	UserService    user_service.Service
+}
+
+func NewOperationHandler(h hosting_service.Service, u user_service.Service) *OperationHandler {
+	return &OperationHandler{HostingService: h, UserService: u}
+}
+
+// GET /operations/:id
+func (h *OperationHandler) GetOperation(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 작업 ID입니다"})
+		return
+	}
+
+	op, err := h.HostingService.GetOperation(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "작업을 찾을 수 없습니다"})
+		return
+	}
+
+	// 본인 작업 또는 관리자만 조회 가능
+	auth := c.MustGet("auth").(*token.TokenValidationResult)
+	if auth.Claims.Role != token.RoleAdmin {
+		user, err := h.UserService.GetUserByEmail(auth.Claims.Email)
+		if err != nil || user.ID != op.UserID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "접근 권한이 없습니다"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, operationJSON(op))
+}
+
+// GET /operations/failed
+func (h *OperationHandler) ListFailedOperations(c *gin.Context) {
+	ops, err := h.HostingService.ListFailedOperations()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "작업 기록 조회 실패: " + err.Error()})
+		return
+	}
+
+	result := []gin.H{}
+	for _, op := range ops {
+		result = append(result, operationJSON(op))
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func operationJSON(op *hosting_service.Operation) gin.H {
+	res := gin.H{
+		"id":         op.ID,
+		"user_id":    op.UserID,
+		"kind":       op.Kind,
+		"vm_name":    op.VMName,
+		"status":     op.Status,
+		"step":       op.Step,
+		"done":       op.Done(),
+		"created_at": op.CreatedAt,
+		"updated_at": op.UpdatedAt,
+	}
+	if op.Error != "" {
+		res["error"] = op.Error
+	}
+	if op.Result != "" {
+		res["result"] = json.RawMessage(op.Result)
+	}
+	if len(op.Rollback) > 0 {
+		res["rollback"] = op.Rollback
+	}
+	return res
+}
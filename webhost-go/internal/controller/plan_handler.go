@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+)
+
+type PlanHandler struct {
+	HostingService hosting_service.Service
+}
+
+func NewPlanHandler(h hosting_service.Service) *PlanHandler {
+	return &PlanHandler{HostingService: h}
+}
+
+// GET /plans
+func (h *PlanHandler) ListPlans(c *gin.Context) {
+	plans, err := h.HostingService.ListPlans()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "플랜 목록 조회 실패: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"plans": plans})
+}
+
+// POST /plans
+func (h *PlanHandler) CreatePlan(c *gin.Context) {
+	var p hosting_service.HostingPlan
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 요청 형식입니다"})
+		return
+	}
+	if err := p.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.HostingService.CreatePlan(&p); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "플랜 생성 실패: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, p)
+}
+
+// PUT /plans/:name
+func (h *PlanHandler) UpdatePlan(c *gin.Context) {
+	var p hosting_service.HostingPlan
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 요청 형식입니다"})
+		return
+	}
+	p.Name = c.Param("name")
+	if err := p.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.HostingService.UpdatePlan(&p)
+	if errors.Is(err, hosting_service.ErrPlanNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "플랜 수정 실패: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// DELETE /plans/:name
+func (h *PlanHandler) DeletePlan(c *gin.Context) {
+	err := h.HostingService.DeletePlan(c.Param("name"))
+	switch {
+	case errors.Is(err, hosting_service.ErrPlanNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, hosting_service.ErrPlanInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "플랜 삭제 실패: " + err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "플랜 삭제 완료"})
+	}
+}
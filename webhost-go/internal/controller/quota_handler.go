@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+	"webhost-go/webhost-go/internal/services/user_service"
+)
+
+type QuotaHandler struct {
+	HostingService hosting_service.Service
+	UserService    user_service.Service
+}
+
+type QuotaRequest struct {
+	MaxVMs      int `json:"max_vms"`
+	MaxVCPUs    int `json:"max_vcpus"`
+	MaxMemoryMB int `json:"max_memory_mb"`
+	MaxDiskGB   int `json:"max_disk_gb"`
+}
+
+func NewQuotaHandler(h hosting_service.Service, u user_service.Service) *QuotaHandler {
+	return &QuotaHandler{HostingService: h, UserService: u}
+}
+
+// GET /hosting/:username/quota, GET /quotas/:username
+// 할당량과 현재 사용량을 함께 돌려준다
+func (h *QuotaHandler) GetQuota(c *gin.Context) {
+	user, err := h.UserService.GetUserByEmail(c.Param("username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "유저 정보를 불러올 수 없습니다: " + err.Error()})
+		return
+	}
+
+	quota, err := h.HostingService.GetQuota(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "할당량 조회 실패: " + err.Error()})
+		return
+	}
+	usage, err := h.HostingService.GetUsage(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "사용량 조회 실패: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"quota": quota, "usage": usage})
+}
+
+// PUT /quotas/:username
+func (h *QuotaHandler) SetQuota(c *gin.Context) {
+	user, err := h.UserService.GetUserByEmail(c.Param("username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "유저 정보를 불러올 수 없습니다: " + err.Error()})
+		return
+	}
+	h.setQuota(c, user.ID)
+}
+
+// DELETE /quotas/:username - 기본 할당량으로 되돌린다
+func (h *QuotaHandler) ResetQuota(c *gin.Context) {
+	user, err := h.UserService.GetUserByEmail(c.Param("username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "유저 정보를 불러올 수 없습니다: " + err.Error()})
+		return
+	}
+
+	if err := h.HostingService.ResetQuota(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "할당량 초기화 실패: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "기본 할당량으로 초기화 완료"})
+}
+
+// GET /quotas/default
+func (h *QuotaHandler) GetDefaultQuota(c *gin.Context) {
+	quota, err := h.HostingService.GetQuota(hosting_service.DefaultQuotaUserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "할당량 조회 실패: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, quota)
+}
+
+// PUT /quotas/default
+func (h *QuotaHandler) SetDefaultQuota(c *gin.Context) {
+	h.setQuota(c, hosting_service.DefaultQuotaUserID)
+}
+
+func (h *QuotaHandler) setQuota(c *gin.Context, userID int64) {
+	var req QuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 요청 형식입니다"})
+		return
+	}
+
+	q := &hosting_service.Quota{
+		UserID:      userID,
+		MaxVMs:      req.MaxVMs,
+		MaxVCPUs:    req.MaxVCPUs,
+		MaxMemoryMB: req.MaxMemoryMB,
+		MaxDiskGB:   req.MaxDiskGB,
+	}
+	err := h.HostingService.SetQuota(q)
+	if errors.Is(err, hosting_service.ErrInvalidQuota) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "할당량 변경 실패: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, q)
+}
@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+)
+
+type ReconcileHandler struct {
+	HostingService hosting_service.Service
+}
+
+func NewReconcileHandler(h hosting_service.Service) *ReconcileHandler {
+	return &ReconcileHandler{HostingService: h}
+}
+
+// GET /reconcile - 마지막 점검 결과 (아직 점검 전이면 바로 실행)
+func (h *ReconcileHandler) GetReport(c *gin.Context) {
+	if report := h.HostingService.LastReconcileReport(); report != nil {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+	h.Run(c)
+}
+
+// POST /reconcile - 즉시 점검
+func (h *ReconcileHandler) Run(c *gin.Context) {
+	report, err := h.HostingService.Reconcile()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "상태 점검 실패: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+	"webhost-go/webhost-go/internal/services/user_service"
+)
+
+type SnapshotHandler struct {
+	HostingService hosting_service.Service
+	UserService    user_service.Service
+}
+
+// CreateSnapshotRequest - vm은 VM ID 또는 이름
+type CreateSnapshotRequest struct {
+	VM          string `json:"vm" binding:"required"`
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+func NewSnapshotHandler(h hosting_service.Service, u user_service.Service) *SnapshotHandler {
+	return &SnapshotHandler{HostingService: h, UserService: u}
+}
+
+// GET /hosting/:username/snapshots?vm=<ID 또는 이름>
+func (h *SnapshotHandler) ListSnapshots(c *gin.Context) {
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	snaps, err := h.HostingService.ListSnapshots(user.ID, c.Query("vm"))
+	if err != nil {
+		hostingError(c, "스냅샷 목록 조회 실패", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"snapshots": snaps})
+}
+
+// POST /hosting/:username/snapshots
+func (h *SnapshotHandler) CreateSnapshot(c *gin.Context) {
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	var req CreateSnapshotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 요청 형식입니다: " + err.Error()})
+		return
+	}
+
+	op, err := h.HostingService.CreateSnapshot(user.ID, req.VM, req.Name, req.Description)
+	if err != nil {
+		hostingError(c, "스냅샷 생성 실패", err)
+		return
+	}
+	accepted(c, "스냅샷 생성 요청 접수", op)
+}
+
+// POST /hosting/:username/snapshots/:snapshotID/revert
+func (h *SnapshotHandler) RevertSnapshot(c *gin.Context) {
+	user, id, ok := h.targetSnapshot(c)
+	if !ok {
+		return
+	}
+
+	op, err := h.HostingService.RevertSnapshot(user.ID, id)
+	if err != nil {
+		hostingError(c, "스냅샷 복원 실패", err)
+		return
+	}
+	accepted(c, "스냅샷 복원 요청 접수", op)
+}
+
+// DELETE /hosting/:username/snapshots/:snapshotID
+func (h *SnapshotHandler) DeleteSnapshot(c *gin.Context) {
+	user, id, ok := h.targetSnapshot(c)
+	if !ok {
+		return
+	}
+
+	op, err := h.HostingService.DeleteSnapshot(user.ID, id)
+	if err != nil {
+		hostingError(c, "스냅샷 삭제 실패", err)
+		return
+	}
+	accepted(c, "스냅샷 삭제 요청 접수", op)
+}
+
+func (h *SnapshotHandler) targetUser(c *gin.Context) (*user_service.User, bool) {
+	user, err := h.UserService.GetUserByEmail(c.Param("username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "유저 정보를 불러올 수 없습니다: " + err.Error()})
+		return nil, false
+	}
+	return user, true
+}
+
+func (h *SnapshotHandler) targetSnapshot(c *gin.Context) (*user_service.User, int64, bool) {
+	id, err := strconv.ParseInt(c.Param("snapshotID"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 스냅샷 ID입니다"})
+		return nil, 0, false
+	}
+	user, ok := h.targetUser(c)
+	if !ok {
+		return nil, 0, false
+	}
+	return user, id, true
+}
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+	"webhost-go/webhost-go/internal/services/user_service"
+)
+
+type SSHKeyHandler struct {
+	HostingService hosting_service.Service
+	UserService    user_service.Service
+}
+
+// AddSSHKeyRequest - public_key는 authorized_keys 한 줄 ("ssh-ed25519 AAAA... comment")
+type AddSSHKeyRequest struct {
+	Name      string `json:"name" binding:"required"`
+	PublicKey string `json:"public_key" binding:"required"`
+}
+
+func NewSSHKeyHandler(h hosting_service.Service, u user_service.Service) *SSHKeyHandler {
+	return &SSHKeyHandler{HostingService: h, UserService: u}
+}
+
+// GET /hosting/:username/ssh-keys
+func (h *SSHKeyHandler) ListSSHKeys(c *gin.Context) {
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.HostingService.ListSSHKeys(user.ID)
+	if err != nil {
+		hostingError(c, "SSH 키 목록 조회 실패", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ssh_keys": keys})
+}
+
+// POST /hosting/:username/ssh-keys
+func (h *SSHKeyHandler) AddSSHKey(c *gin.Context) {
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	var req AddSSHKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 요청 형식입니다: " + err.Error()})
+		return
+	}
+
+	key, err := h.HostingService.AddSSHKey(user.ID, req.Name, req.PublicKey)
+	if err != nil {
+		hostingError(c, "SSH 키 등록 실패", err)
+		return
+	}
+	c.JSON(http.StatusCreated, key)
+}
+
+// DELETE /hosting/:username/ssh-keys/:keyID
+func (h *SSHKeyHandler) DeleteSSHKey(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("keyID"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "잘못된 SSH 키 ID입니다"})
+		return
+	}
+	user, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.HostingService.DeleteSSHKey(user.ID, id); err != nil {
+		hostingError(c, "SSH 키 삭제 실패", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "SSH 키 삭제 완료"})
+}
+
+func (h *SSHKeyHandler) targetUser(c *gin.Context) (*user_service.User, bool) {
+	user, err := h.UserService.GetUserByEmail(c.Param("username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "유저 정보를 불러올 수 없습니다: " + err.Error()})
+		return nil, false
+	}
+	return user, true
+}
@@ -0,0 +1,150 @@
+package db_driver
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+)
+
+type BackupRepository struct {
+	db *sql.DB
+}
+
+func NewBackupRepository(db *sql.DB) *BackupRepository {
+	return &BackupRepository{db: db}
+}
+
+const backupColumns = `id, user_id, hosting_id, name, kind, plan, path, size_bytes, node_name, created_at`
+
+func (r *BackupRepository) Create(b *hosting_service.Backup) error {
+	res, err := r.db.Exec(`
+		INSERT INTO backups (user_id, hosting_id, name, kind, plan, path, size_bytes, node_name, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, b.UserID, b.HostingID, b.Name, b.Kind, b.Plan, b.Path, b.SizeBytes, b.NodeName, b.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	b.ID = id
+	return nil
+}
+
+func (r *BackupRepository) Delete(id int64) error {
+	_, err := r.db.Exec(`DELETE FROM backups WHERE id = ?`, id)
+	return err
+}
+
+func (r *BackupRepository) FindByID(id int64) (*hosting_service.Backup, error) {
+	row := r.db.QueryRow(`SELECT `+backupColumns+` FROM backups WHERE id = ?`, id)
+
+	b, err := scanBackup(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+func (r *BackupRepository) FindByUserID(userID int64) ([]*hosting_service.Backup, error) {
+	return r.findAll(`SELECT `+backupColumns+` FROM backups WHERE user_id = ? ORDER BY id`, userID)
+}
+
+func (r *BackupRepository) FindByHostingID(hostingID int64) ([]*hosting_service.Backup, error) {
+	return r.findAll(`SELECT `+backupColumns+` FROM backups WHERE hosting_id = ? ORDER BY id`, hostingID)
+}
+
+func (r *BackupRepository) findAll(query string, args ...interface{}) ([]*hosting_service.Backup, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var backups []*hosting_service.Backup
+	for rows.Next() {
+		b, err := scanBackup(rows)
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, b)
+	}
+	return backups, nil
+}
+
+func scanBackup(row rowScanner) (*hosting_service.Backup, error) {
+	var b hosting_service.Backup
+	if err := row.Scan(
+		&b.ID, &b.UserID, &b.HostingID, &b.Name, &b.Kind, &b.Plan, &b.Path, &b.SizeBytes, &b.NodeName, &b.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+type BackupScheduleRepository struct {
+	db *sql.DB
+}
+
+func NewBackupScheduleRepository(db *sql.DB) *BackupScheduleRepository {
+	return &BackupScheduleRepository{db: db}
+}
+
+const backupScheduleColumns = `hosting_id, cron, keep_daily, keep_weekly, last_run_at`
+
+func (r *BackupScheduleRepository) FindByHostingID(hostingID int64) (*hosting_service.BackupSchedule, error) {
+	row := r.db.QueryRow(`SELECT `+backupScheduleColumns+` FROM backup_schedules WHERE hosting_id = ?`, hostingID)
+
+	var sched hosting_service.BackupSchedule
+	if err := row.Scan(&sched.HostingID, &sched.Cron, &sched.KeepDaily, &sched.KeepWeekly, &sched.LastRunAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, err
+	}
+	return &sched, nil
+}
+
+func (r *BackupScheduleRepository) FindAll() ([]*hosting_service.BackupSchedule, error) {
+	rows, err := r.db.Query(`SELECT ` + backupScheduleColumns + ` FROM backup_schedules ORDER BY hosting_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*hosting_service.BackupSchedule
+	for rows.Next() {
+		var sched hosting_service.BackupSchedule
+		if err := rows.Scan(&sched.HostingID, &sched.Cron, &sched.KeepDaily, &sched.KeepWeekly, &sched.LastRunAt); err != nil {
+			return nil, err
+		}
+		list = append(list, &sched)
+	}
+	return list, nil
+}
+
+func (r *BackupScheduleRepository) Upsert(sched *hosting_service.BackupSchedule) error {
+	_, err := r.db.Exec(`
+		INSERT INTO backup_schedules (hosting_id, cron, keep_daily, keep_weekly, last_run_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			cron = VALUES(cron), keep_daily = VALUES(keep_daily),
+			keep_weekly = VALUES(keep_weekly), last_run_at = VALUES(last_run_at)
+	`, sched.HostingID, sched.Cron, sched.KeepDaily, sched.KeepWeekly, sched.LastRunAt)
+	return err
+}
+
+func (r *BackupScheduleRepository) UpdateLastRun(hostingID int64, t time.Time) error {
+	_, err := r.db.Exec(`UPDATE backup_schedules SET last_run_at = ? WHERE hosting_id = ?`, t, hostingID)
+	return err
+}
+
+func (r *BackupScheduleRepository) Delete(hostingID int64) error {
+	_, err := r.db.Exec(`DELETE FROM backup_schedules WHERE hosting_id = ?`, hostingID)
+	return err
+}
@@ -15,14 +15,48 @@ func NewHostingRepository(db *sql.DB) *HostingRepository {
 	return &HostingRepository{db: db}
 }
 
+const hostingColumns = `id, user_id, name, vm_name, ip_address, ssh_port, proxy_path, disk_path, plan, image, vnc_password, status, desired_state, node_name, created_at`
+
 func (r *HostingRepository) Create(h *hosting_service.Hosting) error {
+	res, err := r.db.Exec(`
+		INSERT INTO hostings (user_id, name, vm_name, ip_address, ssh_port, proxy_path, disk_path, plan, image, vnc_password, status, desired_state, node_name)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, h.UserID, h.Name, h.VMName, h.IPAddress, h.SSHPort, h.ProxyPath, h.DiskPath, h.Plan, h.Image, h.VNCPassword, h.Status, desiredState(h), h.NodeName)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	h.ID = id
+	return nil
+}
+
+func (r *HostingRepository) Update(h *hosting_service.Hosting) error {
+	_, err := r.db.Exec(`
+		UPDATE hostings
+		SET name = ?, vm_name = ?, ip_address = ?, ssh_port = ?, proxy_path = ?, disk_path = ?, plan = ?, image = ?, vnc_password = ?, status = ?, desired_state = ?, node_name = ?
+		WHERE id = ?
+	`, h.Name, h.VMName, h.IPAddress, h.SSHPort, h.ProxyPath, h.DiskPath, h.Plan, h.Image, h.VNCPassword, h.Status, desiredState(h), h.NodeName, h.ID)
+	return err
+}
+
+func (r *HostingRepository) UpdateDesiredState(vmName string, state string) error {
 	_, err := r.db.Exec(`
-		INSERT INTO hostings (user_id, vm_name, ip_address, ssh_port, proxy_path, disk_path, status)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`, h.UserID, h.VMName, h.IPAddress, h.SSHPort, h.ProxyPath, h.DiskPath, h.Status)
+		UPDATE hostings SET desired_state = ? WHERE vm_name = ?
+	`, state, vmName)
 	return err
 }
 
+// desiredState - 값이 없으면 컬럼 기본값과 같은 running
+func desiredState(h *hosting_service.Hosting) string {
+	if h.DesiredState == "" {
+		return "running"
+	}
+	return h.DesiredState
+}
+
 func (r *HostingRepository) UpdateStatus(vmName string, status string) error {
 	_, err := r.db.Exec(`
 		UPDATE hostings SET status = ? WHERE vm_name = ?
@@ -30,37 +64,33 @@ func (r *HostingRepository) UpdateStatus(vmName string, status string) error {
 	return err
 }
 
-func (r *HostingRepository) Delete(vmName string) error {
+func (r *HostingRepository) Delete(id int64) error {
 	_, err := r.db.Exec(`
-		DELETE FROM hostings WHERE vm_name = ?
-	`, vmName)
+		DELETE FROM hostings WHERE id = ?
+	`, id)
 	return err
 }
 
 func (r *HostingRepository) FindByVMName(vmName string) (*hosting_service.Hosting, error) {
 	row := r.db.QueryRow(`
-		SELECT id, user_id, vm_name, ip_address, ssh_port, proxy_path, disk_path, status, created_at
+		SELECT `+hostingColumns+`
 		FROM hostings
 		WHERE vm_name = ? AND status != 'deleted'
 	`, vmName)
 
-	var h hosting_service.Hosting
-	if err := row.Scan(
-		&h.ID, &h.UserID, &h.VMName, &h.IPAddress,
-		&h.SSHPort, &h.ProxyPath, &h.DiskPath,
-		&h.Status, &h.CreatedAt,
-	); err != nil {
+	h, err := scanHosting(row)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, sql.ErrNoRows
 		}
 		return nil, err
 	}
-	return &h, nil
+	return h, nil
 }
 
 func (r *HostingRepository) FindAllByUserID(userID int64) ([]*hosting_service.Hosting, error) {
 	rows, err := r.db.Query(`
-		SELECT id, user_id, vm_name, ip_address, ssh_port, proxy_path, disk_path, status, created_at
+		SELECT `+hostingColumns+`
 		FROM hostings WHERE user_id = ?
 	`, userID)
 	if err != nil {
@@ -70,22 +100,18 @@ func (r *HostingRepository) FindAllByUserID(userID int64) ([]*hosting_service.Ho
 
 	var list []*hosting_service.Hosting
 	for rows.Next() {
-		var h hosting_service.Hosting
-		if err := rows.Scan(
-			&h.ID, &h.UserID, &h.VMName, &h.IPAddress,
-			&h.SSHPort, &h.ProxyPath, &h.DiskPath,
-			&h.Status, &h.CreatedAt,
-		); err != nil {
+		h, err := scanHosting(rows)
+		if err != nil {
 			return nil, err
 		}
-		list = append(list, &h)
+		list = append(list, h)
 	}
 	return list, nil
 }
 
 func (r *HostingRepository) FindAll() ([]*hosting_service.Hosting, error) {
 	rows, err := r.db.Query(`
-		SELECT id, user_id, vm_name, ip_address, ssh_port, proxy_path, disk_path, status, created_at
+		SELECT ` + hostingColumns + `
 		FROM hostings
 	`)
 	if err != nil {
@@ -95,15 +121,11 @@ func (r *HostingRepository) FindAll() ([]*hosting_service.Hosting, error) {
 
 	var hostings []*hosting_service.Hosting
 	for rows.Next() {
-		var h hosting_service.Hosting
-		if err := rows.Scan(
-			&h.ID, &h.UserID, &h.VMName, &h.IPAddress,
-			&h.SSHPort, &h.ProxyPath, &h.DiskPath,
-			&h.Status, &h.CreatedAt,
-		); err != nil {
+		h, err := scanHosting(rows)
+		if err != nil {
 			return nil, err
 		}
-		hostings = append(hostings, &h)
+		hostings = append(hostings, h)
 	}
 	return hostings, nil
 }
@@ -133,25 +155,34 @@ func (r *HostingRepository) GetAvailablePort(basePort, maxPort int) (int, error)
 	return 0, fmt.Errorf("사용 가능한 포트를 찾을 수 없습니다")
 }
 
-func (r *HostingRepository) FindActiveByUserID(userID int64) (*hosting_service.Hosting, error) {
+func (r *HostingRepository) FindByID(id int64) (*hosting_service.Hosting, error) {
+	row := r.db.QueryRow(`SELECT `+hostingColumns+` FROM hostings WHERE id = ?`, id)
+
+	h, err := scanHosting(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, err
+	}
+	return h, nil
+}
+
+func (r *HostingRepository) FindByUserAndName(userID int64, name string) (*hosting_service.Hosting, error) {
 	row := r.db.QueryRow(`
-		SELECT id, user_id, vm_name, ip_address, ssh_port, proxy_path, disk_path, status, created_at
+		SELECT `+hostingColumns+`
 		FROM hostings
-		WHERE user_id = ? AND status != 'deleted'
-	`, userID)
+		WHERE user_id = ? AND name = ? AND status != 'deleted'
+	`, userID, name)
 
-	var h hosting_service.Hosting
-	if err := row.Scan(
-		&h.ID, &h.UserID, &h.VMName, &h.IPAddress,
-		&h.SSHPort, &h.ProxyPath, &h.DiskPath,
-		&h.Status, &h.CreatedAt,
-	); err != nil {
+	h, err := scanHosting(row)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, sql.ErrNoRows
 		}
 		return nil, err
 	}
-	return &h, nil
+	return h, nil
 }
 
 func (r *HostingRepository) GetUsedIPs() ([]string, error) {
@@ -173,3 +204,23 @@ func (r *HostingRepository) GetUsedIPs() ([]string, error) {
 	}
 	return ips, nil
 }
+
+func (r *HostingRepository) CountActiveByPlan(plan string) (int, error) {
+	var n int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM hostings WHERE plan = ? AND status != 'deleted'
+	`, plan).Scan(&n)
+	return n, err
+}
+
+func scanHosting(row rowScanner) (*hosting_service.Hosting, error) {
+	var h hosting_service.Hosting
+	if err := row.Scan(
+		&h.ID, &h.UserID, &h.Name, &h.VMName, &h.IPAddress,
+		&h.SSHPort, &h.ProxyPath, &h.DiskPath,
+		&h.Plan, &h.Image, &h.VNCPassword, &h.Status, &h.DesiredState, &h.NodeName, &h.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
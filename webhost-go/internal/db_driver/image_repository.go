@@ -0,0 +1,92 @@
+package db_driver
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+)
+
+type ImageRepository struct {
+	db *sql.DB
+}
+
+func NewImageRepository(db *sql.DB) *ImageRepository {
+	return &ImageRepository{db: db}
+}
+
+const imageColumns = `name, os_family, version, path, sha256, default_user, seed_format, features, status, verified_at, created_at`
+
+func (r *ImageRepository) FindByName(name string) (*hosting_service.Image, error) {
+	row := r.db.QueryRow(`SELECT `+imageColumns+` FROM images WHERE name = ?`, name)
+
+	img, err := scanImage(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, err
+	}
+	return img, nil
+}
+
+func (r *ImageRepository) FindAll() ([]*hosting_service.Image, error) {
+	rows, err := r.db.Query(`SELECT ` + imageColumns + ` FROM images ORDER BY os_family, version, name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []*hosting_service.Image
+	for rows.Next() {
+		img, err := scanImage(rows)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+	return images, nil
+}
+
+func (r *ImageRepository) Create(img *hosting_service.Image) error {
+	features, err := json.Marshal(img.Features)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`
+		INSERT INTO images (name, os_family, version, path, sha256, default_user, seed_format, features, status, verified_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, img.Name, img.OSFamily, img.Version, img.Path, img.SHA256, img.DefaultUser, img.SeedFormat, string(features),
+		img.Status, img.VerifiedAt, img.CreatedAt)
+	return err
+}
+
+func (r *ImageRepository) UpdateStatus(name, status string) error {
+	_, err := r.db.Exec(`UPDATE images SET status = ? WHERE name = ?`, status, name)
+	return err
+}
+
+func (r *ImageRepository) MarkVerified(name string, t time.Time) error {
+	_, err := r.db.Exec(`UPDATE images SET verified_at = ? WHERE name = ?`, t, name)
+	return err
+}
+
+func scanImage(row rowScanner) (*hosting_service.Image, error) {
+	var img hosting_service.Image
+	var verifiedAt sql.NullTime
+	var features string
+	if err := row.Scan(
+		&img.Name, &img.OSFamily, &img.Version, &img.Path, &img.SHA256,
+		&img.DefaultUser, &img.SeedFormat, &features, &img.Status, &verifiedAt, &img.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(features), &img.Features); err != nil {
+		return nil, err
+	}
+	if verifiedAt.Valid {
+		img.VerifiedAt = &verifiedAt.Time
+	}
+	return &img, nil
+}
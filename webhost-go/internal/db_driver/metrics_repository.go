@@ -0,0 +1,92 @@
+package db_driver
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+)
+
+type MetricsRepository struct {
+	db *sql.DB
+}
+
+func NewMetricsRepository(db *sql.DB) *MetricsRepository {
+	return &MetricsRepository{db: db}
+}
+
+const metricColumns = `hosting_id, resolution, ts, cpu_percent, memory_kb, memory_used_kb, disk_read_bps, disk_write_bps, net_rx_bps, net_tx_bps`
+
+func (r *MetricsRepository) Save(points []*hosting_service.MetricPoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+	rows := make([]string, 0, len(points))
+	args := make([]interface{}, 0, len(points)*10)
+	for _, p := range points {
+		rows = append(rows, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+		args = append(args, p.HostingID, int(p.Resolution/time.Second), p.Time, p.CPUPercent, p.MemoryKB, p.MemoryUsedKB,
+			p.DiskReadBps, p.DiskWriteBps, p.NetRxBps, p.NetTxBps)
+	}
+	_, err := r.db.Exec(`
+		INSERT INTO vm_metrics (`+metricColumns+`)
+		VALUES `+strings.Join(rows, ", ")+`
+		ON DUPLICATE KEY UPDATE
+			cpu_percent = VALUES(cpu_percent), memory_kb = VALUES(memory_kb), memory_used_kb = VALUES(memory_used_kb),
+			disk_read_bps = VALUES(disk_read_bps), disk_write_bps = VALUES(disk_write_bps),
+			net_rx_bps = VALUES(net_rx_bps), net_tx_bps = VALUES(net_tx_bps)
+	`, args...)
+	return err
+}
+
+func (r *MetricsRepository) FindRange(hostingID int64, resolution time.Duration, from, to time.Time) ([]*hosting_service.MetricPoint, error) {
+	rows, err := r.db.Query(`
+		SELECT `+metricColumns+`
+		FROM vm_metrics
+		WHERE hosting_id = ? AND resolution = ? AND ts >= ? AND ts < ?
+		ORDER BY ts
+	`, hostingID, int(resolution/time.Second), from, to)
+	if err != nil {
+		return nil, err
+	}
+	return scanMetrics(rows)
+}
+
+func (r *MetricsRepository) FindAllRange(resolution time.Duration, from, to time.Time) ([]*hosting_service.MetricPoint, error) {
+	rows, err := r.db.Query(`
+		SELECT `+metricColumns+`
+		FROM vm_metrics
+		WHERE resolution = ? AND ts >= ? AND ts < ?
+		ORDER BY hosting_id, ts
+	`, int(resolution/time.Second), from, to)
+	if err != nil {
+		return nil, err
+	}
+	return scanMetrics(rows)
+}
+
+func (r *MetricsRepository) DeleteBefore(resolution time.Duration, before time.Time) error {
+	_, err := r.db.Exec(`
+		DELETE FROM vm_metrics WHERE resolution = ? AND ts < ?
+	`, int(resolution/time.Second), before)
+	return err
+}
+
+func scanMetrics(rows *sql.Rows) ([]*hosting_service.MetricPoint, error) {
+	defer rows.Close()
+
+	var list []*hosting_service.MetricPoint
+	for rows.Next() {
+		var p hosting_service.MetricPoint
+		var seconds int
+		if err := rows.Scan(
+			&p.HostingID, &seconds, &p.Time, &p.CPUPercent, &p.MemoryKB, &p.MemoryUsedKB,
+			&p.DiskReadBps, &p.DiskWriteBps, &p.NetRxBps, &p.NetTxBps,
+		); err != nil {
+			return nil, err
+		}
+		p.Resolution = time.Duration(seconds) * time.Second
+		list = append(list, &p)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,99 @@
+package db_driver
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+)
+
+type NodeRepository struct {
+	db *sql.DB
+}
+
+func NewNodeRepository(db *sql.DB) *NodeRepository {
+	return &NodeRepository{db: db}
+}
+
+const nodeColumns = `name, address, vcpus, memory_mb, disk_gb, labels, schedulable, shared_storage, migration_uri, created_at`
+
+func (r *NodeRepository) FindByName(name string) (*hosting_service.Node, error) {
+	row := r.db.QueryRow(`SELECT `+nodeColumns+` FROM nodes WHERE name = ?`, name)
+
+	n, err := scanNode(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, err
+	}
+	return n, nil
+}
+
+func (r *NodeRepository) FindAll() ([]*hosting_service.Node, error) {
+	rows, err := r.db.Query(`SELECT ` + nodeColumns + ` FROM nodes ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nodes []*hosting_service.Node
+	for rows.Next() {
+		n, err := scanNode(rows)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+func (r *NodeRepository) Create(n *hosting_service.Node) error {
+	labels, err := labelsJSON(n.Labels)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`
+		INSERT INTO nodes (name, address, vcpus, memory_mb, disk_gb, labels, schedulable, shared_storage, migration_uri) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, n.Name, n.Address, n.VCPUs, n.MemoryMB, n.DiskGB, labels, n.Schedulable, n.SharedStorage, n.MigrationURI)
+	return err
+}
+
+func (r *NodeRepository) Update(n *hosting_service.Node) error {
+	labels, err := labelsJSON(n.Labels)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`
+		UPDATE nodes SET address = ?, vcpus = ?, memory_mb = ?, disk_gb = ?, labels = ?, schedulable = ?, shared_storage = ?, migration_uri = ? WHERE name = ?
+	`, n.Address, n.VCPUs, n.MemoryMB, n.DiskGB, labels, n.Schedulable, n.SharedStorage, n.MigrationURI, n.Name)
+	return err
+}
+
+func (r *NodeRepository) Delete(name string) error {
+	_, err := r.db.Exec(`
+		DELETE FROM nodes WHERE name = ?
+	`, name)
+	return err
+}
+
+func scanNode(row rowScanner) (*hosting_service.Node, error) {
+	var n hosting_service.Node
+	var labels string
+	if err := row.Scan(&n.Name, &n.Address, &n.VCPUs, &n.MemoryMB, &n.DiskGB, &labels, &n.Schedulable, &n.SharedStorage, &n.MigrationURI, &n.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(labels), &n.Labels); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// labelsJSON - 레이블이 없으면 컬럼 기본값과 같은 "{}"
+func labelsJSON(labels map[string]string) (string, error) {
+	if len(labels) == 0 {
+		return "{}", nil
+	}
+	b, err := json.Marshal(labels)
+	return string(b), err
+}
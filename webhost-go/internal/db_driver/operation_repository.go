@@ -0,0 +1,131 @@
+package db_driver
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+)
+
+type OperationRepository struct {
+	db *sql.DB
+}
+
+func NewOperationRepository(db *sql.DB) *OperationRepository {
+	return &OperationRepository{db: db}
+}
+
+const operationColumns = `id, user_id, kind, target, vm_name, status, step, error, params, result, rollback, created_at, updated_at`
+
+func (r *OperationRepository) Create(op *hosting_service.Operation) error {
+	params, err := json.Marshal(op.Params)
+	if err != nil {
+		return err
+	}
+	rollback, err := json.Marshal(op.Rollback)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.db.Exec(`
+		INSERT INTO operations (user_id, kind, target, vm_name, status, step, error, params, result, rollback)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, op.UserID, op.Kind, op.Target, op.VMName, op.Status, op.Step, op.Error, string(params), op.Result, string(rollback))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	op.ID = id
+	return nil
+}
+
+func (r *OperationRepository) Update(op *hosting_service.Operation) error {
+	params, err := json.Marshal(op.Params)
+	if err != nil {
+		return err
+	}
+	rollback, err := json.Marshal(op.Rollback)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(`
+		UPDATE operations
+		SET status = ?, step = ?, error = ?, params = ?, result = ?, rollback = ?
+		WHERE id = ?
+	`, op.Status, op.Step, op.Error, string(params), op.Result, string(rollback), op.ID)
+	return err
+}
+
+func (r *OperationRepository) FindByID(id int64) (*hosting_service.Operation, error) {
+	row := r.db.QueryRow(`SELECT `+operationColumns+` FROM operations WHERE id = ?`, id)
+
+	op, err := scanOperation(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, err
+	}
+	return op, nil
+}
+
+func (r *OperationRepository) FindByStatus(statuses ...string) ([]*hosting_service.Operation, error) {
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(statuses)), ", ")
+	args := make([]interface{}, len(statuses))
+	for i, st := range statuses {
+		args[i] = st
+	}
+
+	rows, err := r.db.Query(`
+		SELECT `+operationColumns+`
+		FROM operations
+		WHERE status IN (`+placeholders+`)
+		ORDER BY id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ops []*hosting_service.Operation
+	for rows.Next() {
+		op, err := scanOperation(rows)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// rowScanner - *sql.Row와 *sql.Rows 공통 인터페이스
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanOperation(row rowScanner) (*hosting_service.Operation, error) {
+	var op hosting_service.Operation
+	var params, rollback string
+	if err := row.Scan(
+		&op.ID, &op.UserID, &op.Kind, &op.Target, &op.VMName, &op.Status,
+		&op.Step, &op.Error, &params, &op.Result, &rollback, &op.CreatedAt, &op.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(params), &op.Params); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(rollback), &op.Rollback); err != nil {
+		return nil, err
+	}
+	return &op, nil
+}
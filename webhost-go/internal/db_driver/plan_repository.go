@@ -0,0 +1,101 @@
+package db_driver
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+)
+
+type PlanRepository struct {
+	db *sql.DB
+}
+
+func NewPlanRepository(db *sql.DB) *PlanRepository {
+	return &PlanRepository{db: db}
+}
+
+const planColumns = `name, cpu, memory_mb, disk_gb, max_snapshots, features, node_labels`
+
+func (r *PlanRepository) FindByName(name string) (*hosting_service.HostingPlan, error) {
+	row := r.db.QueryRow(`SELECT `+planColumns+` FROM plans WHERE name = ?`, name)
+
+	p, err := scanPlan(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+func (r *PlanRepository) FindAll() ([]*hosting_service.HostingPlan, error) {
+	rows, err := r.db.Query(`SELECT ` + planColumns + ` FROM plans ORDER BY cpu, memory_mb, disk_gb`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var plans []*hosting_service.HostingPlan
+	for rows.Next() {
+		p, err := scanPlan(rows)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, p)
+	}
+	return plans, nil
+}
+
+func (r *PlanRepository) Create(p *hosting_service.HostingPlan) error {
+	features, err := json.Marshal(p.Features)
+	if err != nil {
+		return err
+	}
+	labels, err := labelsJSON(p.NodeLabels)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`
+		INSERT INTO plans (name, cpu, memory_mb, disk_gb, max_snapshots, features, node_labels) VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, p.Name, p.CPU, p.MemoryMB, p.DiskGB, p.MaxSnapshots, string(features), labels)
+	return err
+}
+
+func (r *PlanRepository) Update(p *hosting_service.HostingPlan) error {
+	features, err := json.Marshal(p.Features)
+	if err != nil {
+		return err
+	}
+	labels, err := labelsJSON(p.NodeLabels)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`
+		UPDATE plans SET cpu = ?, memory_mb = ?, disk_gb = ?, max_snapshots = ?, features = ?, node_labels = ? WHERE name = ?
+	`, p.CPU, p.MemoryMB, p.DiskGB, p.MaxSnapshots, string(features), labels, p.Name)
+	return err
+}
+
+func (r *PlanRepository) Delete(name string) error {
+	_, err := r.db.Exec(`
+		DELETE FROM plans WHERE name = ?
+	`, name)
+	return err
+}
+
+func scanPlan(row rowScanner) (*hosting_service.HostingPlan, error) {
+	var p hosting_service.HostingPlan
+	var features, labels string
+	if err := row.Scan(&p.Name, &p.CPU, &p.MemoryMB, &p.DiskGB, &p.MaxSnapshots, &features, &labels); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(features), &p.Features); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(labels), &p.NodeLabels); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
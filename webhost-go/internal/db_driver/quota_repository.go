@@ -0,0 +1,49 @@
+package db_driver
+
+import (
+	"database/sql"
+	"errors"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+)
+
+type QuotaRepository struct {
+	db *sql.DB
+}
+
+func NewQuotaRepository(db *sql.DB) *QuotaRepository {
+	return &QuotaRepository{db: db}
+}
+
+func (r *QuotaRepository) FindByUserID(userID int64) (*hosting_service.Quota, error) {
+	row := r.db.QueryRow(`
+		SELECT user_id, max_vms, max_vcpus, max_memory_mb, max_disk_gb
+		FROM quotas WHERE user_id = ?
+	`, userID)
+
+	var q hosting_service.Quota
+	if err := row.Scan(&q.UserID, &q.MaxVMs, &q.MaxVCPUs, &q.MaxMemoryMB, &q.MaxDiskGB); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, err
+	}
+	return &q, nil
+}
+
+func (r *QuotaRepository) Upsert(q *hosting_service.Quota) error {
+	_, err := r.db.Exec(`
+		INSERT INTO quotas (user_id, max_vms, max_vcpus, max_memory_mb, max_disk_gb)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			max_vms = VALUES(max_vms), max_vcpus = VALUES(max_vcpus),
+			max_memory_mb = VALUES(max_memory_mb), max_disk_gb = VALUES(max_disk_gb)
+	`, q.UserID, q.MaxVMs, q.MaxVCPUs, q.MaxMemoryMB, q.MaxDiskGB)
+	return err
+}
+
+func (r *QuotaRepository) Delete(userID int64) error {
+	_, err := r.db.Exec(`
+		DELETE FROM quotas WHERE user_id = ?
+	`, userID)
+	return err
+}
@@ -0,0 +1,89 @@
+package db_driver
+
+import (
+	"database/sql"
+	"errors"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+)
+
+type SnapshotRepository struct {
+	db *sql.DB
+}
+
+func NewSnapshotRepository(db *sql.DB) *SnapshotRepository {
+	return &SnapshotRepository{db: db}
+}
+
+const snapshotColumns = `id, hosting_id, name, description, vm_status, created_at`
+
+func (r *SnapshotRepository) Create(snap *hosting_service.Snapshot) error {
+	res, err := r.db.Exec(`
+		INSERT INTO snapshots (hosting_id, name, description, vm_status, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, snap.HostingID, snap.Name, snap.Description, snap.VMStatus, snap.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	snap.ID = id
+	return nil
+}
+
+func (r *SnapshotRepository) Delete(id int64) error {
+	_, err := r.db.Exec(`DELETE FROM snapshots WHERE id = ?`, id)
+	return err
+}
+
+func (r *SnapshotRepository) FindByID(id int64) (*hosting_service.Snapshot, error) {
+	row := r.db.QueryRow(`SELECT `+snapshotColumns+` FROM snapshots WHERE id = ?`, id)
+
+	snap, err := scanSnapshot(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, err
+	}
+	return snap, nil
+}
+
+func (r *SnapshotRepository) FindByHostingID(hostingID int64) ([]*hosting_service.Snapshot, error) {
+	rows, err := r.db.Query(`
+		SELECT `+snapshotColumns+`
+		FROM snapshots
+		WHERE hosting_id = ?
+		ORDER BY id
+	`, hostingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snaps []*hosting_service.Snapshot
+	for rows.Next() {
+		snap, err := scanSnapshot(rows)
+		if err != nil {
+			return nil, err
+		}
+		snaps = append(snaps, snap)
+	}
+	return snaps, nil
+}
+
+func (r *SnapshotRepository) DeleteByHostingID(hostingID int64) error {
+	_, err := r.db.Exec(`DELETE FROM snapshots WHERE hosting_id = ?`, hostingID)
+	return err
+}
+
+func scanSnapshot(row rowScanner) (*hosting_service.Snapshot, error) {
+	var snap hosting_service.Snapshot
+	if err := row.Scan(
+		&snap.ID, &snap.HostingID, &snap.Name, &snap.Description, &snap.VMStatus, &snap.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
@@ -0,0 +1,84 @@
+package db_driver
+
+import (
+	"database/sql"
+	"errors"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+)
+
+type SSHKeyRepository struct {
+	db *sql.DB
+}
+
+func NewSSHKeyRepository(db *sql.DB) *SSHKeyRepository {
+	return &SSHKeyRepository{db: db}
+}
+
+const sshKeyColumns = `id, user_id, name, public_key, fingerprint, created_at`
+
+func (r *SSHKeyRepository) Create(key *hosting_service.SSHKey) error {
+	res, err := r.db.Exec(`
+		INSERT INTO ssh_keys (user_id, name, public_key, fingerprint, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, key.UserID, key.Name, key.PublicKey, key.Fingerprint, key.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	key.ID = id
+	return nil
+}
+
+func (r *SSHKeyRepository) Delete(id int64) error {
+	_, err := r.db.Exec(`DELETE FROM ssh_keys WHERE id = ?`, id)
+	return err
+}
+
+func (r *SSHKeyRepository) FindByID(id int64) (*hosting_service.SSHKey, error) {
+	row := r.db.QueryRow(`SELECT `+sshKeyColumns+` FROM ssh_keys WHERE id = ?`, id)
+
+	key, err := scanSSHKey(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+func (r *SSHKeyRepository) FindByUserID(userID int64) ([]*hosting_service.SSHKey, error) {
+	rows, err := r.db.Query(`
+		SELECT `+sshKeyColumns+`
+		FROM ssh_keys
+		WHERE user_id = ?
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*hosting_service.SSHKey
+	for rows.Next() {
+		key, err := scanSSHKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func scanSSHKey(row rowScanner) (*hosting_service.SSHKey, error) {
+	var key hosting_service.SSHKey
+	if err := row.Scan(
+		&key.ID, &key.UserID, &key.Name, &key.PublicKey, &key.Fingerprint, &key.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
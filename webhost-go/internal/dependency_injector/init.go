@@ -1,11 +1,15 @@
 package dependency_injector
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
+	"webhost-go/webhost-go/cmd/libvirt-agent/agent"
 	"webhost-go/webhost-go/internal/controller"
 	"webhost-go/webhost-go/internal/db_driver"
+	"webhost-go/webhost-go/internal/hypervisor"
 	"webhost-go/webhost-go/internal/middleware"
 	"webhost-go/webhost-go/internal/services/hosting_service"
 	"webhost-go/webhost-go/internal/services/user_service"
@@ -18,6 +22,33 @@ type AppInitializer struct {
 	DB        DBConfig
 	JWTSecret string
 	TokenTTL  time.Duration
+
+	// LibvirtAgentAddr - compute 노드의 libvirt-agent 주소 (예: "10.0.0.2:5004")
+	// 비어 있으면 로컬 libvirt 소켓에 직접 연결한다. nodes 테이블에 노드가 있으면 쓰지 않는다.
+	LibvirtAgentAddr string
+
+	// LibvirtAgentToken - libvirt-agent에 보내는 Bearer 토큰 (모든 agent의 -token-file과 같은 값)
+	LibvirtAgentToken string
+
+	// SchedulerPolicy - 새 VM을 놓을 노드를 고르는 방법 ("spread", "pack", 기본 spread)
+	SchedulerPolicy string
+
+	// JobWorkers - VM 작업(생성/삭제/시작/중지)을 처리할 워커 수 (기본 4)
+	JobWorkers int
+
+	// ReconcileInterval - hostings 테이블과 libvirt 도메인 상태를 맞추는 주기 (기본 1분)
+	ReconcileInterval time.Duration
+
+	// RecoveryInterval - 서버 시작 시 desired state가 running인 꺼진 VM을 하나씩 부팅하는 간격 (기본 10초)
+	RecoveryInterval time.Duration
+
+	// DomainAutostart - desired state를 libvirt autostart에도 반영한다 (관리 서버 없이 libvirtd가 부팅,
+	// 이때는 모든 VM이 한꺼번에 부팅되어 RecoveryInterval이 적용되지 않는다)
+	DomainAutostart bool
+
+	// BackupDir - 로컬 libvirt를 쓸 때 백업 파일을 저장할 디렉토리 (기본 libvirt.DefaultBackupDir)
+	// libvirt-agent를 쓰면 agent의 -backup-dir 옵션을 따른다.
+	BackupDir string
 }
 
 type DBConfig struct {
@@ -43,21 +74,107 @@ func (ai *AppInitializer) InitApp() (*HandlerRegistry, error) {
 	authMw := middleware.NewAuthMiddleware(tokens)
 
 	hostingRepo := db_driver.NewHostingRepository(db)
-	libvirtManager, err := libvirt.NewLibvirtManager()
+	operationRepo := db_driver.NewOperationRepository(db)
+	planRepo := db_driver.NewPlanRepository(db)
+	imageRepo := db_driver.NewImageRepository(db)
+	quotaRepo := db_driver.NewQuotaRepository(db)
+	snapshotRepo := db_driver.NewSnapshotRepository(db)
+	backupRepo := db_driver.NewBackupRepository(db)
+	scheduleRepo := db_driver.NewBackupScheduleRepository(db)
+	sshKeyRepo := db_driver.NewSSHKeyRepository(db)
+	metricsRepo := db_driver.NewMetricsRepository(db)
+	nodeRepo := db_driver.NewNodeRepository(db)
+	pool := hypervisor.NewNodePool(func(address string) (hosting_service.Hypervisor, error) {
+		return dialNode(address, ai.BackupDir, ai.LibvirtAgentToken)
+	})
+
+	hostingSvc := hosting_service.NewService(hostingRepo, operationRepo, planRepo, imageRepo, quotaRepo, snapshotRepo, backupRepo, scheduleRepo, sshKeyRepo, metricsRepo, nodeRepo, "localhost:5003", pool)
+	hostingSvc.SetDomainAutostart(ai.DomainAutostart)
+	if err := hostingSvc.SetSchedulerPolicy(hosting_service.SchedulerPolicy(ai.SchedulerPolicy)); err != nil {
+		return nil, err
+	}
+	nodes, err := hostingSvc.ConnectNodes()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
+	// 노드를 등록하지 않았으면 LibvirtAgentAddr(비어 있으면 로컬 libvirt) 하나로 동작한다
+	if nodes == 0 {
+		address := "qemu:///system"
+		if ai.LibvirtAgentAddr != "" {
+			address = "agent://" + ai.LibvirtAgentAddr
+		}
+		if err := pool.AddNode(&hosting_service.Node{Name: LocalNodeName, Address: address}); err != nil {
+			return nil, err
+		}
+	}
+	workers := ai.JobWorkers
+	if workers <= 0 {
+		workers = 4
+	}
+	if err := hostingSvc.StartWorkers(context.Background(), workers); err != nil {
+		return nil, err
+	}
+	interval := ai.ReconcileInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	hostingSvc.StartReconciler(context.Background(), interval)
+	recovery := ai.RecoveryInterval
+	if recovery <= 0 {
+		recovery = hosting_service.DefaultRecoveryInterval
+	}
+	hostingSvc.StartRecovery(context.Background(), recovery)
+	hostingSvc.StartEventListener(context.Background())
+	hostingSvc.StartBackupScheduler(context.Background(), time.Minute)
+	hostingSvc.StartMetricsCollector(context.Background())
 
-	hostingSvc := hosting_service.NewService(hostingRepo, "localhost:5003", libvirtManager)
 	hostingHandler := controller.NewHostingHandler(hostingSvc, userSvc)
+	operationHandler := controller.NewOperationHandler(hostingSvc, userSvc)
+	planHandler := controller.NewPlanHandler(hostingSvc)
+	imageHandler := controller.NewImageHandler(hostingSvc)
+	quotaHandler := controller.NewQuotaHandler(hostingSvc, userSvc)
+	reconcileHandler := controller.NewReconcileHandler(hostingSvc)
+	snapshotHandler := controller.NewSnapshotHandler(hostingSvc, userSvc)
+	backupHandler := controller.NewBackupHandler(hostingSvc, userSvc)
+	sshKeyHandler := controller.NewSSHKeyHandler(hostingSvc, userSvc)
+	consoleHandler := controller.NewConsoleHandler(hostingSvc, userSvc)
+	metricsHandler := controller.NewMetricsHandler(hostingSvc, userSvc)
+	nodeHandler := controller.NewNodeHandler(hostingSvc)
 	return &HandlerRegistry{
-		UserHandler:    userHandler,
-		JWTManager:     tokens,
-		AuthMiddleware: authMw,
-		HostingHandler: hostingHandler,
+		UserHandler:      userHandler,
+		JWTManager:       tokens,
+		AuthMiddleware:   authMw,
+		HostingHandler:   hostingHandler,
+		OperationHandler: operationHandler,
+		PlanHandler:      planHandler,
+		ImageHandler:     imageHandler,
+		QuotaHandler:     quotaHandler,
+		ReconcileHandler: reconcileHandler,
+		SnapshotHandler:  snapshotHandler,
+		BackupHandler:    backupHandler,
+		SSHKeyHandler:    sshKeyHandler,
+		ConsoleHandler:   consoleHandler,
+		MetricsHandler:   metricsHandler,
+		NodeHandler:      nodeHandler,
 	}, nil
 }
 
+// LocalNodeName - nodes 테이블이 비어 있을 때 쓰는 노드 (hostings.node_name에는 기록하지 않는다)
+const LocalNodeName = "local"
+
+// dialNode - "agent://host:port"는 libvirt-agent, 나머지는 libvirt URI로 연결한다
+func dialNode(address, backupDir, agentToken string) (hosting_service.Hypervisor, error) {
+	if addr, ok := strings.CutPrefix(address, "agent://"); ok {
+		return hypervisor.NewLibvirtHypervisor(agent.NewClient(addr, agentToken)), nil
+	}
+	libvirtManager, err := libvirt.NewLibvirtManagerURI(address)
+	if err != nil {
+		return nil, err
+	}
+	libvirtManager.BackupDir = backupDir
+	return hypervisor.NewLibvirtHypervisor(libvirtManager), nil
+}
+
 func (c DBConfig) DSN() string {
 	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true",
 		c.User, c.Password, c.Host, c.Port, c.Name)
@@ -7,8 +7,19 @@ import (
 )
 
 type HandlerRegistry struct {
-	UserHandler    *controller.UserHandler
-	JWTManager     *token.JWTManager
-	AuthMiddleware *middleware.AuthMiddleware
-	HostingHandler *controller.HostingHandler
+	UserHandler      *controller.UserHandler
+	JWTManager       *token.JWTManager
+	AuthMiddleware   *middleware.AuthMiddleware
+	HostingHandler   *controller.HostingHandler
+	OperationHandler *controller.OperationHandler
+	PlanHandler      *controller.PlanHandler
+	ImageHandler     *controller.ImageHandler
+	QuotaHandler     *controller.QuotaHandler
+	ReconcileHandler *controller.ReconcileHandler
+	SnapshotHandler  *controller.SnapshotHandler
+	BackupHandler    *controller.BackupHandler
+	SSHKeyHandler    *controller.SSHKeyHandler
+	ConsoleHandler   *controller.ConsoleHandler
+	MetricsHandler   *controller.MetricsHandler
+	NodeHandler      *controller.NodeHandler
 }
@@ -20,12 +20,105 @@ func RegisterRoutes(r *gin.Engine, h *HandlerRegistry) {
 
 	hostingUserProtected := r.Group("/hosting", h.AuthMiddleware.RequireUser(), h.AuthMiddleware.RequireSelfOrAdmin())
 	{
-		hostingUserProtected.POST("/:username", h.HostingHandler.CreateHosting)
-		hostingUserProtected.POST("/", h.HostingHandler.CreateHosting)
-		hostingUserProtected.GET("/:username/status", h.HostingHandler.GetVMStatus)
-		hostingUserProtected.GET("/:username/detail", h.HostingHandler.GetVMDetail)
-		hostingUserProtected.POST("/:username/start", h.HostingHandler.StartVM)
-		hostingUserProtected.POST("/:username/stop", h.HostingHandler.StopVM)
-		hostingUserProtected.DELETE("/:username", h.HostingHandler.DeleteVM)
+		hostingUserProtected.POST("/:username/vms", h.HostingHandler.CreateHosting)
+		hostingUserProtected.GET("/:username/vms", h.HostingHandler.ListVMs)
+		hostingUserProtected.GET("/:username/vms/:vmID", h.HostingHandler.GetVMDetail)
+		hostingUserProtected.GET("/:username/vms/:vmID/status", h.HostingHandler.GetVMStatus)
+		hostingUserProtected.POST("/:username/vms/:vmID/start", h.HostingHandler.StartVM)
+		hostingUserProtected.POST("/:username/vms/:vmID/stop", h.HostingHandler.StopVM)
+		hostingUserProtected.POST("/:username/vms/:vmID/reboot", h.HostingHandler.RebootVM)
+		hostingUserProtected.POST("/:username/vms/:vmID/reset", h.HostingHandler.ResetVM)
+		hostingUserProtected.POST("/:username/vms/:vmID/pause", h.HostingHandler.PauseVM)
+		hostingUserProtected.POST("/:username/vms/:vmID/resume", h.HostingHandler.ResumeVM)
+		hostingUserProtected.POST("/:username/vms/:vmID/resize", h.HostingHandler.ResizeVM)
+		hostingUserProtected.POST("/:username/vms/:vmID/flatten", h.HostingHandler.FlattenVM)
+		hostingUserProtected.POST("/:username/vms/:vmID/password", h.HostingHandler.ResetGuestPassword)
+		hostingUserProtected.DELETE("/:username/vms/:vmID", h.HostingHandler.DeleteVM)
+		hostingUserProtected.GET("/:username/quota", h.QuotaHandler.GetQuota)
+		hostingUserProtected.GET("/:username/events", h.HostingHandler.StreamEvents)
+		hostingUserProtected.GET("/:username/vms/:vmID/backup-schedule", h.BackupHandler.GetSchedule)
+		hostingUserProtected.PUT("/:username/vms/:vmID/backup-schedule", h.BackupHandler.SetSchedule)
+		hostingUserProtected.DELETE("/:username/vms/:vmID/backup-schedule", h.BackupHandler.DeleteSchedule)
+		hostingUserProtected.GET("/:username/backups", h.BackupHandler.ListBackups)
+		hostingUserProtected.POST("/:username/backups", h.BackupHandler.CreateBackup)
+		hostingUserProtected.POST("/:username/backups/:backupID/restore", h.BackupHandler.RestoreBackup)
+		hostingUserProtected.POST("/:username/backups/:backupID/clone", h.BackupHandler.CloneBackup)
+		hostingUserProtected.DELETE("/:username/backups/:backupID", h.BackupHandler.DeleteBackup)
+		hostingUserProtected.GET("/:username/snapshots", h.SnapshotHandler.ListSnapshots)
+		hostingUserProtected.POST("/:username/snapshots", h.SnapshotHandler.CreateSnapshot)
+		hostingUserProtected.POST("/:username/snapshots/:snapshotID/revert", h.SnapshotHandler.RevertSnapshot)
+		hostingUserProtected.DELETE("/:username/snapshots/:snapshotID", h.SnapshotHandler.DeleteSnapshot)
+		hostingUserProtected.GET("/:username/ssh-keys", h.SSHKeyHandler.ListSSHKeys)
+		hostingUserProtected.POST("/:username/ssh-keys", h.SSHKeyHandler.AddSSHKey)
+		hostingUserProtected.DELETE("/:username/ssh-keys/:keyID", h.SSHKeyHandler.DeleteSSHKey)
+		hostingUserProtected.POST("/:username/vms/:vmID/console/ticket", h.ConsoleHandler.IssueTicket)
+		hostingUserProtected.POST("/:username/vnc-ticket", h.ConsoleHandler.IssueVNCTicket)
+		hostingUserProtected.GET("/:username/metrics", h.MetricsHandler.GetMetrics)
+	}
+
+	// 브라우저 WebSocket은 Authorization 헤더를 보낼 수 없으므로 JWT 대신 콘솔/VNC 티켓으로 인증한다
+	r.GET("/hosting/:username/console", h.ConsoleHandler.Console)
+	r.GET("/hosting/:username/vnc", h.ConsoleHandler.VNC)
+
+	operationAdminProtected := r.Group("/operations", h.AuthMiddleware.RequireAdmin())
+	{
+		operationAdminProtected.GET("/failed", h.OperationHandler.ListFailedOperations)
+	}
+
+	operationProtected := r.Group("/operations", h.AuthMiddleware.RequireUserOrAdmin())
+	{
+		operationProtected.GET("/:id", h.OperationHandler.GetOperation)
+	}
+
+	reconcileAdminProtected := r.Group("/reconcile", h.AuthMiddleware.RequireAdmin())
+	{
+		reconcileAdminProtected.GET("", h.ReconcileHandler.GetReport)
+		reconcileAdminProtected.POST("", h.ReconcileHandler.Run)
+	}
+
+	quotaAdminProtected := r.Group("/quotas", h.AuthMiddleware.RequireAdmin())
+	{
+		quotaAdminProtected.GET("/default", h.QuotaHandler.GetDefaultQuota)
+		quotaAdminProtected.PUT("/default", h.QuotaHandler.SetDefaultQuota)
+		quotaAdminProtected.GET("/:username", h.QuotaHandler.GetQuota)
+		quotaAdminProtected.PUT("/:username", h.QuotaHandler.SetQuota)
+		quotaAdminProtected.DELETE("/:username", h.QuotaHandler.ResetQuota)
+	}
+
+	planProtected := r.Group("/plans", h.AuthMiddleware.RequireUserOrAdmin())
+	{
+		planProtected.GET("", h.PlanHandler.ListPlans)
+	}
+
+	planAdminProtected := r.Group("/plans", h.AuthMiddleware.RequireAdmin())
+	{
+		planAdminProtected.POST("", h.PlanHandler.CreatePlan)
+		planAdminProtected.PUT("/:name", h.PlanHandler.UpdatePlan)
+		planAdminProtected.DELETE("/:name", h.PlanHandler.DeletePlan)
+	}
+
+	nodeAdminProtected := r.Group("/nodes", h.AuthMiddleware.RequireAdmin())
+	{
+		nodeAdminProtected.GET("", h.NodeHandler.ListNodes)
+		nodeAdminProtected.POST("", h.NodeHandler.CreateNode)
+		nodeAdminProtected.PUT("/:name", h.NodeHandler.UpdateNode)
+		nodeAdminProtected.DELETE("/:name", h.NodeHandler.DeleteNode)
+	}
+
+	hostingAdminProtected := r.Group("/admin/hostings", h.AuthMiddleware.RequireAdmin())
+	{
+		hostingAdminProtected.POST("/:id/migrate", h.NodeHandler.MigrateHosting)
+	}
+
+	imageProtected := r.Group("/images", h.AuthMiddleware.RequireUserOrAdmin())
+	{
+		imageProtected.GET("", h.ImageHandler.ListImages)
+	}
+
+	imageAdminProtected := r.Group("/images", h.AuthMiddleware.RequireAdmin())
+	{
+		imageAdminProtected.POST("", h.ImageHandler.RegisterImage)
+		imageAdminProtected.POST("/:name/verify", h.ImageHandler.VerifyImage)
+		imageAdminProtected.DELETE("/:name", h.ImageHandler.RetireImage)
 	}
 }
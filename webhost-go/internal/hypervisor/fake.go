@@ -0,0 +1,1046 @@
+package hypervisor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+	"webhost-go/webhost-go/pkg/libvirt"
+	"webhost-go/webhost-go/pkg/nocloud"
+)
+
+// 처음부터 템플릿 디렉토리에 있는 가짜 이미지 (sha256은 빈 파일의 값)
+const (
+	FakeTemplate       = "ubuntu-22.04-server-cloudimg-amd64.img"
+	FakeTemplateSHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// FakeHypervisor - libvirt 없이 도메인 상태와 디스크를 메모리에서 흉내 내는 구현체 (테스트/CI용)
+type FakeHypervisor struct {
+	mu      sync.Mutex
+	network *net.IPNet
+	gateway net.IP
+	domains map[string]*FakeDomain
+	disks   map[string]bool
+	subs    map[chan hosting_service.DomainEvent]struct{}
+	// 도메인 이름 → 스냅샷 이름 → 스냅샷 시점의 도메인 상태
+	snapshots map[string]map[string]hosting_service.DomainState
+	// 백업 파일 경로 → 백업 시점의 디스크 크기(GB)
+	backups map[string]int
+	// 템플릿 파일 이름 → sha256
+	images map[string]string
+	// 도메인 이름 → 열려 있는 콘솔 (도메인마다 하나)
+	consoles map[string]*fakeConsole
+	// 도메인 이름 → ImportVM(define=false)으로 디스크만 준비된 live migration 대상
+	incoming map[string]*FakeDomain
+	// migrateErr - MigrateVM이 돌려줄 오류 (SetMigrationError)
+	migrateErr error
+	// listErr - ListDomains가 돌려줄 오류 (SetListError)
+	listErr error
+}
+
+type FakeDomain struct {
+	Name     string
+	IP       net.IP
+	State    hosting_service.DomainState
+	VCPUs    int
+	MemoryMB int
+	DiskPath string
+	DiskGB   int
+	// BackingFile - 템플릿 overlay면 템플릿 경로, 독립 디스크면 빈 문자열
+	BackingFile string
+	// UserData - libvirt 구현과 같은 방식으로 만든 cloud-init user-data
+	UserData string
+	// SeedFormat - seed 이미지 형식 (nocloud.FormatISO, FormatVFAT)
+	SeedFormat nocloud.Format
+	// Features - 도메인 XML에 반영된 선택 기능
+	Features hosting_service.DomainFeatures
+	// VNCPassword - VNC 장치 비밀번호 (비어 있으면 VNC 장치 없음)
+	VNCPassword string
+	// Usage - AddUsage로 쌓은 누적 사용량 (CPU 시간, 미사용 메모리, 디스크/네트워크 바이트)
+	Usage hosting_service.DomainStats
+	// GuestAgent - 게스트 에이전트가 응답하는지 (생성 시 true, SetGuestAgent로 바꾼다)
+	GuestAgent bool
+	// GuestPasswords - SetGuestPassword로 바꾼 계정 → 비밀번호
+	GuestPasswords map[string]string
+	// Boots - 생성 후 부팅한 횟수 (시작, 재부팅, 리셋마다 늘어난다)
+	Boots int
+	// IgnoreShutdown - ACPI 종료/재부팅 요청을 무시하는 게스트 (SetIgnoreShutdown)
+	IgnoreShutdown bool
+	// Autostart - 호스트 재부팅(RebootHost) 때 자동으로 부팅되는지
+	Autostart bool
+}
+
+// NewFakeHypervisor - libvirt default 네트워크(192.168.122.0/24)를 흉내 낸다
+func NewFakeHypervisor() *FakeHypervisor {
+	_, cidr, _ := net.ParseCIDR("192.168.122.0/24")
+	return &FakeHypervisor{
+		network:   cidr,
+		gateway:   net.ParseIP("192.168.122.1"),
+		domains:   make(map[string]*FakeDomain),
+		disks:     make(map[string]bool),
+		subs:      make(map[chan hosting_service.DomainEvent]struct{}),
+		snapshots: make(map[string]map[string]hosting_service.DomainState),
+		backups:   make(map[string]int),
+		images:    map[string]string{FakeTemplate: FakeTemplateSHA256},
+		consoles:  make(map[string]*fakeConsole),
+		incoming:  make(map[string]*FakeDomain),
+	}
+}
+
+func (f *FakeHypervisor) CreateVM(spec hosting_service.VMSpec, progress func(step string)) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, step := range []string{
+		hosting_service.StepCopyingDisk,
+		hosting_service.StepResizingDisk,
+		hosting_service.StepBuildingCloudInit,
+		hosting_service.StepBooting,
+	} {
+		if progress != nil {
+			progress(step)
+		}
+	}
+
+	if _, ok := f.domains[spec.Name]; ok {
+		return "", fmt.Errorf("도메인이 이미 존재합니다: %s", spec.Name)
+	}
+	if spec.SourceDisk != "" {
+		size, ok := f.backups[spec.SourceDisk]
+		if !ok {
+			return "", fmt.Errorf("백업 파일이 없습니다: %s", spec.SourceDisk)
+		}
+		if spec.DiskGB < size {
+			return "", fmt.Errorf("디스크는 줄일 수 없습니다 (백업 %dGB, 요청 %dGB)", size, spec.DiskGB)
+		}
+	}
+
+	ci := libvirt.CloudInit{
+		Hostname:     spec.CloudInit.Hostname,
+		User:         spec.CloudInit.User,
+		PasswordHash: spec.CloudInit.PasswordHash,
+		SSHKeys:      spec.CloudInit.SSHKeys,
+		UserData:     spec.CloudInit.UserData,
+		SeedFormat:   spec.CloudInit.SeedFormat,
+	}
+	if ci.Hostname == "" {
+		ci.Hostname = spec.Name
+	}
+	userData, err := libvirt.BuildUserData(ci)
+	if err != nil {
+		return "", err
+	}
+	seedFormat, err := nocloud.ParseFormat(ci.SeedFormat)
+	if err != nil {
+		return "", err
+	}
+
+	diskPath := filepath.Join("/fake/instances", spec.Name, "disk.qcow2")
+	backing := ""
+	if spec.SourceDisk == "" {
+		image := spec.Image
+		if image == "" {
+			image = FakeTemplate
+		}
+		if _, ok := f.images[image]; !ok {
+			return "", fmt.Errorf("템플릿 확인 실패: %s", image)
+		}
+		backing = filepath.Join("/fake/templates", image)
+	}
+	f.disks[diskPath] = true
+	f.domains[spec.Name] = &FakeDomain{
+		Name:     spec.Name,
+		IP:       spec.IP,
+		State:    hosting_service.DomainRunning,
+		VCPUs:    spec.VCPUs,
+		MemoryMB: spec.MemoryMB,
+		DiskPath: diskPath,
+		DiskGB:   spec.DiskGB,
+
+		BackingFile: backing,
+		UserData:    string(userData),
+		SeedFormat:  seedFormat,
+		Features:    spec.Features,
+		VNCPassword: spec.VNCPassword,
+		GuestAgent:  true,
+		Boots:       1,
+	}
+	f.emit(spec.Name, hosting_service.EventDefined)
+	f.emit(spec.Name, hosting_service.EventStarted)
+	return diskPath, nil
+}
+
+func (f *FakeHypervisor) DeleteVM(name string, withDisks bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, ok := f.domains[name]
+	if !ok {
+		return fmt.Errorf("도메인 조회 실패: %s", name)
+	}
+	// libvirt와 같이 디스크를 남기는 경우 스냅샷이 있으면 정의를 지울 수 없다
+	if withDisks {
+		delete(f.disks, d.DiskPath)
+		delete(f.snapshots, name)
+	} else if len(f.snapshots[name]) > 0 {
+		return fmt.Errorf("도메인 정의 삭제 실패: 스냅샷이 있습니다: %s", name)
+	}
+	delete(f.domains, name)
+	f.closeConsole(name)
+	if d.State == hosting_service.DomainRunning {
+		f.emit(name, hosting_service.EventStopped)
+	}
+	f.emit(name, hosting_service.EventUndefined)
+	return nil
+}
+
+// StartVM - libvirt 구현처럼 꺼진 도메인은 부팅하고 일시 정지된 도메인은 재개한다
+func (f *FakeHypervisor) StartVM(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, ok := f.domains[name]
+	if !ok {
+		return fmt.Errorf("도메인 조회 실패: %s", name)
+	}
+	switch d.State {
+	case hosting_service.DomainRunning:
+	case hosting_service.DomainPaused:
+		d.State = hosting_service.DomainRunning
+		f.emit(name, hosting_service.EventResumed)
+	default:
+		d.State = hosting_service.DomainRunning
+		d.Boots++
+		f.emit(name, hosting_service.EventStarted)
+	}
+	return nil
+}
+
+// StopVM - 게스트가 종료 요청을 따르면 바로 꺼진다 (SetIgnoreShutdown이면 그대로 실행 중)
+func (f *FakeHypervisor) StopVM(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, err := f.runningDomain(name)
+	if err != nil {
+		return err
+	}
+	if d.IgnoreShutdown {
+		return nil
+	}
+	f.powerOff(d)
+	return nil
+}
+
+func (f *FakeHypervisor) PowerOffVM(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, ok := f.domains[name]
+	if !ok {
+		return fmt.Errorf("도메인 조회 실패: %s", name)
+	}
+	if d.State != hosting_service.DomainRunning && d.State != hosting_service.DomainPaused {
+		return fmt.Errorf("도메인이 실행 중이 아닙니다: %s", name)
+	}
+	f.powerOff(d)
+	return nil
+}
+
+// RebootVM - 재부팅해도 도메인 상태는 running 그대로이고 Boots만 늘어난다
+func (f *FakeHypervisor) RebootVM(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, err := f.runningDomain(name)
+	if err != nil {
+		return err
+	}
+	if !d.IgnoreShutdown {
+		d.Boots++
+	}
+	return nil
+}
+
+func (f *FakeHypervisor) ResetVM(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, err := f.runningDomain(name)
+	if err != nil {
+		return err
+	}
+	d.Boots++
+	return nil
+}
+
+func (f *FakeHypervisor) PauseVM(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, err := f.runningDomain(name)
+	if err != nil {
+		return err
+	}
+	d.State = hosting_service.DomainPaused
+	f.emit(name, hosting_service.EventSuspended)
+	return nil
+}
+
+func (f *FakeHypervisor) ResumeVM(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, ok := f.domains[name]
+	if !ok {
+		return fmt.Errorf("도메인 조회 실패: %s", name)
+	}
+	if d.State != hosting_service.DomainPaused {
+		return fmt.Errorf("도메인이 일시 정지 상태가 아닙니다: %s", name)
+	}
+	d.State = hosting_service.DomainRunning
+	f.emit(name, hosting_service.EventResumed)
+	return nil
+}
+
+func (f *FakeHypervisor) SetAutostart(name string, enabled bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, ok := f.domains[name]
+	if !ok {
+		return fmt.Errorf("도메인 조회 실패: %s", name)
+	}
+	d.Autostart = enabled
+	return nil
+}
+
+// runningDomain - 실행 중인 도메인 (f.mu를 잡은 상태에서 호출)
+func (f *FakeHypervisor) runningDomain(name string) (*FakeDomain, error) {
+	d, ok := f.domains[name]
+	if !ok {
+		return nil, fmt.Errorf("도메인 조회 실패: %s", name)
+	}
+	if d.State != hosting_service.DomainRunning {
+		return nil, fmt.Errorf("도메인이 실행 중이 아닙니다: %s", name)
+	}
+	return d, nil
+}
+
+// powerOff - f.mu를 잡은 상태에서 호출
+func (f *FakeHypervisor) powerOff(d *FakeDomain) {
+	d.State = hosting_service.DomainShutoff
+	f.closeConsole(d.Name)
+	f.emit(d.Name, hosting_service.EventStopped)
+}
+
+// ResizeVM - 실행 중인 도메인은 live로 적용된 것으로 본다
+func (f *FakeHypervisor) ResizeVM(name string, spec hosting_service.VMSpec) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, ok := f.domains[name]
+	if !ok {
+		return false, fmt.Errorf("도메인 조회 실패: %s", name)
+	}
+	if spec.DiskGB < d.DiskGB {
+		return false, fmt.Errorf("디스크는 줄일 수 없습니다 (현재 %dGB, 요청 %dGB)", d.DiskGB, spec.DiskGB)
+	}
+	d.VCPUs = spec.VCPUs
+	d.MemoryMB = spec.MemoryMB
+	d.DiskGB = spec.DiskGB
+	return d.State == hosting_service.DomainRunning, nil
+}
+
+func (f *FakeHypervisor) CreateSnapshot(name, snapshot, description string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, ok := f.domains[name]
+	if !ok {
+		return fmt.Errorf("도메인 조회 실패: %s", name)
+	}
+	if d.Features.UEFI {
+		return fmt.Errorf("%w: UEFI 펌웨어로 부팅하는 VM입니다", hosting_service.ErrSnapshotUnsupported)
+	}
+	if _, ok := f.snapshots[name][snapshot]; ok {
+		return fmt.Errorf("스냅샷이 이미 존재합니다: %s", snapshot)
+	}
+	if f.snapshots[name] == nil {
+		f.snapshots[name] = make(map[string]hosting_service.DomainState)
+	}
+	f.snapshots[name][snapshot] = d.State
+	return nil
+}
+
+// RevertSnapshot - 도메인 상태를 스냅샷 시점의 상태로 되돌린다
+func (f *FakeHypervisor) RevertSnapshot(name, snapshot string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, ok := f.domains[name]
+	if !ok {
+		return fmt.Errorf("도메인 조회 실패: %s", name)
+	}
+	state, ok := f.snapshots[name][snapshot]
+	if !ok {
+		return fmt.Errorf("스냅샷 조회 실패: %s", snapshot)
+	}
+	if d.State != state {
+		d.State = state
+		if state == hosting_service.DomainRunning {
+			f.emit(name, hosting_service.EventStarted)
+		} else {
+			f.emit(name, hosting_service.EventStopped)
+		}
+	}
+	return nil
+}
+
+func (f *FakeHypervisor) DeleteSnapshot(name, snapshot string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.snapshots[name][snapshot]; !ok {
+		return fmt.Errorf("스냅샷 조회 실패: %s", snapshot)
+	}
+	delete(f.snapshots[name], snapshot)
+	return nil
+}
+
+// BackupVM - 백업 시점의 디스크 크기만 기록한다
+func (f *FakeHypervisor) BackupVM(name, backup string) (string, int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, ok := f.domains[name]
+	if !ok {
+		return "", 0, fmt.Errorf("도메인 조회 실패: %s", name)
+	}
+	path := filepath.Join("/fake/backups", name, backup+".qcow2")
+	f.backups[path] = d.DiskGB
+	return path, int64(d.DiskGB) << 30, nil
+}
+
+func (f *FakeHypervisor) RestoreVM(name, backupPath string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, ok := f.domains[name]
+	if !ok {
+		return fmt.Errorf("도메인 조회 실패: %s", name)
+	}
+	size, ok := f.backups[backupPath]
+	if !ok {
+		return fmt.Errorf("백업 파일이 없습니다: %s", backupPath)
+	}
+	if d.State == hosting_service.DomainRunning {
+		return fmt.Errorf("실행 중인 도메인의 디스크는 교체할 수 없습니다: %s", name)
+	}
+	d.DiskGB = size
+	d.BackingFile = ""
+	delete(f.snapshots, name)
+	return nil
+}
+
+func (f *FakeHypervisor) DeleteBackup(backupPath string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.backups, backupPath)
+	return nil
+}
+
+func (f *FakeHypervisor) FlattenVM(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, ok := f.domains[name]
+	if !ok {
+		return fmt.Errorf("도메인 조회 실패: %s", name)
+	}
+	d.BackingFile = ""
+	return nil
+}
+
+func (f *FakeHypervisor) ImageChecksum(path string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sum, ok := f.images[path]
+	if !ok {
+		return "", fmt.Errorf("템플릿 열기 실패: %s", path)
+	}
+	return sum, nil
+}
+
+func (f *FakeHypervisor) IsActive(name string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, ok := f.domains[name]
+	if !ok {
+		return false, fmt.Errorf("도메인 조회 실패: %s", name)
+	}
+	return d.State == hosting_service.DomainRunning, nil
+}
+
+func (f *FakeHypervisor) DomainInfo(name string) (*hosting_service.DomainInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, ok := f.domains[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", hosting_service.ErrDomainNotFound, name)
+	}
+	return &hosting_service.DomainInfo{
+		State:    d.State,
+		MaxMemKB: uint64(d.MemoryMB) * 1024,
+		MemoryKB: uint64(d.MemoryMB) * 1024,
+		VCPUs:    uint16(d.VCPUs),
+	}, nil
+}
+
+func (f *FakeHypervisor) ListDomains() ([]hosting_service.DomainStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+	list := make([]hosting_service.DomainStatus, 0, len(f.domains))
+	for _, d := range f.domains {
+		list = append(list, hosting_service.DomainStatus{Name: d.Name, State: d.State})
+	}
+	return list, nil
+}
+
+// DomainStats - 실행 중인 도메인의 설정값과 AddUsage로 쌓은 카운터
+func (f *FakeHypervisor) DomainStats() ([]hosting_service.DomainStats, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var list []hosting_service.DomainStats
+	for _, d := range f.domains {
+		if d.State != hosting_service.DomainRunning {
+			continue
+		}
+		st := d.Usage
+		st.VMName = d.Name
+		st.VCPUs = d.VCPUs
+		st.MemoryKB = uint64(d.MemoryMB) * 1024
+		list = append(list, st)
+	}
+	return list, nil
+}
+
+func (f *FakeHypervisor) Events(ctx context.Context) (<-chan hosting_service.DomainEvent, error) {
+	ch := make(chan hosting_service.DomainEvent, 64)
+
+	f.mu.Lock()
+	f.subs[ch] = struct{}{}
+	f.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		f.mu.Lock()
+		delete(f.subs, ch)
+		close(ch)
+		f.mu.Unlock()
+	}()
+	return ch, nil
+}
+
+// emit - 구독자에게 이벤트 전달 (f.mu를 잡은 상태에서 호출, 느린 구독자는 버린다)
+func (f *FakeHypervisor) emit(name, eventType string) {
+	ev := hosting_service.DomainEvent{VMName: name, Type: eventType, Time: time.Now()}
+	for ch := range f.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// OpenConsole - 입력을 그대로 출력으로 돌려주는 콘솔 (터미널 echo 흉내).
+// libvirt의 DomainConsoleForce처럼 이전 콘솔은 끊고, 도메인이 멈추면 닫힌다.
+func (f *FakeHypervisor) OpenConsole(name string) (io.ReadWriteCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, ok := f.domains[name]
+	if !ok {
+		return nil, fmt.Errorf("도메인 조회 실패: %s", name)
+	}
+	if d.State != hosting_service.DomainRunning {
+		return nil, fmt.Errorf("실행 중인 도메인이 아닙니다: %s", name)
+	}
+	f.closeConsole(name)
+	r, w := io.Pipe()
+	con := &fakeConsole{r: r, w: w}
+	f.consoles[name] = con
+	return con, nil
+}
+
+// closeConsole - f.mu를 잡은 상태에서 호출
+func (f *FakeHypervisor) closeConsole(name string) {
+	if con, ok := f.consoles[name]; ok {
+		con.Close()
+		delete(f.consoles, name)
+	}
+}
+
+type fakeConsole struct {
+	r *io.PipeReader
+	w *io.PipeWriter
+}
+
+func (c *fakeConsole) Read(p []byte) (int, error)  { return c.r.Read(p) }
+func (c *fakeConsole) Write(p []byte) (int, error) { return c.w.Write(p) }
+
+func (c *fakeConsole) Close() error {
+	c.w.Close()
+	return c.r.Close()
+}
+
+// FakeRFBVersion - 가짜 VNC 서버가 연결 직후 보내는 RFB 버전 문자열
+const FakeRFBVersion = "RFB 003.008\n"
+
+// OpenVNC - RFB 버전 문자열을 보낸 뒤 받은 바이트를 그대로 돌려주는 VNC 서버 흉내
+func (f *FakeHypervisor) OpenVNC(name string) (io.ReadWriteCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, ok := f.domains[name]
+	if !ok {
+		return nil, fmt.Errorf("도메인 조회 실패: %s", name)
+	}
+	if d.State != hosting_service.DomainRunning {
+		return nil, fmt.Errorf("실행 중인 도메인이 아닙니다: %s", name)
+	}
+	if d.VNCPassword == "" {
+		return nil, fmt.Errorf("%w: %s", libvirt.ErrNoVNC, name)
+	}
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		if _, err := server.Write([]byte(FakeRFBVersion)); err != nil {
+			return
+		}
+		_, _ = io.Copy(server, server)
+	}()
+	return client, nil
+}
+
+// GuestInfo - cloud-init이 설치한 에이전트처럼 호스트명과 도메인 IP를 보고한다
+func (f *FakeHypervisor) GuestInfo(name string) (*hosting_service.GuestInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, err := f.guestAgent(name)
+	if err != nil {
+		return nil, err
+	}
+	iface := hosting_service.GuestInterface{Name: "enp1s0", Addresses: []string{}}
+	if d.IP != nil {
+		ones, _ := f.network.Mask.Size()
+		iface.Addresses = append(iface.Addresses, fmt.Sprintf("%s/%d", d.IP, ones))
+	}
+	return &hosting_service.GuestInfo{
+		Hostname:      d.Name,
+		OSID:          "ubuntu",
+		OSPrettyName:  "Ubuntu 22.04.4 LTS",
+		OSVersionID:   "22.04",
+		KernelRelease: "5.15.0-105-generic",
+		Interfaces:    []hosting_service.GuestInterface{iface},
+	}, nil
+}
+
+func (f *FakeHypervisor) SetGuestPassword(name, user, password string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, err := f.guestAgent(name)
+	if err != nil {
+		return err
+	}
+	if d.GuestPasswords == nil {
+		d.GuestPasswords = make(map[string]string)
+	}
+	d.GuestPasswords[user] = password
+	return nil
+}
+
+// SetGuestNetwork - libvirt 구현처럼 이 노드 네트워크의 주소만 받는다
+func (f *FakeHypervisor) SetGuestNetwork(name string, ip net.IP) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, err := f.guestAgent(name)
+	if err != nil {
+		return err
+	}
+	if !f.network.Contains(ip) {
+		return fmt.Errorf("이 노드의 네트워크(%s)에 없는 주소입니다: %s", f.network, ip)
+	}
+	d.IP = ip
+	return nil
+}
+
+// guestAgent - 에이전트가 응답할 수 있는 도메인 (f.mu를 잡은 상태에서 호출)
+func (f *FakeHypervisor) guestAgent(name string) (*FakeDomain, error) {
+	d, ok := f.domains[name]
+	if !ok {
+		return nil, fmt.Errorf("도메인 조회 실패: %s", name)
+	}
+	if d.State != hosting_service.DomainRunning || !d.GuestAgent {
+		return nil, fmt.Errorf("%w: %s", hosting_service.ErrGuestAgentUnavailable, name)
+	}
+	return d, nil
+}
+
+func (f *FakeHypervisor) UsableIPs(used []net.IP) ([]net.IP, error) {
+	usedMap := make(map[string]bool)
+	for _, ip := range used {
+		usedMap[ip.String()] = true
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// 네트워크 주소, 게이트웨이(.1), 브로드캐스트(.255) 제외
+	base := f.network.IP.To4()
+	var ips []net.IP
+	for i := 2; i < 255; i++ {
+		ip := net.IPv4(base[0], base[1], base[2], byte(i))
+		if usedMap[ip.String()] {
+			continue
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
+
+// Domain - 테스트에서 도메인 상태를 확인하기 위한 스냅샷
+func (f *FakeHypervisor) Domain(name string) (FakeDomain, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, ok := f.domains[name]
+	if !ok {
+		return FakeDomain{}, false
+	}
+	return *d, true
+}
+
+// AddUsage - 게스트가 자원을 쓴 것처럼 누적 카운터를 늘린다 (MemoryUnusedKB는 덮어쓴다)
+func (f *FakeHypervisor) AddUsage(name string, delta hosting_service.DomainStats) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, ok := f.domains[name]
+	if !ok {
+		return
+	}
+	d.Usage.CPUTimeNs += delta.CPUTimeNs
+	d.Usage.MemoryUnusedKB = delta.MemoryUnusedKB
+	d.Usage.BlockReadBytes += delta.BlockReadBytes
+	d.Usage.BlockWriteBytes += delta.BlockWriteBytes
+	d.Usage.NetRxBytes += delta.NetRxBytes
+	d.Usage.NetTxBytes += delta.NetTxBytes
+}
+
+// RebootHost - compute 노드 재부팅을 흉내 낸다. 모든 도메인이 꺼지고 Autostart 도메인만 다시 부팅된다
+func (f *FakeHypervisor) RebootHost() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, d := range f.domains {
+		if d.State == hosting_service.DomainRunning || d.State == hosting_service.DomainPaused {
+			f.powerOff(d)
+		}
+		if d.Autostart {
+			d.State = hosting_service.DomainRunning
+			d.Boots++
+			f.emit(d.Name, hosting_service.EventStarted)
+		}
+	}
+}
+
+// SetIgnoreShutdown - 종료/재부팅 요청을 무시하는 게스트를 흉내 낸다
+func (f *FakeHypervisor) SetIgnoreShutdown(name string, ignore bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if d, ok := f.domains[name]; ok {
+		d.IgnoreShutdown = ignore
+	}
+}
+
+// SetGuestAgent - 게스트 에이전트가 없는 VM(채널 도입 전 VM, 에이전트 삭제)을 흉내 낸다
+func (f *FakeHypervisor) SetGuestAgent(name string, available bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if d, ok := f.domains[name]; ok {
+		d.GuestAgent = available
+	}
+}
+
+// SetState - 게스트 내부 종료나 크래시처럼 API를 거치지 않은 상태 변화를 흉내 낸다
+func (f *FakeHypervisor) SetState(name string, state hosting_service.DomainState) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, ok := f.domains[name]
+	if !ok {
+		return
+	}
+	d.State = state
+	if state != hosting_service.DomainRunning {
+		f.closeConsole(name)
+	}
+	switch state {
+	case hosting_service.DomainRunning:
+		f.emit(name, hosting_service.EventStarted)
+	case hosting_service.DomainShutoff:
+		f.emit(name, hosting_service.EventStopped)
+	case hosting_service.DomainPaused:
+		f.emit(name, hosting_service.EventSuspended)
+	case hosting_service.DomainCrashed:
+		f.emit(name, hosting_service.EventCrashed)
+	}
+}
+
+// Snapshots - 테스트에서 도메인에 남아 있는 스냅샷 이름을 확인
+func (f *FakeHypervisor) Snapshots(name string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	names := make([]string, 0, len(f.snapshots[name]))
+	for snap := range f.snapshots[name] {
+		names = append(names, snap)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BackupExists - 백업 파일이 아직 남아 있는지 확인
+func (f *FakeHypervisor) BackupExists(path string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, ok := f.backups[path]
+	return ok
+}
+
+// SetImage - 템플릿 디렉토리에 이미지 파일을 두거나 내용을 바꾼다
+func (f *FakeHypervisor) SetImage(path, sha256 string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.images[path] = sha256
+}
+
+// DiskExists - 디스크가 아직 남아 있는지 확인
+func (f *FakeHypervisor) DiskExists(path string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.disks[path]
+}
+
+// fakeMigrationTargets - ListenMigration으로 등록한 URI → 가짜 하이퍼바이저 (원격 libvirtd를 흉내 낸다)
+var (
+	fakeMigrationMu      sync.Mutex
+	fakeMigrationTargets = make(map[string]*FakeHypervisor)
+)
+
+// ListenMigration - 다른 FakeHypervisor가 uri로 MigrateVM 할 수 있게 한다
+func (f *FakeHypervisor) ListenMigration(uri string) {
+	fakeMigrationMu.Lock()
+	defer fakeMigrationMu.Unlock()
+
+	fakeMigrationTargets[uri] = f
+}
+
+// SetMigrationError - 이후 MigrateVM이 err로 실패한다 (nil이면 다시 성공)
+func (f *FakeHypervisor) SetMigrationError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.migrateErr = err
+}
+
+// SetListError - 이후 ListDomains가 err로 실패한다 (nil이면 다시 성공). 연결이 끊긴 노드를 흉내 낸다
+func (f *FakeHypervisor) SetListError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.listErr = err
+}
+
+// SetNetwork - 노드마다 다른 /24 네트워크를 흉내 낸다 (게이트웨이는 .1)
+func (f *FakeHypervisor) SetNetwork(cidr string) error {
+	ip, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return err
+	}
+	if ones, bits := network.Mask.Size(); ones != 24 || bits != 32 {
+		return fmt.Errorf("fake 네트워크는 IPv4 /24만 지원합니다: %s", cidr)
+	}
+	gw := ip.Mask(network.Mask).To4()
+	gw[3] = 1
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.network, f.gateway = network, gw
+	return nil
+}
+
+// fakeArchive - ExportVM 스트림 (도메인 상태를 JSON으로 옮긴다)
+type fakeArchive struct {
+	Content string
+	Domain  FakeDomain
+}
+
+func (f *FakeHypervisor) ExportVM(name, content string) (io.ReadCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	d, ok := f.domains[name]
+	if !ok {
+		return nil, fmt.Errorf("도메인 조회 실패: %s", name)
+	}
+	if content == hosting_service.ArchiveFull && d.State != hosting_service.DomainShutoff {
+		return nil, fmt.Errorf("%w: 디스크를 복사하려면 도메인을 꺼야 합니다", libvirt.ErrDomainActive)
+	}
+	data, err := json.Marshal(fakeArchive{Content: content, Domain: *d})
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// ImportVM - ArchiveDefinition이면 디스크가 공유 저장소에 이미 있는 것으로 본다.
+// 정의할 때는 libvirt 구현처럼 사양과 선택 기능을 아카이브가 아닌 spec에서 가져온다
+func (f *FakeHypervisor) ImportVM(spec hosting_service.VMSpec, r io.Reader, define bool) error {
+	name := spec.Name
+	var a fakeArchive
+	if err := json.NewDecoder(r).Decode(&a); err != nil {
+		return fmt.Errorf("아카이브 읽기 실패: %w", err)
+	}
+	if a.Domain.Name != name {
+		return fmt.Errorf("아카이브의 도메인 이름이 다릅니다: %s", a.Domain.Name)
+	}
+	seedFormat, err := nocloud.ParseFormat(spec.CloudInit.SeedFormat)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.domains[name]; ok {
+		return fmt.Errorf("도메인이 이미 존재합니다: %s", name)
+	}
+	if a.Content != hosting_service.ArchiveDefinition && f.disks[a.Domain.DiskPath] {
+		return fmt.Errorf("인스턴스 디렉토리가 이미 있습니다: %s", filepath.Dir(a.Domain.DiskPath))
+	}
+	f.disks[a.Domain.DiskPath] = true
+
+	d := a.Domain
+	if !define {
+		f.incoming[name] = &d
+		return nil
+	}
+	d.State = hosting_service.DomainShutoff
+	d.VCPUs, d.MemoryMB = spec.VCPUs, spec.MemoryMB
+	d.Features, d.VNCPassword = spec.Features, spec.VNCPassword
+	d.SeedFormat = seedFormat
+	f.domains[name] = &d
+	f.emit(name, hosting_service.EventDefined)
+	return nil
+}
+
+// MigrateVM - destURI에 ListenMigration 한 FakeHypervisor로 도메인을 상태 그대로 옮긴다.
+// copyStorage면 대상에 ImportVM으로 디스크가 준비되어 있어야 한다
+func (f *FakeHypervisor) MigrateVM(name, destURI string, copyStorage bool) error {
+	fakeMigrationMu.Lock()
+	dst := fakeMigrationTargets[destURI]
+	fakeMigrationMu.Unlock()
+
+	f.mu.Lock()
+	d, ok := f.domains[name]
+	switch {
+	case f.migrateErr != nil:
+		f.mu.Unlock()
+		return fmt.Errorf("도메인 이전 실패: %w", f.migrateErr)
+	case !ok:
+		f.mu.Unlock()
+		return fmt.Errorf("도메인 조회 실패: %s", name)
+	case d.State != hosting_service.DomainRunning && d.State != hosting_service.DomainPaused:
+		f.mu.Unlock()
+		return fmt.Errorf("도메인 이전 실패: 실행 중인 도메인이 아닙니다: %s", name)
+	case dst == nil || dst == f:
+		f.mu.Unlock()
+		return fmt.Errorf("도메인 이전 실패: 대상 libvirtd에 연결할 수 없습니다: %s", destURI)
+	}
+	moved := *d
+	f.mu.Unlock()
+
+	dst.mu.Lock()
+	if _, ok := dst.domains[name]; ok {
+		dst.mu.Unlock()
+		return fmt.Errorf("도메인 이전 실패: 대상에 같은 이름의 도메인이 있습니다: %s", name)
+	}
+	if copyStorage && !dst.disks[moved.DiskPath] {
+		dst.mu.Unlock()
+		return fmt.Errorf("도메인 이전 실패: 대상에 디스크가 없습니다: %s", moved.DiskPath)
+	}
+	dst.disks[moved.DiskPath] = true
+	delete(dst.incoming, name)
+	dst.domains[name] = &moved
+	dst.emit(name, hosting_service.EventDefined)
+	dst.emit(name, hosting_service.EventStarted)
+	dst.mu.Unlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.domains, name)
+	delete(f.snapshots, name)
+	f.closeConsole(name)
+	f.emit(name, hosting_service.EventStopped)
+	f.emit(name, hosting_service.EventUndefined)
+	return nil
+}
+
+func (f *FakeHypervisor) DiscardVM(name string, withFiles bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	diskPath := filepath.Join("/fake/instances", name, "disk.qcow2")
+	if d, ok := f.domains[name]; ok {
+		if d.State == hosting_service.DomainRunning || d.State == hosting_service.DomainPaused {
+			return fmt.Errorf("%w: 정리하려면 도메인을 꺼야 합니다", libvirt.ErrDomainActive)
+		}
+		diskPath = d.DiskPath
+		delete(f.domains, name)
+		delete(f.snapshots, name)
+		f.emit(name, hosting_service.EventUndefined)
+	}
+	delete(f.incoming, name)
+	if withFiles {
+		delete(f.disks, diskPath)
+	}
+	return nil
+}
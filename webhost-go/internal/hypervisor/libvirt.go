@@ -0,0 +1,358 @@
+package hypervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+	"webhost-go/webhost-go/pkg/libvirt"
+
+	golibvirt "github.com/digitalocean/go-libvirt"
+)
+
+// Backend - 로컬 LibvirtManager 또는 원격 libvirt-agent 클라이언트(agent.Client)
+type Backend interface {
+	StartUbuntuVMWithStaticIP(cfg libvirt.VMConfig, staticIP net.IP, progress func(step string)) error
+	DeleteDomain(name string, withDisks bool) error
+	Start(name string) error
+	Shutdown(name string) error
+	Destroy(name string) error
+	Reboot(name string) error
+	Reset(name string) error
+	Suspend(name string) error
+	Resume(domainName string) error
+	SetAutostart(name string, enabled bool) error
+	ResizeDomain(name string, vcpus, memoryMB, diskGB int) (bool, error)
+	CreateSnapshot(domainName, snapshotName, description string) error
+	RevertSnapshot(domainName, snapshotName string) error
+	DeleteSnapshot(domainName, snapshotName string) error
+	BackupDisk(domainName, backupName string) (string, int64, error)
+	RestoreDisk(domainName, backupPath string) error
+	DeleteBackup(backupPath string) error
+	FlattenDisk(domainName string) error
+	TemplateChecksum(name string) (string, error)
+	DomainIsActive(name string) (bool, error)
+	GetDomainInfoByName(name string) (*libvirt.DomainInfo, error)
+	ListDomains() ([]libvirt.DomainSummary, error)
+	GetAllDomainStats() ([]libvirt.DomainStats, error)
+	GuestInterfaceAddresses(name string) ([]libvirt.GuestInterface, error)
+	GetGuestInfo(name string) (*libvirt.GuestInfo, error)
+	SetUserPassword(name, user, password string) error
+	SetGuestNetwork(name string, ip net.IP) error
+	SubscribeLifecycle(ctx context.Context) (<-chan libvirt.LifecycleEvent, error)
+	OpenConsole(name string) (io.ReadWriteCloser, error)
+	OpenVNC(name string) (io.ReadWriteCloser, error)
+	GetUsableIPs(used []net.IP) ([]net.IP, error)
+	ExportInstance(name, content string) (io.ReadCloser, error)
+	ImportInstance(cfg libvirt.VMConfig, r io.Reader, define bool) error
+	MigrateDomain(name, destURI string, copyStorage bool) error
+	DiscardInstance(name string, withFiles bool) error
+}
+
+// LibvirtHypervisor - Backend를 hosting_service.Hypervisor로 감싼 구현체
+type LibvirtHypervisor struct {
+	backend Backend
+}
+
+func NewLibvirtHypervisor(backend Backend) *LibvirtHypervisor {
+	return &LibvirtHypervisor{backend: backend}
+}
+
+// Close - backend가 연결을 들고 있으면(libvirt URI) 닫는다. libvirt-agent 클라이언트는 닫을 것이 없다
+func (h *LibvirtHypervisor) Close() error {
+	if c, ok := h.backend.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (h *LibvirtHypervisor) CreateVM(spec hosting_service.VMSpec, progress func(step string)) (string, error) {
+	cfg := libvirt.VMConfig{
+		Name:       spec.Name,
+		VCPUs:      spec.VCPUs,
+		MemoryMB:   spec.MemoryMB,
+		DiskGB:     spec.DiskGB,
+		Template:   spec.Image,
+		SourceDisk: spec.SourceDisk,
+		CloudInit: libvirt.CloudInit{
+			Hostname:     spec.CloudInit.Hostname,
+			User:         spec.CloudInit.User,
+			PasswordHash: spec.CloudInit.PasswordHash,
+			SSHKeys:      spec.CloudInit.SSHKeys,
+			UserData:     spec.CloudInit.UserData,
+			SeedFormat:   spec.CloudInit.SeedFormat,
+		},
+		UEFI:        spec.Features.UEFI,
+		VirtioRNG:   spec.Features.VirtioRNG,
+		CPUQuota:    spec.Features.CPUQuota,
+		VNCPassword: spec.VNCPassword,
+	}
+	if err := h.backend.StartUbuntuVMWithStaticIP(cfg, spec.IP, progress); err != nil {
+		return "", err
+	}
+	return libvirt.InstanceDiskPath(spec.Name), nil
+}
+
+func (h *LibvirtHypervisor) DeleteVM(name string, withDisks bool) error {
+	return h.backend.DeleteDomain(name, withDisks)
+}
+
+func (h *LibvirtHypervisor) StartVM(name string) error {
+	return h.backend.Start(name)
+}
+
+func (h *LibvirtHypervisor) StopVM(name string) error {
+	return h.backend.Shutdown(name)
+}
+
+func (h *LibvirtHypervisor) PowerOffVM(name string) error {
+	return h.backend.Destroy(name)
+}
+
+func (h *LibvirtHypervisor) RebootVM(name string) error {
+	return h.backend.Reboot(name)
+}
+
+func (h *LibvirtHypervisor) ResetVM(name string) error {
+	return h.backend.Reset(name)
+}
+
+func (h *LibvirtHypervisor) PauseVM(name string) error {
+	return h.backend.Suspend(name)
+}
+
+func (h *LibvirtHypervisor) ResumeVM(name string) error {
+	return h.backend.Resume(name)
+}
+
+func (h *LibvirtHypervisor) SetAutostart(name string, enabled bool) error {
+	return h.backend.SetAutostart(name, enabled)
+}
+
+func (h *LibvirtHypervisor) ResizeVM(name string, spec hosting_service.VMSpec) (bool, error) {
+	return h.backend.ResizeDomain(name, spec.VCPUs, spec.MemoryMB, spec.DiskGB)
+}
+
+func (h *LibvirtHypervisor) CreateSnapshot(name, snapshot, description string) error {
+	err := h.backend.CreateSnapshot(name, snapshot, description)
+	if errors.Is(err, libvirt.ErrSnapshotUnsupported) {
+		return fmt.Errorf("%w: %v", hosting_service.ErrSnapshotUnsupported, err)
+	}
+	return err
+}
+
+func (h *LibvirtHypervisor) RevertSnapshot(name, snapshot string) error {
+	return h.backend.RevertSnapshot(name, snapshot)
+}
+
+func (h *LibvirtHypervisor) DeleteSnapshot(name, snapshot string) error {
+	return h.backend.DeleteSnapshot(name, snapshot)
+}
+
+func (h *LibvirtHypervisor) BackupVM(name, backup string) (string, int64, error) {
+	return h.backend.BackupDisk(name, backup)
+}
+
+func (h *LibvirtHypervisor) RestoreVM(name, backupPath string) error {
+	return h.backend.RestoreDisk(name, backupPath)
+}
+
+func (h *LibvirtHypervisor) DeleteBackup(backupPath string) error {
+	return h.backend.DeleteBackup(backupPath)
+}
+
+func (h *LibvirtHypervisor) FlattenVM(name string) error {
+	return h.backend.FlattenDisk(name)
+}
+
+func (h *LibvirtHypervisor) ImageChecksum(path string) (string, error) {
+	return h.backend.TemplateChecksum(path)
+}
+
+func (h *LibvirtHypervisor) ExportVM(name, content string) (io.ReadCloser, error) {
+	return h.backend.ExportInstance(name, content)
+}
+
+func (h *LibvirtHypervisor) ImportVM(spec hosting_service.VMSpec, r io.Reader, define bool) error {
+	cfg := libvirt.VMConfig{
+		Name:        spec.Name,
+		VCPUs:       spec.VCPUs,
+		MemoryMB:    spec.MemoryMB,
+		CloudInit:   libvirt.CloudInit{SeedFormat: spec.CloudInit.SeedFormat},
+		UEFI:        spec.Features.UEFI,
+		VirtioRNG:   spec.Features.VirtioRNG,
+		CPUQuota:    spec.Features.CPUQuota,
+		VNCPassword: spec.VNCPassword,
+	}
+	return h.backend.ImportInstance(cfg, r, define)
+}
+
+func (h *LibvirtHypervisor) MigrateVM(name, destURI string, copyStorage bool) error {
+	return h.backend.MigrateDomain(name, destURI, copyStorage)
+}
+
+func (h *LibvirtHypervisor) SetGuestNetwork(name string, ip net.IP) error {
+	return guestAgentError(h.backend.SetGuestNetwork(name, ip))
+}
+
+func (h *LibvirtHypervisor) DiscardVM(name string, withFiles bool) error {
+	return h.backend.DiscardInstance(name, withFiles)
+}
+
+func (h *LibvirtHypervisor) IsActive(name string) (bool, error) {
+	return h.backend.DomainIsActive(name)
+}
+
+func (h *LibvirtHypervisor) DomainInfo(name string) (*hosting_service.DomainInfo, error) {
+	info, err := h.backend.GetDomainInfoByName(name)
+	if errors.Is(err, libvirt.ErrDomainNotFound) {
+		return nil, fmt.Errorf("%w: %v", hosting_service.ErrDomainNotFound, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &hosting_service.DomainInfo{
+		State:     domainState(info.State),
+		MaxMemKB:  info.MaxMem,
+		MemoryKB:  info.Memory,
+		VCPUs:     info.NrVirtCpu,
+		CPUTimeNs: info.CpuTime,
+	}, nil
+}
+
+func (h *LibvirtHypervisor) ListDomains() ([]hosting_service.DomainStatus, error) {
+	list, err := h.backend.ListDomains()
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]hosting_service.DomainStatus, 0, len(list))
+	for _, d := range list {
+		statuses = append(statuses, hosting_service.DomainStatus{Name: d.Name, State: domainState(d.State)})
+	}
+	return statuses, nil
+}
+
+func (h *LibvirtHypervisor) DomainStats() ([]hosting_service.DomainStats, error) {
+	list, err := h.backend.GetAllDomainStats()
+	if err != nil {
+		return nil, err
+	}
+	stats := make([]hosting_service.DomainStats, 0, len(list))
+	for _, st := range list {
+		stats = append(stats, hosting_service.DomainStats{
+			VMName:          st.Name,
+			VCPUs:           st.VCPUs,
+			CPUTimeNs:       st.CPUTime,
+			MemoryKB:        st.MemoryKB,
+			MemoryUnusedKB:  st.MemoryUnusedKB,
+			BlockReadBytes:  st.BlockReadBytes,
+			BlockWriteBytes: st.BlockWriteBytes,
+			NetRxBytes:      st.NetRxBytes,
+			NetTxBytes:      st.NetTxBytes,
+		})
+	}
+	return stats, nil
+}
+
+// GuestInfo - 게스트 에이전트의 OS 정보와 인터페이스 주소를 합친다
+func (h *LibvirtHypervisor) GuestInfo(name string) (*hosting_service.GuestInfo, error) {
+	info, err := h.backend.GetGuestInfo(name)
+	if err != nil {
+		return nil, guestAgentError(err)
+	}
+	ifaces, err := h.backend.GuestInterfaceAddresses(name)
+	if err != nil {
+		return nil, guestAgentError(err)
+	}
+
+	guest := &hosting_service.GuestInfo{
+		Hostname:      info.Hostname,
+		OSID:          info.OSID,
+		OSPrettyName:  info.OSPrettyName,
+		OSVersionID:   info.OSVersionID,
+		KernelRelease: info.KernelRelease,
+		Interfaces:    make([]hosting_service.GuestInterface, 0, len(ifaces)),
+	}
+	for _, iface := range ifaces {
+		guest.Interfaces = append(guest.Interfaces, hosting_service.GuestInterface{
+			Name:      iface.Name,
+			MAC:       iface.MAC,
+			Addresses: iface.Addresses,
+		})
+	}
+	return guest, nil
+}
+
+func (h *LibvirtHypervisor) SetGuestPassword(name, user, password string) error {
+	return guestAgentError(h.backend.SetUserPassword(name, user, password))
+}
+
+func (h *LibvirtHypervisor) Events(ctx context.Context) (<-chan hosting_service.DomainEvent, error) {
+	raw, err := h.backend.SubscribeLifecycle(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan hosting_service.DomainEvent, 64)
+	go func() {
+		defer close(ch)
+		for ev := range raw {
+			ch <- hosting_service.DomainEvent{VMName: ev.Domain, Type: ev.Type, Time: ev.Time}
+		}
+	}()
+	return ch, nil
+}
+
+func (h *LibvirtHypervisor) OpenConsole(name string) (io.ReadWriteCloser, error) {
+	stream, err := h.backend.OpenConsole(name)
+	return stream, remoteHostError(err)
+}
+
+func (h *LibvirtHypervisor) OpenVNC(name string) (io.ReadWriteCloser, error) {
+	conn, err := h.backend.OpenVNC(name)
+	return conn, remoteHostError(err)
+}
+
+func (h *LibvirtHypervisor) UsableIPs(used []net.IP) ([]net.IP, error) {
+	return h.backend.GetUsableIPs(used)
+}
+
+// guestAgentError - libvirt.ErrGuestAgentUnavailable을 hosting_service 쪽 에러로 바꾼다
+func guestAgentError(err error) error {
+	if errors.Is(err, libvirt.ErrGuestAgentUnavailable) {
+		return fmt.Errorf("%w: %v", hosting_service.ErrGuestAgentUnavailable, err)
+	}
+	return err
+}
+
+// remoteHostError - 원격 URI 노드에서 콘솔/VNC를 열려고 한 에러를 서비스 에러로 바꾼다
+func remoteHostError(err error) error {
+	if errors.Is(err, libvirt.ErrRemoteHost) {
+		return fmt.Errorf("%w: %v", hosting_service.ErrConsoleUnavailable, err)
+	}
+	return err
+}
+
+// domainState - libvirt의 virDomainState 값을 문자열 상태로 변환
+func domainState(state uint8) hosting_service.DomainState {
+	switch golibvirt.DomainState(state) {
+	case golibvirt.DomainRunning:
+		return hosting_service.DomainRunning
+	case golibvirt.DomainBlocked:
+		return hosting_service.DomainBlocked
+	case golibvirt.DomainPaused:
+		return hosting_service.DomainPaused
+	case golibvirt.DomainShutdown:
+		return hosting_service.DomainShutdown
+	case golibvirt.DomainShutoff:
+		return hosting_service.DomainShutoff
+	case golibvirt.DomainCrashed:
+		return hosting_service.DomainCrashed
+	case golibvirt.DomainPmsuspended:
+		return hosting_service.DomainPMSuspended
+	default:
+		return hosting_service.DomainNoState
+	}
+}
@@ -0,0 +1,568 @@
+package hypervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"time"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+)
+
+// poolResubscribeDelay - 일부 노드의 이벤트 구독이 실패했을 때 전체 구독을 다시 맺기까지의 시간
+const poolResubscribeDelay = 30 * time.Second
+
+// DefaultHealthCheckInterval - 연결해 둔 노드가 살아 있는지 다시 확인하는 주기 (SetHealthCheckInterval)
+const DefaultHealthCheckInterval = 15 * time.Second
+
+// Dialer - 노드 주소로 하이퍼바이저에 연결한다
+type Dialer func(address string) (hosting_service.Hypervisor, error)
+
+// NodePool - 여러 compute 노드의 하이퍼바이저를 하나의 hosting_service.Hypervisor로 묶는다.
+// 도메인 이름으로 노드를 찾아 요청을 보내며, 모르는 도메인은 모든 노드의 도메인 목록에서 찾는다.
+// CreateVM은 VMSpec.Node에 만든다 (노드가 하나뿐이면 비어 있어도 된다)
+type NodePool struct {
+	dial Dialer
+	// healthInterval - 마지막 확인 후 이 시간이 지난 연결은 쓰기 전에 도메인 목록으로 다시 확인한다
+	healthInterval time.Duration
+
+	mu    sync.Mutex
+	nodes map[string]*poolNode
+	// 도메인 이름 → 노드 이름
+	placement map[string]string
+	// 진행 중인 Events 구독 (노드가 바뀌면 끊어서 다시 구독하게 한다)
+	watchers map[*context.CancelFunc]struct{}
+}
+
+type poolNode struct {
+	address string
+	hv      hosting_service.Hypervisor
+	err     error     // 마지막 연결 오류 (hv가 nil일 때)
+	checked time.Time // hv가 응답하는 것을 마지막으로 확인한 시각
+}
+
+func NewNodePool(dial Dialer) *NodePool {
+	return &NodePool{
+		dial:           dial,
+		healthInterval: DefaultHealthCheckInterval,
+		nodes:          make(map[string]*poolNode),
+		placement:      make(map[string]string),
+		watchers:       make(map[*context.CancelFunc]struct{}),
+	}
+}
+
+// SetHealthCheckInterval - 연결 확인 주기 (0이면 쓸 때마다 확인한다)
+func (p *NodePool) SetHealthCheckInterval(d time.Duration) {
+	p.mu.Lock()
+	p.healthInterval = d
+	p.mu.Unlock()
+}
+
+// AddNode - 노드에 연결한다. 연결에 실패해도 노드는 등록되며 다음 요청 때 다시 연결한다
+func (p *NodePool) AddNode(n *hosting_service.Node) error {
+	hv, err := p.dial(n.Address)
+
+	p.mu.Lock()
+	p.nodes[n.Name] = &poolNode{address: n.Address, hv: hv, err: err}
+	p.resetWatchers()
+	p.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("노드 연결 실패 (%s): %w", n.Name, err)
+	}
+	return nil
+}
+
+func (p *NodePool) RemoveNode(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.nodes, name)
+	for vm, node := range p.placement {
+		if node == name {
+			delete(p.placement, vm)
+		}
+	}
+	p.resetWatchers()
+}
+
+// NodeError - 노드에 연결되어 있지 않으면 연결 오류 (등록되지 않은 노드도 오류)
+func (p *NodePool) NodeError(name string) error {
+	_, err := p.connect(name)
+	return err
+}
+
+// Node - 노드의 하이퍼바이저 (연결되어 있지 않으면 다시 연결한다)
+func (p *NodePool) Node(name string) (hosting_service.Hypervisor, error) {
+	return p.connect(name)
+}
+
+// NodeOf - 도메인이 있는 노드 이름
+func (p *NodePool) NodeOf(vmName string) (string, error) {
+	node, _, err := p.locate(vmName)
+	return node, err
+}
+
+// Place - 도메인이 node에 있다고 기록한다 (다른 노드로 옮긴 뒤 호출)
+func (p *NodePool) Place(vmName, node string) {
+	p.mu.Lock()
+	p.placement[vmName] = node
+	p.mu.Unlock()
+}
+
+func (p *NodePool) resetWatchers() {
+	for cancel := range p.watchers {
+		(*cancel)()
+	}
+	p.watchers = make(map[*context.CancelFunc]struct{})
+}
+
+func (p *NodePool) names() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	names := make([]string, 0, len(p.nodes))
+	for name := range p.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// connect - 노드의 하이퍼바이저. 연결이 없으면 다시 연결하고, healthInterval이 지난 연결은 도메인 목록을 읽어
+// 살아 있는지 확인한다. 응답하지 않는 연결은 버리므로(n.err) NodeError와 스케줄러가 그 노드를 피하고
+// 다음 요청 때 다시 연결한다 (libvirt-agent 클라이언트는 연결만으로는 노드가 죽었는지 알 수 없다)
+func (p *NodePool) connect(name string) (hosting_service.Hypervisor, error) {
+	p.mu.Lock()
+	n, ok := p.nodes[name]
+	if !ok {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("등록되지 않은 노드입니다: %s", name)
+	}
+	if n.hv != nil && time.Since(n.checked) < p.healthInterval {
+		p.mu.Unlock()
+		return n.hv, nil
+	}
+	hv, address := n.hv, n.address
+	p.mu.Unlock()
+
+	var err error
+	if hv == nil {
+		hv, err = p.dial(address)
+	}
+	if err == nil {
+		if _, lerr := hv.ListDomains(); lerr != nil {
+			closeHypervisor(hv)
+			hv, err = nil, lerr
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// 연결하는 동안 노드가 바뀌었으면 결과를 버린다
+	if cur, ok := p.nodes[name]; ok && cur == n {
+		n.hv, n.err, n.checked = hv, err, time.Now()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("노드 연결 실패 (%s): %w", name, err)
+	}
+	return hv, nil
+}
+
+// closeHypervisor - 버리는 연결을 닫는다 (닫을 것이 없는 구현체는 그대로 둔다)
+func closeHypervisor(hv hosting_service.Hypervisor) {
+	if c, ok := hv.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+// locate - 도메인이 있는 노드를 찾는다. 모르는 도메인이면 모든 노드의 도메인 목록을 다시 읽는다
+func (p *NodePool) locate(vmName string) (string, hosting_service.Hypervisor, error) {
+	p.mu.Lock()
+	node, ok := p.placement[vmName]
+	p.mu.Unlock()
+
+	if !ok {
+		complete := p.refreshPlacement()
+		p.mu.Lock()
+		node, ok = p.placement[vmName]
+		p.mu.Unlock()
+		switch {
+		case !ok && complete:
+			return "", nil, fmt.Errorf("%w: 어느 노드에도 없습니다: %s", hosting_service.ErrDomainNotFound, vmName)
+		case !ok:
+			// 목록을 읽지 못한 노드에 있을 수 있다
+			return "", nil, fmt.Errorf("도메인 조회 실패: 목록을 읽은 노드에는 없습니다: %s", vmName)
+		}
+	}
+	hv, err := p.connect(node)
+	if err != nil {
+		return "", nil, err
+	}
+	return node, hv, nil
+}
+
+// refreshPlacement - 모든 노드의 도메인 목록으로 placement를 채운다 (모든 노드의 목록을 읽었으면 true)
+func (p *NodePool) refreshPlacement() bool {
+	complete := true
+	for _, name := range p.names() {
+		hv, err := p.connect(name)
+		if err != nil {
+			complete = false
+			continue
+		}
+		doms, err := hv.ListDomains()
+		if err != nil {
+			complete = false
+			continue
+		}
+		p.mu.Lock()
+		for _, d := range doms {
+			p.placement[d.Name] = name
+		}
+		p.mu.Unlock()
+	}
+	return complete
+}
+
+// CreateVM - spec.Node에 도메인을 만든다
+func (p *NodePool) CreateVM(spec hosting_service.VMSpec, progress func(step string)) (string, error) {
+	node := spec.Node
+	if node == "" {
+		names := p.names()
+		if len(names) != 1 {
+			return "", errors.New("VM을 만들 노드가 지정되지 않았습니다")
+		}
+		node = names[0]
+	}
+	hv, err := p.connect(node)
+	if err != nil {
+		return "", err
+	}
+	// 실패해도 디스크가 남았을 수 있으므로 롤백(DeleteVM)이 같은 노드로 가도록 먼저 기록한다
+	p.Place(spec.Name, node)
+	return hv.CreateVM(spec, progress)
+}
+
+func (p *NodePool) DeleteVM(name string, withDisks bool) error {
+	_, hv, err := p.locate(name)
+	if err != nil {
+		return err
+	}
+	if err := hv.DeleteVM(name, withDisks); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	delete(p.placement, name)
+	p.mu.Unlock()
+	return nil
+}
+
+// on - 도메인이 있는 노드의 하이퍼바이저로 f를 실행한다
+func (p *NodePool) on(name string, f func(hv hosting_service.Hypervisor) error) error {
+	_, hv, err := p.locate(name)
+	if err != nil {
+		return err
+	}
+	return f(hv)
+}
+
+func (p *NodePool) StartVM(name string) error {
+	return p.on(name, func(hv hosting_service.Hypervisor) error { return hv.StartVM(name) })
+}
+
+func (p *NodePool) StopVM(name string) error {
+	return p.on(name, func(hv hosting_service.Hypervisor) error { return hv.StopVM(name) })
+}
+
+func (p *NodePool) PowerOffVM(name string) error {
+	return p.on(name, func(hv hosting_service.Hypervisor) error { return hv.PowerOffVM(name) })
+}
+
+func (p *NodePool) RebootVM(name string) error {
+	return p.on(name, func(hv hosting_service.Hypervisor) error { return hv.RebootVM(name) })
+}
+
+func (p *NodePool) ResetVM(name string) error {
+	return p.on(name, func(hv hosting_service.Hypervisor) error { return hv.ResetVM(name) })
+}
+
+func (p *NodePool) PauseVM(name string) error {
+	return p.on(name, func(hv hosting_service.Hypervisor) error { return hv.PauseVM(name) })
+}
+
+func (p *NodePool) ResumeVM(name string) error {
+	return p.on(name, func(hv hosting_service.Hypervisor) error { return hv.ResumeVM(name) })
+}
+
+func (p *NodePool) SetAutostart(name string, enabled bool) error {
+	return p.on(name, func(hv hosting_service.Hypervisor) error { return hv.SetAutostart(name, enabled) })
+}
+
+func (p *NodePool) ResizeVM(name string, spec hosting_service.VMSpec) (bool, error) {
+	var live bool
+	err := p.on(name, func(hv hosting_service.Hypervisor) (err error) {
+		live, err = hv.ResizeVM(name, spec)
+		return err
+	})
+	return live, err
+}
+
+func (p *NodePool) CreateSnapshot(name, snapshot, description string) error {
+	return p.on(name, func(hv hosting_service.Hypervisor) error { return hv.CreateSnapshot(name, snapshot, description) })
+}
+
+func (p *NodePool) RevertSnapshot(name, snapshot string) error {
+	return p.on(name, func(hv hosting_service.Hypervisor) error { return hv.RevertSnapshot(name, snapshot) })
+}
+
+func (p *NodePool) DeleteSnapshot(name, snapshot string) error {
+	return p.on(name, func(hv hosting_service.Hypervisor) error { return hv.DeleteSnapshot(name, snapshot) })
+}
+
+func (p *NodePool) BackupVM(name, backup string) (string, int64, error) {
+	var path string
+	var size int64
+	err := p.on(name, func(hv hosting_service.Hypervisor) (err error) {
+		path, size, err = hv.BackupVM(name, backup)
+		return err
+	})
+	return path, size, err
+}
+
+func (p *NodePool) RestoreVM(name, backupPath string) error {
+	return p.on(name, func(hv hosting_service.Hypervisor) error { return hv.RestoreVM(name, backupPath) })
+}
+
+// DeleteBackup - 백업 파일은 노드마다 따로 있어 서비스는 백업한 노드(Node)에서 지운다.
+// 노드를 기록하지 않은 백업(노드를 등록하기 전의 백업)만 여기로 오며 연결된 첫 노드에서 지운다
+func (p *NodePool) DeleteBackup(backupPath string) error {
+	hv, err := p.any()
+	if err != nil {
+		return err
+	}
+	return hv.DeleteBackup(backupPath)
+}
+
+func (p *NodePool) FlattenVM(name string) error {
+	return p.on(name, func(hv hosting_service.Hypervisor) error { return hv.FlattenVM(name) })
+}
+
+// ImageChecksum - 템플릿은 모든 노드에 같은 내용으로 있어야 한다 (노드마다 다르면 오류)
+func (p *NodePool) ImageChecksum(path string) (string, error) {
+	names := p.names()
+	if len(names) == 0 {
+		return "", errors.New("등록된 노드가 없습니다")
+	}
+	var sum string
+	for _, name := range names {
+		hv, err := p.connect(name)
+		if err != nil {
+			return "", err
+		}
+		s, err := hv.ImageChecksum(path)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+		if sum != "" && s != sum {
+			return "", fmt.Errorf("노드마다 템플릿 내용이 다릅니다: %s (%s)", path, name)
+		}
+		sum = s
+	}
+	return sum, nil
+}
+
+func (p *NodePool) IsActive(name string) (bool, error) {
+	var active bool
+	err := p.on(name, func(hv hosting_service.Hypervisor) (err error) {
+		active, err = hv.IsActive(name)
+		return err
+	})
+	return active, err
+}
+
+func (p *NodePool) DomainInfo(name string) (*hosting_service.DomainInfo, error) {
+	var info *hosting_service.DomainInfo
+	err := p.on(name, func(hv hosting_service.Hypervisor) (err error) {
+		info, err = hv.DomainInfo(name)
+		return err
+	})
+	return info, err
+}
+
+// ListDomains - 모든 노드의 도메인. 조회하지 못한 노드는 건너뛰고 *hosting_service.NodeListError로 알린다
+// (그 노드의 VM이 목록에 없다고 상태 점검이 사라진 것으로 보면 안 된다)
+func (p *NodePool) ListDomains() ([]hosting_service.DomainStatus, error) {
+	var all []hosting_service.DomainStatus
+	failed := make(map[string]error)
+	for _, name := range p.names() {
+		hv, err := p.connect(name)
+		if err != nil {
+			failed[name] = err
+			continue
+		}
+		doms, err := hv.ListDomains()
+		if err != nil {
+			failed[name] = fmt.Errorf("%s: %w", name, err)
+			continue
+		}
+		p.mu.Lock()
+		for _, d := range doms {
+			p.placement[d.Name] = name
+		}
+		p.mu.Unlock()
+		all = append(all, doms...)
+	}
+	if len(failed) > 0 {
+		return all, &hosting_service.NodeListError{Nodes: failed}
+	}
+	return all, nil
+}
+
+// DomainStats - 연결된 노드들의 사용량 (조회하지 못한 노드는 건너뛴다)
+func (p *NodePool) DomainStats() ([]hosting_service.DomainStats, error) {
+	var all []hosting_service.DomainStats
+	var lastErr error
+	ok := 0
+	for _, name := range p.names() {
+		hv, err := p.connect(name)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		stats, err := hv.DomainStats()
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %w", name, err)
+			continue
+		}
+		ok++
+		all = append(all, stats...)
+	}
+	if ok == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return all, nil
+}
+
+// Events - 모든 노드의 이벤트를 합친다. 노드 하나의 스트림이 끊기거나 노드가 추가/삭제되면 채널을 닫아
+// 호출한 쪽이 다시 구독하게 한다. 일부 노드만 구독했으면 poolResubscribeDelay 뒤에 닫는다
+func (p *NodePool) Events(ctx context.Context) (<-chan hosting_service.DomainEvent, error) {
+	subCtx, cancel := context.WithCancel(ctx)
+	p.mu.Lock()
+	p.watchers[&cancel] = struct{}{}
+	p.mu.Unlock()
+
+	sources := make(map[string]<-chan hosting_service.DomainEvent)
+	var lastErr error
+	for _, name := range p.names() {
+		hv, err := p.connect(name)
+		if err == nil {
+			var ch <-chan hosting_service.DomainEvent
+			if ch, err = hv.Events(subCtx); err == nil {
+				sources[name] = ch
+				continue
+			}
+		}
+		lastErr = fmt.Errorf("%s: %w", name, err)
+	}
+	if len(sources) == 0 {
+		p.dropWatcher(&cancel)
+		if lastErr == nil {
+			lastErr = errors.New("등록된 노드가 없습니다")
+		}
+		return nil, lastErr
+	}
+	if lastErr != nil {
+		time.AfterFunc(poolResubscribeDelay, cancel)
+	}
+
+	out := make(chan hosting_service.DomainEvent, 64)
+	var wg sync.WaitGroup
+	for node, src := range sources {
+		wg.Add(1)
+		go func(node string, src <-chan hosting_service.DomainEvent) {
+			defer wg.Done()
+			defer cancel()
+			for ev := range src {
+				ev.Node = node
+				select {
+				case out <- ev:
+				case <-subCtx.Done():
+				}
+			}
+		}(node, src)
+	}
+	go func() {
+		wg.Wait()
+		p.dropWatcher(&cancel)
+		close(out)
+	}()
+	return out, nil
+}
+
+func (p *NodePool) dropWatcher(cancel *context.CancelFunc) {
+	(*cancel)()
+	p.mu.Lock()
+	delete(p.watchers, cancel)
+	p.mu.Unlock()
+}
+
+func (p *NodePool) GuestInfo(name string) (*hosting_service.GuestInfo, error) {
+	var info *hosting_service.GuestInfo
+	err := p.on(name, func(hv hosting_service.Hypervisor) (err error) {
+		info, err = hv.GuestInfo(name)
+		return err
+	})
+	return info, err
+}
+
+func (p *NodePool) SetGuestPassword(name, user, password string) error {
+	return p.on(name, func(hv hosting_service.Hypervisor) error { return hv.SetGuestPassword(name, user, password) })
+}
+
+func (p *NodePool) OpenConsole(name string) (io.ReadWriteCloser, error) {
+	var conn io.ReadWriteCloser
+	err := p.on(name, func(hv hosting_service.Hypervisor) (err error) {
+		conn, err = hv.OpenConsole(name)
+		return err
+	})
+	return conn, err
+}
+
+func (p *NodePool) OpenVNC(name string) (io.ReadWriteCloser, error) {
+	var conn io.ReadWriteCloser
+	err := p.on(name, func(hv hosting_service.Hypervisor) (err error) {
+		conn, err = hv.OpenVNC(name)
+		return err
+	})
+	return conn, err
+}
+
+// UsableIPs - 노드가 하나일 때만 쓸 수 있다. 노드마다 네트워크가 다르므로
+// 여러 노드면 VM을 놓을 노드의 하이퍼바이저(Node)에서 고른다
+func (p *NodePool) UsableIPs(used []net.IP) ([]net.IP, error) {
+	names := p.names()
+	if len(names) != 1 {
+		return nil, errors.New("IP를 고를 노드가 지정되지 않았습니다")
+	}
+	hv, err := p.connect(names[0])
+	if err != nil {
+		return nil, err
+	}
+	return hv.UsableIPs(used)
+}
+
+// any - 연결된 첫 노드
+func (p *NodePool) any() (hosting_service.Hypervisor, error) {
+	lastErr := errors.New("등록된 노드가 없습니다")
+	for _, name := range p.names() {
+		hv, err := p.connect(name)
+		if err == nil {
+			return hv, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
@@ -128,3 +128,29 @@ func (a *AuthMiddleware) RequireAdmin() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequireUserOrAdmin - user 또는 admin 토큰을 허용 (세부 권한은 핸들러에서 확인)
+func (a *AuthMiddleware) RequireUserOrAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "토큰이 없습니다"})
+			return
+		}
+		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
+
+		result := a.tokenManager.Validate(tokenStr)
+		if !result.Valid {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "유효하지 않은 토큰"})
+			return
+		}
+
+		if !token.PassOnlyUser(result) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "user 권한이 필요합니다"})
+			return
+		}
+
+		c.Set("auth", result)
+		c.Next()
+	}
+}
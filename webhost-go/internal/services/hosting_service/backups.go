@@ -0,0 +1,451 @@
+package hosting_service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+	"webhost-go/webhost-go/pkg/cron"
+)
+
+var (
+	ErrBackupNotFound   = errors.New("백업을 찾을 수 없습니다")
+	ErrInvalidSchedule  = errors.New("잘못된 백업 일정입니다")
+	ErrScheduleNotFound = errors.New("백업 일정이 없습니다")
+	ErrVMRunning        = errors.New("VM을 중지한 뒤 다시 시도해 주세요")
+	// ErrBackupOtherNode - 백업한 뒤 VM을 다른 노드로 옮겨서 백업 파일이 VM의 노드에 없다
+	ErrBackupOtherNode = errors.New("백업 파일이 VM과 다른 노드에 있습니다")
+)
+
+// ListBackups - ref가 비어 있으면 사용자의 모든 백업 (삭제된 VM의 백업 포함)
+func (s *HostingService) ListBackups(userID int64, ref string) ([]*Backup, error) {
+	var backups []*Backup
+	var err error
+	if ref == "" {
+		backups, err = s.backups.FindByUserID(userID)
+	} else {
+		h, findErr := s.findVM(userID, ref)
+		if findErr != nil {
+			return nil, findErr
+		}
+		backups, err = s.backups.FindByHostingID(h.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("백업 목록 조회 실패: %w", err)
+	}
+	if backups == nil {
+		backups = []*Backup{}
+	}
+	return backups, nil
+}
+
+// CreateBackup - 즉시 백업 작업을 큐에 넣는다 (보관 정책의 대상이 아니다)
+func (s *HostingService) CreateBackup(userID int64, ref string) (*Operation, error) {
+	return s.enqueueForVM(OpBackupVM, userID, ref, map[string]string{"kind": BackupManual})
+}
+
+// RestoreBackup - 백업을 원래 VM의 디스크에 덮어쓴다 (VM이 중지되어 있어야 한다)
+func (s *HostingService) RestoreBackup(userID, backupID int64) (*Operation, error) {
+	b, err := s.findBackup(userID, backupID)
+	if err != nil {
+		return nil, err
+	}
+	h, err := s.findVM(userID, strconv.FormatInt(b.HostingID, 10))
+	if err != nil {
+		return nil, err
+	}
+	if h.Status != "stopped" {
+		return nil, fmt.Errorf("%w (현재 상태: %s)", ErrVMRunning, h.Status)
+	}
+	if err := checkBackupNode(b, h); err != nil {
+		return nil, err
+	}
+	return s.enqueue(OpRestoreBackup, h.UserID, h.Name, h.VMName, map[string]string{
+		"backup_id": strconv.FormatInt(b.ID, 10),
+	})
+}
+
+// CloneBackup - 백업 디스크로 새 VM을 만든다 (plan이 비어 있으면 백업 시점의 플랜).
+// 백업 파일은 백업한 노드에만 있으므로 새 VM도 그 노드에 놓는다
+func (s *HostingService) CloneBackup(userID, backupID int64, name, plan string) (*Operation, error) {
+	b, err := s.findBackup(userID, backupID)
+	if err != nil {
+		return nil, err
+	}
+	if plan == "" {
+		plan = b.Plan
+	}
+	p, err := s.resolvePlan(plan)
+	if err != nil {
+		return nil, err
+	}
+	// 백업 디스크보다 작은 플랜은 쓸 수 없다 (플랜이 삭제되었으면 확인하지 않는다)
+	if orig, err := s.resolvePlan(b.Plan); err == nil && p.DiskGB < orig.DiskGB {
+		return nil, fmt.Errorf("%w (백업 %dGB, 요청 %dGB)", ErrDiskShrink, orig.DiskGB, p.DiskGB)
+	}
+	params := map[string]string{"backup_id": strconv.FormatInt(b.ID, 10)}
+	// 디스크는 백업에서 오지만 어떤 이미지로 만든 VM인지는 원본 VM을 따른다
+	if orig, err := s.repo.FindByID(b.HostingID); err == nil {
+		params["image"] = orig.Image
+	}
+	return s.enqueueCreate(userID, name, p, b.NodeName, params)
+}
+
+func (s *HostingService) DeleteBackup(userID, backupID int64) (*Operation, error) {
+	b, err := s.findBackup(userID, backupID)
+	if err != nil {
+		return nil, err
+	}
+	// 복원 중인 백업을 지우지 않도록 원래 VM(삭제되었더라도)의 잠금을 잡는다
+	h, err := s.repo.FindByID(b.HostingID)
+	if err != nil {
+		return nil, fmt.Errorf("VM 정보 조회 실패: %w", err)
+	}
+	return s.enqueue(OpDeleteBackup, b.UserID, h.Name, h.VMName, map[string]string{
+		"backup_id": strconv.FormatInt(b.ID, 10),
+	})
+}
+
+func (s *HostingService) findBackup(userID, backupID int64) (*Backup, error) {
+	b, err := s.backups.FindByID(backupID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %d", ErrBackupNotFound, backupID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("백업 조회 실패: %w", err)
+	}
+	if b.UserID != userID {
+		return nil, fmt.Errorf("%w: %d", ErrBackupNotFound, backupID)
+	}
+	return b, nil
+}
+
+// backupHypervisor - 백업 파일이 있는 노드의 하이퍼바이저 (노드마다 백업 디렉토리가 따로 있다)
+func (s *HostingService) backupHypervisor(b *Backup) (Hypervisor, error) {
+	return s.nodeHypervisor(b.NodeName)
+}
+
+// checkBackupNode - 백업 파일이 VM이 있는 노드에 있어야 VM 디스크를 백업으로 바꿀 수 있다
+func checkBackupNode(b *Backup, h *Hosting) error {
+	if b.NodeName != "" && b.NodeName != h.NodeName {
+		return fmt.Errorf("%w (백업 %s, VM %s)", ErrBackupOtherNode, b.NodeName, h.NodeName)
+	}
+	return nil
+}
+
+func (s *HostingService) backupForOp(op *Operation) (*Backup, error) {
+	id, err := strconv.ParseInt(op.Params["backup_id"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("잘못된 백업 ID: %q", op.Params["backup_id"])
+	}
+	return s.findBackup(op.UserID, id)
+}
+
+// --- 예약 백업 ---
+
+func (s *HostingService) GetBackupSchedule(userID int64, ref string) (*BackupSchedule, error) {
+	h, err := s.findVM(userID, ref)
+	if err != nil {
+		return nil, err
+	}
+	sched, err := s.schedules.FindByHostingID(h.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrScheduleNotFound, ref)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("백업 일정 조회 실패: %w", err)
+	}
+	return sched, nil
+}
+
+// SetBackupSchedule - 일정을 만들거나 바꾼다. 다음 실행은 지금 이후 처음 맞는 시각이다.
+func (s *HostingService) SetBackupSchedule(userID int64, ref string, sched *BackupSchedule) error {
+	if _, err := cron.Parse(sched.Cron); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	if sched.KeepDaily < 0 || sched.KeepWeekly < 0 || sched.KeepDaily+sched.KeepWeekly == 0 {
+		return fmt.Errorf("%w: keep_daily 또는 keep_weekly 중 하나는 1 이상이어야 합니다", ErrInvalidSchedule)
+	}
+	h, err := s.findVM(userID, ref)
+	if err != nil {
+		return err
+	}
+	sched.HostingID = h.ID
+	sched.LastRunAt = time.Now()
+	return s.schedules.Upsert(sched)
+}
+
+func (s *HostingService) DeleteBackupSchedule(userID int64, ref string) error {
+	if _, err := s.GetBackupSchedule(userID, ref); err != nil {
+		return err
+	}
+	h, err := s.findVM(userID, ref)
+	if err != nil {
+		return err
+	}
+	return s.schedules.Delete(h.ID)
+}
+
+// StartBackupScheduler - interval마다 실행 시각이 된 예약 백업을 큐에 넣는다
+func (s *HostingService) StartBackupScheduler(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if _, err := s.RunDueBackups(time.Now()); err != nil {
+				log.Printf("예약 백업 실패: %v", err)
+			}
+		}
+	}()
+}
+
+// RunDueBackups - 마지막 실행 이후 now까지 실행 시각이 지난 일정의 백업을 큐에 넣는다.
+// 여러 번 놓쳤더라도 한 번만 실행한다.
+func (s *HostingService) RunDueBackups(now time.Time) ([]*Operation, error) {
+	schedules, err := s.schedules.FindAll()
+	if err != nil {
+		return nil, fmt.Errorf("백업 일정 조회 실패: %w", err)
+	}
+
+	var ops []*Operation
+	for _, sched := range schedules {
+		c, err := cron.Parse(sched.Cron)
+		if err != nil {
+			log.Printf("잘못된 백업 일정 (hosting #%d): %v", sched.HostingID, err)
+			continue
+		}
+		next := c.Next(sched.LastRunAt)
+		if next.IsZero() || next.After(now) {
+			continue
+		}
+
+		h, err := s.repo.FindByID(sched.HostingID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && h.Status == "deleted") {
+			_ = s.schedules.Delete(sched.HostingID)
+			continue
+		}
+		if err != nil {
+			log.Printf("VM 정보 조회 실패 (hosting #%d): %v", sched.HostingID, err)
+			continue
+		}
+		if h.Status == "provisioning" {
+			continue
+		}
+
+		op, err := s.enqueue(OpBackupVM, h.UserID, h.Name, h.VMName, map[string]string{"kind": BackupScheduled})
+		if err != nil {
+			log.Printf("예약 백업 등록 실패 (%s): %v", h.VMName, err)
+			continue
+		}
+		if err := s.schedules.UpdateLastRun(sched.HostingID, now); err != nil {
+			log.Printf("백업 일정 갱신 실패 (%s): %v", h.VMName, err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// ExpiredBackups - 보관 정책에 따라 지울 예약 백업.
+// 최근 keepDaily일은 하루에 가장 최근 것 하나, 최근 keepWeekly주는 한 주에 가장 최근 것 하나를 남긴다.
+// 수동 백업은 대상이 아니다.
+func ExpiredBackups(backups []*Backup, keepDaily, keepWeekly int) []*Backup {
+	scheduled := make([]*Backup, 0, len(backups))
+	for _, b := range backups {
+		if b.Kind == BackupScheduled {
+			scheduled = append(scheduled, b)
+		}
+	}
+	sort.Slice(scheduled, func(i, j int) bool { return scheduled[i].CreatedAt.After(scheduled[j].CreatedAt) })
+
+	days := map[string]bool{}
+	weeks := map[string]bool{}
+	var expired []*Backup
+	for _, b := range scheduled {
+		keep := false
+		day := b.CreatedAt.Format("2006-01-02")
+		if !days[day] && len(days) < keepDaily {
+			days[day] = true
+			keep = true
+		}
+		year, w := b.CreatedAt.ISOWeek()
+		week := fmt.Sprintf("%d-%02d", year, w)
+		if !weeks[week] && len(weeks) < keepWeekly {
+			weeks[week] = true
+			keep = true
+		}
+		if !keep {
+			expired = append(expired, b)
+		}
+	}
+	return expired
+}
+
+// --- 워커 ---
+
+func (s *HostingService) backupVM(op *Operation) error {
+	h, err := s.repo.FindByVMName(op.VMName)
+	if err != nil {
+		return fmt.Errorf("VM 정보 조회 실패: %w", err)
+	}
+
+	now := time.Now()
+	b := &Backup{
+		UserID:    h.UserID,
+		HostingID: h.ID,
+		Name:      fmt.Sprintf("%s-%s-%d", h.Name, now.Format("20060102-150405"), op.ID),
+		Kind:      op.Params["kind"],
+		Plan:      h.Plan,
+		NodeName:  h.NodeName,
+		CreatedAt: now,
+	}
+	hv, err := s.nodeHypervisor(h.NodeName)
+	if err != nil {
+		return err
+	}
+
+	sg := &saga{onStep: func(step string) { s.setStep(op, step) }}
+	// 1. 디스크 복사 (실행 중이면 외부 스냅샷 또는 일시 정지)
+	sg.add(StepBackingUp,
+		func() error {
+			path, size, err := hv.BackupVM(h.VMName, b.Name)
+			b.Path, b.SizeBytes = path, size
+			return err
+		},
+		func() error { return hv.DeleteBackup(b.Path) })
+	// 2. DB 기록
+	sg.add(StepUpdatingStatus,
+		func() error { return s.backups.Create(b) },
+		nil)
+
+	if err := sg.run(); err != nil {
+		op.Step = sg.failedStep
+		op.Rollback = sg.rollback
+		op.Status = OpRolledBack
+		if !sg.rollbackOK {
+			op.Status = OpRollbackFailed
+		}
+		return err
+	}
+
+	// 3. 예약 백업이면 보관 정책 적용 (실패해도 백업 자체는 성공)
+	var expired []string
+	if b.Kind == BackupScheduled {
+		s.setStep(op, StepApplyingRetention)
+		expired = s.applyRetention(h.ID)
+	}
+
+	result, _ := json.Marshal(map[string]interface{}{
+		"backup":  b,
+		"expired": expired,
+	})
+	op.Result = string(result)
+	return nil
+}
+
+// applyRetention - 보관 기간이 지난 예약 백업을 지우고 지운 백업 이름을 돌려준다
+func (s *HostingService) applyRetention(hostingID int64) []string {
+	sched, err := s.schedules.FindByHostingID(hostingID)
+	if err != nil {
+		// 일정이 삭제된 뒤 실행된 백업은 정리하지 않는다
+		return nil
+	}
+	backups, err := s.backups.FindByHostingID(hostingID)
+	if err != nil {
+		log.Printf("백업 목록 조회 실패 (hosting #%d): %v", hostingID, err)
+		return nil
+	}
+
+	var deleted []string
+	for _, b := range ExpiredBackups(backups, sched.KeepDaily, sched.KeepWeekly) {
+		if err := s.removeBackup(b); err != nil {
+			log.Printf("오래된 백업 삭제 실패 (%s): %v", b.Name, err)
+			continue
+		}
+		deleted = append(deleted, b.Name)
+	}
+	return deleted
+}
+
+func (s *HostingService) removeBackup(b *Backup) error {
+	hv, err := s.backupHypervisor(b)
+	if err != nil {
+		return fmt.Errorf("백업 파일 삭제 실패: %w", err)
+	}
+	if err := hv.DeleteBackup(b.Path); err != nil {
+		return fmt.Errorf("백업 파일 삭제 실패: %w", err)
+	}
+	if err := s.backups.Delete(b.ID); err != nil {
+		return fmt.Errorf("백업 기록 삭제 실패: %w", err)
+	}
+	return nil
+}
+
+func (s *HostingService) restoreBackup(op *Operation) error {
+	b, err := s.backupForOp(op)
+	if err != nil {
+		return err
+	}
+	h, err := s.repo.FindByVMName(op.VMName)
+	if err != nil {
+		return fmt.Errorf("VM 정보 조회 실패: %w", err)
+	}
+	// 큐에서 기다리는 동안 다른 노드로 옮겨졌을 수 있다
+	if err := checkBackupNode(b, h); err != nil {
+		return err
+	}
+	// 큐에서 기다리는 동안 다시 시작되었을 수 있다
+	active, err := s.hv.IsActive(h.VMName)
+	if err != nil {
+		return fmt.Errorf("VM 상태 조회 실패: %w", err)
+	}
+	if active {
+		return ErrVMRunning
+	}
+
+	// 1. 디스크 교체
+	s.setStep(op, StepRestoringDisk)
+	if err := s.hv.RestoreVM(h.VMName, b.Path); err != nil {
+		return fmt.Errorf("백업 복원 실패: %w", err)
+	}
+
+	// 2. 백업 이후 더 큰 플랜으로 바꿨다면 디스크를 현재 플랜 크기로 다시 늘린다
+	if b.Plan != h.Plan {
+		s.setStep(op, StepResizingVM)
+		plan, err := s.resolvePlan(h.Plan)
+		if err != nil {
+			return err
+		}
+		if _, err := s.hv.ResizeVM(h.VMName, VMSpec{VCPUs: plan.CPU, MemoryMB: plan.MemoryMB, DiskGB: plan.DiskGB}); err != nil {
+			return fmt.Errorf("디스크 크기 조절 실패: %w", err)
+		}
+	}
+
+	// 3. 교체된 디스크에는 스냅샷이 없다
+	s.setStep(op, StepUpdatingStatus)
+	if err := s.snaps.DeleteByHostingID(h.ID); err != nil {
+		return fmt.Errorf("스냅샷 기록 삭제 실패: %w", err)
+	}
+
+	result, _ := json.Marshal(map[string]interface{}{"backup": b.Name})
+	op.Result = string(result)
+	return nil
+}
+
+func (s *HostingService) deleteBackup(op *Operation) error {
+	b, err := s.backupForOp(op)
+	if err != nil {
+		return err
+	}
+	s.setStep(op, StepDeletingBackup)
+	return s.removeBackup(b)
+}
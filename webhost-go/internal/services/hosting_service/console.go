@@ -0,0 +1,283 @@
+package hosting_service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	ErrConsoleTicket   = errors.New("콘솔 티켓이 없거나 만료되었습니다")
+	ErrConsoleBusy     = errors.New("다른 접속자가 이미 콘솔에 입력 중입니다")
+	ErrConsoleReadOnly = errors.New("읽기 전용 콘솔입니다")
+	ErrVMNotRunning    = errors.New("실행 중인 VM이 아닙니다")
+	// ErrConsoleUnavailable - 원격 libvirt URI로 연결한 노드의 VM (시리얼 콘솔과 VNC는 libvirt-agent 노드에서만 열 수 있다)
+	ErrConsoleUnavailable = errors.New("이 노드의 VM은 웹 콘솔을 쓸 수 없습니다")
+)
+
+// consoleTicketTTL - 티켓 발급 후 WebSocket 연결까지 허용하는 시간
+const consoleTicketTTL = 30 * time.Second
+
+// consoleOutputBuffer - 접속자별 출력 버퍼 (읽은 조각 수). 가득 차면 그 접속자를 끊는다
+const consoleOutputBuffer = 256
+
+// ConsoleTicket - 콘솔 WebSocket 연결에 한 번만 쓸 수 있는 티켓.
+// 브라우저 WebSocket은 Authorization 헤더를 보낼 수 없어서 JWT 대신 쓴다.
+type ConsoleTicket struct {
+	Ticket    string    `json:"ticket"`
+	HostingID int64     `json:"id"`
+	Name      string    `json:"name"`
+	Writable  bool      `json:"writable"` // false면 출력만 볼 수 있다
+	ExpiresAt time.Time `json:"expires_at"`
+
+	userID int64
+	vmName string
+}
+
+// consoleHub - 발급된 티켓과 VM별 콘솔 세션
+type consoleHub struct {
+	mu       sync.Mutex
+	tickets  map[string]*ConsoleTicket
+	sessions map[string]*consoleSession // VM 이름 → 세션
+	// opening - VM 이름 → 하이퍼바이저 콘솔을 여는 중 (다 열면 닫힌다).
+	// 콘솔은 이전 스트림을 빼앗아 오므로 같은 VM은 한 번에 하나만 연다
+	opening map[string]chan struct{}
+}
+
+// consoleSession - 하이퍼바이저 콘솔 스트림 하나를 여러 접속자가 나눠 본다.
+// 입력은 writer 한 명만 보낼 수 있다.
+type consoleSession struct {
+	vmName  string
+	stream  io.ReadWriteCloser
+	clients map[*ConsoleClient]struct{}
+	writer  *ConsoleClient
+}
+
+// ConsoleClient - 콘솔 세션에 붙은 접속자 하나
+type ConsoleClient struct {
+	hub      *consoleHub
+	session  *consoleSession
+	output   chan []byte
+	writable bool
+	detached bool // hub.mu로 보호
+}
+
+// IssueConsoleTicket - 실행 중인 VM의 콘솔 티켓을 발급한다.
+// writable이면 키 입력도 보낼 수 있으며, 이런 접속자는 세션마다 한 명뿐이다.
+func (s *HostingService) IssueConsoleTicket(userID int64, ref string, writable bool) (*ConsoleTicket, error) {
+	h, err := s.findVM(userID, ref)
+	if err != nil {
+		return nil, err
+	}
+	active, err := s.hv.IsActive(h.VMName)
+	if err != nil {
+		return nil, fmt.Errorf("VM 상태 조회 실패: %w", err)
+	}
+	if !active {
+		return nil, fmt.Errorf("%w: %s", ErrVMNotRunning, h.Name)
+	}
+
+	ticket, err := newTicket()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	t := &ConsoleTicket{
+		Ticket:    ticket,
+		HostingID: h.ID,
+		Name:      h.Name,
+		Writable:  writable,
+		ExpiresAt: now.Add(consoleTicketTTL),
+		userID:    userID,
+		vmName:    h.VMName,
+	}
+
+	hub := &s.consoles
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if hub.tickets == nil {
+		hub.tickets = make(map[string]*ConsoleTicket)
+	}
+	// 쓰이지 않고 만료된 티켓 정리
+	for k, old := range hub.tickets {
+		if now.After(old.ExpiresAt) {
+			delete(hub.tickets, k)
+		}
+	}
+	hub.tickets[t.Ticket] = t
+	return t, nil
+}
+
+// newTicket - URL에 그대로 넣을 수 있는 256비트 임의 문자열
+func newTicket() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("티켓 생성 실패: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// AttachConsole - 티켓으로 VM 콘솔 세션에 붙는다. 티켓은 성공 여부와 관계없이 한 번 쓰면 사라진다.
+// 세션의 첫 접속자가 하이퍼바이저 콘솔을 열고, 마지막 접속자가 나가면 닫는다.
+// 콘솔을 여는 것은 노드까지 왕복하므로 hub.mu 밖에서 한다 (느린 노드가 다른 VM의 접속을 막지 않게)
+func (s *HostingService) AttachConsole(userID int64, ticket string) (*ConsoleClient, error) {
+	hub := &s.consoles
+	hub.mu.Lock()
+	t, ok := hub.tickets[ticket]
+	delete(hub.tickets, ticket)
+	hub.mu.Unlock()
+	if !ok || t.userID != userID || time.Now().After(t.ExpiresAt) {
+		return nil, ErrConsoleTicket
+	}
+
+	for {
+		hub.mu.Lock()
+		if sess := hub.sessions[t.vmName]; sess != nil {
+			c, err := hub.join(sess, t.Writable)
+			hub.mu.Unlock()
+			return c, err
+		}
+		if wait, ok := hub.opening[t.vmName]; ok {
+			// 다른 접속자가 여는 중이면 끝나기를 기다렸다가 그 세션에 붙는다
+			hub.mu.Unlock()
+			<-wait
+			continue
+		}
+		if hub.opening == nil {
+			hub.opening = make(map[string]chan struct{})
+		}
+		done := make(chan struct{})
+		hub.opening[t.vmName] = done
+		hub.mu.Unlock()
+
+		stream, err := s.hv.OpenConsole(t.vmName)
+
+		hub.mu.Lock()
+		delete(hub.opening, t.vmName)
+		close(done)
+		if err != nil {
+			hub.mu.Unlock()
+			return nil, fmt.Errorf("콘솔 연결 실패: %w", err)
+		}
+		sess := hub.sessions[t.vmName]
+		if sess != nil {
+			// 그 사이 다른 접속자가 세션을 만들었으면 방금 연 스트림은 닫는다
+			hub.mu.Unlock()
+			stream.Close()
+			continue
+		}
+		if hub.sessions == nil {
+			hub.sessions = make(map[string]*consoleSession)
+		}
+		sess = &consoleSession{
+			vmName:  t.vmName,
+			stream:  stream,
+			clients: make(map[*ConsoleClient]struct{}),
+		}
+		hub.sessions[t.vmName] = sess
+		go hub.pump(sess)
+		c, err := hub.join(sess, t.Writable)
+		hub.mu.Unlock()
+		return c, err
+	}
+}
+
+// join - hub.mu를 잡은 상태에서 호출. 세션에 접속자를 더한다 (writable은 세션마다 한 명)
+func (hub *consoleHub) join(sess *consoleSession, writable bool) (*ConsoleClient, error) {
+	if writable && sess.writer != nil {
+		return nil, ErrConsoleBusy
+	}
+	c := &ConsoleClient{
+		hub:      hub,
+		session:  sess,
+		output:   make(chan []byte, consoleOutputBuffer),
+		writable: writable,
+	}
+	sess.clients[c] = struct{}{}
+	if c.writable {
+		sess.writer = c
+	}
+	return c, nil
+}
+
+// pump - 콘솔 출력을 세션의 모든 접속자에게 보낸다.
+// 스트림이 끝나면(VM 종료, 다른 곳에서 콘솔을 가져감) 모든 접속자를 끊는다.
+func (hub *consoleHub) pump(sess *consoleSession) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := sess.stream.Read(buf)
+		if n > 0 {
+			chunk := append([]byte(nil), buf[:n]...)
+			hub.mu.Lock()
+			for c := range sess.clients {
+				select {
+				case c.output <- chunk:
+				default:
+					log.Printf("콘솔 출력이 밀려 접속을 끊습니다 (%s)", sess.vmName)
+					hub.detach(c)
+				}
+			}
+			hub.mu.Unlock()
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	hub.mu.Lock()
+	for c := range sess.clients {
+		hub.detach(c)
+	}
+	if hub.sessions[sess.vmName] == sess {
+		delete(hub.sessions, sess.vmName)
+	}
+	hub.mu.Unlock()
+	sess.stream.Close()
+}
+
+// detach - hub.mu를 잡은 상태에서 호출. 마지막 접속자면 세션도 닫는다.
+func (hub *consoleHub) detach(c *ConsoleClient) {
+	if c.detached {
+		return
+	}
+	c.detached = true
+	close(c.output)
+
+	sess := c.session
+	delete(sess.clients, c)
+	if sess.writer == c {
+		sess.writer = nil
+	}
+	if len(sess.clients) == 0 && hub.sessions[sess.vmName] == sess {
+		delete(hub.sessions, sess.vmName)
+		sess.stream.Close() // pump가 끝난다
+	}
+}
+
+// Output - 콘솔 출력. 세션이 끝나거나 Close하면 닫힌다.
+func (c *ConsoleClient) Output() <-chan []byte {
+	return c.output
+}
+
+func (c *ConsoleClient) Writable() bool {
+	return c.writable
+}
+
+// Write - 키 입력을 게스트로 보낸다 (쓰기 티켓으로 붙은 접속자만)
+func (c *ConsoleClient) Write(p []byte) (int, error) {
+	if !c.writable {
+		return 0, ErrConsoleReadOnly
+	}
+	return c.session.stream.Write(p)
+}
+
+func (c *ConsoleClient) Close() {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+	c.hub.detach(c)
+}
@@ -0,0 +1,145 @@
+package hosting_service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// eventRetryDelay - 이벤트 구독이 끊겼을 때 다시 연결하기까지 기다리는 시간
+const eventRetryDelay = 5 * time.Second
+
+// VMEvent - 사용자에게 전달되는 VM 라이프사이클 이벤트
+type VMEvent struct {
+	HostingID int64     `json:"id"`
+	Name      string    `json:"name"`
+	VMName    string    `json:"vm_name"`
+	Type      string    `json:"type"`   // EventStarted 등
+	Status    string    `json:"status"` // 이벤트 반영 후 hostings.status
+	Time      time.Time `json:"time"`
+}
+
+// eventBroker - 사용자별 이벤트 구독자 목록
+type eventBroker struct {
+	mu   sync.Mutex
+	subs map[int64]map[chan VMEvent]struct{}
+}
+
+// StartEventListener - 하이퍼바이저 이벤트로 hostings.status를 갱신하고 구독자에게 전달한다.
+// 첫 구독은 호출한 고루틴에서 맺고, 연결이 끊기면 eventRetryDelay 후 다시 구독한다.
+func (s *HostingService) StartEventListener(ctx context.Context) {
+	events, err := s.hv.Events(ctx)
+	go func() {
+		for {
+			if err != nil {
+				log.Printf("도메인 이벤트 구독 실패: %v", err)
+			} else {
+				for ev := range events {
+					s.handleDomainEvent(ev)
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(eventRetryDelay):
+			}
+			events, err = s.hv.Events(ctx)
+		}
+	}()
+}
+
+// SubscribeEvents - 사용자의 VM 이벤트를 받는다. 반환된 함수로 구독을 해제한다.
+func (s *HostingService) SubscribeEvents(userID int64) (<-chan VMEvent, func()) {
+	ch := make(chan VMEvent, 16)
+
+	s.events.mu.Lock()
+	if s.events.subs == nil {
+		s.events.subs = make(map[int64]map[chan VMEvent]struct{})
+	}
+	if s.events.subs[userID] == nil {
+		s.events.subs[userID] = make(map[chan VMEvent]struct{})
+	}
+	s.events.subs[userID][ch] = struct{}{}
+	s.events.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			s.events.mu.Lock()
+			delete(s.events.subs[userID], ch)
+			if len(s.events.subs[userID]) == 0 {
+				delete(s.events.subs, userID)
+			}
+			s.events.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func (s *HostingService) handleDomainEvent(ev DomainEvent) {
+	h, err := s.repo.FindByVMName(ev.VMName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return // 관리하지 않는 도메인이거나 이미 삭제된 VM
+	}
+	if err != nil {
+		log.Printf("이벤트 처리 실패 (%s): %v", ev.VMName, err)
+		return
+	}
+
+	// 다른 노드로 옮긴 VM의 원래 노드에서 온 이벤트 (이전할 때 원본의 stopped/undefined)
+	if ev.Node != "" && h.NodeName != "" && ev.Node != h.NodeName {
+		return
+	}
+
+	// provisioning/migrating 중인 VM은 생성/이전 작업이 상태를 정한다
+	status := h.Status
+	if st, ok := statusForEvent(ev.Type); ok && status != "provisioning" && status != "migrating" && st != status {
+		if err := s.repo.UpdateStatus(h.VMName, st); err != nil {
+			log.Printf("이벤트 상태 반영 실패 (%s): %v", ev.VMName, err)
+		} else {
+			status = st
+		}
+	}
+
+	s.publish(h.UserID, VMEvent{
+		HostingID: h.ID,
+		Name:      h.Name,
+		VMName:    h.VMName,
+		Type:      ev.Type,
+		Status:    status,
+		Time:      ev.Time,
+	})
+}
+
+// publish - 느린 구독자 때문에 이벤트 처리가 막히지 않도록 가득 찬 채널은 건너뛴다
+func (s *HostingService) publish(userID int64, ev VMEvent) {
+	s.events.mu.Lock()
+	defer s.events.mu.Unlock()
+	for ch := range s.events.subs[userID] {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// statusForEvent - 이벤트에 해당하는 hostings.status (상태가 바뀌지 않는 이벤트는 false)
+func statusForEvent(eventType string) (string, bool) {
+	switch eventType {
+	case EventStarted, EventResumed:
+		return "running", true
+	case EventSuspended:
+		return "paused", true
+	case EventStopped, EventPMSuspended:
+		return "stopped", true
+	case EventCrashed:
+		return "error", true
+	default:
+		// defined/undefined/shutdown(종료 진행 중)은 뒤따르는 이벤트로 판단
+		return "", false
+	}
+}
@@ -0,0 +1,74 @@
+package hosting_service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+// ErrGuestAgentUnavailable - 게스트 안에 qemu-guest-agent가 없거나 응답하지 않는다
+// (에이전트 채널 도입 전에 만든 VM, 부팅 중, 사용자가 에이전트를 지운 경우)
+var ErrGuestAgentUnavailable = errors.New("게스트 에이전트를 사용할 수 없습니다")
+
+// GuestInfo - 게스트 에이전트가 보고한 OS 정보와 실제 인터페이스 주소
+type GuestInfo struct {
+	Hostname      string           `json:"hostname,omitempty"`
+	OSID          string           `json:"os_id,omitempty"`
+	OSPrettyName  string           `json:"os_pretty_name,omitempty"`
+	OSVersionID   string           `json:"os_version_id,omitempty"`
+	KernelRelease string           `json:"kernel_release,omitempty"`
+	Interfaces    []GuestInterface `json:"interfaces"`
+}
+
+// GuestInterface - 게스트 안에서 본 네트워크 인터페이스 (loopback 제외)
+type GuestInterface struct {
+	Name      string   `json:"name"`
+	MAC       string   `json:"mac,omitempty"`
+	Addresses []string `json:"addresses"` // CIDR 형식
+}
+
+// guestInfo - 실행 중인 VM의 게스트 정보. 에이전트가 없으면 nil (상세 조회는 그대로 성공)
+func (s *HostingService) guestInfo(vmName string) *GuestInfo {
+	guest, err := s.hv.GuestInfo(vmName)
+	if err != nil {
+		if !errors.Is(err, ErrGuestAgentUnavailable) {
+			log.Printf("게스트 정보 조회 실패 (%s): %v", vmName, err)
+		}
+		return nil
+	}
+	return guest
+}
+
+// ResetGuestPassword - 게스트 에이전트로 이미지 기본 계정의 비밀번호를 새로 정하고 돌려준다.
+// 실행 중이고 에이전트가 응답하는 VM만 가능하다.
+func (s *HostingService) ResetGuestPassword(userID int64, ref string) (string, error) {
+	h, err := s.findVM(userID, ref)
+	if err != nil {
+		return "", err
+	}
+	active, err := s.hv.IsActive(h.VMName)
+	if err != nil {
+		return "", fmt.Errorf("VM 상태 조회 실패: %w", err)
+	}
+	if !active {
+		return "", fmt.Errorf("%w: %s", ErrVMNotRunning, h.Name)
+	}
+
+	// 사용 중지된 이미지여도 기본 사용자는 그대로 쓴다
+	img, err := s.findImage(h.Image)
+	if err != nil {
+		return "", err
+	}
+
+	password, err := randomPassword(vmPasswordLength)
+	if err != nil {
+		return "", err
+	}
+	if err := s.hv.SetGuestPassword(h.VMName, img.DefaultUser, password); err != nil {
+		if errors.Is(err, ErrGuestAgentUnavailable) {
+			return "", fmt.Errorf("%w: %s", ErrGuestAgentUnavailable, h.Name)
+		}
+		return "", fmt.Errorf("비밀번호 변경 실패: %w", err)
+	}
+	return password, nil
+}
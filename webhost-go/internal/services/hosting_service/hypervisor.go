@@ -0,0 +1,240 @@
+package hosting_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Hypervisor - HostingService가 VM을 다루기 위해 필요한 기능
+// 구현체: internal/hypervisor (libvirt, libvirt-agent, in-memory fake)
+type Hypervisor interface {
+	// 프로비저닝: 디스크/cloud-init 준비 후 도메인 정의 및 부팅, 루트 디스크 경로 반환
+	// progress는 각 세부 단계(StepCopyingDisk 등) 시작 시 호출된다
+	CreateVM(spec VMSpec, progress func(step string)) (string, error)
+	DeleteVM(name string, withDisks bool) error
+
+	// 라이프사이클: 요청만 하고 바로 돌아온다. 바뀐 상태는 DomainInfo로 확인한다
+	// StartVM - 꺼진 VM은 부팅하고, 일시 정지된 VM은 재개한다
+	StartVM(name string) error
+	// StopVM - 게스트에 종료를 요청한다 (게스트 에이전트, 없으면 ACPI). 게스트가 무시할 수 있다
+	StopVM(name string) error
+	// PowerOffVM - 게스트를 거치지 않고 바로 끈다
+	PowerOffVM(name string) error
+	// RebootVM - 게스트에 재부팅을 요청한다, ResetVM - 하드 리셋
+	RebootVM(name string) error
+	ResetVM(name string) error
+	// PauseVM - vCPU를 멈춘다 (메모리 유지), ResumeVM - 일시 정지된 VM을 재개한다
+	PauseVM(name string) error
+	ResumeVM(name string) error
+	// SetAutostart - 호스트(libvirtd)가 다시 시작될 때 도메인을 자동으로 부팅할지
+	SetAutostart(name string, enabled bool) error
+
+	// 사양 변경: spec의 VCPUs/MemoryMB/DiskGB만 사용하며 디스크는 늘리기만 한다.
+	// 실행 중 바로 적용되었으면 true, 다음 부팅부터 적용되면 false
+	ResizeVM(name string, spec VMSpec) (bool, error)
+
+	// 스냅샷 (qcow2 내부 스냅샷, 실행 중이면 메모리 상태 포함)
+	// 복원하면 도메인 상태도 스냅샷 시점의 상태가 된다
+	CreateSnapshot(name, snapshot, description string) error
+	RevertSnapshot(name, snapshot string) error
+	DeleteSnapshot(name, snapshot string) error
+
+	// 백업: 루트 디스크의 일관된 복사본을 만들고 경로와 크기(바이트)를 돌려준다
+	BackupVM(name, backup string) (string, int64, error)
+	// 중지된 VM의 루트 디스크를 백업으로 교체 (디스크의 스냅샷은 사라진다)
+	RestoreVM(name, backupPath string) error
+	DeleteBackup(backupPath string) error
+	// 템플릿 overlay 디스크의 backing file 의존을 없앤다 (실행 중이어도 가능)
+	FlattenVM(name string) error
+	// 템플릿 디렉토리 안 이미지 파일의 sha256 (hex)
+	ImageChecksum(path string) (string, error)
+
+	// 정보 조회
+	IsActive(name string) (bool, error)
+	// DomainInfo - 노드가 도메인이 없다고 답했을 때만 ErrDomainNotFound (연결 오류 등은 다른 오류)
+	DomainInfo(name string) (*DomainInfo, error)
+	// 정의된 모든 도메인 (hostings 테이블에 없는 도메인 포함).
+	// 일부 노드만 조회하지 못했으면 나머지 노드의 목록과 *NodeListError를 함께 돌려준다
+	ListDomains() ([]DomainStatus, error)
+	// 도메인 라이프사이클 이벤트 구독, ctx가 끝나거나 연결이 끊기면 채널이 닫힌다
+	Events(ctx context.Context) (<-chan DomainEvent, error)
+	// 실행 중인 모든 도메인의 누적 사용량 카운터 (사용률은 두 샘플의 차이로 계산)
+	DomainStats() ([]DomainStats, error)
+
+	// 게스트 에이전트 (qemu-guest-agent). 에이전트가 없으면 ErrGuestAgentUnavailable
+	// GuestInfo - 게스트 OS 정보와 게스트 안에서 본 인터페이스 주소
+	GuestInfo(name string) (*GuestInfo, error)
+	// SetGuestPassword - 게스트 계정의 비밀번호를 바꾼다 (평문)
+	SetGuestPassword(name, user, password string) error
+
+	// 실행 중인 도메인의 시리얼 콘솔 (Read: 게스트 출력, Write: 키 입력)
+	// 같은 도메인의 콘솔을 다시 열면 이전 스트림은 끊긴다
+	OpenConsole(name string) (io.ReadWriteCloser, error)
+	// 실행 중인 도메인의 VNC 서버 (RFB 그대로, VNC 인증은 접속한 쪽이 한다)
+	OpenVNC(name string) (io.ReadWriteCloser, error)
+
+	// 네트워크: used를 제외한 할당 가능한 IP 목록
+	UsableIPs(used []net.IP) ([]net.IP, error)
+}
+
+// NodePool - 여러 compute 노드를 묶은 Hypervisor (internal/hypervisor.NodePool).
+// 도메인 이름으로 노드를 찾아 요청을 보내고, CreateVM은 VMSpec.Node에 도메인을 만든다
+type NodePool interface {
+	Hypervisor
+	// AddNode - 노드에 연결한다 (같은 이름이 있으면 연결을 바꾼다). 실패해도 노드는 등록되고 다음 요청 때 다시 연결한다
+	AddNode(n *Node) error
+	RemoveNode(name string)
+	// NodeError - 노드에 연결할 수 없으면 그 오류
+	NodeError(name string) error
+	// Node - 노드 하나의 하이퍼바이저 (노드 사이 이전은 Migrator로 노드마다 따로 호출한다)
+	Node(name string) (Hypervisor, error)
+	// NodeOf - 도메인이 있는 노드 이름
+	NodeOf(vmName string) (string, error)
+	// Place - 도메인을 다른 노드로 옮긴 뒤 이후 요청을 node로 보내게 한다
+	Place(vmName, node string)
+}
+
+// Migrator - 노드 사이에서 VM을 옮기는 기능 (NodePool.Node가 돌려주는 노드별 하이퍼바이저가 구현한다)
+type Migrator interface {
+	// ExportVM - 도메인 정의와 인스턴스 파일을 스트림으로 내보낸다 (content: ArchiveFull 등)
+	ExportVM(name, content string) (io.ReadCloser, error)
+	// ImportVM - ExportVM 스트림을 받아 파일을 만들고, define이면 꺼진 상태로 도메인을 정의한다.
+	// 도메인 정의는 스트림의 XML이 아니라 spec(Name, VCPUs, MemoryMB, CloudInit.SeedFormat, Features, VNCPassword)으로 만든다.
+	// 실패하면 만든 파일을 지운다
+	ImportVM(spec VMSpec, r io.Reader, define bool) error
+	// MigrateVM - 실행 중인 도메인을 destURI의 libvirtd로 live migration 한다 (성공하면 원본의 정의는 사라진다).
+	// copyStorage면 루트 디스크 내용도 복사한다. 실패하면 도메인은 원본에서 계속 실행된다
+	MigrateVM(name, destURI string, copyStorage bool) error
+	// DiscardVM - 꺼진 도메인의 정의를 지우고 withFiles면 인스턴스 파일도 지운다 (도메인이 없으면 파일만)
+	DiscardVM(name string, withFiles bool) error
+	// SetGuestNetwork - 실행 중인 도메인의 고정 IP를 이 노드 네트워크의 ip로 바꾼다 (게스트 에이전트로 netplan을
+	// 다시 쓰고 적용하며 seed의 network-config도 바꾼다). 에이전트가 없으면 ErrGuestAgentUnavailable
+	SetGuestNetwork(name string, ip net.IP) error
+}
+
+// ExportVM 아카이브 내용 (pkg/libvirt의 Archive* 값과 같다)
+const (
+	// ArchiveFull - 도메인 정의와 디스크를 포함한 인스턴스 파일 전체 (cold 이전)
+	ArchiveFull = "full"
+	// ArchiveSkeleton - 도메인 정의와 디스크를 뺀 파일. 받는 쪽은 같은 크기의 빈 디스크를 만든다 (live 이전)
+	ArchiveSkeleton = "skeleton"
+	// ArchiveDefinition - 도메인 정의만 (인스턴스 파일을 공유 저장소에 두는 노드 사이)
+	ArchiveDefinition = "definition"
+)
+
+type VMSpec struct {
+	Name string
+	// Node - 도메인을 만들 compute 노드 (NodePool일 때만 사용, 노드가 하나면 비어 있어도 된다)
+	Node     string
+	IP       net.IP
+	VCPUs    int
+	MemoryMB int
+	DiskGB   int
+	// Image - 템플릿 디렉토리 안의 이미지 파일 (Image.Path)
+	Image string
+	// SourceDisk - 템플릿 대신 복사할 백업 파일 (비어 있으면 Image)
+	SourceDisk string
+	CloudInit  CloudInit
+	// Features - 플랜과 이미지의 선택 기능을 합친 값
+	Features DomainFeatures
+	// VNCPassword - localhost VNC 장치의 비밀번호 (최대 8자, 비어 있으면 VNC 없음)
+	VNCPassword string
+}
+
+// CloudInit - VM 전용 user-data 입력 (baseline 계정 + 사용자 cloud-config)
+type CloudInit struct {
+	Hostname     string
+	User         string // 이미지의 기본 사용자
+	PasswordHash string // bcrypt (crypt 형식)
+	SSHKeys      []string
+	UserData     string // 사용자가 준 #cloud-config
+	SeedFormat   string // 이미지의 seed 형식 ("iso", "vfat")
+}
+
+// CreateVM 세부 단계 (pkg/libvirt의 Step* 값과 같다)
+const (
+	StepCopyingDisk       = "copying_disk"
+	StepResizingDisk      = "resizing_disk"
+	StepBuildingCloudInit = "building_cloud_init"
+	StepBooting           = "booting"
+)
+
+type DomainState string
+
+const (
+	DomainNoState     DomainState = "nostate"
+	DomainRunning     DomainState = "running"
+	DomainBlocked     DomainState = "blocked"
+	DomainPaused      DomainState = "paused"
+	DomainShutdown    DomainState = "shutdown"
+	DomainShutoff     DomainState = "shutoff"
+	DomainCrashed     DomainState = "crashed"
+	DomainPMSuspended DomainState = "pmsuspended"
+)
+
+type DomainInfo struct {
+	State     DomainState `json:"state"`
+	MaxMemKB  uint64      `json:"max_mem_kb"`  // 할당된 최대 메모리
+	MemoryKB  uint64      `json:"memory_kb"`   // 현재 사용 중인 메모리
+	VCPUs     uint16      `json:"vcpus"`       // 가상 CPU 수
+	CPUTimeNs uint64      `json:"cpu_time_ns"` // 누적 CPU 사용 시간
+	// Guest - 게스트 에이전트가 응답할 때만 채워진다 (GetVMDetail)
+	Guest *GuestInfo `json:"guest,omitempty"`
+}
+
+// DomainStats - DomainStats 결과. 모든 값은 부팅 이후 누적이다
+type DomainStats struct {
+	VMName          string
+	VCPUs           int
+	CPUTimeNs       uint64
+	MemoryKB        uint64 // balloon에 할당된 메모리
+	MemoryUnusedKB  uint64 // 게스트가 보고한 미사용 메모리 (보고하지 않으면 0)
+	BlockReadBytes  uint64
+	BlockWriteBytes uint64
+	NetRxBytes      uint64
+	NetTxBytes      uint64
+}
+
+// DomainStatus - ListDomains 결과
+type DomainStatus struct {
+	Name  string      `json:"name"`
+	State DomainState `json:"state"`
+}
+
+// ErrDomainNotFound - 하이퍼바이저에 그 이름의 도메인이 정의되어 있지 않다
+var ErrDomainNotFound = errors.New("도메인이 없습니다")
+
+// NodeListError - ListDomains가 조회하지 못한 노드 (노드 이름 → 오류).
+// 함께 돌려준 목록에는 이 노드들의 도메인이 없으므로, 이 노드들의 VM이 사라졌다고 보면 안 된다
+type NodeListError struct {
+	Nodes map[string]error
+}
+
+func (e *NodeListError) Error() string {
+	return fmt.Sprintf("도메인 목록을 조회하지 못한 노드가 있습니다: %d개", len(e.Nodes))
+}
+
+// 도메인 라이프사이클 이벤트 종류 (pkg/libvirt의 Event* 값과 같다)
+const (
+	EventDefined     = "defined"
+	EventUndefined   = "undefined"
+	EventStarted     = "started"
+	EventSuspended   = "suspended"
+	EventResumed     = "resumed"
+	EventStopped     = "stopped"
+	EventShutdown    = "shutdown"
+	EventPMSuspended = "pmsuspended"
+	EventCrashed     = "crashed"
+)
+
+type DomainEvent struct {
+	VMName string
+	Type   string // EventStarted 등
+	Time   time.Time
+	// Node - 이벤트를 보낸 compute 노드 (NodePool만 채운다)
+	Node string
+}
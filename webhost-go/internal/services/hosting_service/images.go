@@ -0,0 +1,98 @@
+package hosting_service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"webhost-go/webhost-go/pkg/nocloud"
+)
+
+var (
+	ErrImageNotFound = errors.New("존재하지 않는 이미지입니다")
+	ErrImageRetired  = errors.New("더 이상 사용할 수 없는 이미지입니다")
+	ErrImageChecksum = errors.New("이미지 체크섬이 일치하지 않습니다")
+)
+
+// findImage - 이름으로 이미지를 찾는다 (빈 이름이면 DefaultImageName)
+func (s *HostingService) findImage(name string) (*Image, error) {
+	if name == "" {
+		name = DefaultImageName
+	}
+	img, err := s.images.FindByName(name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrImageNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("이미지 조회 실패: %w", err)
+	}
+	return img, nil
+}
+
+// resolveImage - 새 VM에 쓸 이미지 (retired 이미지는 거부)
+func (s *HostingService) resolveImage(name string) (*Image, error) {
+	img, err := s.findImage(name)
+	if err != nil {
+		return nil, err
+	}
+	if img.Status != ImageActive {
+		return nil, fmt.Errorf("%w: %s", ErrImageRetired, img.Name)
+	}
+	return img, nil
+}
+
+func (s *HostingService) ListImages() ([]*Image, error) {
+	return s.images.FindAll()
+}
+
+// RegisterImage - 이미지를 등록한다. 체크섬은 첫 사용(또는 VerifyImage) 때 확인한다.
+func (s *HostingService) RegisterImage(img *Image) error {
+	if err := img.Validate(); err != nil {
+		return err
+	}
+	if img.SeedFormat == "" {
+		img.SeedFormat = string(nocloud.FormatISO)
+	}
+	img.Status = ImageActive
+	img.VerifiedAt = nil
+	img.CreatedAt = time.Now()
+	return s.images.Create(img)
+}
+
+// VerifyImage - compute 노드의 파일 체크섬을 등록된 값과 비교한다
+func (s *HostingService) VerifyImage(name string) (*Image, error) {
+	img, err := s.findImage(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyImage(img); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// RetireImage - 새 VM 생성에서 제외한다 (파일과 기존 VM은 그대로 둔다)
+func (s *HostingService) RetireImage(name string) error {
+	img, err := s.findImage(name)
+	if err != nil {
+		return err
+	}
+	return s.images.UpdateStatus(img.Name, ImageRetired)
+}
+
+func (s *HostingService) verifyImage(img *Image) error {
+	sum, err := s.hv.ImageChecksum(img.Path)
+	if err != nil {
+		return fmt.Errorf("이미지 체크섬 계산 실패: %w", err)
+	}
+	if sum != img.SHA256 {
+		return fmt.Errorf("%w: %s (등록 %s, 실제 %s)", ErrImageChecksum, img.Name, img.SHA256, sum)
+	}
+
+	now := time.Now()
+	if err := s.images.MarkVerified(img.Name, now); err != nil {
+		return fmt.Errorf("이미지 확인 시각 저장 실패: %w", err)
+	}
+	img.VerifiedAt = &now
+	return nil
+}
@@ -0,0 +1,244 @@
+package hosting_service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// 진행 단계 (Operation.Step)
+const (
+	StepVerifyingImage     = "verifying_image"
+	StepReserving          = "reserving"
+	StepCreatingVM         = "creating_vm"
+	StepRegisteringProxy   = "registering_proxy"
+	StepActivating         = "activating"
+	StepDeletingVM         = "deleting_vm"
+	StepRemovingProxy      = "removing_proxy"
+	StepStartingVM         = "starting_vm"
+	StepStoppingVM         = "stopping_vm"
+	StepPoweringOff        = "powering_off"
+	StepRebootingVM        = "rebooting_vm"
+	StepResettingVM        = "resetting_vm"
+	StepPausingVM          = "pausing_vm"
+	StepResumingVM         = "resuming_vm"
+	StepResizingVM         = "resizing_vm"
+	StepFlatteningDisk     = "flattening_disk"
+	StepCreatingSnapshot   = "creating_snapshot"
+	StepRevertingSnapshot  = "reverting_snapshot"
+	StepDeletingSnapshot   = "deleting_snapshot"
+	StepBackingUp          = "backing_up"
+	StepApplyingRetention  = "applying_retention"
+	StepRestoringDisk      = "restoring_disk"
+	StepDeletingBackup     = "deleting_backup"
+	StepCopyingFiles       = "copying_files"
+	StepMigratingVM        = "migrating_vm"
+	StepReaddressing       = "readdressing"
+	StepConfiguringNetwork = "configuring_network"
+	StepRemovingSource     = "removing_source"
+	StepUpdatingStatus     = "updating_status"
+)
+
+const jobQueueSize = 100
+
+// StartWorkers - operations 큐를 처리하는 워커 n개를 띄운다.
+// 서버가 내려가 있는 동안 남은 queued 작업은 다시 큐에 넣고,
+// running 상태로 멈춘 작업은 실패로 기록한다.
+func (s *HostingService) StartWorkers(ctx context.Context, n int) error {
+	interrupted, err := s.ops.FindByStatus(OpRunning)
+	if err != nil {
+		return fmt.Errorf("중단된 작업 조회 실패: %w", err)
+	}
+	for _, op := range interrupted {
+		op.Status = OpFailed
+		op.Error = "서버 재시작으로 작업이 중단되었습니다"
+		if err := s.saveOp(op); err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		go s.worker(ctx)
+	}
+
+	queued, err := s.ops.FindByStatus(OpQueued)
+	if err != nil {
+		return fmt.Errorf("대기 작업 조회 실패: %w", err)
+	}
+	for _, op := range queued {
+		s.dispatch(op.ID)
+	}
+	return nil
+}
+
+// GetOperation - 작업 진행 상황 조회
+func (s *HostingService) GetOperation(id int64) (*Operation, error) {
+	return s.ops.FindByID(id)
+}
+
+// ListFailedOperations - 실패/롤백된 작업 목록 (관리자용)
+func (s *HostingService) ListFailedOperations() ([]*Operation, error) {
+	return s.ops.FindByStatus(OpFailed, OpRolledBack, OpRollbackFailed)
+}
+
+// enqueue - 작업을 queued 상태로 저장하고 워커에게 넘긴다
+func (s *HostingService) enqueue(kind string, userID int64, target, vmName string, params map[string]string) (*Operation, error) {
+	op := &Operation{
+		UserID:    userID,
+		Kind:      kind,
+		Target:    target,
+		VMName:    vmName,
+		Params:    params,
+		Status:    OpQueued,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := s.ops.Create(op); err != nil {
+		return nil, fmt.Errorf("작업 저장 실패: %w", err)
+	}
+	s.dispatch(op.ID)
+	return op, nil
+}
+
+func (s *HostingService) dispatch(id int64) {
+	select {
+	case s.queue <- id:
+	default:
+		// 큐가 가득 찬 경우 요청을 막지 않도록 별도 고루틴에서 대기
+		go func() { s.queue <- id }()
+	}
+}
+
+func (s *HostingService) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-s.queue:
+			op, err := s.ops.FindByID(id)
+			if err != nil {
+				log.Printf("작업 #%d 조회 실패: %v", id, err)
+				continue
+			}
+			if op.Status != OpQueued {
+				continue
+			}
+			s.execute(op)
+		}
+	}
+}
+
+// execute - 작업 종류에 맞는 처리를 실행하고 결과를 op에 기록
+func (s *HostingService) execute(op *Operation) {
+	unlock := s.lockVM(op.VMName)
+	defer unlock()
+
+	op.Status = OpRunning
+	if err := s.saveOp(op); err != nil {
+		log.Printf("작업 #%d 상태 저장 실패: %v", op.ID, err)
+		return
+	}
+
+	var err error
+	switch op.Kind {
+	case OpCreateHosting:
+		err = s.createHosting(op)
+	case OpDeleteVM:
+		err = s.deleteVM(op)
+	case OpStartVM:
+		err = s.startVM(op)
+	case OpStopVM:
+		err = s.stopVM(op)
+	case OpRebootVM:
+		err = s.rebootVM(op, false)
+	case OpResetVM:
+		err = s.rebootVM(op, true)
+	case OpPauseVM:
+		err = s.pauseVM(op)
+	case OpResumeVM:
+		err = s.resumeVM(op)
+	case OpResizeVM:
+		err = s.resizeVM(op)
+	case OpFlattenVM:
+		err = s.flattenVM(op)
+	case OpMigrateVM:
+		err = s.migrateVM(op)
+	case OpCreateSnapshot:
+		err = s.createSnapshot(op)
+	case OpRevertSnapshot:
+		err = s.revertSnapshot(op)
+	case OpDeleteSnapshot:
+		err = s.deleteSnapshot(op)
+	case OpBackupVM:
+		err = s.backupVM(op)
+	case OpRestoreBackup:
+		err = s.restoreBackup(op)
+	case OpDeleteBackup:
+		err = s.deleteBackup(op)
+	default:
+		err = fmt.Errorf("알 수 없는 작업 종류: %s", op.Kind)
+	}
+
+	switch {
+	case err == nil:
+		op.Status = OpSucceeded
+	case op.Status == OpRunning:
+		// 롤백 결과가 따로 기록되지 않은 일반 실패
+		op.Status = OpFailed
+	}
+	if err != nil {
+		op.Error = err.Error()
+	}
+	if err := s.saveOp(op); err != nil {
+		log.Printf("작업 #%d 결과 저장 실패: %v", op.ID, err)
+	}
+}
+
+// dropParams - 작업 인자에서 keys를 뺀다 (결과를 저장할 때 operations.params에도 반영된다).
+// 저장소가 가진 맵을 건드리지 않도록 새 맵으로 바꾼다
+func dropParams(op *Operation, keys ...string) {
+	params := make(map[string]string, len(op.Params))
+	for k, v := range op.Params {
+		params[k] = v
+	}
+	for _, k := range keys {
+		delete(params, k)
+	}
+	op.Params = params
+}
+
+// setStep - 진행 단계를 기록 (저장 실패는 작업을 멈추지 않는다)
+func (s *HostingService) setStep(op *Operation, step string) {
+	op.Step = step
+	if err := s.saveOp(op); err != nil {
+		log.Printf("작업 #%d 단계 저장 실패: %v", op.ID, err)
+	}
+}
+
+func (s *HostingService) saveOp(op *Operation) error {
+	op.UpdatedAt = time.Now()
+	if err := s.ops.Update(op); err != nil {
+		return fmt.Errorf("작업 #%d 저장 실패: %w", op.ID, err)
+	}
+	return nil
+}
+
+// lockVM - 같은 VM에 대한 작업이 동시에 실행되지 않도록 한다
+func (s *HostingService) lockVM(vmName string) func() {
+	v, _ := s.vmLocks.LoadOrStore(vmName, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// tryLockVM - 다른 작업이 진행 중이면 기다리지 않고 false를 돌려준다
+func (s *HostingService) tryLockVM(vmName string) (func(), bool) {
+	v, _ := s.vmLocks.LoadOrStore(vmName, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	if !mu.TryLock() {
+		return nil, false
+	}
+	return mu.Unlock, true
+}
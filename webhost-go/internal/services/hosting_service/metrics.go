@@ -0,0 +1,316 @@
+package hosting_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// 수집한 사용량은 1분 간격으로 저장하고, 1시간 평균으로 합쳐 더 오래 보관한다
+const (
+	MetricsRawResolution    = time.Minute
+	MetricsRollupResolution = time.Hour
+	metricsRawRetention     = 24 * time.Hour
+	metricsRollupRetention  = 30 * 24 * time.Hour
+	// maxMetricsPoints - 조회 한 번에 VM마다 돌려줄 수 있는 최대 점 수
+	maxMetricsPoints = 1500
+)
+
+var ErrInvalidMetricsQuery = errors.New("잘못된 사용량 조회 범위입니다")
+
+// MetricPoint - VM 하나의 한 구간(Time부터 Resolution 동안) 평균 사용량
+type MetricPoint struct {
+	HostingID  int64         `json:"-"`
+	Resolution time.Duration `json:"-"`
+	Time       time.Time     `json:"time"`
+	CPUPercent float64       `json:"cpu_percent"` // 할당된 vCPU 전체 대비 (0~100)
+	MemoryKB   uint64        `json:"memory_kb"`   // 할당된 메모리
+	// MemoryUsedKB - 게스트가 미사용 메모리를 보고하지 않으면 MemoryKB와 같다
+	MemoryUsedKB uint64  `json:"memory_used_kb"`
+	DiskReadBps  float64 `json:"disk_read_bps"`
+	DiskWriteBps float64 `json:"disk_write_bps"`
+	NetRxBps     float64 `json:"net_rx_bps"`
+	NetTxBps     float64 `json:"net_tx_bps"`
+}
+
+// VMMetrics - 조회 결과 (VM별 시계열)
+type VMMetrics struct {
+	ID     int64          `json:"id"`
+	Name   string         `json:"name"`
+	Points []*MetricPoint `json:"points"`
+}
+
+// MetricsQuery - [From, To) 구간을 Step 간격 평균으로 조회한다. VM이 비어 있으면 사용자의 모든 VM
+type MetricsQuery struct {
+	VM   string
+	From time.Time
+	To   time.Time
+	Step time.Duration
+}
+
+// metricsState - 사용률 계산에 쓰는 직전 샘플과 마지막으로 합친 시각
+type metricsState struct {
+	mu       sync.Mutex
+	last     map[string]metricsSample // 도메인 이름 → 직전 샘플
+	rolledUp time.Time                // 이 시각 전까지 1시간 평균을 만들었다
+}
+
+type metricsSample struct {
+	stats DomainStats
+	at    time.Time
+}
+
+// StartMetricsCollector - MetricsRawResolution마다 사용량을 수집한다
+func (s *HostingService) StartMetricsCollector(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(MetricsRawResolution)
+		defer ticker.Stop()
+		for {
+			if err := s.CollectMetrics(time.Now()); err != nil {
+				log.Printf("사용량 수집 실패: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// CollectMetrics - 모든 도메인의 카운터를 읽어 직전 샘플과의 차이로 사용률을 저장한다.
+// 처음 본 도메인이나 재시작해 카운터가 줄어든 도메인은 이번 샘플만 기억한다.
+// 정시가 지났으면 지난 시간의 1분 값을 1시간 평균으로 합치고 보관 기간이 지난 값을 지운다.
+func (s *HostingService) CollectMetrics(now time.Time) error {
+	stats, err := s.hv.DomainStats()
+	if err != nil {
+		return fmt.Errorf("도메인 통계 조회 실패: %w", err)
+	}
+	rows, err := s.repo.FindAll()
+	if err != nil {
+		return fmt.Errorf("호스팅 목록 조회 실패: %w", err)
+	}
+	hostings := make(map[string]*Hosting, len(rows))
+	for _, h := range rows {
+		if h.Status != "deleted" {
+			hostings[h.VMName] = h
+		}
+	}
+
+	m := &s.collector
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var points []*MetricPoint
+	next := make(map[string]metricsSample, len(stats))
+	for _, st := range stats {
+		next[st.VMName] = metricsSample{stats: st, at: now}
+		h, ok := hostings[st.VMName]
+		if !ok {
+			continue
+		}
+		prev, ok := m.last[st.VMName]
+		if !ok {
+			continue
+		}
+		if p := metricPoint(prev, metricsSample{stats: st, at: now}); p != nil {
+			p.HostingID = h.ID
+			points = append(points, p)
+		}
+	}
+	// 멈춘 도메인은 빠지므로 다시 시작하면 첫 샘플부터 새로 잰다
+	m.last = next
+
+	if len(points) > 0 {
+		if err := s.metrics.Save(points); err != nil {
+			return fmt.Errorf("사용량 저장 실패: %w", err)
+		}
+	}
+	return s.rollupMetrics(now)
+}
+
+// metricPoint - 두 샘플 사이의 사용률. 카운터가 줄었으면(재시작) nil
+func metricPoint(prev, cur metricsSample) *MetricPoint {
+	elapsed := cur.at.Sub(prev.at).Seconds()
+	a, b := prev.stats, cur.stats
+	if elapsed <= 0 || b.CPUTimeNs < a.CPUTimeNs || b.BlockReadBytes < a.BlockReadBytes ||
+		b.BlockWriteBytes < a.BlockWriteBytes || b.NetRxBytes < a.NetRxBytes || b.NetTxBytes < a.NetTxBytes {
+		return nil
+	}
+
+	p := &MetricPoint{
+		Resolution:   MetricsRawResolution,
+		Time:         cur.at.Truncate(MetricsRawResolution),
+		MemoryKB:     b.MemoryKB,
+		MemoryUsedKB: b.MemoryKB,
+		DiskReadBps:  float64(b.BlockReadBytes-a.BlockReadBytes) / elapsed,
+		DiskWriteBps: float64(b.BlockWriteBytes-a.BlockWriteBytes) / elapsed,
+		NetRxBps:     float64(b.NetRxBytes-a.NetRxBytes) / elapsed,
+		NetTxBps:     float64(b.NetTxBytes-a.NetTxBytes) / elapsed,
+	}
+	if b.MemoryUnusedKB > 0 && b.MemoryUnusedKB < b.MemoryKB {
+		p.MemoryUsedKB = b.MemoryKB - b.MemoryUnusedKB
+	}
+	if b.VCPUs > 0 {
+		cpu := float64(b.CPUTimeNs-a.CPUTimeNs) / (elapsed * 1e9 * float64(b.VCPUs)) * 100
+		p.CPUPercent = min(cpu, 100)
+	}
+	return p
+}
+
+// rollupMetrics - 끝난 시간들의 1분 값을 1시간 평균으로 저장하고 오래된 값을 지운다 (m.mu를 잡은 상태에서 호출)
+func (s *HostingService) rollupMetrics(now time.Time) error {
+	m := &s.collector
+	hour := now.Truncate(MetricsRollupResolution)
+	if !m.rolledUp.Before(hour) {
+		return nil
+	}
+	from := m.rolledUp
+	if earliest := hour.Add(-metricsRawRetention); from.Before(earliest) {
+		from = earliest
+	}
+
+	raw, err := s.metrics.FindAllRange(MetricsRawResolution, from, hour)
+	if err != nil {
+		return fmt.Errorf("사용량 조회 실패: %w", err)
+	}
+	byHosting := make(map[int64][]*MetricPoint)
+	for _, p := range raw {
+		byHosting[p.HostingID] = append(byHosting[p.HostingID], p)
+	}
+	var rolled []*MetricPoint
+	for id, points := range byHosting {
+		for _, p := range downsampleMetrics(points, from, MetricsRollupResolution) {
+			p.HostingID = id
+			p.Resolution = MetricsRollupResolution
+			rolled = append(rolled, p)
+		}
+	}
+	if len(rolled) > 0 {
+		if err := s.metrics.Save(rolled); err != nil {
+			return fmt.Errorf("사용량 저장 실패: %w", err)
+		}
+	}
+
+	if err := s.metrics.DeleteBefore(MetricsRawResolution, now.Add(-metricsRawRetention)); err != nil {
+		return fmt.Errorf("오래된 사용량 삭제 실패: %w", err)
+	}
+	if err := s.metrics.DeleteBefore(MetricsRollupResolution, now.Add(-metricsRollupRetention)); err != nil {
+		return fmt.Errorf("오래된 사용량 삭제 실패: %w", err)
+	}
+	m.rolledUp = hour
+	return nil
+}
+
+// downsampleMetrics - from부터 step 간격 구간마다 평균을 낸다 (값이 없는 구간은 건너뛴다).
+// points는 시간순이어야 한다.
+func downsampleMetrics(points []*MetricPoint, from time.Time, step time.Duration) []*MetricPoint {
+	var out []*MetricPoint
+	var sum MetricPoint
+	var memKB, memUsedKB float64
+	n := 0
+	flush := func() {
+		if n == 0 {
+			return
+		}
+		f := float64(n)
+		out = append(out, &MetricPoint{
+			Time:         sum.Time,
+			CPUPercent:   sum.CPUPercent / f,
+			MemoryKB:     uint64(memKB / f),
+			MemoryUsedKB: uint64(memUsedKB / f),
+			DiskReadBps:  sum.DiskReadBps / f,
+			DiskWriteBps: sum.DiskWriteBps / f,
+			NetRxBps:     sum.NetRxBps / f,
+			NetTxBps:     sum.NetTxBps / f,
+		})
+	}
+
+	for _, p := range points {
+		if p.Time.Before(from) {
+			continue
+		}
+		bucket := from.Add(p.Time.Sub(from) / step * step)
+		if n > 0 && !bucket.Equal(sum.Time) {
+			flush()
+			n = 0
+		}
+		if n == 0 {
+			sum = MetricPoint{Time: bucket}
+			memKB, memUsedKB = 0, 0
+		}
+		sum.CPUPercent += p.CPUPercent
+		memKB += float64(p.MemoryKB)
+		memUsedKB += float64(p.MemoryUsedKB)
+		sum.DiskReadBps += p.DiskReadBps
+		sum.DiskWriteBps += p.DiskWriteBps
+		sum.NetRxBps += p.NetRxBps
+		sum.NetTxBps += p.NetTxBps
+		n++
+	}
+	flush()
+	return out
+}
+
+// defaultMetricsStep - 구간에 맞는 가장 작은 해상도. 점이 너무 많으면 해상도의 배수로 늘린다
+func defaultMetricsStep(from, to time.Time) time.Duration {
+	res := MetricsRawResolution
+	if from.Before(time.Now().Add(-metricsRawRetention)) {
+		res = MetricsRollupResolution
+	}
+	n := (to.Sub(from) + res*maxMetricsPoints - 1) / (res * maxMetricsPoints)
+	return res * max(n, 1)
+}
+
+// GetMetrics - 사용자 VM의 사용량 시계열. Step이 0이면 구간에 맞게 고른다.
+// Step이 1시간 이상이거나 From이 1분 값 보관 기간보다 오래되면 1시간 평균에서 계산한다.
+func (s *HostingService) GetMetrics(userID int64, q MetricsQuery) ([]*VMMetrics, error) {
+	if !q.From.Before(q.To) {
+		return nil, fmt.Errorf("%w: from은 to보다 앞이어야 합니다", ErrInvalidMetricsQuery)
+	}
+	if q.Step == 0 {
+		q.Step = defaultMetricsStep(q.From, q.To)
+	}
+	resolution := MetricsRawResolution
+	if q.Step >= MetricsRollupResolution || q.From.Before(time.Now().Add(-metricsRawRetention)) {
+		resolution = MetricsRollupResolution
+	}
+	if q.Step < resolution {
+		return nil, fmt.Errorf("%w: 이 범위의 step은 %s 이상이어야 합니다", ErrInvalidMetricsQuery, resolution)
+	}
+	if q.To.Sub(q.From)/q.Step > maxMetricsPoints {
+		return nil, fmt.Errorf("%w: 점이 %d개를 넘습니다 (step을 늘리세요)", ErrInvalidMetricsQuery, maxMetricsPoints)
+	}
+
+	var vms []*Hosting
+	if q.VM != "" {
+		h, err := s.findVM(userID, q.VM)
+		if err != nil {
+			return nil, err
+		}
+		vms = []*Hosting{h}
+	} else {
+		list, err := s.ListVMs(userID)
+		if err != nil {
+			return nil, err
+		}
+		vms = list
+	}
+
+	result := make([]*VMMetrics, 0, len(vms))
+	for _, h := range vms {
+		points, err := s.metrics.FindRange(h.ID, resolution, q.From, q.To)
+		if err != nil {
+			return nil, fmt.Errorf("사용량 조회 실패: %w", err)
+		}
+		series := downsampleMetrics(points, q.From, q.Step)
+		if series == nil {
+			series = []*MetricPoint{}
+		}
+		result = append(result, &VMMetrics{ID: h.ID, Name: h.Name, Points: series})
+	}
+	return result, nil
+}
@@ -0,0 +1,459 @@
+package hosting_service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+)
+
+var (
+	ErrMigrationSameNode = errors.New("VM이 이미 그 노드에 있습니다")
+	// ErrMigrationSnapshots - libvirt는 내부 스냅샷이 있는 도메인을 옮기지 않는다
+	ErrMigrationSnapshots    = errors.New("스냅샷이 있는 VM은 다른 노드로 옮길 수 없습니다 (스냅샷을 먼저 삭제해 주세요)")
+	ErrInvalidMigrationMode  = errors.New("이전 방식은 live 또는 cold여야 합니다")
+	errMigrationNotSupported = errors.New("노드 사이 이전을 지원하지 않는 하이퍼바이저입니다 (Migrator가 아님)")
+)
+
+const (
+	// MigrationLive - 실행 중인 VM을 멈추지 않고 옮긴다 (메모리와 디스크를 libvirt가 복사)
+	MigrationLive = "live"
+	// MigrationCold - 꺼진 VM의 인스턴스 파일을 복사하고 대상 노드에 다시 정의한다
+	MigrationCold = "cold"
+
+	// guestBootTimeout - 꺼진 VM의 IP를 바꾸려고 잠깐 부팅했을 때 게스트 에이전트가 응답하기를 기다리는 시간
+	guestBootTimeout      = 3 * time.Minute
+	guestBootPollInterval = time.Second
+)
+
+// MigrateOptions - VM 이전 옵션
+type MigrateOptions struct {
+	// Node - 대상 노드 (비어 있으면 스케줄러가 지금 노드를 뺀 노드 중에서 고른다)
+	Node string `json:"node"`
+	// Mode - MigrationLive 또는 MigrationCold (비어 있으면 실행 중이면 live, 꺼져 있으면 cold)
+	Mode string `json:"mode"`
+}
+
+// migrationPlan - 이전할 VM의 원본/대상 노드와 방식
+type migrationPlan struct {
+	src, dst *Node
+	mode     string
+	plan     *HostingPlan
+	// readdress - 대상 노드의 네트워크에서 지금 IP를 쓸 수 없어 새 IP로 바꿔야 한다
+	readdress bool
+}
+
+// MigrateVM - 대상 노드와 방식을 확인하고 이전 작업을 큐에 넣는다 (실행할 때 한 번 더 확인한다)
+func (s *HostingService) MigrateVM(hostingID int64, opts MigrateOptions) (*Operation, error) {
+	h, err := s.repo.FindByID(hostingID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && h.Status == "deleted") {
+		return nil, fmt.Errorf("%w: %d", ErrVMNotFound, hostingID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("VM 정보 조회 실패: %w", err)
+	}
+
+	mp, err := s.planMigration(h, opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.enqueue(OpMigrateVM, h.UserID, h.Name, h.VMName, map[string]string{"node": mp.dst.Name, "mode": mp.mode})
+}
+
+// planMigration - 스냅샷, 도메인 상태, 대상 노드의 용량/레이블/네트워크를 확인한다.
+// 명시한 대상 노드는 배치 중지(Schedulable=false)여도 고를 수 있다
+func (s *HostingService) planMigration(h *Hosting, opts MigrateOptions) (*migrationPlan, error) {
+	pool, err := s.nodePool()
+	if err != nil {
+		return nil, err
+	}
+	srcName := h.NodeName
+	if srcName == "" {
+		// 노드를 등록하기 전에 만든 VM
+		if srcName, err = pool.NodeOf(h.VMName); err != nil {
+			return nil, err
+		}
+	}
+
+	snaps, err := s.snaps.FindByHostingID(h.ID)
+	if err != nil {
+		return nil, fmt.Errorf("스냅샷 조회 실패: %w", err)
+	}
+	if len(snaps) > 0 {
+		return nil, fmt.Errorf("%w (%d개)", ErrMigrationSnapshots, len(snaps))
+	}
+
+	state, err := s.domainState(h.VMName)
+	if err != nil {
+		return nil, err
+	}
+	running := state == DomainRunning || state == DomainPaused
+	mode := opts.Mode
+	switch {
+	case mode == "" && running:
+		mode = MigrationLive
+	case mode == "":
+		mode = MigrationCold
+	case mode == MigrationLive && !running:
+		return nil, fmt.Errorf("%w: 꺼진 VM은 cold 방식으로 옮겨야 합니다", ErrVMNotRunning)
+	case mode == MigrationCold && state != DomainShutoff:
+		return nil, ErrVMRunning
+	case mode != MigrationLive && mode != MigrationCold:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMigrationMode, mode)
+	}
+
+	plan, err := s.resolvePlan(h.Plan)
+	if err != nil {
+		return nil, err
+	}
+	dstName := opts.Node
+	switch {
+	case dstName == srcName:
+		return nil, fmt.Errorf("%w: %s", ErrMigrationSameNode, dstName)
+	case dstName == "":
+		if dstName, err = s.scheduleNode(plan, srcName); err != nil {
+			return nil, err
+		}
+		if dstName == "" {
+			return nil, fmt.Errorf("%w (등록된 노드가 없습니다)", ErrNoNodeAvailable)
+		}
+	}
+
+	src, err := s.findNode(srcName)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := s.findNode(dstName)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkNodeFits(dst, plan); err != nil {
+		return nil, err
+	}
+	dstHV, err := pool.Node(dst.Name)
+	if err != nil {
+		return nil, err
+	}
+	readdress, err := s.needsReaddress(h, dstHV)
+	if err != nil {
+		return nil, err
+	}
+	return &migrationPlan{src: src, dst: dst, mode: mode, plan: plan, readdress: readdress}, nil
+}
+
+// checkNodeFits - 노드의 레이블이 플랜과 맞고 플랜 사양이 남은 용량 안에 드는지
+func (s *HostingService) checkNodeFits(n *Node, plan *HostingPlan) error {
+	if !matchLabels(n.Labels, plan.NodeLabels) {
+		return fmt.Errorf("%w: %s의 레이블이 플랜 %s와 맞지 않습니다", ErrNoNodeAvailable, n.Name, plan.Name)
+	}
+	alloc, err := s.nodeAllocations()
+	if err != nil {
+		return err
+	}
+	used := Usage{}
+	if u := alloc[n.Name]; u != nil {
+		used = *u
+	}
+	if _, ok := freeAfter(n, used, plan); !ok {
+		return fmt.Errorf("%w: %s", ErrNodeCapacity, n.Name)
+	}
+	return nil
+}
+
+// needsReaddress - 대상 노드에서 VM의 지금 IP를 할당할 수 없으면 true.
+// 고정 IP는 cloud-init이 첫 부팅 때만 적용하므로 이전하면서 게스트 에이전트로 다시 적용한다
+func (s *HostingService) needsReaddress(h *Hosting, dst Hypervisor) (bool, error) {
+	used, err := s.usedIPs(h.IPAddress)
+	if err != nil {
+		return false, err
+	}
+	ips, err := dst.UsableIPs(used)
+	if err != nil {
+		return false, fmt.Errorf("대상 노드 네트워크 조회 실패: %w", err)
+	}
+	want := net.ParseIP(h.IPAddress)
+	for _, ip := range ips {
+		if ip.Equal(want) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// migrateVM - 실패하면 VM을 원본 노드에 그대로 둔다 (대상에 만든 파일과 정의는 지운다).
+// 대상 노드 네트워크에서 지금 IP를 쓸 수 없으면 새 IP를 할당해 게스트와 hostings, nginx-agent upstream을 함께 바꾼다
+func (s *HostingService) migrateVM(op *Operation) error {
+	h, err := s.repo.FindByVMName(op.VMName)
+	if err != nil {
+		return fmt.Errorf("VM 정보 조회 실패: %w", err)
+	}
+	mp, err := s.planMigration(h, MigrateOptions{Node: op.Params["node"], Mode: op.Params["mode"]})
+	if err != nil {
+		return err
+	}
+	pool, err := s.nodePool()
+	if err != nil {
+		return err
+	}
+	src, err := s.migrator(pool, mp.src.Name)
+	if err != nil {
+		return err
+	}
+	dst, err := s.migrator(pool, mp.dst.Name)
+	if err != nil {
+		return err
+	}
+
+	spec, err := s.importSpec(h, mp.plan)
+	if err != nil {
+		return err
+	}
+	vmName := h.VMName
+	shared := mp.src.SharedStorage && mp.dst.SharedStorage
+	prevNode, prevStatus, prevIP := h.NodeName, h.Status, h.IPAddress
+	var newIP net.IP
+	// guestTouched - 게스트 네트워크를 바꾸기 시작했는지 (되돌릴 때 원래 IP를 다시 적용한다)
+	guestTouched := false
+
+	sg := &saga{onStep: func(step string) { s.setStep(op, step) }}
+	// 1. 대상 노드 용량을 선점하고 migrating으로 표시 (이전 중 이벤트가 상태를 바꾸지 않게)
+	sg.add(StepReserving,
+		func() error {
+			s.placeMu.Lock()
+			defer s.placeMu.Unlock()
+			if err := s.checkNodeFits(mp.dst, mp.plan); err != nil {
+				return err
+			}
+			h.NodeName, h.Status = mp.dst.Name, "migrating"
+			return s.repo.Update(h)
+		},
+		func() error {
+			h.NodeName, h.Status = prevNode, prevStatus
+			return s.repo.Update(h)
+		})
+	if mp.readdress {
+		// 2. 대상 노드 네트워크에서 새 IP를 할당한다 (게스트에는 도메인을 대상에 옮긴 뒤 적용)
+		sg.add(StepReaddressing,
+			func() error {
+				s.placeMu.Lock()
+				defer s.placeMu.Unlock()
+				ip, err := s.allocateIP(mp.dst.Name)
+				if err != nil {
+					return err
+				}
+				newIP, h.IPAddress = ip, ip.String()
+				return s.repo.Update(h)
+			},
+			func() error {
+				h.IPAddress = prevIP
+				return s.repo.Update(h)
+			})
+	}
+
+	if mp.mode == MigrationLive {
+		// 3. 대상에 seed 이미지와 같은 크기의 빈 디스크를 만든다 (디스크 내용은 4에서 복사)
+		if !shared {
+			sg.add(StepCopyingFiles,
+				func() error { return copyInstance(src, dst, spec, ArchiveSkeleton, false) },
+				func() error { return dst.DiscardVM(vmName, true) })
+		}
+		// 4. live migration. 실패하면 VM은 원본에서 계속 실행된다.
+		// 뒤 단계가 실패하면 원본으로 다시 옮기고 게스트에 원래 IP를 적용한다
+		var migrateBack func() error
+		if mp.readdress {
+			migrateBack = func() error {
+				if err := dst.MigrateVM(vmName, mp.src.MigrationTarget(), !shared); err != nil {
+					return err
+				}
+				if guestTouched {
+					return src.SetGuestNetwork(vmName, net.ParseIP(prevIP))
+				}
+				return nil
+			}
+		}
+		sg.add(StepMigratingVM,
+			func() error { return src.MigrateVM(vmName, mp.dst.MigrationTarget(), !shared) },
+			migrateBack)
+		if mp.readdress {
+			// 5. 실행 중인 게스트에 새 IP를 적용한다
+			sg.add(StepConfiguringNetwork,
+				func() error {
+					guestTouched = true
+					return dst.SetGuestNetwork(vmName, newIP)
+				},
+				nil)
+		}
+	} else {
+		// 3. 인스턴스 파일을 복사하고 대상에 꺼진 상태로 정의 (공유 저장소면 정의만).
+		// 공유 저장소에서 게스트 IP를 바꿨으면 되돌릴 때 원본에서 원래 IP를 다시 적용한다
+		content := ArchiveFull
+		if shared {
+			content = ArchiveDefinition
+		}
+		sg.add(StepCopyingFiles,
+			func() error { return copyInstance(src, dst, spec, content, true) },
+			func() error {
+				if err := dst.DiscardVM(vmName, !shared); err != nil {
+					return err
+				}
+				if shared && guestTouched {
+					return s.setGuestNetworkOffline(pool, mp.src.Name, vmName, net.ParseIP(prevIP))
+				}
+				return nil
+			})
+		if mp.readdress {
+			// 4. 대상에서 잠깐 부팅해 게스트에 새 IP를 적용하고 다시 끈다
+			sg.add(StepConfiguringNetwork,
+				func() error {
+					guestTouched = true
+					return s.setGuestNetworkOffline(pool, mp.dst.Name, vmName, newIP)
+				},
+				nil)
+		}
+	}
+
+	if mp.readdress {
+		// nginx-agent upstream을 새 IP로 바꾼다
+		sg.add(StepRegisteringProxy,
+			func() error { return RegisterWithNginxAgent(s.agentAddr, proxyInfo(h)) },
+			func() error {
+				prev := *h
+				prev.IPAddress = prevIP
+				return RegisterWithNginxAgent(s.agentAddr, proxyInfo(&prev))
+			})
+	}
+	if mp.mode == MigrationCold {
+		// 마지막으로 원본의 정의를 지운다 (되돌릴 수 없으므로 맨 뒤, 파일은 이전이 끝난 뒤 정리)
+		sg.add(StepRemovingSource,
+			func() error { return src.DiscardVM(vmName, false) },
+			nil)
+	}
+
+	if err := sg.run(); err != nil {
+		op.Step = sg.failedStep
+		op.Rollback = sg.rollback
+		op.Status = OpRolledBack
+		if !sg.rollbackOK {
+			op.Status = OpRollbackFailed
+		}
+		return err
+	}
+
+	// 여기부터 VM은 대상 노드에만 있으므로 실패해도 되돌리지 않는다
+	pool.Place(vmName, mp.dst.Name)
+	if !shared {
+		if err := src.DiscardVM(vmName, true); err != nil {
+			log.Printf("원본 인스턴스 파일 정리 실패 (%s, %s): %v", vmName, mp.src.Name, err)
+		}
+	}
+	s.syncAutostart(vmName, h.DesiredState)
+
+	s.setStep(op, StepUpdatingStatus)
+	h.Status = prevStatus
+	if state, err := s.domainState(vmName); err == nil {
+		if st, ok := statusForDomain(state); ok {
+			h.Status = st
+		}
+	}
+	if err := s.repo.Update(h); err != nil {
+		return fmt.Errorf("상태 갱신 실패: %w", err)
+	}
+
+	res := map[string]interface{}{
+		"from":           mp.src.Name,
+		"to":             mp.dst.Name,
+		"mode":           mp.mode,
+		"shared_storage": shared,
+	}
+	if mp.readdress {
+		res["ip"] = h.IPAddress
+	}
+	result, _ := json.Marshal(res)
+	op.Result = string(result)
+	return nil
+}
+
+// setGuestNetworkOffline - 꺼진 도메인을 node에서 잠깐 부팅해 게스트 IP를 ip로 바꾸고 다시 끈다
+func (s *HostingService) setGuestNetworkOffline(pool NodePool, node, vmName string, ip net.IP) error {
+	hv, err := pool.Node(node)
+	if err != nil {
+		return err
+	}
+	m, err := s.migrator(pool, node)
+	if err != nil {
+		return err
+	}
+	if err := hv.StartVM(vmName); err != nil {
+		return fmt.Errorf("VM 시작 실패: %w", err)
+	}
+	err = setGuestNetworkWhenReady(m, vmName, ip)
+	if serr := shutdownOn(hv, vmName); serr != nil && err == nil {
+		err = serr
+	}
+	return err
+}
+
+// setGuestNetworkWhenReady - 방금 부팅한 게스트의 에이전트가 뜰 때까지 guestBootTimeout 동안 다시 시도한다
+func setGuestNetworkWhenReady(m Migrator, vmName string, ip net.IP) error {
+	deadline := time.Now().Add(guestBootTimeout)
+	for {
+		err := m.SetGuestNetwork(vmName, ip)
+		if !errors.Is(err, ErrGuestAgentUnavailable) || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(guestBootPollInterval)
+	}
+}
+
+// shutdownOn - 게스트에 종료를 요청하고 DefaultStopTimeout 안에 꺼지지 않으면 강제로 끈다
+func shutdownOn(hv Hypervisor, vmName string) error {
+	if err := hv.StopVM(vmName); err == nil && waitForStateOn(hv, vmName, DomainShutoff, DefaultStopTimeout) == nil {
+		return nil
+	}
+	if err := hv.PowerOffVM(vmName); err != nil {
+		return fmt.Errorf("VM 강제 종료 실패: %w", err)
+	}
+	return waitForStateOn(hv, vmName, DomainShutoff, powerConfirmTimeout)
+}
+
+func (s *HostingService) migrator(pool NodePool, node string) (Migrator, error) {
+	hv, err := pool.Node(node)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := hv.(Migrator)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errMigrationNotSupported, node)
+	}
+	return m, nil
+}
+
+// importSpec - 대상 노드에 도메인을 다시 정의할 때 쓰는 사양. 아카이브의 도메인 XML이 아니라
+// hostings 행, 플랜, 이미지에서 만든다
+func (s *HostingService) importSpec(h *Hosting, plan *HostingPlan) (VMSpec, error) {
+	img, err := s.findImage(h.Image)
+	if err != nil {
+		return VMSpec{}, err
+	}
+	return VMSpec{
+		Name:        h.VMName,
+		VCPUs:       plan.CPU,
+		MemoryMB:    plan.MemoryMB,
+		CloudInit:   CloudInit{SeedFormat: img.SeedFormat},
+		Features:    plan.Features.Merge(img.Features),
+		VNCPassword: h.VNCPassword,
+	}, nil
+}
+
+// copyInstance - 원본 노드의 인스턴스 아카이브를 관리 서버를 거쳐 대상 노드로 스트리밍한다
+func copyInstance(src, dst Migrator, spec VMSpec, content string, define bool) error {
+	r, err := src.ExportVM(spec.Name, content)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return dst.ImportVM(spec, r, define)
+}
@@ -1,28 +1,340 @@
 package hosting_service
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"webhost-go/webhost-go/pkg/nocloud"
+)
 
 type Hosting struct {
 	ID        int64  // 내부 DB용 ID
 	UserID    int64  // 소유자
-	VMName    string // libvirt 도메인 이름
+	Name      string // 사용자가 정한 VM 이름 (사용자 내에서 유일)
+	VMName    string // libvirt 도메인 이름 (vm-<UUID 앞 8자리>)
 	IPAddress string // VM의 내부 IP 주소
 	SSHPort   int    // 외부에서 접속 가능한 SSH 포트 (nginx stream용)
 	ProxyPath string
 	DiskPath  string // qcow2 디스크 경로
+	Plan      string // 적용된 HostingPlan 이름
+	Image     string // 생성에 사용한 Image 이름 (백업에서 만든 VM은 원본 VM의 이미지)
 	Status    string // Running, Stopped, Error 등
 	CreatedAt time.Time
+	// DesiredState - 사용자가 마지막으로 요청한 전원 상태 ("running", "stopped").
+	// compute 노드가 재부팅되면 "running"인 VM을 다시 부팅한다 (RecoverVMs)
+	DesiredState string
+	// VNCPassword - 도메인 VNC 장치의 비밀번호 (VNC 티켓으로만 내보낸다, 비어 있으면 VNC 없음)
+	VNCPassword string `json:"-"`
+	// NodeName - VM이 배치된 compute 노드 (nodes.name, 노드를 등록하기 전에 만든 VM은 비어 있다)
+	NodeName string
 }
 
+// HostingPlan - plans 테이블 (기본값 small/medium/large는 scripts/init.sql에서 생성)
 type HostingPlan struct {
-	Name     string // "small", "medium", "large"
-	CPU      int
-	MemoryMB int
-	DiskGB   int
+	Name     string `json:"name"` // "small", "medium", "large"
+	CPU      int    `json:"cpu"`
+	MemoryMB int    `json:"memory_mb"`
+	DiskGB   int    `json:"disk_gb"`
+	// VM 하나당 보관할 수 있는 스냅샷 수 (0이면 스냅샷 불가)
+	MaxSnapshots int `json:"max_snapshots"`
+	// Features - 이 플랜의 VM에 켜는 선택 기능 (plans.features JSON)
+	Features DomainFeatures `json:"features"`
+	// NodeLabels - 이 플랜의 VM은 레이블이 모두 일치하는 노드에만 배치한다 (plans.node_labels JSON)
+	NodeLabels map[string]string `json:"node_labels,omitempty"`
 }
 
-var DefaultPlans = map[string]HostingPlan{
-	"small":  {Name: "small", CPU: 1, MemoryMB: 1024, DiskGB: 10},
-	"medium": {Name: "medium", CPU: 2, MemoryMB: 2048, DiskGB: 20},
-	"large":  {Name: "large", CPU: 4, MemoryMB: 4096, DiskGB: 40},
+// DomainFeatures - 플랜이나 이미지가 도메인 XML에 추가하는 선택 기능
+type DomainFeatures struct {
+	UEFI      bool `json:"uefi,omitempty"`       // OVMF 펌웨어로 부팅 (UEFI 전용 이미지)
+	VirtioRNG bool `json:"virtio_rng,omitempty"` // 게스트 엔트로피 장치
+	// CPUQuota - vCPU 하나가 쓸 수 있는 호스트 CPU 비율 (%, 0이면 제한 없음). 플랜에서만 지정한다.
+	CPUQuota int `json:"cpu_quota,omitempty"`
+}
+
+// Merge - 이미지 기능을 플랜 기능에 더한다 (켜진 기능은 어느 쪽이든 켜진다)
+func (f DomainFeatures) Merge(image DomainFeatures) DomainFeatures {
+	f.UEFI = f.UEFI || image.UEFI
+	f.VirtioRNG = f.VirtioRNG || image.VirtioRNG
+	return f
+}
+
+// DefaultPlanName - 생성 요청에 플랜이 없을 때 사용
+const DefaultPlanName = "small"
+
+// 플랜 최솟값 (클라우드 이미지가 부팅 가능한 수준)
+const (
+	MinPlanMemoryMB = 512
+	MinPlanDiskGB   = 10
+)
+
+func (p *HostingPlan) Validate() error {
+	if p.Name == "" {
+		return errors.New("플랜 이름이 필요합니다")
+	}
+	if p.CPU < 1 {
+		return errors.New("CPU는 1 이상이어야 합니다")
+	}
+	if p.MemoryMB < MinPlanMemoryMB {
+		return fmt.Errorf("메모리는 %dMB 이상이어야 합니다", MinPlanMemoryMB)
+	}
+	if p.DiskGB < MinPlanDiskGB {
+		return fmt.Errorf("디스크는 %dGB 이상이어야 합니다", MinPlanDiskGB)
+	}
+	if p.MaxSnapshots < 0 {
+		return errors.New("스냅샷 개수는 0 이상이어야 합니다")
+	}
+	if p.Features.CPUQuota < 0 || p.Features.CPUQuota > 100 {
+		return errors.New("CPU 할당률은 0~100%여야 합니다")
+	}
+	return nil
+}
+
+// Image - 부팅 디스크 템플릿 (images 테이블). 파일은 compute 노드의 템플릿 디렉토리에 있다.
+type Image struct {
+	Name        string `json:"name"`      // "ubuntu-22.04"
+	OSFamily    string `json:"os_family"` // "ubuntu", "debian" 등
+	Version     string `json:"version"`
+	Path        string `json:"path"`         // 템플릿 디렉토리 안의 파일 이름
+	SHA256      string `json:"sha256"`       // 파일 체크섬 (hex)
+	DefaultUser string `json:"default_user"` // 클라우드 이미지의 기본 로그인 계정
+	SeedFormat  string `json:"seed_format"`  // cloud-init seed 형식 ("iso", iso를 못 읽는 이미지는 "vfat")
+	// Features - 이 이미지에 필요한 선택 기능 (images.features JSON, uefi와 virtio_rng만)
+	Features   DomainFeatures `json:"features"`
+	Status     string         `json:"status"`      // ImageActive, ImageRetired
+	VerifiedAt *time.Time     `json:"verified_at"` // 체크섬 확인 시각 (nil이면 첫 사용 전에 확인)
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+const (
+	ImageActive  = "active"
+	ImageRetired = "retired" // 새 VM에는 쓸 수 없다 (기존 VM은 그대로)
+)
+
+// DefaultImageName - 생성 요청에 이미지가 없을 때 사용
+const DefaultImageName = "ubuntu-22.04"
+
+var (
+	sha256Pattern    = regexp.MustCompile(`^[0-9a-f]{64}$`)
+	imageNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,49}$`)
+)
+
+func (img *Image) Validate() error {
+	if !imageNamePattern.MatchString(img.Name) {
+		return errors.New("이미지 이름은 영문 소문자, 숫자, '.', '_', '-'로 된 1~50자여야 합니다")
+	}
+	if img.OSFamily == "" {
+		return errors.New("OS 종류가 필요합니다")
+	}
+	if img.Path == "" || img.Path != filepath.Base(img.Path) || img.Path == "." || img.Path == ".." {
+		return errors.New("이미지 경로는 템플릿 디렉토리 안의 파일 이름이어야 합니다")
+	}
+	if !sha256Pattern.MatchString(img.SHA256) {
+		return errors.New("sha256은 64자리 소문자 hex여야 합니다")
+	}
+	if img.DefaultUser == "" {
+		return errors.New("기본 사용자가 필요합니다")
+	}
+	if _, err := nocloud.ParseFormat(img.SeedFormat); err != nil {
+		return err
+	}
+	if img.Features.CPUQuota != 0 {
+		return errors.New("CPU 할당률은 플랜에서만 지정할 수 있습니다")
+	}
+	return nil
+}
+
+// Node - VM을 실행하는 compute 노드 (nodes 테이블)
+type Node struct {
+	Name string `json:"name"`
+	// Address - "agent://10.0.0.2:5004" (libvirt-agent) 또는 libvirt URI ("qemu+tcp://10.0.0.3/system",
+	// "qemu+ssh://root@10.0.0.4/system", 로컬은 "qemu:///system")
+	Address string `json:"address"`
+	// 할당 가능한 용량 (vCPU는 오버커밋을 포함한 값). 스케줄러는 플랜 사양의 합으로 사용량을 계산한다
+	VCPUs    int `json:"vcpus"`
+	MemoryMB int `json:"memory_mb"`
+	DiskGB   int `json:"disk_gb"`
+	// Labels - 플랜의 NodeLabels와 비교한다 (nodes.labels JSON)
+	Labels map[string]string `json:"labels,omitempty"`
+	// Schedulable - false면 새 VM을 배치하지 않는다 (기존 VM은 그대로)
+	Schedulable bool `json:"schedulable"`
+	// SharedStorage - 인스턴스 디렉토리가 공유 저장소(NFS 등)에 있다. 둘 다 true인 노드 사이의 이전은 디스크를 복사하지 않는다
+	SharedStorage bool `json:"shared_storage"`
+	// MigrationURI - 다른 노드의 libvirtd가 live migration으로 이 노드에 연결할 URI
+	// (비어 있으면 MigrationTarget이 Address에서 만든다)
+	MigrationURI string    `json:"migration_uri,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+var nodeAddressPattern = regexp.MustCompile(`^(agent://[^/]+|qemu(\+(tcp|tls|ssh|unix))?://.*)$`)
+var migrationURIPattern = regexp.MustCompile(`^qemu(\+(tcp|tls|ssh))?://[^/]+/.*$`)
+
+func (n *Node) Validate() error {
+	if !imageNamePattern.MatchString(n.Name) {
+		return errors.New("노드 이름은 영문 소문자, 숫자, '.', '_', '-'로 된 1~50자여야 합니다")
+	}
+	if !nodeAddressPattern.MatchString(n.Address) {
+		return errors.New("노드 주소는 agent://host:port 또는 qemu[+tcp|tls|ssh|unix]:// URI여야 합니다")
+	}
+	if n.MigrationURI != "" && !migrationURIPattern.MatchString(n.MigrationURI) {
+		return errors.New("이전 URI는 qemu[+tcp|tls|ssh]://host/ 형식이어야 합니다")
+	}
+	if n.VCPUs < 1 || n.MemoryMB < MinPlanMemoryMB || n.DiskGB < MinPlanDiskGB {
+		return errors.New("노드 용량(vCPU, 메모리, 디스크)은 가장 작은 플랜보다 커야 합니다")
+	}
+	return nil
+}
+
+// MigrationTarget - live migration의 대상 URI. MigrationURI가 없으면 libvirt URI 주소는 그대로,
+// agent 주소는 같은 호스트의 qemu+tcp://<host>/system
+func (n *Node) MigrationTarget() string {
+	if n.MigrationURI != "" {
+		return n.MigrationURI
+	}
+	addr, ok := strings.CutPrefix(n.Address, "agent://")
+	if !ok {
+		return n.Address
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return "qemu+tcp://" + addr + "/system"
+}
+
+// NodeStatus - 노드와 배치된 VM들의 플랜 사양 합계 (관리자용 노드 목록)
+type NodeStatus struct {
+	*Node
+	Allocated Usage `json:"allocated"`
+	// Connected - 하이퍼바이저에 연결되어 있는지 (연결 실패 시 Error)
+	Connected bool   `json:"connected"`
+	Error     string `json:"error,omitempty"`
+}
+
+// SSHKey - 사용자 SSH 공개키 (ssh_keys 테이블). 새 VM의 기본 계정 authorized_keys에 들어간다.
+type SSHKey struct {
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"user_id"`
+	Name        string    `json:"name"`
+	PublicKey   string    `json:"public_key"`
+	Fingerprint string    `json:"fingerprint"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CreateOptions - VM 생성 옵션 (빈 값이면 기본 플랜/이미지, UserData는 baseline 위에 병합)
+type CreateOptions struct {
+	Plan     string
+	Image    string
+	UserData string // #cloud-config
+}
+
+// Snapshot - VM 스냅샷 (snapshots 테이블, libvirt에는 같은 이름의 내부 스냅샷으로 저장)
+type Snapshot struct {
+	ID          int64     `json:"id"`
+	HostingID   int64     `json:"hosting_id"`
+	Name        string    `json:"name"` // VM 내에서 유일, libvirt 스냅샷 이름
+	Description string    `json:"description"`
+	VMStatus    string    `json:"vm_status"` // 생성 시점의 VM 상태 (복원하면 이 상태가 된다)
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Backup - VM 루트 디스크 백업 (backups 테이블). VM을 삭제해도 남아 새 VM으로 복원할 수 있다.
+type Backup struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	HostingID int64     `json:"hosting_id"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"` // BackupManual, BackupScheduled
+	Plan      string    `json:"plan"` // 백업 시점의 플랜 (복원할 VM의 최소 디스크 크기)
+	Path      string    `json:"-"`    // compute 노드의 백업 파일 경로
+	SizeBytes int64     `json:"size_bytes"`
+	NodeName  string    `json:"node_name,omitempty"` // 백업 파일이 있는 노드 (복원, 복제, 삭제는 이 노드에서 한다)
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const (
+	BackupManual    = "manual"
+	BackupScheduled = "scheduled" // 보관 정책(KeepDaily/KeepWeekly)은 예약 백업에만 적용
+)
+
+// BackupSchedule - VM별 예약 백업 (backup_schedules 테이블)
+type BackupSchedule struct {
+	HostingID  int64     `json:"hosting_id"`
+	Cron       string    `json:"cron"`        // 5필드 cron 표현식 또는 @daily 등
+	KeepDaily  int       `json:"keep_daily"`  // 최근 N일 동안 하루에 하나씩 보관
+	KeepWeekly int       `json:"keep_weekly"` // 최근 N주 동안 한 주에 하나씩 보관
+	LastRunAt  time.Time `json:"last_run_at"` // 마지막 실행 (설정 시각으로 초기화)
+}
+
+// Quota - 사용자별 리소스 할당량 (quotas 테이블, 행이 없으면 기본 할당량)
+type Quota struct {
+	UserID      int64 `json:"user_id"`
+	MaxVMs      int   `json:"max_vms"`
+	MaxVCPUs    int   `json:"max_vcpus"`
+	MaxMemoryMB int   `json:"max_memory_mb"`
+	MaxDiskGB   int   `json:"max_disk_gb"`
+}
+
+// Usage - 삭제되지 않은 VM들이 사용 중인 리소스 합계
+type Usage struct {
+	VMs      int `json:"vms"`
+	VCPUs    int `json:"vcpus"`
+	MemoryMB int `json:"memory_mb"`
+	DiskGB   int `json:"disk_gb"`
+}
+
+// Operation - 비동기로 처리되는 VM 작업 (operations 테이블)
+type Operation struct {
+	ID        int64
+	UserID    int64
+	Kind      string            // OpCreateHosting 등
+	Target    string            // 작업 대상 VM 이름 (Hosting.Name)
+	VMName    string            // libvirt 도메인 이름
+	Status    string            // OpQueued → OpRunning → OpSucceeded / OpFailed / OpRolledBack / OpRollbackFailed
+	Step      string            // 현재 (또는 실패한) 단계
+	Error     string            // 실패 원인
+	Params    map[string]string // 작업 인자 (예: "plan")
+	Result    string            // 성공 시 결과 (JSON)
+	Rollback  []string          // 보상 작업 결과 ("creating_vm: ok" 등)
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// Password - VM 생성 요청의 응답으로 한 번만 돌려주는 초기 비밀번호 (저장하지 않는다)
+	Password string
+}
+
+const (
+	OpCreateHosting = "create_hosting"
+	OpDeleteVM      = "delete_vm"
+	OpStartVM       = "start_vm"
+	OpStopVM        = "stop_vm"
+	OpRebootVM      = "reboot_vm"
+	OpResetVM       = "reset_vm"
+	OpPauseVM       = "pause_vm"
+	OpResumeVM      = "resume_vm"
+	OpResizeVM      = "resize_vm"
+	OpFlattenVM     = "flatten_vm"
+	OpMigrateVM     = "migrate_vm"
+
+	OpCreateSnapshot = "create_snapshot"
+	OpRevertSnapshot = "revert_snapshot"
+	OpDeleteSnapshot = "delete_snapshot"
+
+	OpBackupVM      = "backup_vm"
+	OpRestoreBackup = "restore_backup"
+	OpDeleteBackup  = "delete_backup"
+
+	OpQueued         = "queued"
+	OpRunning        = "running"
+	OpSucceeded      = "succeeded"
+	OpFailed         = "failed"
+	OpRolledBack     = "rolled_back"     // 완료된 단계를 모두 되돌림
+	OpRollbackFailed = "rollback_failed" // 일부 보상 작업 실패, 수동 정리 필요
+)
+
+// Done - 더 이상 진행되지 않는 상태인지
+func (op *Operation) Done() bool {
+	return op.Status != OpQueued && op.Status != OpRunning
 }
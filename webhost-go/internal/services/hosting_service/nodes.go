@@ -0,0 +1,325 @@
+package hosting_service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+)
+
+var (
+	ErrNodeNotFound = errors.New("존재하지 않는 노드입니다")
+	ErrNodeInUse    = errors.New("VM이 배치된 노드는 삭제할 수 없습니다")
+	// ErrNoNodeAvailable - 플랜 사양이 들어갈 용량과 레이블을 가진 노드가 없다
+	ErrNoNodeAvailable = errors.New("VM을 배치할 수 있는 노드가 없습니다")
+	// ErrNodeCapacity - 사양 변경 후의 플랜이 지금 노드에 들어가지 않는다
+	ErrNodeCapacity           = errors.New("노드에 남은 용량이 부족합니다")
+	ErrInvalidSchedulerPolicy = errors.New("스케줄러 정책은 spread 또는 pack이어야 합니다")
+	errNodePoolNotConfigured  = errors.New("노드를 관리할 수 없는 하이퍼바이저입니다 (NodePool이 아님)")
+)
+
+// SchedulerPolicy - 새 VM을 놓을 노드를 고르는 방법
+type SchedulerPolicy string
+
+const (
+	// SchedulerSpread - 배치 후 남는 용량 비율이 가장 큰 노드 (기본값, 노드 하나가 죽었을 때 영향이 작다)
+	SchedulerSpread SchedulerPolicy = "spread"
+	// SchedulerPack - 배치 후 남는 용량 비율이 가장 작은 노드 (빈 노드를 남겨 끄거나 점검하기 쉽다)
+	SchedulerPack SchedulerPolicy = "pack"
+)
+
+// SetSchedulerPolicy - StartWorkers 전에 호출한다 (빈 값이면 SchedulerSpread)
+func (s *HostingService) SetSchedulerPolicy(policy SchedulerPolicy) error {
+	switch policy {
+	case "":
+		policy = SchedulerSpread
+	case SchedulerSpread, SchedulerPack:
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidSchedulerPolicy, policy)
+	}
+	s.policy = policy
+	return nil
+}
+
+func (s *HostingService) nodePool() (NodePool, error) {
+	pool, ok := s.hv.(NodePool)
+	if !ok {
+		return nil, errNodePoolNotConfigured
+	}
+	return pool, nil
+}
+
+// nodeHypervisor - node의 하이퍼바이저. 노드를 등록하지 않았거나(node가 빈 문자열) NodePool이 아니면 s.hv
+func (s *HostingService) nodeHypervisor(node string) (Hypervisor, error) {
+	pool, err := s.nodePool()
+	if err != nil || node == "" {
+		return s.hv, nil
+	}
+	return pool.Node(node)
+}
+
+// ConnectNodes - nodes 테이블의 노드들을 NodePool에 연결하고 등록된 노드 수를 돌려준다.
+// 연결하지 못한 노드는 로그만 남긴다 (다음 요청 때 다시 연결한다)
+func (s *HostingService) ConnectNodes() (int, error) {
+	pool, err := s.nodePool()
+	if err != nil {
+		return 0, err
+	}
+	nodes, err := s.nodes.FindAll()
+	if err != nil {
+		return 0, fmt.Errorf("노드 목록 조회 실패: %w", err)
+	}
+	for _, n := range nodes {
+		if err := pool.AddNode(n); err != nil {
+			log.Printf("%v", err)
+		}
+	}
+	return len(nodes), nil
+}
+
+func (s *HostingService) findNode(name string) (*Node, error) {
+	n, err := s.nodes.FindByName(name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("노드 조회 실패: %w", err)
+	}
+	return n, nil
+}
+
+// ListNodes - 노드마다 배치된 VM들의 플랜 사양 합계와 연결 상태
+func (s *HostingService) ListNodes() ([]*NodeStatus, error) {
+	nodes, err := s.nodes.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	alloc, err := s.nodeAllocations()
+	if err != nil {
+		return nil, err
+	}
+	pool, poolErr := s.nodePool()
+
+	list := make([]*NodeStatus, 0, len(nodes))
+	for _, n := range nodes {
+		st := &NodeStatus{Node: n}
+		if u := alloc[n.Name]; u != nil {
+			st.Allocated = *u
+		}
+		err := poolErr
+		if err == nil {
+			err = pool.NodeError(n.Name)
+		}
+		st.Connected = err == nil
+		if err != nil {
+			st.Error = err.Error()
+		}
+		list = append(list, st)
+	}
+	return list, nil
+}
+
+// CreateNode - 노드를 등록하고 연결한다. 연결에 실패해도 등록은 유지된다 (ListNodes의 error)
+func (s *HostingService) CreateNode(n *Node) error {
+	if err := n.Validate(); err != nil {
+		return err
+	}
+	pool, err := s.nodePool()
+	if err != nil {
+		return err
+	}
+	if err := s.nodes.Create(n); err != nil {
+		return err
+	}
+	if err := pool.AddNode(n); err != nil {
+		log.Printf("%v", err)
+	}
+	return nil
+}
+
+// UpdateNode - 용량/레이블/배치 여부는 다음 배치부터 적용된다. 주소가 바뀌면 다시 연결한다
+func (s *HostingService) UpdateNode(n *Node) error {
+	if err := n.Validate(); err != nil {
+		return err
+	}
+	pool, err := s.nodePool()
+	if err != nil {
+		return err
+	}
+	old, err := s.findNode(n.Name)
+	if err != nil {
+		return err
+	}
+	if err := s.nodes.Update(n); err != nil {
+		return err
+	}
+	if old.Address != n.Address {
+		if err := pool.AddNode(n); err != nil {
+			log.Printf("%v", err)
+		}
+	}
+	return nil
+}
+
+func (s *HostingService) DeleteNode(name string) error {
+	if _, err := s.findNode(name); err != nil {
+		return err
+	}
+	alloc, err := s.nodeAllocations()
+	if err != nil {
+		return fmt.Errorf("노드 사용 여부 확인 실패: %w", err)
+	}
+	if u := alloc[name]; u != nil && u.VMs > 0 {
+		return fmt.Errorf("%w (%d개)", ErrNodeInUse, u.VMs)
+	}
+	if err := s.nodes.Delete(name); err != nil {
+		return err
+	}
+	if pool, err := s.nodePool(); err == nil {
+		pool.RemoveNode(name)
+	}
+	return nil
+}
+
+// nodeAllocations - 노드 이름 → 삭제되지 않은 VM들의 플랜 사양 합계
+func (s *HostingService) nodeAllocations() (map[string]*Usage, error) {
+	all, err := s.repo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	plans := make(map[string]*HostingPlan)
+	alloc := make(map[string]*Usage)
+	for _, h := range all {
+		if h.Status == "deleted" || h.NodeName == "" {
+			continue
+		}
+		p, ok := plans[h.Plan]
+		if !ok {
+			if p, err = s.resolvePlan(h.Plan); err != nil {
+				return nil, err
+			}
+			plans[h.Plan] = p
+		}
+		if alloc[h.NodeName] == nil {
+			alloc[h.NodeName] = &Usage{}
+		}
+		alloc[h.NodeName].add(p, 1)
+	}
+	return alloc, nil
+}
+
+// scheduleNode - plan이 들어갈 노드를 정책에 따라 고른다. 등록된 노드가 없으면 "" (단일 호스트 구성)
+// exclude 노드는 고르지 않는다 (이전할 VM의 지금 노드).
+// 호출한 쪽은 placeMu를 잡고 고른 노드를 hostings에 기록할 때까지 놓지 않아야 한다
+func (s *HostingService) scheduleNode(plan *HostingPlan, exclude string) (string, error) {
+	return s.pickNode(plan, func(name string) bool { return name != exclude })
+}
+
+// scheduleNodeOn - plan을 반드시 node에 놓아야 할 때 (백업 파일이 있는 노드) 그 노드에 들어가는지 확인한다.
+// node가 비어 있으면 scheduleNode와 같다
+func (s *HostingService) scheduleNodeOn(plan *HostingPlan, node string) (string, error) {
+	if node == "" {
+		return s.scheduleNode(plan, "")
+	}
+	picked, err := s.pickNode(plan, func(name string) bool { return name == node })
+	if errors.Is(err, ErrNoNodeAvailable) {
+		return "", fmt.Errorf("%w (플랜 %s, 노드 %s)", ErrNoNodeAvailable, plan.Name, node)
+	}
+	return picked, err
+}
+
+// pickNode - allow가 허락한 노드 가운데 plan이 들어가는 노드를 정책에 따라 고른다
+func (s *HostingService) pickNode(plan *HostingPlan, allow func(name string) bool) (string, error) {
+	nodes, err := s.nodes.FindAll()
+	if err != nil {
+		return "", fmt.Errorf("노드 목록 조회 실패: %w", err)
+	}
+	if len(nodes) == 0 {
+		return "", nil
+	}
+	alloc, err := s.nodeAllocations()
+	if err != nil {
+		return "", err
+	}
+	pool, _ := s.nodePool()
+
+	best, bestScore := "", 0.0
+	for _, n := range nodes {
+		if !allow(n.Name) || !n.Schedulable || !matchLabels(n.Labels, plan.NodeLabels) {
+			continue
+		}
+		used := Usage{}
+		if u := alloc[n.Name]; u != nil {
+			used = *u
+		}
+		score, ok := freeAfter(n, used, plan)
+		if !ok {
+			continue
+		}
+		// 연결할 수 없는 노드에는 놓지 않는다
+		if pool != nil && pool.NodeError(n.Name) != nil {
+			continue
+		}
+		better := score > bestScore
+		if s.policy == SchedulerPack {
+			better = score < bestScore
+		}
+		if best == "" || better {
+			best, bestScore = n.Name, score
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("%w (플랜 %s)", ErrNoNodeAvailable, plan.Name)
+	}
+	return best, nil
+}
+
+// checkNodeCapacity - 노드에 배치된 VM의 플랜을 current에서 target으로 바꿔도 노드 용량 안에 드는지
+func (s *HostingService) checkNodeCapacity(h *Hosting, target, current *HostingPlan) error {
+	if h.NodeName == "" {
+		return nil
+	}
+	n, err := s.findNode(h.NodeName)
+	if err != nil {
+		return err
+	}
+	alloc, err := s.nodeAllocations()
+	if err != nil {
+		return err
+	}
+	used := Usage{}
+	if u := alloc[n.Name]; u != nil {
+		used = *u
+	}
+	used.add(current, -1)
+	if _, ok := freeAfter(n, used, target); !ok {
+		return fmt.Errorf("%w: %s", ErrNodeCapacity, n.Name)
+	}
+	return nil
+}
+
+// freeAfter - plan을 더한 뒤 vCPU/메모리/디스크 중 가장 적게 남는 자원의 비율 (들어가지 않으면 false)
+func freeAfter(n *Node, used Usage, plan *HostingPlan) (float64, bool) {
+	used.add(plan, 1)
+	if used.VCPUs > n.VCPUs || used.MemoryMB > n.MemoryMB || used.DiskGB > n.DiskGB {
+		return 0, false
+	}
+	free := 1 - float64(used.VCPUs)/float64(n.VCPUs)
+	if f := 1 - float64(used.MemoryMB)/float64(n.MemoryMB); f < free {
+		free = f
+	}
+	if f := 1 - float64(used.DiskGB)/float64(n.DiskGB); f < free {
+		free = f
+	}
+	return free, true
+}
+
+// matchLabels - want의 레이블이 모두 labels에 같은 값으로 있는지
+func matchLabels(labels, want map[string]string) bool {
+	for k, v := range want {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
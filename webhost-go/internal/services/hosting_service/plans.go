@@ -0,0 +1,68 @@
+package hosting_service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrPlanNotFound = errors.New("존재하지 않는 플랜입니다")
+	ErrPlanInUse    = errors.New("사용 중인 VM이 있는 플랜은 삭제할 수 없습니다")
+	ErrDiskShrink   = errors.New("디스크가 더 작은 플랜으로는 변경할 수 없습니다")
+)
+
+// resolvePlan - 이름으로 플랜을 찾는다 (빈 이름이면 DefaultPlanName)
+func (s *HostingService) resolvePlan(name string) (*HostingPlan, error) {
+	if name == "" {
+		name = DefaultPlanName
+	}
+	p, err := s.plans.FindByName(name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrPlanNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("플랜 조회 실패: %w", err)
+	}
+	return p, nil
+}
+
+func (s *HostingService) ListPlans() ([]*HostingPlan, error) {
+	return s.plans.FindAll()
+}
+
+func (s *HostingService) GetPlan(name string) (*HostingPlan, error) {
+	return s.resolvePlan(name)
+}
+
+func (s *HostingService) CreatePlan(p *HostingPlan) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	return s.plans.Create(p)
+}
+
+// UpdatePlan - 이미 생성된 VM에는 영향을 주지 않는다 (새로 만드는 VM부터 적용)
+func (s *HostingService) UpdatePlan(p *HostingPlan) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if _, err := s.resolvePlan(p.Name); err != nil {
+		return err
+	}
+	return s.plans.Update(p)
+}
+
+func (s *HostingService) DeletePlan(name string) error {
+	if _, err := s.resolvePlan(name); err != nil {
+		return err
+	}
+	n, err := s.repo.CountActiveByPlan(name)
+	if err != nil {
+		return fmt.Errorf("플랜 사용 여부 확인 실패: %w", err)
+	}
+	if n > 0 {
+		return fmt.Errorf("%w (%d개)", ErrPlanInUse, n)
+	}
+	return s.plans.Delete(name)
+}
@@ -0,0 +1,249 @@
+package hosting_service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrVMNotPaused        = errors.New("일시 정지된 VM이 아닙니다")
+	ErrInvalidStopTimeout = errors.New("종료 대기 시간은 1초 이상 10분 이하여야 합니다")
+	// ErrPowerStateTimeout - 하이퍼바이저가 요청한 상태로 바뀌었다고 확인해 주지 않았다
+	ErrPowerStateTimeout = errors.New("VM 상태 변경을 확인하지 못했습니다")
+)
+
+const (
+	// DefaultStopTimeout - 게스트가 종료 요청에 응하기를 기다리는 기본 시간, 지나면 강제로 끈다
+	DefaultStopTimeout = time.Minute
+	MaxStopTimeout     = 10 * time.Minute
+
+	// powerConfirmTimeout - 시작/강제 종료/일시 정지처럼 게스트와 상관없는 동작의 상태 확인 대기 시간
+	powerConfirmTimeout = 30 * time.Second
+	powerPollInterval   = 200 * time.Millisecond
+)
+
+// StartVM - VM 시작 작업을 큐에 넣는다 (꺼진 VM은 부팅, 일시 정지된 VM은 재개)
+func (s *HostingService) StartVM(userID int64, ref string) (*Operation, error) {
+	return s.enqueueForVM(OpStartVM, userID, ref, nil)
+}
+
+// StopVM - 정상 종료 작업을 큐에 넣는다. 게스트가 timeout 안에 꺼지지 않으면 강제로 끈다
+// (0이면 DefaultStopTimeout)
+func (s *HostingService) StopVM(userID int64, ref string, timeout time.Duration) (*Operation, error) {
+	if timeout == 0 {
+		timeout = DefaultStopTimeout
+	}
+	if timeout < time.Second || timeout > MaxStopTimeout {
+		return nil, ErrInvalidStopTimeout
+	}
+	return s.enqueueForVM(OpStopVM, userID, ref, map[string]string{
+		"timeout": strconv.Itoa(int(timeout / time.Second)),
+	})
+}
+
+// RebootVM - 게스트에 재부팅을 요청하는 작업을 큐에 넣는다
+func (s *HostingService) RebootVM(userID int64, ref string) (*Operation, error) {
+	return s.enqueuePower(OpRebootVM, userID, ref, DomainRunning)
+}
+
+// ResetVM - 하드 리셋 작업을 큐에 넣는다 (게스트 OS를 거치지 않는다)
+func (s *HostingService) ResetVM(userID int64, ref string) (*Operation, error) {
+	return s.enqueuePower(OpResetVM, userID, ref, DomainRunning)
+}
+
+// PauseVM - 일시 정지 작업을 큐에 넣는다
+func (s *HostingService) PauseVM(userID int64, ref string) (*Operation, error) {
+	return s.enqueuePower(OpPauseVM, userID, ref, DomainRunning)
+}
+
+// ResumeVM - 일시 정지된 VM을 재개하는 작업을 큐에 넣는다
+func (s *HostingService) ResumeVM(userID int64, ref string) (*Operation, error) {
+	return s.enqueuePower(OpResumeVM, userID, ref, DomainPaused)
+}
+
+// enqueuePower - 도메인이 want 상태일 때만 큐에 넣는다 (실행할 때 한 번 더 확인한다)
+func (s *HostingService) enqueuePower(kind string, userID int64, ref string, want DomainState) (*Operation, error) {
+	h, err := s.findVM(userID, ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireState(h.VMName, want); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, h.Name)
+	}
+	return s.enqueue(kind, h.UserID, h.Name, h.VMName, nil)
+}
+
+// requireState - 도메인이 want 상태가 아니면 ErrVMNotRunning 또는 ErrVMNotPaused
+func (s *HostingService) requireState(vmName string, want DomainState) error {
+	state, err := s.domainState(vmName)
+	if err != nil {
+		return err
+	}
+	if state == want {
+		return nil
+	}
+	if want == DomainPaused {
+		return ErrVMNotPaused
+	}
+	return ErrVMNotRunning
+}
+
+func (s *HostingService) domainState(vmName string) (DomainState, error) {
+	info, err := s.hv.DomainInfo(vmName)
+	if err != nil {
+		return "", fmt.Errorf("도메인 정보 조회 실패: %w", err)
+	}
+	return info.State, nil
+}
+
+// waitForState - 도메인이 want 상태가 될 때까지 기다린다 (timeout이 지나면 ErrPowerStateTimeout)
+func (s *HostingService) waitForState(vmName string, want DomainState, timeout time.Duration) error {
+	return waitForStateOn(s.hv, vmName, want, timeout)
+}
+
+// waitForStateOn - waitForState와 같지만 hv에 바로 묻는다 (이전 중 대상 노드처럼 아직 배치되지 않은 도메인)
+func waitForStateOn(hv Hypervisor, vmName string, want DomainState, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		info, err := hv.DomainInfo(vmName)
+		if err != nil {
+			return fmt.Errorf("도메인 정보 조회 실패: %w", err)
+		}
+		if info.State == want {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w (%s, 현재 상태: %s)", ErrPowerStateTimeout, want, info.State)
+		}
+		time.Sleep(powerPollInterval)
+	}
+}
+
+// setPowerStatus - 하이퍼바이저가 확인해 준 상태만 DB에 기록한다
+func (s *HostingService) setPowerStatus(op *Operation, status string) error {
+	s.setStep(op, StepUpdatingStatus)
+	if err := s.repo.UpdateStatus(op.VMName, status); err != nil {
+		return fmt.Errorf("상태 갱신 실패: %w", err)
+	}
+	return nil
+}
+
+func (s *HostingService) startVM(op *Operation) error {
+	s.setStep(op, StepStartingVM)
+	if err := s.hv.StartVM(op.VMName); err != nil {
+		return err
+	}
+	if err := s.waitForState(op.VMName, DomainRunning, powerConfirmTimeout); err != nil {
+		return err
+	}
+	if err := s.setPowerStatus(op, "running"); err != nil {
+		return err
+	}
+	return s.setDesiredState(op.VMName, "running")
+}
+
+// stopVM - 종료를 요청하고 기다린 뒤, 시간이 지나면 강제로 끈다.
+// 일시 정지된 게스트는 종료 요청을 처리할 수 없으므로 바로 끈다.
+func (s *HostingService) stopVM(op *Operation) error {
+	timeout := DefaultStopTimeout
+	if secs, err := strconv.Atoi(op.Params["timeout"]); err == nil && secs > 0 {
+		timeout = time.Duration(secs) * time.Second
+	}
+
+	state, err := s.domainState(op.VMName)
+	if err != nil {
+		return err
+	}
+	forced := false
+	switch state {
+	case DomainShutoff:
+	case DomainRunning:
+		s.setStep(op, StepStoppingVM)
+		if err := s.hv.StopVM(op.VMName); err != nil {
+			return err
+		}
+		err := s.waitForState(op.VMName, DomainShutoff, timeout)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrPowerStateTimeout) {
+			return err
+		}
+		fallthrough
+	default:
+		s.setStep(op, StepPoweringOff)
+		if err := s.hv.PowerOffVM(op.VMName); err != nil {
+			return fmt.Errorf("강제 종료 실패: %w", err)
+		}
+		if err := s.waitForState(op.VMName, DomainShutoff, powerConfirmTimeout); err != nil {
+			return err
+		}
+		forced = true
+	}
+
+	if err := s.setPowerStatus(op, "stopped"); err != nil {
+		return err
+	}
+	if err := s.setDesiredState(op.VMName, "stopped"); err != nil {
+		return err
+	}
+	result, _ := json.Marshal(map[string]interface{}{"forced": forced})
+	op.Result = string(result)
+	return nil
+}
+
+// rebootVM - 재부팅/리셋 후에도 도메인은 running이어야 한다
+func (s *HostingService) rebootVM(op *Operation, hard bool) error {
+	if err := s.requireState(op.VMName, DomainRunning); err != nil {
+		return err
+	}
+	if hard {
+		s.setStep(op, StepResettingVM)
+		if err := s.hv.ResetVM(op.VMName); err != nil {
+			return err
+		}
+	} else {
+		s.setStep(op, StepRebootingVM)
+		if err := s.hv.RebootVM(op.VMName); err != nil {
+			return err
+		}
+	}
+	if err := s.waitForState(op.VMName, DomainRunning, powerConfirmTimeout); err != nil {
+		return err
+	}
+	return s.setPowerStatus(op, "running")
+}
+
+func (s *HostingService) pauseVM(op *Operation) error {
+	if err := s.requireState(op.VMName, DomainRunning); err != nil {
+		return err
+	}
+	s.setStep(op, StepPausingVM)
+	if err := s.hv.PauseVM(op.VMName); err != nil {
+		return err
+	}
+	if err := s.waitForState(op.VMName, DomainPaused, powerConfirmTimeout); err != nil {
+		return err
+	}
+	return s.setPowerStatus(op, "paused")
+}
+
+func (s *HostingService) resumeVM(op *Operation) error {
+	if err := s.requireState(op.VMName, DomainPaused); err != nil {
+		return err
+	}
+	s.setStep(op, StepResumingVM)
+	if err := s.hv.ResumeVM(op.VMName); err != nil {
+		return err
+	}
+	if err := s.waitForState(op.VMName, DomainRunning, powerConfirmTimeout); err != nil {
+		return err
+	}
+	if err := s.setPowerStatus(op, "running"); err != nil {
+		return err
+	}
+	return s.setDesiredState(op.VMName, "running")
+}
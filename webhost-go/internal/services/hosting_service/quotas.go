@@ -0,0 +1,108 @@
+package hosting_service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrQuotaExceeded = errors.New("리소스 할당량을 초과했습니다")
+	ErrInvalidQuota  = errors.New("할당량 값은 0 이상이어야 합니다")
+)
+
+// DefaultQuotaUserID - quotas 테이블에서 기본 할당량을 저장하는 행
+const DefaultQuotaUserID int64 = 0
+
+// DefaultQuota - quotas 테이블에 기본 할당량 행이 없을 때 사용
+var DefaultQuota = Quota{MaxVMs: 3, MaxVCPUs: 8, MaxMemoryMB: 8192, MaxDiskGB: 100}
+
+// GetQuota - 사용자 할당량 (따로 지정하지 않았으면 기본 할당량)
+func (s *HostingService) GetQuota(userID int64) (*Quota, error) {
+	q, err := s.quotas.FindByUserID(userID)
+	if err == nil {
+		return q, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("할당량 조회 실패: %w", err)
+	}
+	if userID == DefaultQuotaUserID {
+		def := DefaultQuota
+		return &def, nil
+	}
+
+	def, err := s.GetQuota(DefaultQuotaUserID)
+	if err != nil {
+		return nil, err
+	}
+	def.UserID = userID
+	return def, nil
+}
+
+// SetQuota - 사용자 할당량 지정 (UserID가 DefaultQuotaUserID면 기본 할당량 변경)
+func (s *HostingService) SetQuota(q *Quota) error {
+	if q.MaxVMs < 0 || q.MaxVCPUs < 0 || q.MaxMemoryMB < 0 || q.MaxDiskGB < 0 {
+		return ErrInvalidQuota
+	}
+	return s.quotas.Upsert(q)
+}
+
+// ResetQuota - 사용자 할당량을 지우고 기본 할당량을 따르게 한다
+func (s *HostingService) ResetQuota(userID int64) error {
+	return s.quotas.Delete(userID)
+}
+
+// GetUsage - 삭제되지 않은 VM들의 플랜 사양 합계
+func (s *HostingService) GetUsage(userID int64) (*Usage, error) {
+	vms, err := s.ListVMs(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &Usage{}
+	for _, h := range vms {
+		p, err := s.resolvePlan(h.Plan)
+		if err != nil {
+			return nil, err
+		}
+		u.add(p, 1)
+	}
+	return u, nil
+}
+
+// checkQuota - add 플랜을 더하고 remove 플랜을 뺀 사용량이 할당량 안에 드는지 확인한다.
+// 생성은 remove=nil, 사양 변경은 remove=현재 플랜
+func (s *HostingService) checkQuota(userID int64, add, remove *HostingPlan) error {
+	q, err := s.GetQuota(userID)
+	if err != nil {
+		return err
+	}
+	u, err := s.GetUsage(userID)
+	if err != nil {
+		return err
+	}
+
+	u.add(add, 1)
+	if remove != nil {
+		u.add(remove, -1)
+	}
+
+	switch {
+	case u.VMs > q.MaxVMs:
+		return fmt.Errorf("%w: VM 개수 %d/%d", ErrQuotaExceeded, u.VMs, q.MaxVMs)
+	case u.VCPUs > q.MaxVCPUs:
+		return fmt.Errorf("%w: vCPU %d/%d", ErrQuotaExceeded, u.VCPUs, q.MaxVCPUs)
+	case u.MemoryMB > q.MaxMemoryMB:
+		return fmt.Errorf("%w: 메모리 %d/%dMB", ErrQuotaExceeded, u.MemoryMB, q.MaxMemoryMB)
+	case u.DiskGB > q.MaxDiskGB:
+		return fmt.Errorf("%w: 디스크 %d/%dGB", ErrQuotaExceeded, u.DiskGB, q.MaxDiskGB)
+	}
+	return nil
+}
+
+func (u *Usage) add(p *HostingPlan, n int) {
+	u.VMs += n
+	u.VCPUs += n * p.CPU
+	u.MemoryMB += n * p.MemoryMB
+	u.DiskGB += n * p.DiskGB
+}
@@ -0,0 +1,184 @@
+package hosting_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ReconcileReport - hostings 테이블과 실제 도메인 상태를 비교한 결과
+type ReconcileReport struct {
+	CheckedAt     time.Time      `json:"checked_at"`
+	Updated       []StatusChange `json:"updated"`        // 상태를 고친 행
+	OrphanDomains []string       `json:"orphan_domains"` // 행이 없는 도메인
+	OrphanRows    []*Hosting     `json:"orphan_rows"`    // 도메인이 없는 행 (error로 표시)
+	Skipped       []string       `json:"skipped"`        // 작업이 진행 중이거나 노드를 조회하지 못해 건너뛴 VM
+	// UnreachableNodes - 도메인 목록을 읽지 못한 노드 (이 노드의 VM은 건드리지 않는다)
+	UnreachableNodes []string `json:"unreachable_nodes"`
+}
+
+type StatusChange struct {
+	ID     int64  `json:"id"`
+	VMName string `json:"vm_name"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// reconcileState - 마지막 점검 결과 (관리자 조회용)
+type reconcileState struct {
+	mu   sync.Mutex
+	last *ReconcileReport
+}
+
+// StartReconciler - interval마다 Reconcile을 실행한다
+func (s *HostingService) StartReconciler(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if _, err := s.Reconcile(); err != nil {
+				log.Printf("상태 점검 실패: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// LastReconcileReport - 마지막 점검 결과 (아직 없으면 nil)
+func (s *HostingService) LastReconcileReport() *ReconcileReport {
+	s.reconciled.mu.Lock()
+	defer s.reconciled.mu.Unlock()
+	return s.reconciled.last
+}
+
+// Reconcile - libvirt 도메인 목록과 hostings 행을 비교해 status를 실제 상태로 맞춘다.
+// provisioning 중이거나 작업이 진행 중인 VM, 도메인 목록을 읽지 못한 노드의 VM은 건드리지 않는다.
+func (s *HostingService) Reconcile() (*ReconcileReport, error) {
+	domains, err := s.hv.ListDomains()
+	var nodeErr *NodeListError
+	if err != nil && !errors.As(err, &nodeErr) {
+		return nil, fmt.Errorf("도메인 목록 조회 실패: %w", err)
+	}
+	rows, err := s.repo.FindAll()
+	if err != nil {
+		return nil, fmt.Errorf("호스팅 목록 조회 실패: %w", err)
+	}
+
+	report := &ReconcileReport{CheckedAt: time.Now()}
+	var unreachable map[string]error
+	if nodeErr != nil {
+		unreachable = nodeErr.Nodes
+		for name, err := range unreachable {
+			report.UnreachableNodes = append(report.UnreachableNodes, name)
+			log.Printf("상태 점검: 노드 %s의 도메인 목록을 읽지 못해 건너뜀: %v", name, err)
+		}
+		sort.Strings(report.UnreachableNodes)
+	}
+	states := make(map[string]DomainState, len(domains))
+	for _, d := range domains {
+		states[d.Name] = d.State
+	}
+
+	known := make(map[string]bool, len(rows))
+	for _, h := range rows {
+		if h.Status == "deleted" {
+			continue
+		}
+		known[h.VMName] = true
+		if h.Status == "provisioning" {
+			continue
+		}
+		// 노드를 등록하지 않은 행(NodeName이 빈 문자열)은 어느 노드에 있는지 모르므로 하나라도 실패하면 건너뛴다
+		if _, down := unreachable[h.NodeName]; down || (h.NodeName == "" && len(unreachable) > 0) {
+			report.Skipped = append(report.Skipped, h.VMName)
+			continue
+		}
+
+		unlock, ok := s.tryLockVM(h.VMName)
+		if !ok {
+			report.Skipped = append(report.Skipped, h.VMName)
+			continue
+		}
+
+		// 목록을 읽은 뒤 락을 잡기 전에 끝난 작업이 있을 수 있으므로 행과 도메인 상태를 다시 읽는다
+		h, state, exists, ok := s.reloadForReconcile(h, states)
+		if !ok {
+			report.Skipped = append(report.Skipped, h.VMName)
+			unlock()
+			continue
+		}
+		want := h.Status
+		if !exists {
+			report.OrphanRows = append(report.OrphanRows, h)
+			want = "error"
+		} else if st, ok := statusForDomain(state); ok {
+			want = st
+		}
+
+		if from := h.Status; want != from {
+			if err := s.repo.UpdateStatus(h.VMName, want); err != nil {
+				unlock()
+				return nil, fmt.Errorf("상태 갱신 실패 (%s): %w", h.VMName, err)
+			}
+			report.Updated = append(report.Updated, StatusChange{ID: h.ID, VMName: h.VMName, From: from, To: want})
+		}
+		unlock()
+	}
+
+	for _, d := range domains {
+		if !known[d.Name] {
+			report.OrphanDomains = append(report.OrphanDomains, d.Name)
+		}
+	}
+
+	s.reconciled.mu.Lock()
+	s.reconciled.last = report
+	s.reconciled.mu.Unlock()
+	return report, nil
+}
+
+// reloadForReconcile - VM 락을 잡은 뒤의 행과 도메인 상태. 그 사이 삭제/생성 중으로 바뀌었거나
+// 도메인을 지금 조회할 수 없으면(노드 연결 끊김 등) 이번 점검에서 건너뛴다(false).
+// 도메인이 없다고 판단하는 것(exists=false)은 목록에 없고 노드도 ErrDomainNotFound로 답했을 때뿐이다
+func (s *HostingService) reloadForReconcile(h *Hosting, listed map[string]DomainState) (*Hosting, DomainState, bool, bool) {
+	cur, err := s.repo.FindByVMName(h.VMName)
+	if err != nil || cur.Status == "deleted" || cur.Status == "provisioning" {
+		return h, "", false, false
+	}
+
+	_, wasListed := listed[cur.VMName]
+	info, err := s.hv.DomainInfo(cur.VMName)
+	switch {
+	case err == nil:
+		return cur, info.State, true, true
+	case wasListed || !errors.Is(err, ErrDomainNotFound):
+		return cur, "", false, false
+	default:
+		// 목록에도 없고 노드도 없다고 답했다
+		return cur, "", false, true
+	}
+}
+
+// statusForDomain - 도메인 상태에 해당하는 hostings.status (판단할 수 없으면 false)
+func statusForDomain(state DomainState) (string, bool) {
+	switch state {
+	case DomainRunning, DomainBlocked:
+		return "running", true
+	case DomainPaused:
+		return "paused", true
+	case DomainShutoff, DomainShutdown, DomainPMSuspended:
+		return "stopped", true
+	case DomainCrashed:
+		return "error", true
+	default:
+		return "", false
+	}
+}
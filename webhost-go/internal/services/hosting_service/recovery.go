@@ -0,0 +1,87 @@
+package hosting_service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// DefaultRecoveryInterval - RecoverVMs가 VM 하나를 부팅하고 다음 VM까지 기다리는 기본 간격
+const DefaultRecoveryInterval = 10 * time.Second
+
+// SetDomainAutostart - true면 desired state를 libvirt autostart에도 반영해 관리 서버 없이도
+// libvirtd가 VM을 부팅한다. libvirtd는 모든 VM을 한꺼번에 부팅하므로 RecoverVMs의 간격은 적용되지 않는다
+func (s *HostingService) SetDomainAutostart(enabled bool) {
+	s.domainAutostart = enabled
+}
+
+// setDesiredState - 사용자가 요청한 전원 상태를 기록한다
+func (s *HostingService) setDesiredState(vmName, state string) error {
+	if err := s.repo.UpdateDesiredState(vmName, state); err != nil {
+		return fmt.Errorf("원하는 상태 저장 실패: %w", err)
+	}
+	s.syncAutostart(vmName, state)
+	return nil
+}
+
+// syncAutostart - 실패해도 RecoverVMs가 DB 기준으로 복구하므로 로그만 남긴다
+func (s *HostingService) syncAutostart(vmName, state string) {
+	if !s.domainAutostart {
+		return
+	}
+	if err := s.hv.SetAutostart(vmName, state == "running"); err != nil {
+		log.Printf("자동 시작 설정 실패 (%s): %v", vmName, err)
+	}
+}
+
+// StartRecovery - 서버 시작 시 한 번 RecoverVMs를 실행한다
+func (s *HostingService) StartRecovery(ctx context.Context, interval time.Duration) {
+	go func() {
+		ops, err := s.RecoverVMs(ctx, interval)
+		if err != nil {
+			log.Printf("VM 복구 실패: %v", err)
+		}
+		if len(ops) > 0 {
+			log.Printf("꺼져 있던 VM %d개의 시작 작업을 등록했습니다", len(ops))
+		}
+	}()
+}
+
+// RecoverVMs - desired state가 running인데 꺼져 있거나 크래시된 VM의 시작 작업을 큐에 넣는다.
+// compute 노드가 한꺼번에 부팅하느라 느려지지 않도록 작업 사이에 interval만큼 기다린다.
+func (s *HostingService) RecoverVMs(ctx context.Context, interval time.Duration) ([]*Operation, error) {
+	list, err := s.repo.FindAll()
+	if err != nil {
+		return nil, fmt.Errorf("VM 목록 조회 실패: %w", err)
+	}
+
+	var ops []*Operation
+	for _, h := range list {
+		if h.Status == "deleted" || h.Status == "provisioning" || h.DesiredState != "running" {
+			continue
+		}
+		if len(ops) > 0 && interval > 0 {
+			select {
+			case <-ctx.Done():
+				return ops, ctx.Err()
+			case <-time.After(interval):
+			}
+		}
+		// 기다리는 동안 libvirt autostart나 사용자가 이미 켰을 수 있으므로 직전에 확인한다
+		state, err := s.domainState(h.VMName)
+		if err != nil {
+			log.Printf("VM 복구 건너뜀 (%s): %v", h.VMName, err)
+			continue
+		}
+		if state != DomainShutoff && state != DomainCrashed {
+			continue
+		}
+		op, err := s.enqueue(OpStartVM, h.UserID, h.Name, h.VMName, nil)
+		if err != nil {
+			return ops, err
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
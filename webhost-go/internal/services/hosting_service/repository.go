@@ -1,13 +1,97 @@
 package hosting_service
 
+import "time"
+
 type HostingRepository interface {
 	Create(h *Hosting) error
+	Update(h *Hosting) error
 	UpdateStatus(vmName string, status string) error
-	Delete(vmName string) error
+	UpdateDesiredState(vmName string, state string) error
+	Delete(id int64) error
 	FindByVMName(vmName string) (*Hosting, error)
 	FindAllByUserID(userID int64) ([]*Hosting, error)
 	FindAll() ([]*Hosting, error) // ✅ 모든 VM 조회 추가
 	GetAvailablePort(basePort, maxPort int) (int, error)
-	FindActiveByUserID(userID int64) (*Hosting, error)
+	FindByID(id int64) (*Hosting, error)
+	FindByUserAndName(userID int64, name string) (*Hosting, error)
 	GetUsedIPs() ([]string, error)
+	CountActiveByPlan(plan string) (int, error)
+}
+
+type OperationRepository interface {
+	Create(op *Operation) error
+	Update(op *Operation) error
+	FindByID(id int64) (*Operation, error)
+	FindByStatus(statuses ...string) ([]*Operation, error)
+}
+
+type QuotaRepository interface {
+	FindByUserID(userID int64) (*Quota, error)
+	Upsert(q *Quota) error
+	Delete(userID int64) error
+}
+
+type SnapshotRepository interface {
+	Create(snap *Snapshot) error
+	Delete(id int64) error
+	FindByID(id int64) (*Snapshot, error)
+	FindByHostingID(hostingID int64) ([]*Snapshot, error)
+	DeleteByHostingID(hostingID int64) error
+}
+
+type BackupRepository interface {
+	Create(b *Backup) error
+	Delete(id int64) error
+	FindByID(id int64) (*Backup, error)
+	FindByUserID(userID int64) ([]*Backup, error)
+	FindByHostingID(hostingID int64) ([]*Backup, error)
+}
+
+type BackupScheduleRepository interface {
+	FindByHostingID(hostingID int64) (*BackupSchedule, error)
+	FindAll() ([]*BackupSchedule, error)
+	Upsert(sched *BackupSchedule) error
+	UpdateLastRun(hostingID int64, t time.Time) error
+	Delete(hostingID int64) error
+}
+
+type ImageRepository interface {
+	FindByName(name string) (*Image, error)
+	FindAll() ([]*Image, error)
+	Create(img *Image) error
+	UpdateStatus(name, status string) error
+	MarkVerified(name string, t time.Time) error
+}
+
+type SSHKeyRepository interface {
+	Create(key *SSHKey) error
+	Delete(id int64) error
+	FindByID(id int64) (*SSHKey, error)
+	FindByUserID(userID int64) ([]*SSHKey, error)
+}
+
+// MetricsRepository - 해상도(Resolution)별 사용량 시계열
+type MetricsRepository interface {
+	// Save - 같은 VM, 해상도, 시각의 값이 있으면 덮어쓴다
+	Save(points []*MetricPoint) error
+	// FindRange/FindAllRange - [from, to) 구간을 시간순으로
+	FindRange(hostingID int64, resolution time.Duration, from, to time.Time) ([]*MetricPoint, error)
+	FindAllRange(resolution time.Duration, from, to time.Time) ([]*MetricPoint, error)
+	DeleteBefore(resolution time.Duration, before time.Time) error
+}
+
+type PlanRepository interface {
+	FindByName(name string) (*HostingPlan, error)
+	FindAll() ([]*HostingPlan, error)
+	Create(p *HostingPlan) error
+	Update(p *HostingPlan) error
+	Delete(name string) error
+}
+
+type NodeRepository interface {
+	FindByName(name string) (*Node, error)
+	FindAll() ([]*Node, error)
+	Create(n *Node) error
+	Update(n *Node) error
+	Delete(name string) error
 }
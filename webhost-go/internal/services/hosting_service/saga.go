@@ -0,0 +1,56 @@
+package hosting_service
+
+import "fmt"
+
+// sagaStep - 하나의 작업 단계와 그 보상(undo) 작업
+type sagaStep struct {
+	name string
+	do   func() error
+	undo func() error // nil이면 되돌릴 것이 없는 단계
+}
+
+// saga - 단계를 순서대로 실행하고, 실패하면 이미 끝난 단계를 역순으로 되돌린다
+type saga struct {
+	steps  []sagaStep
+	onStep func(name string) // 각 단계 시작 시 호출 (진행 상황 기록용)
+
+	done       []string // 완료된 단계
+	failedStep string   // 실패한 단계
+	rollback   []string // 보상 작업 결과 ("creating_vm: ok", "reserving: <에러>")
+	rollbackOK bool
+}
+
+func (sg *saga) add(name string, do, undo func() error) {
+	sg.steps = append(sg.steps, sagaStep{name: name, do: do, undo: undo})
+}
+
+func (sg *saga) run() error {
+	for i, step := range sg.steps {
+		if sg.onStep != nil {
+			sg.onStep(step.name)
+		}
+		if err := step.do(); err != nil {
+			sg.failedStep = step.name
+			sg.compensate(sg.steps[:i])
+			return fmt.Errorf("%s 단계 실패: %w", step.name, err)
+		}
+		sg.done = append(sg.done, step.name)
+	}
+	return nil
+}
+
+func (sg *saga) compensate(done []sagaStep) {
+	sg.rollbackOK = true
+	for i := len(done) - 1; i >= 0; i-- {
+		step := done[i]
+		if step.undo == nil {
+			continue
+		}
+		if err := step.undo(); err != nil {
+			sg.rollbackOK = false
+			sg.rollback = append(sg.rollback, fmt.Sprintf("%s: %v", step.name, err))
+			continue
+		}
+		sg.rollback = append(sg.rollback, step.name+": ok")
+	}
+}
@@ -1,14 +1,101 @@
 package hosting_service
 
 import (
-	"webhost-go/webhost-go/pkg/libvirt"
+	"io"
+	"time"
 )
 
 type Service interface {
-	CreateHosting(userID int64, email string) (*Hosting, error)
-	DeleteVM(name string) error
-	GetVMStatus(name string) (*VMStatus, error)
-	GetVMDetail(name string) (*Hosting, *libvirt.DomainInfo, error)
-	StartVM(name string) error
-	StopVM(name string) error
+	// VM은 숫자 ID 또는 사용자가 정한 이름(ref)으로 지정하며, 다른 사용자의 VM은 ErrVMNotFound
+	// 아래 작업들은 큐에 넣고 바로 Operation을 돌려준다 (진행 상황은 GetOperation)
+	CreateHosting(userID int64, name string, opts CreateOptions) (*Operation, error)
+	DeleteVM(userID int64, ref string) (*Operation, error)
+	StartVM(userID int64, ref string) (*Operation, error)
+	// StopVM - 정상 종료를 timeout(0이면 DefaultStopTimeout)까지 기다리고 안 되면 강제로 끈다
+	StopVM(userID int64, ref string, timeout time.Duration) (*Operation, error)
+	// 재부팅(게스트에 요청)/하드 리셋/일시 정지는 실행 중인 VM만, 재개는 일시 정지된 VM만
+	RebootVM(userID int64, ref string) (*Operation, error)
+	ResetVM(userID int64, ref string) (*Operation, error)
+	PauseVM(userID int64, ref string) (*Operation, error)
+	ResumeVM(userID int64, ref string) (*Operation, error)
+	ResizeVM(userID int64, ref, plan string) (*Operation, error)
+	// FlattenVM - 템플릿 overlay 디스크를 독립 디스크로 만든다 (템플릿 삭제 전에 필요)
+	FlattenVM(userID int64, ref string) (*Operation, error)
+
+	// 스냅샷: 생성/복원/삭제도 작업으로 처리된다. ListSnapshots의 ref가 비어 있으면 모든 VM
+	CreateSnapshot(userID int64, ref, name, description string) (*Operation, error)
+	RevertSnapshot(userID, snapshotID int64) (*Operation, error)
+	DeleteSnapshot(userID, snapshotID int64) (*Operation, error)
+	ListSnapshots(userID int64, ref string) ([]*Snapshot, error)
+
+	// 백업: 생성/복원/삭제는 작업으로 처리된다. ListBackups의 ref가 비어 있으면 삭제된 VM의 백업까지 모두
+	CreateBackup(userID int64, ref string) (*Operation, error)
+	RestoreBackup(userID, backupID int64) (*Operation, error)
+	CloneBackup(userID, backupID int64, name, plan string) (*Operation, error)
+	DeleteBackup(userID, backupID int64) (*Operation, error)
+	ListBackups(userID int64, ref string) ([]*Backup, error)
+	GetBackupSchedule(userID int64, ref string) (*BackupSchedule, error)
+	SetBackupSchedule(userID int64, ref string, sched *BackupSchedule) error
+	DeleteBackupSchedule(userID int64, ref string) error
+
+	ListVMs(userID int64) ([]*Hosting, error)
+	GetVMStatus(userID int64, ref string) (*VMStatus, error)
+	// 실행 중이고 게스트 에이전트가 응답하면 DomainInfo.Guest가 채워진다
+	GetVMDetail(userID int64, ref string) (*Hosting, *DomainInfo, error)
+	// 게스트 에이전트로 이미지 기본 계정의 비밀번호를 새로 만든다 (새 비밀번호 반환)
+	ResetGuestPassword(userID int64, ref string) (string, error)
+
+	// 사용자 VM의 라이프사이클 이벤트 구독 (반환된 함수로 해제)
+	SubscribeEvents(userID int64) (<-chan VMEvent, func())
+
+	// 시리얼 콘솔: 발급한 티켓은 consoleTicketTTL 안에 한 번만 쓸 수 있다.
+	// 같은 VM의 접속자는 출력을 함께 보고, 입력은 writable 티켓으로 붙은 한 명만 보낸다
+	IssueConsoleTicket(userID int64, ref string, writable bool) (*ConsoleTicket, error)
+	AttachConsole(userID int64, ticket string) (*ConsoleClient, error)
+
+	// VNC: 티켓 응답에 noVNC가 쓸 VNC 비밀번호가 들어 있다. 티켓은 콘솔 티켓처럼 한 번만 쓸 수 있다
+	IssueVNCTicket(userID int64, ref string) (*VNCTicket, error)
+	ConnectVNC(userID int64, ticket string) (io.ReadWriteCloser, error)
+
+	// 사용량 시계열 (CPU %, 메모리, 디스크 IO, 네트워크 처리량)
+	GetMetrics(userID int64, q MetricsQuery) ([]*VMMetrics, error)
+
+	// 상태 점검 (관리자용)
+	Reconcile() (*ReconcileReport, error)
+	LastReconcileReport() *ReconcileReport
+
+	GetOperation(id int64) (*Operation, error)
+	ListFailedOperations() ([]*Operation, error)
+
+	// 할당량: userID가 DefaultQuotaUserID면 기본 할당량
+	GetQuota(userID int64) (*Quota, error)
+	SetQuota(q *Quota) error
+	ResetQuota(userID int64) error
+	GetUsage(userID int64) (*Usage, error)
+
+	// SSH 공개키: 새로 만드는 VM의 기본 계정에 등록된다
+	ListSSHKeys(userID int64) ([]*SSHKey, error)
+	AddSSHKey(userID int64, name, publicKey string) (*SSHKey, error)
+	DeleteSSHKey(userID, keyID int64) error
+
+	// 이미지: 등록/확인/사용 중지는 관리자용. 새 VM은 active 이미지로만 만들 수 있다
+	ListImages() ([]*Image, error)
+	RegisterImage(img *Image) error
+	VerifyImage(name string) (*Image, error)
+	RetireImage(name string) error
+
+	// 플랜 관리
+	ListPlans() ([]*HostingPlan, error)
+	GetPlan(name string) (*HostingPlan, error)
+	CreatePlan(p *HostingPlan) error
+	UpdatePlan(p *HostingPlan) error
+	DeletePlan(name string) error
+
+	// compute 노드 관리 (관리자용). 새 VM은 스케줄러가 용량과 레이블을 보고 노드를 고른다
+	ListNodes() ([]*NodeStatus, error)
+	CreateNode(n *Node) error
+	UpdateNode(n *Node) error
+	DeleteNode(name string) error
+	// MigrateVM - VM을 다른 노드로 옮기는 작업을 큐에 넣는다 (관리자용, 노드 점검 전 비우기)
+	MigrateVM(hostingID int64, opts MigrateOptions) (*Operation, error)
 }
@@ -2,6 +2,7 @@ package hosting_service
 
 import (
 	"bytes"
+	"crypto/rand"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -9,16 +10,61 @@ import (
 	"io"
 	"net"
 	"net/http"
-	"strings"
+	"regexp"
+	"strconv"
+	"sync"
 	"time"
 	"webhost-go/webhost-go/cmd/nginx-agent/nginx"
-	"webhost-go/webhost-go/pkg/libvirt"
 )
 
 type HostingService struct {
 	repo      HostingRepository
+	ops       OperationRepository
+	plans     PlanRepository
+	images    ImageRepository
+	quotas    QuotaRepository
+	snaps     SnapshotRepository
+	backups   BackupRepository
+	schedules BackupScheduleRepository
+	sshKeys   SSHKeyRepository
+	metrics   MetricsRepository
+	nodes     NodeRepository
 	agentAddr string
-	Libvirt   *libvirt.LibvirtManager
+	hv        Hypervisor
+
+	queue   chan int64 // 처리할 operation ID
+	vmLocks sync.Map   // VM 이름 → *sync.Mutex
+
+	reconciled reconcileState
+	events     eventBroker
+	consoles   consoleHub
+	vnc        vncTickets
+	collector  metricsState
+
+	// domainAutostart - desired state를 libvirt autostart에도 반영할지 (SetDomainAutostart)
+	domainAutostart bool
+
+	// policy - 새 VM의 노드 선택 정책 (SetSchedulerPolicy)
+	policy SchedulerPolicy
+	// placeMu - 노드를 고르고 hostings에 기록하기까지 다른 배치가 끼어들지 않게 한다
+	placeMu sync.Mutex
+}
+
+var (
+	ErrVMNotFound    = errors.New("VM을 찾을 수 없습니다")
+	ErrInvalidVMName = errors.New("VM 이름은 영문 소문자, 숫자, '-'로 된 1~32자여야 합니다")
+	ErrVMNameTaken   = errors.New("이미 사용 중인 VM 이름입니다")
+)
+
+// vmNamePattern - 사용자가 정하는 VM 이름 (URL 경로에 그대로 쓰인다)
+var vmNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,30}[a-z0-9])?$`)
+
+// validVMName - 숫자로만 된 이름은 ID와 구분할 수 없으므로 허용하지 않는다
+func validVMName(name string) bool {
+	if _, err := strconv.ParseInt(name, 10, 64); err == nil {
+		return false
+	}
+	return vmNamePattern.MatchString(name)
 }
 
 type VMRequest struct {
@@ -44,161 +90,578 @@ type VMStatus struct {
 	Active bool
 }
 
-func NewService(repo HostingRepository, agentAddr string, libvirt *libvirt.LibvirtManager) *HostingService {
+func NewService(repo HostingRepository, ops OperationRepository, plans PlanRepository, images ImageRepository, quotas QuotaRepository, snaps SnapshotRepository, backups BackupRepository, schedules BackupScheduleRepository, sshKeys SSHKeyRepository, metrics MetricsRepository, nodes NodeRepository, agentAddr string, hv Hypervisor) *HostingService {
 	return &HostingService{
 		repo:      repo,
+		ops:       ops,
+		plans:     plans,
+		images:    images,
+		quotas:    quotas,
+		snaps:     snaps,
+		backups:   backups,
+		schedules: schedules,
+		sshKeys:   sshKeys,
+		metrics:   metrics,
+		nodes:     nodes,
 		agentAddr: agentAddr,
-		Libvirt:   libvirt,
+		hv:        hv,
+		queue:     make(chan int64, jobQueueSize),
+		policy:    SchedulerSpread,
 	}
 }
 
-// CreateHosting - 새로운 VM을 생성하고 Hosting 엔트리를 DB에 등록
-func (s *HostingService) CreateHosting(userID int64, email string) (*Hosting, error) {
-	username := removeDomain(email)
-	hostname := removeDomain(email) + "_VM"
+// CreateHosting - VM 생성 작업을 큐에 넣는다.
+// name은 사용자가 정한 VM 이름이며, libvirt 도메인 이름은 별도로 생성한다.
+// 기본 계정의 초기 비밀번호는 돌려주는 Operation의 Password에만 담긴다.
+func (s *HostingService) CreateHosting(userID int64, name string, opts CreateOptions) (*Operation, error) {
+	p, err := s.resolvePlan(opts.Plan)
+	if err != nil {
+		return nil, err
+	}
+	img, err := s.resolveImage(opts.Image)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateUserData(opts.UserData); err != nil {
+		return nil, err
+	}
+	return s.enqueueCreate(userID, name, p, "", map[string]string{
+		"image":     img.Name,
+		"user_data": opts.UserData,
+	})
+}
 
-	existing, err := s.repo.FindActiveByUserID(userID)
-	if err == nil && existing != nil {
-		return nil, fmt.Errorf("사용자에게 이미 할당된 VM이 있습니다: %s", existing.VMName)
+// enqueueCreate - 할당량과 이름을 확인하고 VM 생성 작업을 큐에 넣는다.
+// node가 비어 있지 않으면 그 노드에만 놓을 수 있다 (백업에서 만드는 VM)
+func (s *HostingService) enqueueCreate(userID int64, name string, p *HostingPlan, node string, params map[string]string) (*Operation, error) {
+	if !validVMName(name) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidVMName, name)
 	}
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		return nil, fmt.Errorf("VM 존재 여부 확인 실패: %w", err)
+	if err := s.checkQuota(userID, p, nil); err != nil {
+		return nil, err
 	}
+	if err := s.checkNameAvailable(userID, name); err != nil {
+		return nil, err
+	}
+	// 배치할 노드가 없으면 바로 거절한다 (실제 배치는 작업을 실행할 때 다시 고른다)
+	if _, err := s.scheduleNodeOn(p, node); err != nil {
+		return nil, err
+	}
+
+	vmName, err := s.newVMName()
+	if err != nil {
+		return nil, err
+	}
+	password, hash, err := newVMPassword()
+	if err != nil {
+		return nil, err
+	}
+	params["plan"] = p.Name
+	params["password_hash"] = hash
+	op, err := s.enqueue(OpCreateHosting, userID, name, vmName, params)
+	if err != nil {
+		return nil, err
+	}
+	op.Password = password
+	return op, nil
+}
+
+// ListVMs - 사용자의 (삭제되지 않은) VM 목록
+func (s *HostingService) ListVMs(userID int64) ([]*Hosting, error) {
+	list, err := s.repo.FindAllByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("VM 목록 조회 실패: %w", err)
+	}
+	var active []*Hosting
+	for _, h := range list {
+		if h.Status != "deleted" {
+			active = append(active, h)
+		}
+	}
+	return active, nil
+}
+
+// DeleteVM - VM 삭제 작업을 큐에 넣는다
+func (s *HostingService) DeleteVM(userID int64, ref string) (*Operation, error) {
+	return s.enqueueForVM(OpDeleteVM, userID, ref, nil)
+}
+
+// FlattenVM - 디스크 평탄화 작업을 큐에 넣는다
+func (s *HostingService) FlattenVM(userID int64, ref string) (*Operation, error) {
+	return s.enqueueForVM(OpFlattenVM, userID, ref, nil)
+}
+
+// ResizeVM - 다른 플랜으로 사양 변경 작업을 큐에 넣는다 (디스크 축소는 거부)
+func (s *HostingService) ResizeVM(userID int64, ref, plan string) (*Operation, error) {
+	h, err := s.findVM(userID, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := s.resolvePlan(plan)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.resolvePlan(h.Plan)
+	if err != nil {
+		return nil, err
+	}
+	if target.DiskGB < current.DiskGB {
+		return nil, fmt.Errorf("%w (현재 %dGB, 요청 %dGB)", ErrDiskShrink, current.DiskGB, target.DiskGB)
+	}
+	if err := s.checkQuota(userID, target, current); err != nil {
+		return nil, err
+	}
+	if err := s.checkNodeCapacity(h, target, current); err != nil {
+		return nil, err
+	}
+
+	return s.enqueue(OpResizeVM, h.UserID, h.Name, h.VMName, map[string]string{"plan": target.Name})
+}
+
+// enqueueForVM - 이미 존재하는 VM에 대한 작업을 큐에 넣는다
+func (s *HostingService) enqueueForVM(kind string, userID int64, ref string, params map[string]string) (*Operation, error) {
+	h, err := s.findVM(userID, ref)
+	if err != nil {
+		return nil, err
+	}
+	return s.enqueue(kind, h.UserID, h.Name, h.VMName, params)
+}
+
+// findVM - ref(숫자 ID 또는 VM 이름)로 사용자의 VM을 찾는다
+func (s *HostingService) findVM(userID int64, ref string) (*Hosting, error) {
+	var h *Hosting
+	var err error
+	if id, convErr := strconv.ParseInt(ref, 10, 64); convErr == nil {
+		h, err = s.repo.FindByID(id)
+	} else {
+		h, err = s.repo.FindByUserAndName(userID, ref)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrVMNotFound, ref)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("VM 정보 조회 실패: %w", err)
+	}
+	// 다른 사용자의 VM이나 삭제된 VM은 없는 것으로 취급
+	if h.UserID != userID || h.Status == "deleted" {
+		return nil, fmt.Errorf("%w: %s", ErrVMNotFound, ref)
+	}
+	return h, nil
+}
+
+func (s *HostingService) checkNameAvailable(userID int64, name string) error {
+	_, err := s.repo.FindByUserAndName(userID, name)
+	if err == nil {
+		return fmt.Errorf("%w: %s", ErrVMNameTaken, name)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("VM 이름 확인 실패: %w", err)
+	}
+	return nil
+}
 
-	usedIPsStr, _ := s.repo.GetUsedIPs()
+// newVMName - 사용자 이메일과 무관한 libvirt 도메인 이름 (vm-<UUID 앞 8자리>)
+func (s *HostingService) newVMName() (string, error) {
+	for i := 0; i < 5; i++ {
+		id, err := newUUID()
+		if err != nil {
+			return "", fmt.Errorf("도메인 이름 생성 실패: %w", err)
+		}
+		name := "vm-" + id[:8]
+		if _, err := s.repo.FindByVMName(name); errors.Is(err, sql.ErrNoRows) {
+			return name, nil
+		}
+	}
+	return "", errors.New("도메인 이름 생성 실패: 중복이 계속 발생합니다")
+}
+
+// newUUID - 랜덤(v4) UUID 문자열
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// allocateAddress - node의 네트워크에서 아직 쓰지 않는 IP와 SSH 포트.
+// placeMu를 잡은 채로 부르고 고른 값을 hostings에 기록할 때까지 놓지 않는다
+func (s *HostingService) allocateAddress(node string) (net.IP, int, error) {
+	ip, err := s.allocateIP(node)
+	if err != nil {
+		return nil, 0, err
+	}
+	port, err := s.repo.GetAvailablePort(20000, 30000)
+	if err != nil {
+		return nil, 0, fmt.Errorf("사용 가능한 포트 없음: %w", err)
+	}
+	return ip, port, nil
+}
+
+// allocateIP - node 네트워크에서 hostings가 쓰지 않는 IP (allocateAddress와 같이 placeMu 안에서 부른다)
+func (s *HostingService) allocateIP(node string) (net.IP, error) {
+	used, err := s.usedIPs("")
+	if err != nil {
+		return nil, err
+	}
+	hv, err := s.nodeHypervisor(node)
+	if err != nil {
+		return nil, err
+	}
+	ipList, err := hv.UsableIPs(used)
+	if err != nil || len(ipList) == 0 {
+		return nil, fmt.Errorf("사용 가능한 IP 없음: %w", err)
+	}
+	return ipList[0], nil
+}
+
+// usedIPs - hostings에 기록된 IP 중 except를 뺀 것
+func (s *HostingService) usedIPs(except string) ([]net.IP, error) {
+	usedIPsStr, err := s.repo.GetUsedIPs()
+	if err != nil {
+		return nil, fmt.Errorf("사용 중인 IP 조회 실패: %w", err)
+	}
 	var used []net.IP
 	for _, ipStr := range usedIPsStr {
-		if ip := net.ParseIP(ipStr); ip != nil {
+		if ip := net.ParseIP(ipStr); ip != nil && ipStr != except {
 			used = append(used, ip)
 		}
 	}
+	return used, nil
+}
 
-	// 사용 가능한 IP 및 포트 확보
-	ipList, err := s.Libvirt.GetUsableIPs(used)
-	if err != nil || len(ipList) == 0 {
-		return nil, fmt.Errorf("사용 가능한 IP 없음: %w", err)
+// proxyInfo - nginx 설정 파일/location 키는 사용자별이 아닌 도메인 이름별로 만든다
+func proxyInfo(h *Hosting) nginx.AgentInfo {
+	return nginx.AgentInfo{
+		Username: h.VMName,
+		Hostname: h.VMName,
+		VMIP:     h.IPAddress,
+		SSHPort:  h.SSHPort,
 	}
-	ip := ipList[0]
+}
 
-	port, err := s.repo.GetAvailablePort(20000, 30000)
+// createHosting - 새로운 VM을 생성하고 Hosting 엔트리를 DB에 등록
+func (s *HostingService) createHosting(op *Operation) error {
+	vmName := op.VMName
+	// 초기 비밀번호 해시와 user-data는 VM을 만들 때만 쓰고 작업 기록에는 남기지 않는다
+	defer dropParams(op, "password_hash", "user_data")
+
+	plan, err := s.resolvePlan(op.Params["plan"])
 	if err != nil {
-		return nil, fmt.Errorf("사용 가능한 포트 없음: %w", err)
+		return err
 	}
 
-	// VM 생성
-	if err := s.Libvirt.StartUbuntuVMWithStaticIP(hostname, ip); err != nil {
-		return nil, fmt.Errorf("VM 생성 실패: %w", err)
+	if err := s.checkNameAvailable(op.UserID, op.Target); err != nil {
+		return err
+	}
+
+	// 백업에서 만드는 경우 템플릿 대신 백업 디스크를 복사한다
+	var sourceDisk, sourceNode, imagePath, defaultUser, seedFormat string
+	var imageFeatures DomainFeatures
+	imageName := op.Params["image"]
+	if op.Params["backup_id"] != "" {
+		b, err := s.backupForOp(op)
+		if err != nil {
+			return err
+		}
+		// 백업 파일은 백업한 노드에만 있으므로 그 노드에 만든다
+		sourceDisk, sourceNode = b.Path, b.NodeName
+		// 원본 VM의 이미지가 사용 중지되었더라도 기본 사용자는 그대로 쓴다
+		if imageName != "" {
+			if img, err := s.findImage(imageName); err == nil {
+				defaultUser, seedFormat, imageFeatures = img.DefaultUser, img.SeedFormat, img.Features
+			}
+		}
+	} else {
+		img, err := s.resolveImage(imageName)
+		if err != nil {
+			return err
+		}
+		// 등록 후 처음 쓰는 이미지는 체크섬부터 확인
+		if img.VerifiedAt == nil {
+			s.setStep(op, StepVerifyingImage)
+			if err := s.verifyImage(img); err != nil {
+				return err
+			}
+		}
+		imageName, imagePath, defaultUser, seedFormat = img.Name, img.Path, img.DefaultUser, img.SeedFormat
+		imageFeatures = img.Features
 	}
 
-	// nginx-agent 등록
-	agent := nginx.AgentInfo{
-		Username: username,
-		Hostname: hostname,
-		VMIP:     ip.String(),
-		SSHPort:  port,
+	sshKeys, err := s.userSSHKeys(op.UserID)
+	if err != nil {
+		return err
 	}
-	if err := RegisterWithNginxAgent(s.agentAddr, agent); err != nil {
-		return nil, err
+
+	vncPassword, err := randomPassword(vncPasswordLength)
+	if err != nil {
+		return err
 	}
 
-	// DB에 기록
 	h := &Hosting{
-		UserID:    userID,
-		VMName:    hostname,
-		IPAddress: ip.String(),
-		SSHPort:   port,
-		ProxyPath: "/" + username,
-		DiskPath:  fmt.Sprintf("/var/lib/libvirt/images/instances/%s/disk.qcow2", hostname),
-		Status:    "running",
+		UserID:    op.UserID,
+		Name:      op.Target,
+		VMName:    vmName,
+		ProxyPath: "/" + vmName,
+		Plan:      plan.Name,
+		Image:     imageName,
+		Status:    "provisioning",
 		CreatedAt: time.Now(),
+
+		DesiredState: "running",
+
+		VNCPassword: vncPassword,
 	}
-	if err := s.repo.Create(h); err != nil {
-		return nil, fmt.Errorf("DB 저장 실패: %w", err)
+	var ip net.IP
+
+	sg := &saga{onStep: func(step string) { s.setStep(op, step) }}
+	// 1. 노드와 IP/포트를 고르고 DB에 provisioning 상태로 먼저 기록해 선점
+	// (고르는 것부터 기록까지 placeMu 안에서 해야 동시에 만드는 VM이 같은 IP/포트를 받지 않는다)
+	sg.add(StepReserving,
+		func() error {
+			s.placeMu.Lock()
+			defer s.placeMu.Unlock()
+			// 큐에서 기다리는 동안 같은 사용자의 다른 작업이 끝났을 수 있으므로 기록 직전에 다시 확인
+			if err := s.checkQuota(op.UserID, plan, nil); err != nil {
+				return err
+			}
+			node, err := s.scheduleNodeOn(plan, sourceNode)
+			if err != nil {
+				return err
+			}
+			if ip, h.SSHPort, err = s.allocateAddress(node); err != nil {
+				return err
+			}
+			h.NodeName, h.IPAddress = node, ip.String()
+			return s.repo.Create(h)
+		},
+		func() error { return s.repo.Delete(h.ID) })
+	// 2. VM 생성 (디스크 복사, cloud-init, 부팅 단계는 하이퍼바이저가 보고)
+	sg.add(StepCreatingVM,
+		func() error {
+			spec := VMSpec{
+				Name:       vmName,
+				Node:       h.NodeName,
+				IP:         ip,
+				VCPUs:      plan.CPU,
+				MemoryMB:   plan.MemoryMB,
+				DiskGB:     plan.DiskGB,
+				Image:      imagePath,
+				SourceDisk: sourceDisk,
+				CloudInit: CloudInit{
+					Hostname:     op.Target,
+					User:         defaultUser,
+					PasswordHash: op.Params["password_hash"],
+					SSHKeys:      sshKeys,
+					UserData:     op.Params["user_data"],
+					SeedFormat:   seedFormat,
+				},
+				Features:    plan.Features.Merge(imageFeatures),
+				VNCPassword: vncPassword,
+			}
+			diskPath, err := s.hv.CreateVM(spec, func(step string) { s.setStep(op, step) })
+			h.DiskPath = diskPath
+			return err
+		},
+		func() error { return s.hv.DeleteVM(vmName, true) })
+	// 3. nginx-agent 등록
+	sg.add(StepRegisteringProxy,
+		func() error { return RegisterWithNginxAgent(s.agentAddr, proxyInfo(h)) },
+		func() error { return RemoveFromNginxAgent(s.agentAddr, vmName) })
+	// 4. running 상태로 전환
+	sg.add(StepActivating,
+		func() error {
+			h.Status = "running"
+			if err := s.repo.Update(h); err != nil {
+				return err
+			}
+			s.syncAutostart(vmName, h.DesiredState)
+			return nil
+		},
+		nil)
+
+	if err := sg.run(); err != nil {
+		op.Step = sg.failedStep
+		op.Rollback = sg.rollback
+		op.Status = OpRolledBack
+		if !sg.rollbackOK {
+			op.Status = OpRollbackFailed
+		}
+		return err
 	}
 
-	return h, nil
+	fields := map[string]interface{}{
+		"id":       h.ID,
+		"name":     h.Name,
+		"hostname": h.VMName,
+		"ip":       h.IPAddress,
+		"ssh_port": h.SSHPort,
+		"proxy":    h.ProxyPath,
+		"plan":     h.Plan,
+		"image":    h.Image,
+	}
+	if defaultUser != "" {
+		fields["default_user"] = defaultUser
+	}
+	result, _ := json.Marshal(fields)
+	op.Result = string(result)
+	return nil
 }
 
-func (s *HostingService) DeleteVM(email string) error {
-	hostname := removeDomain(email) + "_VM"
+func (s *HostingService) deleteVM(op *Operation) error {
+	hostname := op.VMName
 	// 1. DB에서 VM 정보 조회
 	hosting, err := s.repo.FindByVMName(hostname)
 	if err != nil {
 		return fmt.Errorf("VM 정보 조회 실패: %w", err)
 	}
 
-	// 2. libvirt에서 VM 삭제
-	if err := s.Libvirt.DeleteDomain(hostname, true); err != nil {
+	// 2. libvirt에서 VM 삭제 (디스크와 함께 스냅샷도 사라진다)
+	s.setStep(op, StepDeletingVM)
+	if err := s.hv.DeleteVM(hostname, true); err != nil {
 		return fmt.Errorf("libvirt 도메인 삭제 실패: %w", err)
 	}
 
 	// 3. nginx-agent에 설정 제거 요청
+	s.setStep(op, StepRemovingProxy)
 	if err := RemoveFromNginxAgent(s.agentAddr, hosting.VMName); err != nil {
 		return fmt.Errorf("nginx-agent 설정 제거 실패: %w", err)
 	}
 
 	// 4. DB에서 상태를 'deleted'로 업데이트
+	s.setStep(op, StepUpdatingStatus)
 	if err := s.repo.UpdateStatus(hostname, "deleted"); err != nil {
 		return fmt.Errorf("DB 상태 업데이트 실패: %w", err)
 	}
+	if err := s.snaps.DeleteByHostingID(hosting.ID); err != nil {
+		return fmt.Errorf("스냅샷 기록 삭제 실패: %w", err)
+	}
+	// 백업은 남겨 두어 새 VM으로 복원할 수 있게 하고, 예약 백업만 멈춘다
+	if err := s.schedules.Delete(hosting.ID); err != nil {
+		return fmt.Errorf("백업 일정 삭제 실패: %w", err)
+	}
 
 	return nil
 }
 
-func (s *HostingService) GetVMStatus(email string) (*VMStatus, error) {
-	hostname := removeDomain(email) + "_VM"
-	active, err := s.Libvirt.DomainIsActive(hostname)
+func (s *HostingService) GetVMStatus(userID int64, ref string) (*VMStatus, error) {
+	h, err := s.findVM(userID, ref)
+	if err != nil {
+		return nil, err
+	}
+	active, err := s.hv.IsActive(h.VMName)
 	if err != nil {
 		return nil, fmt.Errorf("VM 상태 조회 실패: %w", err)
 	}
 
 	Status := &VMStatus{
-		VMName: hostname,
+		VMName: h.VMName,
 		Active: active,
 	}
 
 	return Status, nil
 }
 
-func (s *HostingService) GetVMDetail(email string) (*Hosting, *libvirt.DomainInfo, error) {
-	hostname := removeDomain(email) + "_VM"
+func (s *HostingService) GetVMDetail(userID int64, ref string) (*Hosting, *DomainInfo, error) {
 	// 1. DB에서 Hosting 정보 조회
-	h, err := s.repo.FindByVMName(hostname)
+	h, err := s.findVM(userID, ref)
 	if err != nil {
-		return nil, nil, fmt.Errorf("DB 조회 실패: %w", err)
+		return nil, nil, err
 	}
 
-	info, err := s.Libvirt.GetDomainInfoByName(hostname)
+	info, err := s.hv.DomainInfo(h.VMName)
 	if err != nil {
 		return nil, nil, fmt.Errorf("도메인 정보 조회 실패: %w", err)
 	}
+	if info.State == DomainRunning {
+		info.Guest = s.guestInfo(h.VMName)
+	}
 
 	return h, info, nil
 }
 
-func (s *HostingService) StartVM(email string) error {
-	hostname := removeDomain(email) + "_VM"
-	// 1. Resume
-	if err := s.Libvirt.Resume(hostname); err != nil {
-		return err
-	}
-	// 2. DB 상태 업데이트
-	if err := s.repo.UpdateStatus(hostname, "running"); err != nil {
-		return fmt.Errorf("상태 갱신 실패: %w", err)
+func (s *HostingService) flattenVM(op *Operation) error {
+	s.setStep(op, StepFlatteningDisk)
+	if err := s.hv.FlattenVM(op.VMName); err != nil {
+		return fmt.Errorf("디스크 평탄화 실패: %w", err)
 	}
 	return nil
 }
 
-func (s *HostingService) StopVM(email string) error {
-	hostname := removeDomain(email) + "_VM"
-	// 1. Shutdown
-	if err := s.Libvirt.Shutdown(hostname); err != nil {
+func (s *HostingService) resizeVM(op *Operation) error {
+	hostname := op.VMName
+	h, err := s.repo.FindByVMName(hostname)
+	if err != nil {
+		return fmt.Errorf("VM 정보 조회 실패: %w", err)
+	}
+	plan, err := s.resolvePlan(op.Params["plan"])
+	if err != nil {
 		return err
 	}
-	// 2. DB 상태 업데이트
-	if err := s.repo.UpdateStatus(hostname, "stopped"); err != nil {
-		return fmt.Errorf("상태 갱신 실패: %w", err)
+	current, err := s.resolvePlan(h.Plan)
+	if err != nil {
+		return err
+	}
+
+	var live bool
+	sg := &saga{onStep: func(step string) { s.setStep(op, step) }}
+	// 1. 할당량과 노드 용량을 확인하고 새 플랜을 먼저 기록해 선점
+	// (확인부터 기록까지 placeMu 안에서 해야 같은 사용자의 다른 생성/변경과 합쳐 할당량을 넘지 않는다)
+	sg.add(StepReserving,
+		func() error {
+			s.placeMu.Lock()
+			defer s.placeMu.Unlock()
+			if err := s.checkQuota(op.UserID, plan, current); err != nil {
+				return err
+			}
+			if err := s.checkNodeCapacity(h, plan, current); err != nil {
+				return err
+			}
+			h.Plan = plan.Name
+			if err := s.repo.Update(h); err != nil {
+				return fmt.Errorf("플랜 갱신 실패: %w", err)
+			}
+			return nil
+		},
+		func() error {
+			h.Plan = current.Name
+			return s.repo.Update(h)
+		})
+	// 2. vCPU/메모리/디스크 변경
+	sg.add(StepResizingVM,
+		func() error {
+			var err error
+			live, err = s.hv.ResizeVM(hostname, VMSpec{
+				VCPUs:    plan.CPU,
+				MemoryMB: plan.MemoryMB,
+				DiskGB:   plan.DiskGB,
+			})
+			if err != nil {
+				return fmt.Errorf("VM 사양 변경 실패: %w", err)
+			}
+			return nil
+		},
+		nil)
+
+	if err := sg.run(); err != nil {
+		op.Step = sg.failedStep
+		op.Rollback = sg.rollback
+		op.Status = OpRolledBack
+		if !sg.rollbackOK {
+			op.Status = OpRollbackFailed
+		}
+		return err
 	}
+
+	// live=false면 vCPU/메모리는 다음 부팅부터 적용된다
+	result, _ := json.Marshal(map[string]interface{}{
+		"plan": plan.Name,
+		"live": live,
+	})
+	op.Result = string(result)
 	return nil
 }
 
@@ -248,10 +711,3 @@ func RemoveFromNginxAgent(agentAddr, hostname string) error {
 
 	return nil
 }
-
-func removeDomain(email string) string {
-	if at := strings.Index(email, "@"); at != -1 {
-		return email[:at]
-	}
-	return email // @가 없는 경우 그대로 반환
-}
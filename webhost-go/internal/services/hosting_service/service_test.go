@@ -0,0 +1,2716 @@
+package hosting_service_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"webhost-go/webhost-go/cmd/nginx-agent/nginx"
+	"webhost-go/webhost-go/internal/hypervisor"
+	"webhost-go/webhost-go/internal/services/hosting_service"
+	"webhost-go/webhost-go/pkg/nocloud"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// 임시 테스트 구현체
+type mockHostingRepo struct {
+	mu       sync.Mutex
+	hostings map[string]*hosting_service.Hosting
+	idSeq    int64
+}
+
+func newMockHostingRepo() *mockHostingRepo {
+	return &mockHostingRepo{hostings: make(map[string]*hosting_service.Hosting)}
+}
+
+func (m *mockHostingRepo) Create(h *hosting_service.Hosting) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.idSeq++
+	h.ID = m.idSeq
+	m.hostings[h.VMName] = h
+	return nil
+}
+
+func (m *mockHostingRepo) UpdateStatus(vmName string, status string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.hostings[vmName]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	h.Status = status
+	return nil
+}
+
+func (m *mockHostingRepo) UpdateDesiredState(vmName string, state string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.hostings[vmName]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	h.DesiredState = state
+	return nil
+}
+
+func (m *mockHostingRepo) Update(h *hosting_service.Hosting) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hostings[h.VMName] = h
+	return nil
+}
+
+func (m *mockHostingRepo) Delete(id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, h := range m.hostings {
+		if h.ID == id {
+			delete(m.hostings, name)
+		}
+	}
+	return nil
+}
+
+func (m *mockHostingRepo) FindByVMName(vmName string) (*hosting_service.Hosting, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.hostings[vmName]
+	if !ok || h.Status == "deleted" {
+		return nil, sql.ErrNoRows
+	}
+	return h, nil
+}
+
+func (m *mockHostingRepo) FindAllByUserID(userID int64) ([]*hosting_service.Hosting, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []*hosting_service.Hosting
+	for _, h := range m.hostings {
+		if h.UserID == userID {
+			list = append(list, h)
+		}
+	}
+	return list, nil
+}
+
+func (m *mockHostingRepo) FindAll() ([]*hosting_service.Hosting, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []*hosting_service.Hosting
+	for _, h := range m.hostings {
+		list = append(list, h)
+	}
+	return list, nil
+}
+
+func (m *mockHostingRepo) GetAvailablePort(basePort, maxPort int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	used := make(map[int]bool)
+	for _, h := range m.hostings {
+		if h.Status != "deleted" {
+			used[h.SSHPort] = true
+		}
+	}
+	for p := basePort; p <= maxPort; p++ {
+		if !used[p] {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("no port")
+}
+
+func (m *mockHostingRepo) FindByID(id int64) (*hosting_service.Hosting, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, h := range m.hostings {
+		if h.ID == id {
+			return h, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *mockHostingRepo) FindByUserAndName(userID int64, name string) (*hosting_service.Hosting, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, h := range m.hostings {
+		if h.UserID == userID && h.Name == name && h.Status != "deleted" {
+			return h, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// hosting - 테스트에서 VM 이름으로 행을 찾는다
+func (m *mockHostingRepo) hosting(userID int64, name string) *hosting_service.Hosting {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, h := range m.hostings {
+		if h.UserID == userID && h.Name == name {
+			return h
+		}
+	}
+	return nil
+}
+
+func (m *mockHostingRepo) GetUsedIPs() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ips []string
+	for _, h := range m.hostings {
+		if h.Status != "deleted" {
+			ips = append(ips, h.IPAddress)
+		}
+	}
+	return ips, nil
+}
+
+func (m *mockHostingRepo) CountActiveByPlan(plan string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, h := range m.hostings {
+		if h.Plan == plan && h.Status != "deleted" {
+			n++
+		}
+	}
+	return n, nil
+}
+
+type mockPlanRepo struct {
+	mu    sync.Mutex
+	plans map[string]hosting_service.HostingPlan
+}
+
+// newMockPlanRepo - init.sql과 같은 기본 플랜으로 채운다
+func newMockPlanRepo() *mockPlanRepo {
+	return &mockPlanRepo{plans: map[string]hosting_service.HostingPlan{
+		"small":  {Name: "small", CPU: 1, MemoryMB: 1024, DiskGB: 10, MaxSnapshots: 1},
+		"medium": {Name: "medium", CPU: 2, MemoryMB: 2048, DiskGB: 20, MaxSnapshots: 3},
+		"large":  {Name: "large", CPU: 4, MemoryMB: 4096, DiskGB: 40, MaxSnapshots: 5},
+	}}
+}
+
+func (m *mockPlanRepo) FindByName(name string) (*hosting_service.HostingPlan, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.plans[name]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &p, nil
+}
+
+func (m *mockPlanRepo) FindAll() ([]*hosting_service.HostingPlan, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []*hosting_service.HostingPlan
+	for _, p := range m.plans {
+		p := p
+		list = append(list, &p)
+	}
+	return list, nil
+}
+
+func (m *mockPlanRepo) Create(p *hosting_service.HostingPlan) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.plans[p.Name] = *p
+	return nil
+}
+
+func (m *mockPlanRepo) Update(p *hosting_service.HostingPlan) error {
+	return m.Create(p)
+}
+
+func (m *mockPlanRepo) Delete(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.plans, name)
+	return nil
+}
+
+type mockNodeRepo struct {
+	mu    sync.Mutex
+	nodes map[string]hosting_service.Node
+}
+
+func newMockNodeRepo() *mockNodeRepo {
+	return &mockNodeRepo{nodes: map[string]hosting_service.Node{}}
+}
+
+func (m *mockNodeRepo) FindByName(name string) (*hosting_service.Node, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, ok := m.nodes[name]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &n, nil
+}
+
+func (m *mockNodeRepo) FindAll() ([]*hosting_service.Node, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []*hosting_service.Node
+	for _, n := range m.nodes {
+		n := n
+		list = append(list, &n)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+func (m *mockNodeRepo) Create(n *hosting_service.Node) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nodes[n.Name] = *n
+	return nil
+}
+
+func (m *mockNodeRepo) Update(n *hosting_service.Node) error {
+	return m.Create(n)
+}
+
+func (m *mockNodeRepo) Delete(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.nodes, name)
+	return nil
+}
+
+type mockImageRepo struct {
+	mu     sync.Mutex
+	images map[string]hosting_service.Image
+}
+
+// newMockImageRepo - FakeHypervisor의 기본 템플릿을 아직 확인하지 않은 상태로 등록
+func newMockImageRepo() *mockImageRepo {
+	return &mockImageRepo{images: map[string]hosting_service.Image{
+		hosting_service.DefaultImageName: {
+			Name: hosting_service.DefaultImageName, OSFamily: "ubuntu", Version: "22.04",
+			Path: hypervisor.FakeTemplate, SHA256: hypervisor.FakeTemplateSHA256,
+			DefaultUser: "ubuntu", Status: hosting_service.ImageActive,
+		},
+	}}
+}
+
+func (m *mockImageRepo) FindByName(name string) (*hosting_service.Image, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	img, ok := m.images[name]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &img, nil
+}
+
+func (m *mockImageRepo) FindAll() ([]*hosting_service.Image, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []*hosting_service.Image
+	for _, img := range m.images {
+		img := img
+		list = append(list, &img)
+	}
+	return list, nil
+}
+
+func (m *mockImageRepo) Create(img *hosting_service.Image) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.images[img.Name] = *img
+	return nil
+}
+
+func (m *mockImageRepo) UpdateStatus(name, status string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	img := m.images[name]
+	img.Status = status
+	m.images[name] = img
+	return nil
+}
+
+func (m *mockImageRepo) MarkVerified(name string, t time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	img := m.images[name]
+	img.VerifiedAt = &t
+	m.images[name] = img
+	return nil
+}
+
+type mockMetricsRepo struct {
+	mu     sync.Mutex
+	points []hosting_service.MetricPoint
+}
+
+func newMockMetricsRepo() *mockMetricsRepo {
+	return &mockMetricsRepo{}
+}
+
+func (m *mockMetricsRepo) Save(points []*hosting_service.MetricPoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+next:
+	for _, p := range points {
+		for i, old := range m.points {
+			if old.HostingID == p.HostingID && old.Resolution == p.Resolution && old.Time.Equal(p.Time) {
+				m.points[i] = *p
+				continue next
+			}
+		}
+		m.points = append(m.points, *p)
+	}
+	sort.SliceStable(m.points, func(i, j int) bool { return m.points[i].Time.Before(m.points[j].Time) })
+	return nil
+}
+
+func (m *mockMetricsRepo) FindRange(hostingID int64, resolution time.Duration, from, to time.Time) ([]*hosting_service.MetricPoint, error) {
+	list, _ := m.FindAllRange(resolution, from, to)
+	var out []*hosting_service.MetricPoint
+	for _, p := range list {
+		if p.HostingID == hostingID {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockMetricsRepo) FindAllRange(resolution time.Duration, from, to time.Time) ([]*hosting_service.MetricPoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []*hosting_service.MetricPoint
+	for _, p := range m.points {
+		if p.Resolution == resolution && !p.Time.Before(from) && p.Time.Before(to) {
+			p := p
+			list = append(list, &p)
+		}
+	}
+	return list, nil
+}
+
+func (m *mockMetricsRepo) DeleteBefore(resolution time.Duration, before time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.points[:0]
+	for _, p := range m.points {
+		if p.Resolution != resolution || !p.Time.Before(before) {
+			kept = append(kept, p)
+		}
+	}
+	m.points = kept
+	return nil
+}
+
+type mockSSHKeyRepo struct {
+	mu     sync.Mutex
+	nextID int64
+	keys   map[int64]hosting_service.SSHKey
+}
+
+func newMockSSHKeyRepo() *mockSSHKeyRepo {
+	return &mockSSHKeyRepo{keys: make(map[int64]hosting_service.SSHKey)}
+}
+
+func (m *mockSSHKeyRepo) Create(key *hosting_service.SSHKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	key.ID = m.nextID
+	m.keys[key.ID] = *key
+	return nil
+}
+
+func (m *mockSSHKeyRepo) Delete(id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.keys, id)
+	return nil
+}
+
+func (m *mockSSHKeyRepo) FindByID(id int64) (*hosting_service.SSHKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key, ok := m.keys[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &key, nil
+}
+
+func (m *mockSSHKeyRepo) FindByUserID(userID int64) ([]*hosting_service.SSHKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []*hosting_service.SSHKey
+	for id := int64(1); id <= m.nextID; id++ {
+		if key, ok := m.keys[id]; ok && key.UserID == userID {
+			list = append(list, &key)
+		}
+	}
+	return list, nil
+}
+
+type mockQuotaRepo struct {
+	mu     sync.Mutex
+	quotas map[int64]hosting_service.Quota
+}
+
+func newMockQuotaRepo() *mockQuotaRepo {
+	return &mockQuotaRepo{quotas: make(map[int64]hosting_service.Quota)}
+}
+
+func (m *mockQuotaRepo) FindByUserID(userID int64) (*hosting_service.Quota, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	q, ok := m.quotas[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &q, nil
+}
+
+func (m *mockQuotaRepo) Upsert(q *hosting_service.Quota) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.quotas[q.UserID] = *q
+	return nil
+}
+
+func (m *mockQuotaRepo) Delete(userID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.quotas, userID)
+	return nil
+}
+
+type mockSnapshotRepo struct {
+	mu     sync.Mutex
+	nextID int64
+	snaps  map[int64]hosting_service.Snapshot
+}
+
+func newMockSnapshotRepo() *mockSnapshotRepo {
+	return &mockSnapshotRepo{snaps: make(map[int64]hosting_service.Snapshot)}
+}
+
+func (m *mockSnapshotRepo) Create(snap *hosting_service.Snapshot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	snap.ID = m.nextID
+	m.snaps[snap.ID] = *snap
+	return nil
+}
+
+func (m *mockSnapshotRepo) Delete(id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.snaps, id)
+	return nil
+}
+
+func (m *mockSnapshotRepo) FindByID(id int64) (*hosting_service.Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snap, ok := m.snaps[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &snap, nil
+}
+
+func (m *mockSnapshotRepo) FindByHostingID(hostingID int64) ([]*hosting_service.Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []*hosting_service.Snapshot
+	for id := int64(1); id <= m.nextID; id++ {
+		if snap, ok := m.snaps[id]; ok && snap.HostingID == hostingID {
+			list = append(list, &snap)
+		}
+	}
+	return list, nil
+}
+
+func (m *mockSnapshotRepo) DeleteByHostingID(hostingID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, snap := range m.snaps {
+		if snap.HostingID == hostingID {
+			delete(m.snaps, id)
+		}
+	}
+	return nil
+}
+
+type mockBackupRepo struct {
+	mu      sync.Mutex
+	nextID  int64
+	backups map[int64]hosting_service.Backup
+}
+
+func newMockBackupRepo() *mockBackupRepo {
+	return &mockBackupRepo{backups: make(map[int64]hosting_service.Backup)}
+}
+
+func (m *mockBackupRepo) Create(b *hosting_service.Backup) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	b.ID = m.nextID
+	m.backups[b.ID] = *b
+	return nil
+}
+
+func (m *mockBackupRepo) Delete(id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.backups, id)
+	return nil
+}
+
+func (m *mockBackupRepo) FindByID(id int64) (*hosting_service.Backup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.backups[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &b, nil
+}
+
+func (m *mockBackupRepo) FindByUserID(userID int64) ([]*hosting_service.Backup, error) {
+	return m.find(func(b hosting_service.Backup) bool { return b.UserID == userID })
+}
+
+func (m *mockBackupRepo) FindByHostingID(hostingID int64) ([]*hosting_service.Backup, error) {
+	return m.find(func(b hosting_service.Backup) bool { return b.HostingID == hostingID })
+}
+
+func (m *mockBackupRepo) find(match func(hosting_service.Backup) bool) ([]*hosting_service.Backup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []*hosting_service.Backup
+	for id := int64(1); id <= m.nextID; id++ {
+		if b, ok := m.backups[id]; ok && match(b) {
+			list = append(list, &b)
+		}
+	}
+	return list, nil
+}
+
+type mockScheduleRepo struct {
+	mu        sync.Mutex
+	schedules map[int64]hosting_service.BackupSchedule
+}
+
+func newMockScheduleRepo() *mockScheduleRepo {
+	return &mockScheduleRepo{schedules: make(map[int64]hosting_service.BackupSchedule)}
+}
+
+func (m *mockScheduleRepo) FindByHostingID(hostingID int64) (*hosting_service.BackupSchedule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sched, ok := m.schedules[hostingID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &sched, nil
+}
+
+func (m *mockScheduleRepo) FindAll() ([]*hosting_service.BackupSchedule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []*hosting_service.BackupSchedule
+	for _, sched := range m.schedules {
+		sched := sched
+		list = append(list, &sched)
+	}
+	return list, nil
+}
+
+func (m *mockScheduleRepo) Upsert(sched *hosting_service.BackupSchedule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.schedules[sched.HostingID] = *sched
+	return nil
+}
+
+func (m *mockScheduleRepo) UpdateLastRun(hostingID int64, t time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sched, ok := m.schedules[hostingID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	sched.LastRunAt = t
+	m.schedules[hostingID] = sched
+	return nil
+}
+
+func (m *mockScheduleRepo) Delete(hostingID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.schedules, hostingID)
+	return nil
+}
+
+type mockOperationRepo struct {
+	mu  sync.Mutex
+	ops map[int64]hosting_service.Operation
+}
+
+func newMockOperationRepo() *mockOperationRepo {
+	return &mockOperationRepo{ops: make(map[int64]hosting_service.Operation)}
+}
+
+func (m *mockOperationRepo) Create(op *hosting_service.Operation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	op.ID = int64(len(m.ops) + 1)
+	m.ops[op.ID] = *op
+	return nil
+}
+
+func (m *mockOperationRepo) Update(op *hosting_service.Operation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ops[op.ID] = *op
+	return nil
+}
+
+func (m *mockOperationRepo) FindByID(id int64) (*hosting_service.Operation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	op, ok := m.ops[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &op, nil
+}
+
+func (m *mockOperationRepo) FindByStatus(statuses ...string) ([]*hosting_service.Operation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []*hosting_service.Operation
+	for id := int64(1); id <= int64(len(m.ops)); id++ {
+		op := m.ops[id]
+		for _, st := range statuses {
+			if op.Status == st {
+				list = append(list, &op)
+				break
+			}
+		}
+	}
+	return list, nil
+}
+
+// nginx-agent 대역: 등록/삭제된 hostname과 마지막으로 등록한 VM IP를 기록한다
+type fakeNginxAgent struct {
+	mu         sync.Mutex
+	registered map[string]bool
+	upstreams  map[string]string
+	failPost   bool // true면 등록 요청에 500 응답
+}
+
+func (a *fakeNginxAgent) isRegistered(hostname string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.registered[hostname]
+}
+
+func (a *fakeNginxAgent) upstream(hostname string) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.upstreams[hostname]
+}
+
+func (a *fakeNginxAgent) setFailPost(fail bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.failPost = fail
+}
+
+func newFakeNginxAgent(t *testing.T) (*fakeNginxAgent, string) {
+	agent := &fakeNginxAgent{registered: make(map[string]bool), upstreams: make(map[string]string)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agent.mu.Lock()
+		defer agent.mu.Unlock()
+		switch r.Method {
+		case http.MethodPost:
+			if agent.failPost {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			var info nginx.AgentInfo
+			_ = json.NewDecoder(r.Body).Decode(&info)
+			agent.registered[info.Hostname] = true
+			agent.upstreams[info.Hostname] = info.VMIP
+		case http.MethodDelete:
+			delete(agent.registered, strings.TrimPrefix(r.URL.Path, "/api/nginx/"))
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	return agent, strings.TrimPrefix(srv.URL, "http://")
+}
+
+func newTestService(t *testing.T, repo hosting_service.HostingRepository, ops hosting_service.OperationRepository, agentAddr string, hv hosting_service.Hypervisor) *hosting_service.HostingService {
+	svc := hosting_service.NewService(repo, ops, newMockPlanRepo(), newMockImageRepo(), newMockQuotaRepo(), newMockSnapshotRepo(), newMockBackupRepo(), newMockScheduleRepo(), newMockSSHKeyRepo(), newMockMetricsRepo(), newMockNodeRepo(), agentAddr, hv)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	require.NoError(t, svc.StartWorkers(ctx, 2))
+	return svc
+}
+
+// wait - 작업이 끝날 때까지 기다린 뒤 최종 상태를 돌려준다
+func wait(t *testing.T, svc *hosting_service.HostingService, op *hosting_service.Operation, err error) *hosting_service.Operation {
+	t.Helper()
+	require.NoError(t, err)
+	require.Equal(t, hosting_service.OpQueued, op.Status)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		cur, err := svc.GetOperation(op.ID)
+		require.NoError(t, err)
+		if cur.Done() {
+			return cur
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("operation #%d did not finish", op.ID)
+	return nil
+}
+
+// --- 테스트 시작 ---
+
+func TestHostingService_Lifecycle(t *testing.T) {
+	repo := newMockHostingRepo()
+	hv := hypervisor.NewFakeHypervisor()
+	nginxAgent, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, hv)
+
+	// 1. 생성
+	op, err := svc.CreateHosting(1, "web", hosting_service.CreateOptions{})
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+
+	h := repo.hosting(1, "web")
+	require.NotNil(t, h)
+	assert.Equal(t, "running", h.Status)
+	assert.Regexp(t, `^vm-[0-9a-f]{8}$`, h.VMName)
+	assert.Equal(t, "/"+h.VMName, h.ProxyPath)
+	assert.JSONEq(t, fmt.Sprintf(`{"id":%d,"name":"web","hostname":%q,"ip":"192.168.122.2","ssh_port":20000,"proxy":%q,"plan":"small","image":"ubuntu-22.04","default_user":"ubuntu"}`,
+		h.ID, h.VMName, h.ProxyPath), done.Result)
+
+	dom, ok := hv.Domain(h.VMName)
+	require.True(t, ok)
+	assert.Equal(t, hosting_service.DomainRunning, dom.State)
+	assert.True(t, hv.DiskExists(h.DiskPath))
+	assert.True(t, nginxAgent.isRegistered(h.VMName))
+
+	// 2. 같은 이름은 거부 (큐에 넣기 전에 거부)
+	_, err = svc.CreateHosting(1, "web", hosting_service.CreateOptions{})
+	assert.ErrorIs(t, err, hosting_service.ErrVMNameTaken)
+
+	// 3. 상태/상세 (ID 또는 이름으로 지정)
+	id := fmt.Sprint(h.ID)
+	status, err := svc.GetVMStatus(1, id)
+	require.NoError(t, err)
+	assert.True(t, status.Active)
+
+	_, info, err := svc.GetVMDetail(1, "web")
+	require.NoError(t, err)
+	assert.Equal(t, hosting_service.DomainRunning, info.State)
+
+	// 다른 사용자의 VM은 보이지 않는다
+	_, _, err = svc.GetVMDetail(2, id)
+	assert.ErrorIs(t, err, hosting_service.ErrVMNotFound)
+
+	// 4. 중지
+	op, err = svc.StopVM(1, id, 0)
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	dom, _ = hv.Domain(h.VMName)
+	assert.Equal(t, hosting_service.DomainShutoff, dom.State)
+	assert.Equal(t, "stopped", h.Status)
+
+	// 5. 시작
+	op, err = svc.StartVM(1, "web")
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	dom, _ = hv.Domain(h.VMName)
+	assert.Equal(t, hosting_service.DomainRunning, dom.State)
+	assert.Equal(t, "running", h.Status)
+
+	// 6. 삭제
+	op, err = svc.DeleteVM(1, id)
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	_, ok = hv.Domain(h.VMName)
+	assert.False(t, ok)
+	assert.False(t, hv.DiskExists(h.DiskPath))
+	assert.False(t, nginxAgent.isRegistered(h.VMName))
+	assert.Equal(t, "deleted", h.Status)
+
+	_, err = svc.GetVMStatus(1, id)
+	assert.ErrorIs(t, err, hosting_service.ErrVMNotFound)
+
+	// 7. 삭제 후 이름/IP/포트 재사용 가능
+	op, err = svc.CreateHosting(1, "web", hosting_service.CreateOptions{})
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	var h2 *hosting_service.Hosting
+	for _, vm := range mustList(t, svc, 1) {
+		h2 = vm
+	}
+	require.NotNil(t, h2)
+	assert.NotEqual(t, h.VMName, h2.VMName)
+	assert.Equal(t, "192.168.122.2", h2.IPAddress)
+	assert.Equal(t, 20000, h2.SSHPort)
+}
+
+func mustList(t *testing.T, svc *hosting_service.HostingService, userID int64) []*hosting_service.Hosting {
+	t.Helper()
+	list, err := svc.ListVMs(userID)
+	require.NoError(t, err)
+	return list
+}
+
+func TestHostingService_MultipleVMs(t *testing.T) {
+	repo := newMockHostingRepo()
+	hv := hypervisor.NewFakeHypervisor()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, hv)
+	require.NoError(t, svc.SetQuota(&hosting_service.Quota{UserID: 1, MaxVMs: 2, MaxVCPUs: 8, MaxMemoryMB: 8192, MaxDiskGB: 100}))
+
+	// 이메일 앞부분이 같은 두 사용자도 같은 이름의 VM을 따로 가진다
+	for _, userID := range []int64{1, 2} {
+		op, err := svc.CreateHosting(userID, "web", hosting_service.CreateOptions{})
+		done := wait(t, svc, op, err)
+		require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	}
+	a, b := repo.hosting(1, "web"), repo.hosting(2, "web")
+	assert.NotEqual(t, a.VMName, b.VMName)
+	assert.NotEqual(t, a.IPAddress, b.IPAddress)
+	assert.NotEqual(t, a.SSHPort, b.SSHPort)
+
+	// 사용자당 개수 제한
+	op, err := svc.CreateHosting(1, "db", hosting_service.CreateOptions{})
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	assert.Len(t, mustList(t, svc, 1), 2)
+
+	_, err = svc.CreateHosting(1, "cache", hosting_service.CreateOptions{})
+	assert.ErrorIs(t, err, hosting_service.ErrQuotaExceeded)
+
+	// 이름 형식
+	for _, name := range []string{"", "Web", "123", "-web", strings.Repeat("a", 33)} {
+		_, err = svc.CreateHosting(3, name, hosting_service.CreateOptions{})
+		assert.ErrorIs(t, err, hosting_service.ErrInvalidVMName, name)
+	}
+
+	// 동시에 처리되는 생성도 IP/포트가 겹치지 않는다
+	var ops []*hosting_service.Operation
+	for userID := int64(4); userID < 10; userID++ {
+		op, err := svc.CreateHosting(userID, "web", hosting_service.CreateOptions{})
+		require.NoError(t, err)
+		ops = append(ops, op)
+	}
+	ips, ports := map[string]bool{a.IPAddress: true, b.IPAddress: true}, map[int]bool{a.SSHPort: true, b.SSHPort: true}
+	for i, op := range ops {
+		done := wait(t, svc, op, nil)
+		require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+		h := repo.hosting(int64(i+4), "web")
+		assert.False(t, ips[h.IPAddress], h.IPAddress)
+		assert.False(t, ports[h.SSHPort], h.SSHPort)
+		ips[h.IPAddress], ports[h.SSHPort] = true, true
+	}
+}
+
+func TestHostingService_CreateRollback(t *testing.T) {
+	repo := newMockHostingRepo()
+	hv := hypervisor.NewFakeHypervisor()
+	nginxAgent, agentAddr := newFakeNginxAgent(t)
+	nginxAgent.setFailPost(true)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, hv)
+
+	// nginx-agent 등록 단계에서 실패
+	op, err := svc.CreateHosting(1, "web", hosting_service.CreateOptions{})
+	done := wait(t, svc, op, err)
+	assert.Equal(t, hosting_service.OpRolledBack, done.Status)
+	assert.Equal(t, hosting_service.StepRegisteringProxy, done.Step)
+	assert.NotEmpty(t, done.Error)
+	assert.Equal(t, []string{"creating_vm: ok", "reserving: ok"}, done.Rollback)
+
+	// 도메인/디스크/DB 행 모두 정리되어야 함
+	_, ok := hv.Domain(done.VMName)
+	assert.False(t, ok)
+	assert.False(t, hv.DiskExists("/fake/instances/"+done.VMName+"/disk.qcow2"))
+	assert.Empty(t, repo.hostings)
+
+	// 실패 기록
+	failed, err := svc.ListFailedOperations()
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	assert.Equal(t, hosting_service.OpCreateHosting, failed[0].Kind)
+
+	// 롤백 후 같은 이름/IP/포트로 다시 생성 가능
+	nginxAgent.setFailPost(false)
+	op, err = svc.CreateHosting(1, "web", hosting_service.CreateOptions{})
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	assert.Equal(t, "192.168.122.2", repo.hosting(1, "web").IPAddress)
+}
+
+func TestHostingService_ResumeQueuedOnStart(t *testing.T) {
+	repo := newMockHostingRepo()
+	ops := newMockOperationRepo()
+	hv := hypervisor.NewFakeHypervisor()
+	_, agentAddr := newFakeNginxAgent(t)
+
+	// 서버가 내려가기 전에 남은 작업: 하나는 대기 중, 하나는 실행 도중 중단
+	queued := &hosting_service.Operation{Kind: hosting_service.OpCreateHosting, UserID: 1, Target: "web", VMName: "vm-00000001", Status: hosting_service.OpQueued}
+	running := &hosting_service.Operation{Kind: hosting_service.OpStopVM, UserID: 2, Target: "web", VMName: "vm-00000002", Status: hosting_service.OpRunning}
+	require.NoError(t, ops.Create(queued))
+	require.NoError(t, ops.Create(running))
+
+	svc := newTestService(t, repo, ops, agentAddr, hv)
+
+	done := wait(t, svc, queued, nil)
+	assert.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+
+	interrupted, err := svc.GetOperation(running.ID)
+	require.NoError(t, err)
+	assert.Equal(t, hosting_service.OpFailed, interrupted.Status)
+}
+
+func TestHostingService_Plans(t *testing.T) {
+	repo := newMockHostingRepo()
+	hv := hypervisor.NewFakeHypervisor()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, hv)
+
+	// 존재하지 않는 플랜은 큐에 넣기 전에 거부
+	_, err := svc.CreateHosting(1, "web", hosting_service.CreateOptions{Plan: "huge"})
+	assert.ErrorIs(t, err, hosting_service.ErrPlanNotFound)
+
+	// 선택한 플랜의 사양으로 VM 생성
+	op, err := svc.CreateHosting(1, "web", hosting_service.CreateOptions{Plan: "medium"})
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	assert.Equal(t, "medium", repo.hosting(1, "web").Plan)
+
+	dom, ok := hv.Domain(done.VMName)
+	require.True(t, ok)
+	assert.Equal(t, 2, dom.VCPUs)
+	assert.Equal(t, 2048, dom.MemoryMB)
+	assert.Equal(t, 20, dom.DiskGB)
+
+	// 사양 검증
+	assert.Error(t, svc.CreatePlan(&hosting_service.HostingPlan{Name: "tiny", CPU: 1, MemoryMB: 128, DiskGB: 10}))
+
+	// 관리자가 추가한 플랜도 바로 사용 가능
+	require.NoError(t, svc.CreatePlan(&hosting_service.HostingPlan{Name: "xlarge", CPU: 8, MemoryMB: 8192, DiskGB: 80}))
+	op, err = svc.CreateHosting(2, "web", hosting_service.CreateOptions{Plan: "xlarge"})
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	dom, _ = hv.Domain(done.VMName)
+	assert.Equal(t, 8, dom.VCPUs)
+
+	// 사용 중인 플랜은 삭제 불가, 사용하지 않는 플랜은 삭제 가능
+	assert.ErrorIs(t, svc.DeletePlan("medium"), hosting_service.ErrPlanInUse)
+	require.NoError(t, svc.DeletePlan("large"))
+	_, err = svc.GetPlan("large")
+	assert.ErrorIs(t, err, hosting_service.ErrPlanNotFound)
+}
+
+func TestHostingService_Resize(t *testing.T) {
+	repo := newMockHostingRepo()
+	hv := hypervisor.NewFakeHypervisor()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, hv)
+
+	op, err := svc.CreateHosting(1, "web", hosting_service.CreateOptions{Plan: "medium"})
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	vmName := done.VMName
+
+	// 디스크가 더 작은 플랜으로는 변경 불가
+	_, err = svc.ResizeVM(1, "web", "small")
+	assert.ErrorIs(t, err, hosting_service.ErrDiskShrink)
+	_, err = svc.ResizeVM(1, "web", "huge")
+	assert.ErrorIs(t, err, hosting_service.ErrPlanNotFound)
+
+	// 실행 중 업그레이드는 live로 적용
+	op, err = svc.ResizeVM(1, "web", "large")
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	assert.JSONEq(t, `{"plan":"large","live":true}`, done.Result)
+	assert.Equal(t, "large", repo.hosting(1, "web").Plan)
+
+	dom, _ := hv.Domain(vmName)
+	assert.Equal(t, 4, dom.VCPUs)
+	assert.Equal(t, 4096, dom.MemoryMB)
+	assert.Equal(t, 40, dom.DiskGB)
+
+	// 중지된 VM은 다음 부팅부터 적용
+	op, err = svc.StopVM(1, "web", 0)
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	require.NoError(t, svc.CreatePlan(&hosting_service.HostingPlan{Name: "xlarge", CPU: 8, MemoryMB: 8192, DiskGB: 40}))
+
+	op, err = svc.ResizeVM(1, "web", "xlarge")
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	assert.JSONEq(t, `{"plan":"xlarge","live":false}`, done.Result)
+}
+
+func TestHostingService_Images(t *testing.T) {
+	repo := newMockHostingRepo()
+	hv := hypervisor.NewFakeHypervisor()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, hv)
+
+	_, err := svc.CreateHosting(1, "web", hosting_service.CreateOptions{Image: "centos-7"})
+	assert.ErrorIs(t, err, hosting_service.ErrImageNotFound)
+
+	// 기본 이미지는 첫 사용 때 체크섬을 확인한다
+	op, err := svc.CreateHosting(1, "web", hosting_service.CreateOptions{})
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	assert.Equal(t, hosting_service.DefaultImageName, repo.hosting(1, "web").Image)
+	assert.Contains(t, done.Result, `"default_user":"ubuntu"`)
+	dom, _ := hv.Domain(done.VMName)
+	assert.Equal(t, nocloud.FormatISO, dom.SeedFormat)
+
+	images, err := svc.ListImages()
+	require.NoError(t, err)
+	require.Len(t, images, 1)
+	assert.NotNil(t, images[0].VerifiedAt)
+
+	// 등록된 체크섬과 다른 파일이면 VM을 만들지 않는다
+	debian := &hosting_service.Image{
+		Name: "debian-12", OSFamily: "debian", Version: "12", Path: "debian-12-generic-amd64.qcow2",
+		SHA256: strings.Repeat("a", 64), DefaultUser: "debian", SeedFormat: "vfat",
+	}
+	require.NoError(t, svc.RegisterImage(debian))
+	hv.SetImage(debian.Path, strings.Repeat("b", 64))
+
+	op, err = svc.CreateHosting(1, "db", hosting_service.CreateOptions{Image: "debian-12"})
+	done = wait(t, svc, op, err)
+	assert.Equal(t, hosting_service.OpFailed, done.Status)
+	assert.Equal(t, hosting_service.StepVerifyingImage, done.Step)
+	assert.Contains(t, done.Error, hosting_service.ErrImageChecksum.Error())
+	assert.Nil(t, repo.hosting(1, "db"))
+
+	_, err = svc.VerifyImage("debian-12")
+	assert.ErrorIs(t, err, hosting_service.ErrImageChecksum)
+
+	hv.SetImage(debian.Path, debian.SHA256)
+	img, err := svc.VerifyImage("debian-12")
+	require.NoError(t, err)
+	assert.NotNil(t, img.VerifiedAt)
+
+	op, err = svc.CreateHosting(1, "db", hosting_service.CreateOptions{Image: "debian-12"})
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	dom, _ = hv.Domain(done.VMName)
+	assert.Equal(t, "/fake/templates/"+debian.Path, dom.BackingFile)
+	assert.Equal(t, nocloud.FormatVFAT, dom.SeedFormat)
+
+	// 사용 중지된 이미지로는 새로 만들 수 없지만 기존 VM은 그대로
+	require.NoError(t, svc.RetireImage("debian-12"))
+	_, err = svc.CreateHosting(1, "cache", hosting_service.CreateOptions{Image: "debian-12"})
+	assert.ErrorIs(t, err, hosting_service.ErrImageRetired)
+	assert.Equal(t, "running", repo.hosting(1, "db").Status)
+
+	assert.Error(t, svc.RegisterImage(&hosting_service.Image{
+		Name: "bad", OSFamily: "ubuntu", Path: "../etc/passwd", SHA256: debian.SHA256, DefaultUser: "ubuntu",
+	}))
+	assert.ErrorIs(t, svc.RegisterImage(&hosting_service.Image{
+		Name: "bad", OSFamily: "ubuntu", Path: "bad.img", SHA256: debian.SHA256, DefaultUser: "ubuntu", SeedFormat: "udf",
+	}), nocloud.ErrUnknownFormat)
+}
+
+func TestHostingService_DomainFeatures(t *testing.T) {
+	repo := newMockHostingRepo()
+	hv := hypervisor.NewFakeHypervisor()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, hv)
+
+	plan := &hosting_service.HostingPlan{
+		Name: "capped", CPU: 2, MemoryMB: 2048, DiskGB: 20,
+		Features: hosting_service.DomainFeatures{VirtioRNG: true, CPUQuota: 50},
+	}
+	require.NoError(t, svc.CreatePlan(plan))
+
+	// UEFI 전용 이미지의 기능은 플랜 기능에 더해진다
+	uefi := &hosting_service.Image{
+		Name: "ubuntu-24.04-uefi", OSFamily: "ubuntu", Version: "24.04", Path: "noble-uefi.img",
+		SHA256: strings.Repeat("c", 64), DefaultUser: "ubuntu",
+		Features: hosting_service.DomainFeatures{UEFI: true},
+	}
+	require.NoError(t, svc.RegisterImage(uefi))
+	hv.SetImage(uefi.Path, uefi.SHA256)
+
+	op, err := svc.CreateHosting(1, "web", hosting_service.CreateOptions{Plan: "capped", Image: uefi.Name})
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	dom, _ := hv.Domain(done.VMName)
+	assert.Equal(t, hosting_service.DomainFeatures{UEFI: true, VirtioRNG: true, CPUQuota: 50}, dom.Features)
+
+	// UEFI VM은 NVRAM이 내부 스냅샷에 담기지 않으므로 큐에 넣기 전에 거절한다
+	_, err = svc.CreateSnapshot(1, "web", "before", "")
+	assert.ErrorIs(t, err, hosting_service.ErrSnapshotUnsupported)
+
+	// 기본 플랜과 이미지는 선택 기능이 없다
+	op, err = svc.CreateHosting(1, "db", hosting_service.CreateOptions{})
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	dom, _ = hv.Domain(done.VMName)
+	assert.Zero(t, dom.Features)
+
+	plan.Features.CPUQuota = 150
+	assert.Error(t, plan.Validate())
+	uefi.Features.CPUQuota = 50
+	assert.Error(t, uefi.Validate(), "CPU 할당률은 플랜에서만 지정한다")
+}
+
+// testSSHKey - 형식만 맞는 ed25519 공개키
+func testSSHKey(seed byte) string {
+	var blob []byte
+	for _, part := range [][]byte{[]byte("ssh-ed25519"), make([]byte, 32)} {
+		n := make([]byte, 4)
+		binary.BigEndian.PutUint32(n, uint32(len(part)))
+		blob = append(blob, n...)
+		blob = append(blob, part...)
+	}
+	blob[len(blob)-1] = seed
+	return "ssh-ed25519 " + base64.StdEncoding.EncodeToString(blob) + " test"
+}
+
+func TestHostingService_CloudInit(t *testing.T) {
+	repo := newMockHostingRepo()
+	hv := hypervisor.NewFakeHypervisor()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, hv)
+
+	_, err := svc.AddSSHKey(1, "laptop", "ssh-ed25519 AAAA")
+	assert.ErrorIs(t, err, hosting_service.ErrInvalidSSHKey)
+	key, err := svc.AddSSHKey(1, "laptop", testSSHKey(1)+"\n")
+	require.NoError(t, err)
+	assert.Regexp(t, `^SHA256:[A-Za-z0-9+/]{43}$`, key.Fingerprint)
+	other, err := svc.AddSSHKey(2, "desktop", testSSHKey(2))
+	require.NoError(t, err)
+	assert.ErrorIs(t, svc.DeleteSSHKey(1, other.ID), hosting_service.ErrSSHKeyNotFound)
+
+	_, err = svc.CreateHosting(1, "web", hosting_service.CreateOptions{UserData: "#cloud-config\nusers:\n  - name: root\n"})
+	assert.ErrorIs(t, err, hosting_service.ErrInvalidUserData)
+
+	op, err := svc.CreateHosting(1, "web", hosting_service.CreateOptions{
+		UserData: "#cloud-config\npackages: [git]\nruncmd: ['echo hi']\n",
+	})
+	require.NoError(t, err)
+	password := op.Password
+	require.Len(t, password, 16)
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	// 비밀번호는 작업 기록에 남지 않는다
+	assert.Empty(t, done.Password)
+	assert.NotContains(t, done.Result, password)
+	// 비밀번호 해시와 user-data도 작업이 끝나면 작업 인자에서 지운다
+	assert.NotContains(t, done.Params, "password_hash")
+	assert.NotContains(t, done.Params, "user_data")
+	assert.Equal(t, "small", done.Params["plan"])
+
+	dom, _ := hv.Domain(done.VMName)
+	var cfg struct {
+		Hostname string   `yaml:"hostname"`
+		Packages []string `yaml:"packages"`
+		RunCmd   []string `yaml:"runcmd"`
+		Users    []struct {
+			Name   string   `yaml:"name"`
+			Passwd string   `yaml:"passwd"`
+			Keys   []string `yaml:"ssh_authorized_keys"`
+		} `yaml:"users"`
+		SSHPwauth bool `yaml:"ssh_pwauth"`
+	}
+	require.NoError(t, yaml.Unmarshal([]byte(dom.UserData), &cfg))
+	assert.Equal(t, "web", cfg.Hostname)
+	assert.Equal(t, []string{"nginx", "qemu-guest-agent", "git"}, cfg.Packages)
+	assert.Equal(t, "echo hi", cfg.RunCmd[len(cfg.RunCmd)-1])
+	require.Len(t, cfg.Users, 1)
+	assert.Equal(t, "ubuntu", cfg.Users[0].Name)
+	assert.Equal(t, []string{testSSHKey(1)}, cfg.Users[0].Keys)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(cfg.Users[0].Passwd), []byte(password)))
+	assert.False(t, cfg.SSHPwauth)
+
+	// 키를 지운 뒤 만든 VM은 비밀번호 로그인만 가능
+	require.NoError(t, svc.DeleteSSHKey(1, key.ID))
+	keys, err := svc.ListSSHKeys(1)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	op, err = svc.CreateHosting(1, "db", hosting_service.CreateOptions{})
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	dom, _ = hv.Domain(done.VMName)
+	assert.Contains(t, dom.UserData, "ssh_pwauth: true")
+}
+
+func TestHostingService_Flatten(t *testing.T) {
+	repo := newMockHostingRepo()
+	hv := hypervisor.NewFakeHypervisor()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, hv)
+
+	op, err := svc.CreateHosting(1, "web", hosting_service.CreateOptions{Plan: "small"})
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+
+	// 새 VM 디스크는 템플릿 위의 overlay
+	dom, _ := hv.Domain(done.VMName)
+	assert.Equal(t, "/fake/templates/"+hypervisor.FakeTemplate, dom.BackingFile)
+
+	op, err = svc.FlattenVM(1, "web")
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	assert.Equal(t, hosting_service.StepFlatteningDisk, done.Step)
+
+	dom, _ = hv.Domain(done.VMName)
+	assert.Empty(t, dom.BackingFile)
+	assert.Equal(t, "running", repo.hosting(1, "web").Status)
+
+	_, err = svc.FlattenVM(2, "web")
+	assert.ErrorIs(t, err, hosting_service.ErrVMNotFound)
+}
+
+func TestHostingService_Quota(t *testing.T) {
+	repo := newMockHostingRepo()
+	hv := hypervisor.NewFakeHypervisor()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, hv)
+
+	// 지정하지 않으면 기본 할당량
+	q, err := svc.GetQuota(1)
+	require.NoError(t, err)
+	assert.Equal(t, hosting_service.DefaultQuota.MaxVCPUs, q.MaxVCPUs)
+	assert.Equal(t, int64(1), q.UserID)
+
+	// 기본 할당량 변경은 모든 사용자에게 적용
+	require.NoError(t, svc.SetQuota(&hosting_service.Quota{UserID: hosting_service.DefaultQuotaUserID, MaxVMs: 5, MaxVCPUs: 4, MaxMemoryMB: 4096, MaxDiskGB: 50}))
+	assert.ErrorIs(t, svc.SetQuota(&hosting_service.Quota{UserID: 1, MaxVMs: -1}), hosting_service.ErrInvalidQuota)
+
+	op, err := svc.CreateHosting(1, "web", hosting_service.CreateOptions{Plan: "medium"})
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+
+	usage, err := svc.GetUsage(1)
+	require.NoError(t, err)
+	assert.Equal(t, hosting_service.Usage{VMs: 1, VCPUs: 2, MemoryMB: 2048, DiskGB: 20}, *usage)
+
+	// vCPU 2 + 4 > 4
+	_, err = svc.CreateHosting(1, "db", hosting_service.CreateOptions{Plan: "large"})
+	assert.ErrorIs(t, err, hosting_service.ErrQuotaExceeded)
+	assert.Contains(t, err.Error(), "vCPU")
+
+	// 사양 변경은 현재 플랜을 뺀 값으로 계산: 4 <= 4 이므로 허용
+	op, err = svc.ResizeVM(1, "web", "large")
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+
+	_, err = svc.CreateHosting(1, "db", hosting_service.CreateOptions{Plan: "small"})
+	assert.ErrorIs(t, err, hosting_service.ErrQuotaExceeded)
+
+	// 사용자별 할당량이 기본 할당량보다 우선하고, 초기화하면 기본값으로 돌아간다
+	require.NoError(t, svc.SetQuota(&hosting_service.Quota{UserID: 1, MaxVMs: 5, MaxVCPUs: 16, MaxMemoryMB: 16384, MaxDiskGB: 200}))
+	op, err = svc.CreateHosting(1, "db", hosting_service.CreateOptions{Plan: "small"})
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+
+	require.NoError(t, svc.ResetQuota(1))
+	q, err = svc.GetQuota(1)
+	require.NoError(t, err)
+	assert.Equal(t, 4, q.MaxVCPUs)
+
+	// 같은 사용자의 작업이 동시에 처리되어도 합쳐서 할당량을 넘지 않는다
+	require.NoError(t, svc.SetQuota(&hosting_service.Quota{UserID: 2, MaxVMs: 2, MaxVCPUs: 16, MaxMemoryMB: 16384, MaxDiskGB: 200}))
+	var ops []*hosting_service.Operation
+	for _, name := range []string{"a", "b", "c", "d"} {
+		if op, err := svc.CreateHosting(2, name, hosting_service.CreateOptions{Plan: "small"}); err == nil {
+			ops = append(ops, op)
+		}
+	}
+	succeeded := 0
+	for _, op := range ops {
+		if done := wait(t, svc, op, nil); done.Status == hosting_service.OpSucceeded {
+			succeeded++
+		}
+	}
+	assert.Equal(t, 2, succeeded)
+	usage, err = svc.GetUsage(2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, usage.VMs)
+}
+
+func TestHostingService_Reconcile(t *testing.T) {
+	repo := newMockHostingRepo()
+	hv := hypervisor.NewFakeHypervisor()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, hv)
+	require.NoError(t, svc.SetQuota(&hosting_service.Quota{UserID: 1, MaxVMs: 5, MaxVCPUs: 8, MaxMemoryMB: 8192, MaxDiskGB: 100}))
+
+	vms := map[string]*hosting_service.Hosting{}
+	for _, name := range []string{"web", "db", "cache", "gone"} {
+		op, err := svc.CreateHosting(1, name, hosting_service.CreateOptions{})
+		done := wait(t, svc, op, err)
+		require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+		vms[name] = repo.hosting(1, name)
+	}
+	assert.Nil(t, svc.LastReconcileReport())
+
+	// API를 거치지 않은 변화: 게스트 내부 종료, 크래시, virsh undefine, 수동 생성
+	hv.SetState(vms["db"].VMName, hosting_service.DomainShutoff)
+	hv.SetState(vms["cache"].VMName, hosting_service.DomainCrashed)
+	require.NoError(t, hv.DeleteVM(vms["gone"].VMName, true))
+	_, err := hv.CreateVM(hosting_service.VMSpec{Name: "manual-vm"}, nil)
+	require.NoError(t, err)
+
+	report, err := svc.Reconcile()
+	require.NoError(t, err)
+	assert.Same(t, report, svc.LastReconcileReport())
+
+	assert.Equal(t, "running", vms["web"].Status)
+	assert.Equal(t, "stopped", vms["db"].Status)
+	assert.Equal(t, "error", vms["cache"].Status)
+	assert.Equal(t, "error", vms["gone"].Status)
+
+	assert.ElementsMatch(t, []hosting_service.StatusChange{
+		{ID: vms["db"].ID, VMName: vms["db"].VMName, From: "running", To: "stopped"},
+		{ID: vms["cache"].ID, VMName: vms["cache"].VMName, From: "running", To: "error"},
+		{ID: vms["gone"].ID, VMName: vms["gone"].VMName, From: "running", To: "error"},
+	}, report.Updated)
+	assert.Equal(t, []string{"manual-vm"}, report.OrphanDomains)
+	require.Len(t, report.OrphanRows, 1)
+	assert.Equal(t, vms["gone"].ID, report.OrphanRows[0].ID)
+
+	// 상태가 맞춰진 뒤에는 변경 없음 (고아 행/도메인은 계속 보고)
+	report, err = svc.Reconcile()
+	require.NoError(t, err)
+	assert.Empty(t, report.Updated)
+	assert.Len(t, report.OrphanRows, 1)
+	assert.Len(t, report.OrphanDomains, 1)
+}
+
+// staleListHypervisor - ListDomains가 목록을 읽은 뒤 끝난 작업을 반영하지 못한 경우와
+// DomainInfo가 일시적으로 실패하는 경우(노드 RPC, 에이전트 오류)를 흉내 낸다
+type staleListHypervisor struct {
+	*hypervisor.FakeHypervisor
+	stale   []hosting_service.DomainStatus
+	infoErr error
+}
+
+func (h *staleListHypervisor) DomainInfo(name string) (*hosting_service.DomainInfo, error) {
+	if h.infoErr != nil {
+		return nil, h.infoErr
+	}
+	return h.FakeHypervisor.DomainInfo(name)
+}
+
+func (h *staleListHypervisor) ListDomains() ([]hosting_service.DomainStatus, error) {
+	if h.stale != nil {
+		return h.stale, nil
+	}
+	return h.FakeHypervisor.ListDomains()
+}
+
+func TestHostingService_ReconcileStaleList(t *testing.T) {
+	repo := newMockHostingRepo()
+	hv := &staleListHypervisor{FakeHypervisor: hypervisor.NewFakeHypervisor()}
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, hv)
+
+	op, err := svc.CreateHosting(1, "web", hosting_service.CreateOptions{})
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	web := repo.hosting(1, "web")
+
+	// 목록은 running일 때 읽었고 그 사이 중지가 끝났다
+	hv.stale, err = hv.FakeHypervisor.ListDomains()
+	require.NoError(t, err)
+	op, err = svc.StopVM(1, "web", 0)
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+
+	report, err := svc.Reconcile()
+	require.NoError(t, err)
+	assert.Empty(t, report.Updated)
+	assert.Equal(t, "stopped", repo.hosting(1, "web").Status)
+
+	// 목록에 없던 도메인도 지금 조회되면 고아 행으로 보지 않는다
+	hv.stale = []hosting_service.DomainStatus{}
+	report, err = svc.Reconcile()
+	require.NoError(t, err)
+	assert.Empty(t, report.Updated)
+	assert.Empty(t, report.OrphanRows)
+	assert.Equal(t, "stopped", web.Status)
+
+	// 목록에 없고 조회가 일시적으로 실패해도 도메인이 없다고 보지 않고 건너뛴다
+	require.NoError(t, hv.FakeHypervisor.DeleteVM(web.VMName, true))
+	hv.infoErr = errors.New("libvirt-agent 요청 실패: connection reset")
+	report, err = svc.Reconcile()
+	require.NoError(t, err)
+	assert.Empty(t, report.Updated)
+	assert.Empty(t, report.OrphanRows)
+	assert.Equal(t, []string{web.VMName}, report.Skipped)
+	assert.Equal(t, "stopped", repo.hosting(1, "web").Status)
+
+	// 노드가 도메인이 없다고 답하면 고아 행이다
+	hv.infoErr = nil
+	report, err = svc.Reconcile()
+	require.NoError(t, err)
+	require.Len(t, report.OrphanRows, 1)
+	assert.Equal(t, "error", repo.hosting(1, "web").Status)
+}
+
+func TestHostingService_ReconcileUnreachableNode(t *testing.T) {
+	repo := newMockHostingRepo()
+	pool, fakes := newTestPool()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, pool)
+	require.NoError(t, svc.SetQuota(&hosting_service.Quota{UserID: 1, MaxVMs: 10, MaxVCPUs: 32, MaxMemoryMB: 32768, MaxDiskGB: 400}))
+
+	require.NoError(t, svc.CreateNode(&hosting_service.Node{Name: "node-a", Address: "agent://a:5004", VCPUs: 4, MemoryMB: 4096, DiskGB: 40, Schedulable: true}))
+	require.NoError(t, svc.CreateNode(&hosting_service.Node{Name: "node-b", Address: "agent://b:5004", VCPUs: 8, MemoryMB: 8192, DiskGB: 80, Schedulable: true}))
+	for _, name := range []string{"web1", "web2"} {
+		op, err := svc.CreateHosting(1, name, hosting_service.CreateOptions{Plan: "small"})
+		done := wait(t, svc, op, err)
+		require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	}
+	web1, web2 := repo.hosting(1, "web1"), repo.hosting(1, "web2")
+	require.Equal(t, "node-b", web1.NodeName)
+	require.Equal(t, "node-a", web2.NodeName)
+
+	// node-b의 목록을 읽지 못해도 나머지 노드는 점검하고, node-b의 VM은 고아 행으로 보지 않는다
+	b := fakes["agent://b:5004"]
+	b.SetListError(errors.New("connection reset"))
+	fakes["agent://a:5004"].SetState(web2.VMName, hosting_service.DomainShutoff)
+
+	report, err := svc.Reconcile()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"node-b"}, report.UnreachableNodes)
+	assert.Equal(t, []string{web1.VMName}, report.Skipped)
+	assert.Empty(t, report.OrphanRows)
+	assert.Equal(t, []hosting_service.StatusChange{
+		{ID: web2.ID, VMName: web2.VMName, From: "running", To: "stopped"},
+	}, report.Updated)
+	assert.Equal(t, "running", repo.hosting(1, "web1").Status)
+
+	// 노드가 돌아오면 다시 점검한다
+	b.SetListError(nil)
+	report, err = svc.Reconcile()
+	require.NoError(t, err)
+	assert.Empty(t, report.UnreachableNodes)
+	assert.Empty(t, report.Skipped)
+	assert.Empty(t, report.OrphanRows)
+}
+
+func TestHostingService_Events(t *testing.T) {
+	repo := newMockHostingRepo()
+	hv := hypervisor.NewFakeHypervisor()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, hv)
+
+	op, err := svc.CreateHosting(1, "web", hosting_service.CreateOptions{})
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	vm := repo.hosting(1, "web")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	svc.StartEventListener(ctx)
+
+	mine, unsubscribe := svc.SubscribeEvents(1)
+	defer unsubscribe()
+	other, unsubscribeOther := svc.SubscribeEvents(2)
+	defer unsubscribeOther()
+
+	next := func() hosting_service.VMEvent {
+		t.Helper()
+		select {
+		case ev := <-mine:
+			return ev
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event received")
+			return hosting_service.VMEvent{}
+		}
+	}
+
+	// API를 거치지 않은 크래시도 바로 반영된다
+	hv.SetState(vm.VMName, hosting_service.DomainCrashed)
+	ev := next()
+	assert.Equal(t, hosting_service.EventCrashed, ev.Type)
+	assert.Equal(t, "web", ev.Name)
+	assert.Equal(t, vm.ID, ev.HostingID)
+	assert.Equal(t, "error", ev.Status)
+	assert.Equal(t, "error", vm.Status)
+
+	hv.SetState(vm.VMName, hosting_service.DomainRunning)
+	ev = next()
+	assert.Equal(t, hosting_service.EventStarted, ev.Type)
+	assert.Equal(t, "running", vm.Status)
+
+	// 관리하지 않는 도메인 이벤트는 무시
+	_, err = hv.CreateVM(hosting_service.VMSpec{Name: "manual-vm"}, nil)
+	require.NoError(t, err)
+
+	// 다른 사용자에게는 전달되지 않는다
+	select {
+	case ev := <-other:
+		t.Fatalf("unexpected event for another user: %+v", ev)
+	case ev := <-mine:
+		t.Fatalf("unexpected event for unmanaged domain: %+v", ev)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestHostingService_Console(t *testing.T) {
+	repo := newMockHostingRepo()
+	hv := hypervisor.NewFakeHypervisor()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, hv)
+
+	op, err := svc.CreateHosting(1, "web", hosting_service.CreateOptions{})
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+
+	attach := func(writable bool) *hosting_service.ConsoleClient {
+		t.Helper()
+		ticket, err := svc.IssueConsoleTicket(1, "web", writable)
+		require.NoError(t, err)
+		client, err := svc.AttachConsole(1, ticket.Ticket)
+		require.NoError(t, err)
+		return client
+	}
+	read := func(c *hosting_service.ConsoleClient) string {
+		t.Helper()
+		select {
+		case chunk, ok := <-c.Output():
+			require.True(t, ok, "console closed")
+			return string(chunk)
+		case <-time.After(5 * time.Second):
+			t.Fatal("no console output")
+			return ""
+		}
+	}
+	closed := func(c *hosting_service.ConsoleClient) bool {
+		select {
+		case _, ok := <-c.Output():
+			return !ok
+		case <-time.After(5 * time.Second):
+			return false
+		}
+	}
+
+	// 다른 사용자의 VM이나 티켓은 쓸 수 없다
+	_, err = svc.IssueConsoleTicket(2, "web", true)
+	assert.ErrorIs(t, err, hosting_service.ErrVMNotFound)
+	ticket, err := svc.IssueConsoleTicket(1, "web", true)
+	require.NoError(t, err)
+	assert.True(t, ticket.ExpiresAt.After(time.Now()))
+	_, err = svc.AttachConsole(2, ticket.Ticket)
+	assert.ErrorIs(t, err, hosting_service.ErrConsoleTicket)
+	_, err = svc.AttachConsole(1, "bogus")
+	assert.ErrorIs(t, err, hosting_service.ErrConsoleTicket)
+
+	// 쓰기 접속자 (fake 콘솔은 입력을 그대로 출력한다)
+	writer := attach(true)
+	_, err = writer.Write([]byte("ls\r"))
+	require.NoError(t, err)
+	assert.Equal(t, "ls\r", read(writer))
+
+	// 티켓은 한 번만 쓸 수 있다
+	ticket, err = svc.IssueConsoleTicket(1, "web", false)
+	require.NoError(t, err)
+	viewer, err := svc.AttachConsole(1, ticket.Ticket)
+	require.NoError(t, err)
+	_, err = svc.AttachConsole(1, ticket.Ticket)
+	assert.ErrorIs(t, err, hosting_service.ErrConsoleTicket)
+
+	// 같은 세션의 출력은 모두 받고, 입력은 쓰기 접속자만
+	_, err = viewer.Write([]byte("x"))
+	assert.ErrorIs(t, err, hosting_service.ErrConsoleReadOnly)
+	_, err = writer.Write([]byte("uptime\r"))
+	require.NoError(t, err)
+	assert.Equal(t, "uptime\r", read(writer))
+	assert.Equal(t, "uptime\r", read(viewer))
+
+	// 쓰기 접속자는 세션마다 한 명
+	ticket, err = svc.IssueConsoleTicket(1, "web", true)
+	require.NoError(t, err)
+	_, err = svc.AttachConsole(1, ticket.Ticket)
+	assert.ErrorIs(t, err, hosting_service.ErrConsoleBusy)
+
+	writer.Close()
+	assert.True(t, closed(writer))
+	writer = attach(true)
+
+	// VM이 멈추면 모든 접속자가 끊긴다
+	op, err = svc.StopVM(1, "web", 0)
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	assert.True(t, closed(writer))
+	assert.True(t, closed(viewer))
+
+	_, err = svc.IssueConsoleTicket(1, "web", true)
+	assert.ErrorIs(t, err, hosting_service.ErrVMNotRunning)
+}
+
+// slowConsoleHypervisor - OpenConsole이 노드 응답을 기다리는 동안 멈춰 있는 경우를 흉내 낸다
+type slowConsoleHypervisor struct {
+	*hypervisor.FakeHypervisor
+	mu      sync.Mutex
+	opens   map[string]int
+	release map[string]chan struct{}
+}
+
+func (h *slowConsoleHypervisor) OpenConsole(name string) (io.ReadWriteCloser, error) {
+	h.mu.Lock()
+	h.opens[name]++
+	release := h.release[name]
+	h.mu.Unlock()
+	if release != nil {
+		<-release
+	}
+	return h.FakeHypervisor.OpenConsole(name)
+}
+
+func (h *slowConsoleHypervisor) openCount(name string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.opens[name]
+}
+
+func TestHostingService_ConsoleSlowNode(t *testing.T) {
+	repo := newMockHostingRepo()
+	hv := &slowConsoleHypervisor{FakeHypervisor: hypervisor.NewFakeHypervisor(), opens: map[string]int{}, release: map[string]chan struct{}{}}
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, hv)
+	for _, name := range []string{"web", "db"} {
+		op, err := svc.CreateHosting(1, name, hosting_service.CreateOptions{})
+		done := wait(t, svc, op, err)
+		require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	}
+	web, db := repo.hosting(1, "web"), repo.hosting(1, "db")
+	release := make(chan struct{})
+	hv.release[web.VMName] = release
+
+	type result struct {
+		client *hosting_service.ConsoleClient
+		err    error
+	}
+	attach := func(ref string, writable bool) <-chan result {
+		ticket, err := svc.IssueConsoleTicket(1, ref, writable)
+		require.NoError(t, err)
+		ch := make(chan result, 1)
+		go func() {
+			c, err := svc.AttachConsole(1, ticket.Ticket)
+			ch <- result{c, err}
+		}()
+		return ch
+	}
+
+	// web 콘솔을 여는 동안에도 다른 VM의 콘솔은 바로 붙는다
+	writer := attach("web", true)
+	require.Eventually(t, func() bool { return hv.openCount(web.VMName) == 1 }, 5*time.Second, 10*time.Millisecond)
+	select {
+	case r := <-attach("db", true):
+		require.NoError(t, r.err)
+		r.client.Close()
+	case <-time.After(5 * time.Second):
+		t.Fatal("console attach blocked by another VM's slow node")
+	}
+
+	// 같은 VM의 두 번째 접속자는 기다렸다가 같은 세션에 붙는다 (콘솔은 한 번만 연다)
+	viewer := attach("web", false)
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	w, v := <-writer, <-viewer
+	require.NoError(t, w.err)
+	require.NoError(t, v.err)
+	defer w.client.Close()
+	defer v.client.Close()
+	assert.Equal(t, 1, hv.openCount(web.VMName))
+	assert.Equal(t, 1, hv.openCount(db.VMName))
+}
+
+func TestHostingService_VNC(t *testing.T) {
+	repo := newMockHostingRepo()
+	hv := hypervisor.NewFakeHypervisor()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, hv)
+
+	op, err := svc.CreateHosting(1, "web", hosting_service.CreateOptions{})
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	vm := repo.hosting(1, "web")
+
+	// 도메인에는 VM마다 만든 8자 VNC 비밀번호가 들어간다
+	require.Len(t, vm.VNCPassword, 8)
+	dom, ok := hv.Domain(vm.VMName)
+	require.True(t, ok)
+	assert.Equal(t, vm.VNCPassword, dom.VNCPassword)
+
+	_, err = svc.IssueVNCTicket(2, "web")
+	assert.ErrorIs(t, err, hosting_service.ErrVMNotFound)
+	ticket, err := svc.IssueVNCTicket(1, "web")
+	require.NoError(t, err)
+	assert.Equal(t, vm.VNCPassword, ticket.Password)
+	assert.Equal(t, vm.ID, ticket.HostingID)
+
+	// 다른 사용자는 티켓을 쓸 수 없고, 한 번 시도하면 티켓은 사라진다
+	_, err = svc.ConnectVNC(2, ticket.Ticket)
+	assert.ErrorIs(t, err, hosting_service.ErrVNCTicket)
+	_, err = svc.ConnectVNC(1, ticket.Ticket)
+	assert.ErrorIs(t, err, hosting_service.ErrVNCTicket)
+
+	// fake VNC 서버는 RFB 버전을 먼저 보내고 받은 바이트를 돌려준다
+	ticket, err = svc.IssueVNCTicket(1, "web")
+	require.NoError(t, err)
+	conn, err := svc.ConnectVNC(1, ticket.Ticket)
+	require.NoError(t, err)
+	buf := make([]byte, len(hypervisor.FakeRFBVersion))
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, hypervisor.FakeRFBVersion, string(buf))
+	_, err = conn.Write([]byte("RFB 003.008\n"))
+	require.NoError(t, err)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+	_, err = svc.ConnectVNC(1, ticket.Ticket)
+	assert.ErrorIs(t, err, hosting_service.ErrVNCTicket)
+
+	// VNC 비밀번호 없이 만든 VM
+	vm.VNCPassword = ""
+	_, err = svc.IssueVNCTicket(1, "web")
+	assert.ErrorIs(t, err, hosting_service.ErrNoVNC)
+	vm.VNCPassword = dom.VNCPassword
+
+	op, err = svc.StopVM(1, "web", 0)
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	_, err = svc.IssueVNCTicket(1, "web")
+	assert.ErrorIs(t, err, hosting_service.ErrVMNotRunning)
+}
+
+func TestHostingService_GuestAgent(t *testing.T) {
+	repo := newMockHostingRepo()
+	hv := hypervisor.NewFakeHypervisor()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, hv)
+
+	op, err := svc.CreateHosting(1, "web", hosting_service.CreateOptions{})
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	vm := repo.hosting(1, "web")
+
+	// 1. 실행 중이면 게스트가 보고한 OS와 실제 주소가 상세 정보에 들어간다
+	_, info, err := svc.GetVMDetail(1, "web")
+	require.NoError(t, err)
+	require.NotNil(t, info.Guest)
+	assert.Equal(t, "ubuntu", info.Guest.OSID)
+	require.Len(t, info.Guest.Interfaces, 1)
+	assert.Equal(t, []string{vm.IPAddress + "/24"}, info.Guest.Interfaces[0].Addresses)
+
+	// 2. 비밀번호 재설정은 이미지 기본 계정에 적용된다
+	password, err := svc.ResetGuestPassword(1, "web")
+	require.NoError(t, err)
+	assert.Len(t, password, 16)
+	dom, _ := hv.Domain(vm.VMName)
+	assert.Equal(t, password, dom.GuestPasswords["ubuntu"])
+	_, err = svc.ResetGuestPassword(2, "web")
+	assert.ErrorIs(t, err, hosting_service.ErrVMNotFound)
+
+	// 3. 에이전트가 없으면 상세 조회는 게스트 정보 없이 성공하고, 재설정은 거부된다
+	hv.SetGuestAgent(vm.VMName, false)
+	_, info, err = svc.GetVMDetail(1, "web")
+	require.NoError(t, err)
+	assert.Equal(t, hosting_service.DomainRunning, info.State)
+	assert.Nil(t, info.Guest)
+	_, err = svc.ResetGuestPassword(1, "web")
+	assert.ErrorIs(t, err, hosting_service.ErrGuestAgentUnavailable)
+
+	// 4. 중지된 VM
+	hv.SetGuestAgent(vm.VMName, true)
+	hv.SetState(vm.VMName, hosting_service.DomainShutoff)
+	_, info, err = svc.GetVMDetail(1, "web")
+	require.NoError(t, err)
+	assert.Nil(t, info.Guest)
+	_, err = svc.ResetGuestPassword(1, "web")
+	assert.ErrorIs(t, err, hosting_service.ErrVMNotRunning)
+}
+
+func TestHostingService_Power(t *testing.T) {
+	repo := newMockHostingRepo()
+	hv := hypervisor.NewFakeHypervisor()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, hv)
+
+	op, err := svc.CreateHosting(1, "web", hosting_service.CreateOptions{})
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	vm := repo.hosting(1, "web")
+
+	// 1. 일시 정지 / 재개 (상태에 맞지 않는 요청은 큐에 넣기 전에 거부)
+	_, err = svc.ResumeVM(1, "web")
+	assert.ErrorIs(t, err, hosting_service.ErrVMNotPaused)
+	op, err = svc.PauseVM(1, "web")
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	assert.Equal(t, "paused", vm.Status)
+	_, err = svc.RebootVM(1, "web")
+	assert.ErrorIs(t, err, hosting_service.ErrVMNotRunning)
+
+	op, err = svc.ResumeVM(1, "web")
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	assert.Equal(t, "running", vm.Status)
+
+	// 2. 재부팅과 하드 리셋은 도메인을 끄지 않는다
+	op, err = svc.RebootVM(1, "web")
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	op, err = svc.ResetVM(1, "web")
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	dom, _ := hv.Domain(vm.VMName)
+	assert.Equal(t, 3, dom.Boots)
+	assert.Equal(t, hosting_service.DomainRunning, dom.State)
+
+	// 3. 종료 요청을 무시하는 게스트는 대기 시간이 지나면 강제로 끈다
+	_, err = svc.StopVM(1, "web", time.Hour)
+	assert.ErrorIs(t, err, hosting_service.ErrInvalidStopTimeout)
+	hv.SetIgnoreShutdown(vm.VMName, true)
+	op, err = svc.StopVM(1, "web", time.Second)
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	assert.JSONEq(t, `{"forced":true}`, done.Result)
+	assert.Equal(t, "stopped", vm.Status)
+
+	// 4. 꺼진 VM은 부팅한다
+	op, err = svc.StartVM(1, "web")
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	dom, _ = hv.Domain(vm.VMName)
+	assert.Equal(t, hosting_service.DomainRunning, dom.State)
+	assert.Equal(t, 4, dom.Boots)
+	assert.Equal(t, "running", vm.Status)
+
+	// 5. 종료 요청에 응하는 게스트
+	hv.SetIgnoreShutdown(vm.VMName, false)
+	op, err = svc.StopVM(1, "web", 0)
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	assert.JSONEq(t, `{"forced":false}`, done.Result)
+	assert.Equal(t, "stopped", vm.Status)
+}
+
+func TestHostingService_Recovery(t *testing.T) {
+	repo := newMockHostingRepo()
+	hv := hypervisor.NewFakeHypervisor()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, hv)
+
+	for _, name := range []string{"web1", "web2", "web3"} {
+		op, err := svc.CreateHosting(1, name, hosting_service.CreateOptions{})
+		done := wait(t, svc, op, err)
+		require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+		assert.Equal(t, "running", repo.hosting(1, name).DesiredState)
+	}
+	op, err := svc.StopVM(1, "web2", 0)
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	web1, web2, web3 := repo.hosting(1, "web1"), repo.hosting(1, "web2"), repo.hosting(1, "web3")
+	assert.Equal(t, "stopped", web2.DesiredState)
+
+	// 1. 노드 재부팅 후 running을 원하는 VM만 다시 부팅한다 (autostart는 기본적으로 쓰지 않는다)
+	hv.RebootHost()
+	ops, err := svc.RecoverVMs(context.Background(), 0)
+	require.NoError(t, err)
+	require.Len(t, ops, 2)
+	for _, op := range ops {
+		done := wait(t, svc, op, nil)
+		require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	}
+	for _, h := range []*hosting_service.Hosting{web1, web3} {
+		dom, _ := hv.Domain(h.VMName)
+		assert.Equal(t, hosting_service.DomainRunning, dom.State, h.Name)
+		assert.Equal(t, "running", h.Status)
+	}
+	dom, _ := hv.Domain(web2.VMName)
+	assert.Equal(t, hosting_service.DomainShutoff, dom.State)
+
+	// 2. 크래시된 VM도 다시 부팅하고, 간격을 기다리는 중에 멈추면 그때까지 등록한 작업만 돌려준다
+	hv.SetState(web1.VMName, hosting_service.DomainCrashed)
+	hv.SetState(web3.VMName, hosting_service.DomainCrashed)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	ops, err = svc.RecoverVMs(ctx, time.Minute)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Len(t, ops, 1)
+	done = wait(t, svc, ops[0], nil)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+
+	// 3. autostart를 켜면 desired state가 libvirt autostart에도 반영된다
+	svc.SetDomainAutostart(true)
+	op, err = svc.StartVM(1, "web2")
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	dom, _ = hv.Domain(web2.VMName)
+	assert.True(t, dom.Autostart)
+	assert.Equal(t, "running", web2.DesiredState)
+
+	op, err = svc.StopVM(1, "web2", 0)
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	dom, _ = hv.Domain(web2.VMName)
+	assert.False(t, dom.Autostart)
+	assert.Equal(t, "stopped", web2.DesiredState)
+}
+
+// newTestPool - 주소마다 FakeHypervisor 하나를 둔 NodePool
+func newTestPool() (*hypervisor.NodePool, map[string]*hypervisor.FakeHypervisor) {
+	fakes := map[string]*hypervisor.FakeHypervisor{}
+	var mu sync.Mutex
+	pool := hypervisor.NewNodePool(func(address string) (hosting_service.Hypervisor, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if fakes[address] == nil {
+			fakes[address] = hypervisor.NewFakeHypervisor()
+		}
+		return fakes[address], nil
+	})
+	return pool, fakes
+}
+
+func TestHostingService_NodeScheduling(t *testing.T) {
+	repo := newMockHostingRepo()
+	pool, fakes := newTestPool()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, pool)
+	require.NoError(t, svc.SetQuota(&hosting_service.Quota{UserID: 1, MaxVMs: 10, MaxVCPUs: 32, MaxMemoryMB: 32768, MaxDiskGB: 400}))
+
+	require.NoError(t, svc.CreateNode(&hosting_service.Node{Name: "node-a", Address: "agent://a:5004", VCPUs: 4, MemoryMB: 4096, DiskGB: 40, Schedulable: true}))
+	require.NoError(t, svc.CreateNode(&hosting_service.Node{Name: "node-b", Address: "agent://b:5004", VCPUs: 8, MemoryMB: 8192, DiskGB: 80, Schedulable: true}))
+	assert.Error(t, svc.CreateNode(&hosting_service.Node{Name: "node-c", Address: "10.0.0.3", VCPUs: 4, MemoryMB: 4096, DiskGB: 40}))
+
+	create := func(name, plan string) *hosting_service.Hosting {
+		op, err := svc.CreateHosting(1, name, hosting_service.CreateOptions{Plan: plan})
+		done := wait(t, svc, op, err)
+		require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+		return repo.hosting(1, name)
+	}
+
+	// 1. spread: 배치 후 남는 비율이 큰 노드, 같으면 이름순
+	web1 := create("web1", "small")
+	assert.Equal(t, "node-b", web1.NodeName)
+	_, ok := fakes["agent://b:5004"].Domain(web1.VMName)
+	assert.True(t, ok)
+	web2 := create("web2", "small")
+	assert.Equal(t, "node-a", web2.NodeName)
+
+	// 2. 이후 작업은 VM이 있는 노드로 간다
+	op, err := svc.StopVM(1, "web1", 0)
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	dom, _ := fakes["agent://b:5004"].Domain(web1.VMName)
+	assert.Equal(t, hosting_service.DomainShutoff, dom.State)
+	dom, _ = fakes["agent://a:5004"].Domain(web2.VMName)
+	assert.Equal(t, hosting_service.DomainRunning, dom.State)
+
+	// 3. 플랜의 NodeLabels와 레이블이 모두 맞는 노드에만 배치한다
+	require.NoError(t, svc.CreatePlan(&hosting_service.HostingPlan{Name: "gpu", CPU: 1, MemoryMB: 1024, DiskGB: 10, NodeLabels: map[string]string{"gpu": "true"}}))
+	_, err = svc.CreateHosting(1, "gpu1", hosting_service.CreateOptions{Plan: "gpu"})
+	assert.ErrorIs(t, err, hosting_service.ErrNoNodeAvailable)
+	require.NoError(t, svc.UpdateNode(&hosting_service.Node{Name: "node-a", Address: "agent://a:5004", VCPUs: 4, MemoryMB: 4096, DiskGB: 40, Labels: map[string]string{"gpu": "true"}, Schedulable: true}))
+	gpu1 := create("gpu1", "gpu")
+	assert.Equal(t, "node-a", gpu1.NodeName)
+
+	// 4. 용량이 남은 노드가 없으면 거절하고, 사양 변경도 노드 용량을 넘지 못한다
+	big1 := create("big1", "large")
+	assert.Equal(t, "node-b", big1.NodeName)
+	_, err = svc.CreateHosting(1, "big2", hosting_service.CreateOptions{Plan: "large"})
+	assert.ErrorIs(t, err, hosting_service.ErrNoNodeAvailable)
+	_, err = svc.ResizeVM(1, "web2", "large")
+	assert.ErrorIs(t, err, hosting_service.ErrNodeCapacity)
+
+	nodes, err := svc.ListNodes()
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	assert.Equal(t, hosting_service.Usage{VMs: 2, VCPUs: 2, MemoryMB: 2048, DiskGB: 20}, nodes[0].Allocated)
+	assert.Equal(t, hosting_service.Usage{VMs: 2, VCPUs: 5, MemoryMB: 5120, DiskGB: 50}, nodes[1].Allocated)
+	assert.True(t, nodes[0].Connected)
+
+	// 5. 배치 중지된 노드는 건너뛰고, VM이 있는 노드는 삭제할 수 없다
+	require.NoError(t, svc.UpdateNode(&hosting_service.Node{Name: "node-b", Address: "agent://b:5004", VCPUs: 8, MemoryMB: 8192, DiskGB: 80}))
+	web3 := create("web3", "small")
+	assert.Equal(t, "node-a", web3.NodeName)
+	assert.ErrorIs(t, svc.DeleteNode("node-b"), hosting_service.ErrNodeInUse)
+
+	// 6. pack: 배치 후 남는 비율이 작은 노드 (spread였다면 node-b)
+	require.NoError(t, svc.UpdateNode(&hosting_service.Node{Name: "node-b", Address: "agent://b:5004", VCPUs: 8, MemoryMB: 8192, DiskGB: 80, Schedulable: true}))
+	require.NoError(t, svc.SetSchedulerPolicy(hosting_service.SchedulerPack))
+	web4 := create("web4", "small")
+	assert.Equal(t, "node-a", web4.NodeName)
+	assert.ErrorIs(t, svc.SetSchedulerPolicy("random"), hosting_service.ErrInvalidSchedulerPolicy)
+
+	// 7. 삭제하면 노드 용량이 돌아온다
+	op, err = svc.DeleteVM(1, "big1")
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	_, ok = fakes["agent://b:5004"].Domain(big1.VMName)
+	assert.False(t, ok)
+	nodes, err = svc.ListNodes()
+	require.NoError(t, err)
+	assert.Equal(t, hosting_service.Usage{VMs: 1, VCPUs: 1, MemoryMB: 1024, DiskGB: 10}, nodes[1].Allocated)
+}
+func TestHostingService_NodeReconnect(t *testing.T) {
+	repo := newMockHostingRepo()
+	pool, fakes := newTestPool()
+	pool.SetHealthCheckInterval(0)
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, pool)
+	require.NoError(t, svc.SetQuota(&hosting_service.Quota{UserID: 1, MaxVMs: 10, MaxVCPUs: 32, MaxMemoryMB: 32768, MaxDiskGB: 400}))
+
+	require.NoError(t, svc.CreateNode(&hosting_service.Node{Name: "node-a", Address: "agent://a:5004", VCPUs: 4, MemoryMB: 4096, DiskGB: 40, Schedulable: true}))
+	require.NoError(t, svc.CreateNode(&hosting_service.Node{Name: "node-b", Address: "agent://b:5004", VCPUs: 8, MemoryMB: 8192, DiskGB: 80, Schedulable: true}))
+	create := func(name string) *hosting_service.Hosting {
+		t.Helper()
+		op, err := svc.CreateHosting(1, name, hosting_service.CreateOptions{})
+		done := wait(t, svc, op, err)
+		require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+		return repo.hosting(1, name)
+	}
+	assert.Equal(t, "node-b", create("web1").NodeName)
+
+	// 연결해 둔 노드가 응답하지 않으면 연결되지 않은 노드로 보고 배치하지 않는다
+	fakes["agent://b:5004"].SetListError(errors.New("libvirt-agent 요청 실패: connection refused"))
+	nodes, err := svc.ListNodes()
+	require.NoError(t, err)
+	assert.True(t, nodes[0].Connected)
+	assert.False(t, nodes[1].Connected)
+	assert.Contains(t, nodes[1].Error, "connection refused")
+	assert.Equal(t, "node-a", create("web2").NodeName)
+
+	// 노드가 살아나면 다시 연결한다
+	fakes["agent://b:5004"].SetListError(nil)
+	nodes, err = svc.ListNodes()
+	require.NoError(t, err)
+	assert.True(t, nodes[1].Connected)
+	assert.Equal(t, "node-b", create("web3").NodeName)
+}
+
+func TestHostingService_NodeNetworks(t *testing.T) {
+	repo := newMockHostingRepo()
+	pool, fakes := newTestPool()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, pool)
+	require.NoError(t, svc.SetQuota(&hosting_service.Quota{UserID: 1, MaxVMs: 10, MaxVCPUs: 32, MaxMemoryMB: 32768, MaxDiskGB: 400}))
+
+	require.NoError(t, svc.CreateNode(&hosting_service.Node{Name: "node-a", Address: "agent://a:5004", VCPUs: 4, MemoryMB: 4096, DiskGB: 40, Schedulable: true}))
+	require.NoError(t, svc.CreateNode(&hosting_service.Node{Name: "node-b", Address: "agent://b:5004", VCPUs: 8, MemoryMB: 8192, DiskGB: 80, Schedulable: true}))
+	require.NoError(t, fakes["agent://a:5004"].SetNetwork("10.0.1.0/24"))
+
+	// 네트워크가 겹치지 않아도 VM은 놓인 노드의 네트워크에서 IP를 받는다
+	for _, name := range []string{"web1", "web2"} {
+		op, err := svc.CreateHosting(1, name, hosting_service.CreateOptions{Plan: "small"})
+		done := wait(t, svc, op, err)
+		require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	}
+	web1, web2 := repo.hosting(1, "web1"), repo.hosting(1, "web2")
+	assert.Equal(t, "node-b", web1.NodeName)
+	assert.Equal(t, "192.168.122.2", web1.IPAddress)
+	assert.Equal(t, "node-a", web2.NodeName)
+	assert.Equal(t, "10.0.1.2", web2.IPAddress)
+
+	// 노드가 여러 개면 풀 전체의 IP는 고를 수 없다
+	_, err := pool.UsableIPs(nil)
+	assert.Error(t, err)
+}
+
+func TestHostingService_Metrics(t *testing.T) {
+	repo := newMockHostingRepo()
+	hv := hypervisor.NewFakeHypervisor()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, hv)
+
+	op, err := svc.CreateHosting(1, "web", hosting_service.CreateOptions{Plan: "medium"})
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	vm := repo.hosting(1, "web")
+
+	// 첫 샘플은 기준값으로만 쓴다
+	base := time.Now().Truncate(time.Hour).Add(-3 * time.Hour)
+	require.NoError(t, svc.CollectMetrics(base.Add(10*time.Minute)))
+
+	// 1분 동안 vCPU 2개 중 1개를 다 쓰고 디스크/네트워크를 쓴 것처럼
+	hv.AddUsage(vm.VMName, hosting_service.DomainStats{
+		CPUTimeNs:       uint64(60 * time.Second),
+		MemoryUnusedKB:  1024 * 1024,
+		BlockReadBytes:  60 * 1000,
+		BlockWriteBytes: 60 * 2000,
+		NetRxBytes:      60 * 3000,
+		NetTxBytes:      60 * 4000,
+	})
+	require.NoError(t, svc.CollectMetrics(base.Add(11*time.Minute)))
+	// 다음 1분은 쉬었다
+	require.NoError(t, svc.CollectMetrics(base.Add(12*time.Minute)))
+
+	metrics, err := svc.GetMetrics(1, hosting_service.MetricsQuery{From: base, To: base.Add(time.Hour), Step: time.Minute})
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, vm.ID, metrics[0].ID)
+	points := metrics[0].Points
+	require.Len(t, points, 2)
+	assert.True(t, points[0].Time.Equal(base.Add(11*time.Minute)))
+	assert.InDelta(t, 50, points[0].CPUPercent, 0.01)
+	assert.Equal(t, uint64(2048*1024), points[0].MemoryKB)
+	assert.Equal(t, uint64(1024*1024), points[0].MemoryUsedKB)
+	assert.InDelta(t, 1000, points[0].DiskReadBps, 0.01)
+	assert.InDelta(t, 2000, points[0].DiskWriteBps, 0.01)
+	assert.InDelta(t, 3000, points[0].NetRxBps, 0.01)
+	assert.InDelta(t, 4000, points[0].NetTxBps, 0.01)
+	assert.InDelta(t, 0, points[1].CPUPercent, 0.01)
+
+	// step 간격으로 평균을 낸다
+	metrics, err = svc.GetMetrics(1, hosting_service.MetricsQuery{VM: "web", From: base, To: base.Add(time.Hour), Step: 30 * time.Minute})
+	require.NoError(t, err)
+	require.Len(t, metrics[0].Points, 1)
+	assert.True(t, metrics[0].Points[0].Time.Equal(base))
+	assert.InDelta(t, 25, metrics[0].Points[0].CPUPercent, 0.01)
+	assert.InDelta(t, 500, metrics[0].Points[0].DiskReadBps, 0.01)
+
+	// 정시가 지나면 지난 시간이 1시간 평균으로 합쳐진다
+	require.NoError(t, svc.CollectMetrics(base.Add(time.Hour+time.Minute)))
+	metrics, err = svc.GetMetrics(1, hosting_service.MetricsQuery{From: base, To: base.Add(2 * time.Hour), Step: time.Hour})
+	require.NoError(t, err)
+	require.Len(t, metrics[0].Points, 1)
+	assert.True(t, metrics[0].Points[0].Time.Equal(base))
+	assert.InDelta(t, 25, metrics[0].Points[0].CPUPercent, 0.01)
+
+	// 멈춘 VM은 값이 생기지 않는다 (정시 직후 한 점은 12분부터 쉬었던 구간)
+	op, err = svc.StopVM(1, "web", 0)
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	require.NoError(t, svc.CollectMetrics(base.Add(time.Hour+2*time.Minute)))
+	metrics, err = svc.GetMetrics(1, hosting_service.MetricsQuery{From: base.Add(time.Hour), To: base.Add(2 * time.Hour), Step: time.Minute})
+	require.NoError(t, err)
+	assert.Len(t, metrics[0].Points, 1)
+
+	// 잘못된 범위
+	for _, q := range []hosting_service.MetricsQuery{
+		{From: base.Add(time.Hour), To: base, Step: time.Minute},
+		{From: base, To: base.Add(time.Hour), Step: time.Second},
+		{From: base.Add(-48 * time.Hour), To: base, Step: time.Minute},
+		{From: base.Add(-24 * time.Hour), To: base, Step: time.Minute},
+	} {
+		_, err = svc.GetMetrics(1, q)
+		assert.ErrorIs(t, err, hosting_service.ErrInvalidMetricsQuery, "%+v", q)
+	}
+	_, err = svc.GetMetrics(2, hosting_service.MetricsQuery{VM: "web", From: base, To: base.Add(time.Hour), Step: time.Minute})
+	assert.ErrorIs(t, err, hosting_service.ErrVMNotFound)
+
+	// step을 생략하면 해상도와 점 수 제한에 맞게 고른다 (1분 값 보관 기간 밖이면 1시간)
+	metrics, err = svc.GetMetrics(1, hosting_service.MetricsQuery{From: base.Add(-30 * time.Hour), To: base.Add(2 * time.Hour)})
+	require.NoError(t, err)
+	assert.Len(t, metrics[0].Points, 1)
+}
+
+func TestHostingService_Snapshots(t *testing.T) {
+	repo := newMockHostingRepo()
+	hv := hypervisor.NewFakeHypervisor()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, hv)
+
+	op, err := svc.CreateHosting(1, "web", hosting_service.CreateOptions{Plan: "medium"})
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	vm := repo.hosting(1, "web")
+
+	// 실행 중 스냅샷 → 중지 → 복원하면 다시 실행 상태
+	op, err = svc.CreateSnapshot(1, "web", "before-upgrade", "apt upgrade 전")
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	assert.Equal(t, []string{"before-upgrade"}, hv.Snapshots(vm.VMName))
+
+	snaps, err := svc.ListSnapshots(1, "web")
+	require.NoError(t, err)
+	require.Len(t, snaps, 1)
+	snap := snaps[0]
+	assert.Equal(t, vm.ID, snap.HostingID)
+	assert.Equal(t, "running", snap.VMStatus)
+
+	_, err = svc.CreateSnapshot(1, "web", "before-upgrade", "")
+	assert.ErrorIs(t, err, hosting_service.ErrSnapshotNameTaken)
+	_, err = svc.CreateSnapshot(1, "web", "Bad Name", "")
+	assert.ErrorIs(t, err, hosting_service.ErrInvalidSnapshotName)
+
+	op, err = svc.StopVM(1, "web", 0)
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+
+	op, err = svc.RevertSnapshot(1, snap.ID)
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	d, _ := hv.Domain(vm.VMName)
+	assert.Equal(t, hosting_service.DomainRunning, d.State)
+	assert.Equal(t, "running", repo.hosting(1, "web").Status)
+
+	// 다른 사용자는 스냅샷을 볼 수 없다
+	_, err = svc.RevertSnapshot(2, snap.ID)
+	assert.ErrorIs(t, err, hosting_service.ErrSnapshotNotFound)
+	all, err := svc.ListSnapshots(2, "")
+	require.NoError(t, err)
+	assert.Empty(t, all)
+
+	// medium 플랜은 VM당 3개까지
+	for _, name := range []string{"two", "three"} {
+		op, err = svc.CreateSnapshot(1, "web", name, "")
+		done = wait(t, svc, op, err)
+		require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	}
+	_, err = svc.CreateSnapshot(1, "web", "four", "")
+	assert.ErrorIs(t, err, hosting_service.ErrSnapshotLimit)
+
+	op, err = svc.DeleteSnapshot(1, snap.ID)
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	assert.Equal(t, []string{"three", "two"}, hv.Snapshots(vm.VMName))
+	_, err = svc.DeleteSnapshot(1, snap.ID)
+	assert.ErrorIs(t, err, hosting_service.ErrSnapshotNotFound)
+
+	// VM을 지우면 스냅샷도 함께 정리된다
+	op, err = svc.DeleteVM(1, "web")
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	assert.Empty(t, hv.Snapshots(vm.VMName))
+	all, err = svc.ListSnapshots(1, "")
+	require.NoError(t, err)
+	assert.Empty(t, all)
+}
+
+func TestHostingService_Backups(t *testing.T) {
+	repo := newMockHostingRepo()
+	hv := hypervisor.NewFakeHypervisor()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, hv)
+
+	op, err := svc.CreateHosting(1, "web", hosting_service.CreateOptions{Plan: "small"})
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	vm := repo.hosting(1, "web")
+
+	// 실행 중인 VM도 백업할 수 있다
+	op, err = svc.CreateBackup(1, "web")
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+
+	backups, err := svc.ListBackups(1, "web")
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	b := backups[0]
+	assert.Equal(t, hosting_service.BackupManual, b.Kind)
+	assert.Equal(t, "small", b.Plan)
+	assert.True(t, hv.BackupExists(b.Path))
+
+	_, err = svc.ListBackups(2, "web")
+	assert.ErrorIs(t, err, hosting_service.ErrVMNotFound)
+	_, err = svc.RestoreBackup(2, b.ID)
+	assert.ErrorIs(t, err, hosting_service.ErrBackupNotFound)
+
+	// 제자리 복원은 중지된 VM에만, 이후 더 큰 플랜으로 바꿨다면 디스크를 다시 늘린다
+	_, err = svc.RestoreBackup(1, b.ID)
+	assert.ErrorIs(t, err, hosting_service.ErrVMRunning)
+
+	op, err = svc.ResizeVM(1, "web", "medium")
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	op, err = svc.StopVM(1, "web", 0)
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+
+	op, err = svc.RestoreBackup(1, b.ID)
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	d, _ := hv.Domain(vm.VMName)
+	assert.Equal(t, 20, d.DiskGB)
+	assert.Equal(t, hosting_service.DomainShutoff, d.State)
+
+	// 백업으로 새 VM 생성
+	op, err = svc.CloneBackup(1, b.ID, "web-copy", "")
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	clone := repo.hosting(1, "web-copy")
+	assert.Equal(t, "small", clone.Plan)
+	assert.Equal(t, "running", clone.Status)
+
+	// VM을 삭제해도 백업은 남아 복원할 수 있다
+	op, err = svc.DeleteVM(1, "web")
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	all, err := svc.ListBackups(1, "")
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+	_, err = svc.RestoreBackup(1, b.ID)
+	assert.ErrorIs(t, err, hosting_service.ErrVMNotFound)
+
+	op, err = svc.DeleteBackup(1, b.ID)
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	assert.False(t, hv.BackupExists(b.Path))
+	all, err = svc.ListBackups(1, "")
+	require.NoError(t, err)
+	assert.Empty(t, all)
+}
+
+func TestHostingService_BackupNodes(t *testing.T) {
+	repo := newMockHostingRepo()
+	pool, fakes := newTestPool()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, pool)
+	require.NoError(t, svc.SetQuota(&hosting_service.Quota{UserID: 1, MaxVMs: 10, MaxVCPUs: 32, MaxMemoryMB: 32768, MaxDiskGB: 400}))
+
+	require.NoError(t, svc.CreateNode(&hosting_service.Node{Name: "node-a", Address: "agent://a:5004", VCPUs: 4, MemoryMB: 4096, DiskGB: 40, Schedulable: true}))
+	require.NoError(t, svc.CreateNode(&hosting_service.Node{Name: "node-b", Address: "agent://b:5004", VCPUs: 8, MemoryMB: 8192, DiskGB: 80, Schedulable: true}))
+	a, b := fakes["agent://a:5004"], fakes["agent://b:5004"]
+
+	op, err := svc.CreateHosting(1, "web", hosting_service.CreateOptions{Plan: "small"})
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	vm := repo.hosting(1, "web")
+	require.Equal(t, "node-b", vm.NodeName)
+
+	// 1. 백업 파일은 VM이 있는 노드에 만들고 그 노드를 기록한다
+	op, err = svc.CreateBackup(1, "web")
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	backups, err := svc.ListBackups(1, "web")
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	bk := backups[0]
+	assert.Equal(t, "node-b", bk.NodeName)
+	assert.True(t, b.BackupExists(bk.Path))
+	assert.False(t, a.BackupExists(bk.Path))
+
+	// 2. 백업에서 만드는 VM은 스케줄러가 node-a를 고르더라도 백업 파일이 있는 node-b에 놓는다
+	op, err = svc.CloneBackup(1, bk.ID, "web-copy", "")
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	clone := repo.hosting(1, "web-copy")
+	assert.Equal(t, "node-b", clone.NodeName)
+	_, ok := b.Domain(clone.VMName)
+	assert.True(t, ok)
+
+	// 3. 다른 노드로 옮긴 VM에는 복원할 수 없다
+	op, err = svc.StopVM(1, "web", 0)
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	op, err = svc.MigrateVM(vm.ID, hosting_service.MigrateOptions{Node: "node-a"})
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	_, err = svc.RestoreBackup(1, bk.ID)
+	assert.ErrorIs(t, err, hosting_service.ErrBackupOtherNode)
+
+	// 4. 삭제는 백업 파일이 있는 노드에서 한다
+	op, err = svc.DeleteBackup(1, bk.ID)
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	assert.False(t, b.BackupExists(bk.Path))
+}
+
+func TestHostingService_BackupSchedule(t *testing.T) {
+	repo := newMockHostingRepo()
+	hv := hypervisor.NewFakeHypervisor()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, hv)
+
+	op, err := svc.CreateHosting(1, "web", hosting_service.CreateOptions{})
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+
+	_, err = svc.GetBackupSchedule(1, "web")
+	assert.ErrorIs(t, err, hosting_service.ErrScheduleNotFound)
+	err = svc.SetBackupSchedule(1, "web", &hosting_service.BackupSchedule{Cron: "61 * * * *", KeepDaily: 1})
+	assert.ErrorIs(t, err, hosting_service.ErrInvalidSchedule)
+	err = svc.SetBackupSchedule(1, "web", &hosting_service.BackupSchedule{Cron: "@daily"})
+	assert.ErrorIs(t, err, hosting_service.ErrInvalidSchedule)
+
+	require.NoError(t, svc.SetBackupSchedule(1, "web", &hosting_service.BackupSchedule{Cron: "0 3 * * *", KeepDaily: 1}))
+	sched, err := svc.GetBackupSchedule(1, "web")
+	require.NoError(t, err)
+
+	// 다음 실행 시각 전에는 아무것도 하지 않는다
+	ops, err := svc.RunDueBackups(sched.LastRunAt)
+	require.NoError(t, err)
+	assert.Empty(t, ops)
+
+	// 하루에 하나만 보관하므로 같은 날 두 번째 예약 백업이 첫 번째를 대체한다
+	now := sched.LastRunAt
+	for i := 0; i < 2; i++ {
+		now = now.Add(24 * time.Hour)
+		ops, err = svc.RunDueBackups(now)
+		require.NoError(t, err)
+		require.Len(t, ops, 1)
+		done = wait(t, svc, ops[0], nil)
+		require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	}
+	backups, err := svc.ListBackups(1, "web")
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, hosting_service.BackupScheduled, backups[0].Kind)
+
+	// 이미 실행한 시각은 다시 실행하지 않는다
+	ops, err = svc.RunDueBackups(now)
+	require.NoError(t, err)
+	assert.Empty(t, ops)
+
+	require.NoError(t, svc.DeleteBackupSchedule(1, "web"))
+	_, err = svc.GetBackupSchedule(1, "web")
+	assert.ErrorIs(t, err, hosting_service.ErrScheduleNotFound)
+}
+
+func TestExpiredBackups(t *testing.T) {
+	day := func(d, h int) time.Time { return time.Date(2025, 1, d, h, 0, 0, 0, time.UTC) }
+	var backups []*hosting_service.Backup
+	// 2025-01-01(수) ~ 01-20 매일 03시, 01-20은 15시에 한 번 더
+	for d := 1; d <= 20; d++ {
+		backups = append(backups, &hosting_service.Backup{ID: int64(d), Kind: hosting_service.BackupScheduled, CreatedAt: day(d, 3)})
+	}
+	backups = append(backups,
+		&hosting_service.Backup{ID: 21, Kind: hosting_service.BackupScheduled, CreatedAt: day(20, 15)},
+		&hosting_service.Backup{ID: 22, Kind: hosting_service.BackupManual, CreatedAt: day(1, 1)},
+	)
+
+	expired := hosting_service.ExpiredBackups(backups, 3, 2)
+	kept := map[int64]bool{}
+	for _, b := range backups {
+		kept[b.ID] = true
+	}
+	for _, b := range expired {
+		kept[b.ID] = false
+	}
+
+	var keptIDs []int64
+	for id := int64(1); id <= 22; id++ {
+		if kept[id] {
+			keptIDs = append(keptIDs, id)
+		}
+	}
+	// 일별: 20일(15시), 19일, 18일 / 주별: 13~19일 주의 19일, 20일이 속한 주의 21번 / 수동 백업
+	assert.Equal(t, []int64{18, 19, 21, 22}, keptIDs)
+}
+
+func TestHostingService_Migration(t *testing.T) {
+	repo := newMockHostingRepo()
+	pool, fakes := newTestPool()
+	_, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, pool)
+	require.NoError(t, svc.SetQuota(&hosting_service.Quota{UserID: 1, MaxVMs: 10, MaxVCPUs: 32, MaxMemoryMB: 32768, MaxDiskGB: 400}))
+
+	require.NoError(t, svc.CreateNode(&hosting_service.Node{Name: "node-a", Address: "agent://a:5004", VCPUs: 4, MemoryMB: 4096, DiskGB: 40, Schedulable: true}))
+	require.NoError(t, svc.CreateNode(&hosting_service.Node{Name: "node-b", Address: "agent://b:5004", VCPUs: 8, MemoryMB: 8192, DiskGB: 80, Schedulable: true}))
+	a, b := fakes["agent://a:5004"], fakes["agent://b:5004"]
+	a.ListenMigration("qemu+tcp://a/system")
+	b.ListenMigration("qemu+tcp://b/system")
+
+	op, err := svc.CreateHosting(1, "web1", hosting_service.CreateOptions{Plan: "small"})
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	web1 := repo.hosting(1, "web1")
+	require.Equal(t, "node-b", web1.NodeName)
+	dom, _ := b.Domain(web1.VMName)
+	diskPath := dom.DiskPath
+
+	// 1. 대상을 생략하면 지금 노드를 뺀 노드 중에서 고르고, 실행 중이면 live로 옮긴다
+	op, err = svc.MigrateVM(web1.ID, hosting_service.MigrateOptions{})
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	assert.JSONEq(t, `{"from":"node-b","to":"node-a","mode":"live","shared_storage":false}`, done.Result)
+	web1 = repo.hosting(1, "web1")
+	assert.Equal(t, "node-a", web1.NodeName)
+	assert.Equal(t, "running", web1.Status)
+	dom, ok := a.Domain(web1.VMName)
+	require.True(t, ok)
+	assert.Equal(t, hosting_service.DomainRunning, dom.State)
+	_, ok = b.Domain(web1.VMName)
+	assert.False(t, ok)
+	assert.False(t, b.DiskExists(diskPath))
+	assert.True(t, a.DiskExists(diskPath))
+
+	// 원본 노드의 stopped/undefined 이벤트가 상태를 바꾸지 않는다
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, "running", repo.hosting(1, "web1").Status)
+
+	// 2. 이전 후 작업은 새 노드로 간다
+	op, err = svc.StopVM(1, "web1", 0)
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	dom, _ = a.Domain(web1.VMName)
+	assert.Equal(t, hosting_service.DomainShutoff, dom.State)
+
+	// 3. 잘못된 요청은 큐에 넣기 전에 거절한다
+	_, err = svc.MigrateVM(web1.ID, hosting_service.MigrateOptions{Node: "node-a"})
+	assert.ErrorIs(t, err, hosting_service.ErrMigrationSameNode)
+	_, err = svc.MigrateVM(web1.ID, hosting_service.MigrateOptions{Node: "node-x"})
+	assert.ErrorIs(t, err, hosting_service.ErrNodeNotFound)
+	_, err = svc.MigrateVM(web1.ID, hosting_service.MigrateOptions{Mode: "warm"})
+	assert.ErrorIs(t, err, hosting_service.ErrInvalidMigrationMode)
+	_, err = svc.MigrateVM(web1.ID, hosting_service.MigrateOptions{Mode: hosting_service.MigrationLive})
+	assert.ErrorIs(t, err, hosting_service.ErrVMNotRunning)
+	_, err = svc.MigrateVM(9999, hosting_service.MigrateOptions{})
+	assert.ErrorIs(t, err, hosting_service.ErrVMNotFound)
+
+	// 4. 꺼져 있으면 cold: 인스턴스 파일을 복사하고 대상에 꺼진 채로 정의한다
+	op, err = svc.MigrateVM(web1.ID, hosting_service.MigrateOptions{Node: "node-b"})
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	assert.JSONEq(t, `{"from":"node-a","to":"node-b","mode":"cold","shared_storage":false}`, done.Result)
+	web1 = repo.hosting(1, "web1")
+	assert.Equal(t, "node-b", web1.NodeName)
+	assert.Equal(t, "stopped", web1.Status)
+	dom, ok = b.Domain(web1.VMName)
+	require.True(t, ok)
+	assert.Equal(t, hosting_service.DomainShutoff, dom.State)
+	_, ok = a.Domain(web1.VMName)
+	assert.False(t, ok)
+	assert.False(t, a.DiskExists(diskPath))
+
+	// 5. 실행 중인 VM은 cold로 옮길 수 없다
+	op, err = svc.StartVM(1, "web1")
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	_, err = svc.MigrateVM(web1.ID, hosting_service.MigrateOptions{Mode: hosting_service.MigrationCold})
+	assert.ErrorIs(t, err, hosting_service.ErrVMRunning)
+
+	// 6. live migration이 실패하면 VM은 원본에서 계속 실행되고 대상에 만든 디스크는 지운다
+	b.SetMigrationError(errors.New("connection reset"))
+	op, err = svc.MigrateVM(web1.ID, hosting_service.MigrateOptions{})
+	done = wait(t, svc, op, err)
+	assert.Equal(t, hosting_service.OpRolledBack, done.Status)
+	assert.Equal(t, hosting_service.StepMigratingVM, done.Step)
+	web1 = repo.hosting(1, "web1")
+	assert.Equal(t, "node-b", web1.NodeName)
+	assert.Equal(t, "running", web1.Status)
+	dom, _ = b.Domain(web1.VMName)
+	assert.Equal(t, hosting_service.DomainRunning, dom.State)
+	assert.False(t, a.DiskExists(diskPath))
+	b.SetMigrationError(nil)
+
+	// 7. 스냅샷이 있으면 옮길 수 없다
+	op, err = svc.CreateSnapshot(1, "web1", "before", "")
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	_, err = svc.MigrateVM(web1.ID, hosting_service.MigrateOptions{})
+	assert.ErrorIs(t, err, hosting_service.ErrMigrationSnapshots)
+}
+
+func TestHostingService_MigrationReaddress(t *testing.T) {
+	repo := newMockHostingRepo()
+	pool, fakes := newTestPool()
+	nginxAgent, agentAddr := newFakeNginxAgent(t)
+	svc := newTestService(t, repo, newMockOperationRepo(), agentAddr, pool)
+	require.NoError(t, svc.SetQuota(&hosting_service.Quota{UserID: 1, MaxVMs: 10, MaxVCPUs: 32, MaxMemoryMB: 32768, MaxDiskGB: 400}))
+
+	require.NoError(t, svc.CreateNode(&hosting_service.Node{Name: "node-a", Address: "agent://a:5004", VCPUs: 4, MemoryMB: 4096, DiskGB: 40, Schedulable: true}))
+	require.NoError(t, svc.CreateNode(&hosting_service.Node{Name: "node-b", Address: "agent://b:5004", VCPUs: 8, MemoryMB: 8192, DiskGB: 80, Schedulable: true}))
+	a, b := fakes["agent://a:5004"], fakes["agent://b:5004"]
+	require.NoError(t, a.SetNetwork("10.0.1.0/24"))
+	a.ListenMigration("qemu+tcp://a/system")
+	b.ListenMigration("qemu+tcp://b/system")
+	_, netA, _ := net.ParseCIDR("10.0.1.0/24")
+	_, netB, _ := net.ParseCIDR("192.168.122.0/24")
+
+	op, err := svc.CreateHosting(1, "web1", hosting_service.CreateOptions{Plan: "small"})
+	done := wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	web1 := repo.hosting(1, "web1")
+	require.Equal(t, "node-b", web1.NodeName)
+
+	// 1. live: 대상 네트워크의 새 IP를 할당해 게스트, hostings, upstream을 함께 바꾼다
+	op, err = svc.MigrateVM(web1.ID, hosting_service.MigrateOptions{Node: "node-a"})
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	web1 = repo.hosting(1, "web1")
+	assert.Equal(t, "node-a", web1.NodeName)
+	assert.Equal(t, "running", web1.Status)
+	assert.True(t, netA.Contains(net.ParseIP(web1.IPAddress)), web1.IPAddress)
+	assert.JSONEq(t, `{"from":"node-b","to":"node-a","mode":"live","shared_storage":false,"ip":"`+web1.IPAddress+`"}`, done.Result)
+	dom, ok := a.Domain(web1.VMName)
+	require.True(t, ok)
+	assert.Equal(t, web1.IPAddress, dom.IP.String())
+	assert.Equal(t, web1.IPAddress, nginxAgent.upstream(web1.VMName))
+
+	// 2. cold: 대상에서 잠깐 부팅해 IP를 바꾸고 다시 끈 상태로 둔다
+	op, err = svc.StopVM(1, "web1", 0)
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	op, err = svc.MigrateVM(web1.ID, hosting_service.MigrateOptions{Node: "node-b"})
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	web1 = repo.hosting(1, "web1")
+	assert.Equal(t, "node-b", web1.NodeName)
+	assert.Equal(t, "stopped", web1.Status)
+	assert.True(t, netB.Contains(net.ParseIP(web1.IPAddress)), web1.IPAddress)
+	dom, ok = b.Domain(web1.VMName)
+	require.True(t, ok)
+	assert.Equal(t, hosting_service.DomainShutoff, dom.State)
+	assert.Equal(t, web1.IPAddress, dom.IP.String())
+	assert.Equal(t, web1.IPAddress, nginxAgent.upstream(web1.VMName))
+	_, ok = a.Domain(web1.VMName)
+	assert.False(t, ok)
+
+	// 3. upstream 등록이 실패하면 VM을 원본으로 되돌리고 원래 IP를 다시 적용한다
+	op, err = svc.StartVM(1, "web1")
+	done = wait(t, svc, op, err)
+	require.Equal(t, hosting_service.OpSucceeded, done.Status, done.Error)
+	oldIP := web1.IPAddress
+	nginxAgent.setFailPost(true)
+	op, err = svc.MigrateVM(web1.ID, hosting_service.MigrateOptions{Node: "node-a"})
+	done = wait(t, svc, op, err)
+	assert.Equal(t, hosting_service.OpRolledBack, done.Status, done.Rollback)
+	assert.Equal(t, hosting_service.StepRegisteringProxy, done.Step)
+	web1 = repo.hosting(1, "web1")
+	assert.Equal(t, "node-b", web1.NodeName)
+	assert.Equal(t, "running", web1.Status)
+	assert.Equal(t, oldIP, web1.IPAddress)
+	dom, ok = b.Domain(web1.VMName)
+	require.True(t, ok)
+	assert.Equal(t, hosting_service.DomainRunning, dom.State)
+	assert.Equal(t, oldIP, dom.IP.String())
+	_, ok = a.Domain(web1.VMName)
+	assert.False(t, ok)
+	assert.False(t, a.DiskExists(dom.DiskPath))
+	assert.Equal(t, oldIP, nginxAgent.upstream(web1.VMName))
+	nginxAgent.setFailPost(false)
+}
@@ -0,0 +1,245 @@
+package hosting_service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrSnapshotNotFound    = errors.New("스냅샷을 찾을 수 없습니다")
+	ErrInvalidSnapshotName = errors.New("스냅샷 이름은 영문 소문자, 숫자, '-'로 된 1~32자여야 합니다")
+	ErrSnapshotNameTaken   = errors.New("이미 사용 중인 스냅샷 이름입니다")
+	ErrSnapshotLimit       = errors.New("플랜의 스냅샷 개수 한도를 초과했습니다")
+	// ErrSnapshotUnsupported - UEFI VM은 NVRAM(pflash)이 내부 스냅샷에 담기지 않아 스냅샷을 만들 수 없다
+	ErrSnapshotUnsupported = errors.New("이 VM은 스냅샷을 지원하지 않습니다")
+)
+
+// ListSnapshots - ref가 비어 있으면 사용자의 모든 VM 스냅샷
+func (s *HostingService) ListSnapshots(userID int64, ref string) ([]*Snapshot, error) {
+	var vms []*Hosting
+	if ref != "" {
+		h, err := s.findVM(userID, ref)
+		if err != nil {
+			return nil, err
+		}
+		vms = append(vms, h)
+	} else {
+		all, err := s.ListVMs(userID)
+		if err != nil {
+			return nil, err
+		}
+		vms = all
+	}
+
+	snaps := []*Snapshot{}
+	for _, h := range vms {
+		list, err := s.snaps.FindByHostingID(h.ID)
+		if err != nil {
+			return nil, fmt.Errorf("스냅샷 목록 조회 실패: %w", err)
+		}
+		snaps = append(snaps, list...)
+	}
+	return snaps, nil
+}
+
+// CreateSnapshot - 스냅샷 생성 작업을 큐에 넣는다 (VM당 개수는 플랜의 MaxSnapshots까지)
+func (s *HostingService) CreateSnapshot(userID int64, ref, name, description string) (*Operation, error) {
+	if !vmNamePattern.MatchString(name) {
+		return nil, ErrInvalidSnapshotName
+	}
+	h, err := s.findVM(userID, ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkSnapshotAllowed(h, name); err != nil {
+		return nil, err
+	}
+
+	return s.enqueue(OpCreateSnapshot, h.UserID, h.Name, h.VMName, map[string]string{
+		"snapshot":    name,
+		"description": description,
+	})
+}
+
+// RevertSnapshot - VM을 스냅샷 시점으로 되돌리는 작업을 큐에 넣는다
+func (s *HostingService) RevertSnapshot(userID, snapshotID int64) (*Operation, error) {
+	return s.enqueueForSnapshot(OpRevertSnapshot, userID, snapshotID)
+}
+
+func (s *HostingService) DeleteSnapshot(userID, snapshotID int64) (*Operation, error) {
+	return s.enqueueForSnapshot(OpDeleteSnapshot, userID, snapshotID)
+}
+
+func (s *HostingService) enqueueForSnapshot(kind string, userID, snapshotID int64) (*Operation, error) {
+	_, h, err := s.findSnapshot(userID, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	return s.enqueue(kind, h.UserID, h.Name, h.VMName, map[string]string{
+		"snapshot_id": strconv.FormatInt(snapshotID, 10),
+	})
+}
+
+// findSnapshot - 사용자의 VM에 속한 스냅샷과 그 VM
+func (s *HostingService) findSnapshot(userID, snapshotID int64) (*Snapshot, *Hosting, error) {
+	snap, err := s.snaps.FindByID(snapshotID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, fmt.Errorf("%w: %d", ErrSnapshotNotFound, snapshotID)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("스냅샷 조회 실패: %w", err)
+	}
+
+	h, err := s.findVM(userID, strconv.FormatInt(snap.HostingID, 10))
+	if errors.Is(err, ErrVMNotFound) {
+		return nil, nil, fmt.Errorf("%w: %d", ErrSnapshotNotFound, snapshotID)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return snap, h, nil
+}
+
+// checkSnapshotAllowed - UEFI 여부, 이름 중복과 플랜의 스냅샷 개수 한도 확인
+func (s *HostingService) checkSnapshotAllowed(h *Hosting, name string) error {
+	plan, err := s.resolvePlan(h.Plan)
+	if err != nil {
+		return err
+	}
+	features := plan.Features
+	if img, err := s.findImage(h.Image); err == nil {
+		features = features.Merge(img.Features)
+	}
+	if features.UEFI {
+		return fmt.Errorf("%w: UEFI 펌웨어로 부팅하는 VM입니다", ErrSnapshotUnsupported)
+	}
+	existing, err := s.snaps.FindByHostingID(h.ID)
+	if err != nil {
+		return fmt.Errorf("스냅샷 목록 조회 실패: %w", err)
+	}
+	for _, snap := range existing {
+		if snap.Name == name {
+			return fmt.Errorf("%w: %s", ErrSnapshotNameTaken, name)
+		}
+	}
+	if len(existing) >= plan.MaxSnapshots {
+		return fmt.Errorf("%w (%s 플랜: %d개)", ErrSnapshotLimit, plan.Name, plan.MaxSnapshots)
+	}
+	return nil
+}
+
+func (s *HostingService) createSnapshot(op *Operation) error {
+	h, err := s.repo.FindByVMName(op.VMName)
+	if err != nil {
+		return fmt.Errorf("VM 정보 조회 실패: %w", err)
+	}
+	name := op.Params["snapshot"]
+	// 큐에서 기다리는 동안 다른 스냅샷이 만들어졌을 수 있으므로 다시 확인
+	if err := s.checkSnapshotAllowed(h, name); err != nil {
+		return err
+	}
+
+	snap := &Snapshot{
+		HostingID:   h.ID,
+		Name:        name,
+		Description: op.Params["description"],
+		VMStatus:    h.Status,
+		CreatedAt:   time.Now(),
+	}
+
+	sg := &saga{onStep: func(step string) { s.setStep(op, step) }}
+	// 1. libvirt 스냅샷 생성 (실행 중이면 메모리 상태 포함)
+	sg.add(StepCreatingSnapshot,
+		func() error { return s.hv.CreateSnapshot(h.VMName, name, snap.Description) },
+		func() error { return s.hv.DeleteSnapshot(h.VMName, name) })
+	// 2. DB 기록
+	sg.add(StepUpdatingStatus,
+		func() error { return s.snaps.Create(snap) },
+		nil)
+
+	if err := sg.run(); err != nil {
+		op.Step = sg.failedStep
+		op.Rollback = sg.rollback
+		op.Status = OpRolledBack
+		if !sg.rollbackOK {
+			op.Status = OpRollbackFailed
+		}
+		return err
+	}
+
+	result, _ := json.Marshal(snap)
+	op.Result = string(result)
+	return nil
+}
+
+func (s *HostingService) revertSnapshot(op *Operation) error {
+	snap, err := s.snapshotForOp(op)
+	if err != nil {
+		return err
+	}
+
+	// 1. 스냅샷 시점으로 복원 (도메인 상태도 스냅샷 시점으로 돌아간다)
+	s.setStep(op, StepRevertingSnapshot)
+	if err := s.hv.RevertSnapshot(op.VMName, snap.Name); err != nil {
+		return fmt.Errorf("스냅샷 복원 실패: %w", err)
+	}
+
+	// 2. 복원된 실행 상태를 DB에 반영
+	s.setStep(op, StepUpdatingStatus)
+	state, err := s.domainState(op.VMName)
+	if err != nil {
+		return err
+	}
+	status, ok := statusForDomain(state)
+	if !ok {
+		status = "stopped"
+	}
+	if err := s.repo.UpdateStatus(op.VMName, status); err != nil {
+		return fmt.Errorf("상태 갱신 실패: %w", err)
+	}
+	// 스냅샷 시점의 전원 상태가 새로 원하는 상태가 된다 (일시 정지는 그대로 둔다)
+	if status == "running" || status == "stopped" {
+		if err := s.setDesiredState(op.VMName, status); err != nil {
+			return err
+		}
+	}
+
+	result, _ := json.Marshal(map[string]interface{}{
+		"snapshot": snap.Name,
+		"status":   status,
+	})
+	op.Result = string(result)
+	return nil
+}
+
+func (s *HostingService) deleteSnapshot(op *Operation) error {
+	snap, err := s.snapshotForOp(op)
+	if err != nil {
+		return err
+	}
+
+	s.setStep(op, StepDeletingSnapshot)
+	if err := s.hv.DeleteSnapshot(op.VMName, snap.Name); err != nil {
+		return fmt.Errorf("스냅샷 삭제 실패: %w", err)
+	}
+
+	s.setStep(op, StepUpdatingStatus)
+	if err := s.snaps.Delete(snap.ID); err != nil {
+		return fmt.Errorf("스냅샷 기록 삭제 실패: %w", err)
+	}
+	return nil
+}
+
+// snapshotForOp - 작업 인자의 snapshot_id로 스냅샷을 찾는다 (큐에서 기다리는 동안 삭제되었을 수 있다)
+func (s *HostingService) snapshotForOp(op *Operation) (*Snapshot, error) {
+	id, err := strconv.ParseInt(op.Params["snapshot_id"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("잘못된 스냅샷 ID: %q", op.Params["snapshot_id"])
+	}
+	snap, _, err := s.findSnapshot(op.UserID, id)
+	return snap, err
+}
@@ -0,0 +1,129 @@
+package hosting_service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+	"webhost-go/webhost-go/pkg/libvirt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrSSHKeyNotFound  = errors.New("SSH 키를 찾을 수 없습니다")
+	ErrInvalidSSHKey   = errors.New("잘못된 SSH 공개키입니다")
+	ErrInvalidUserData = errors.New("잘못된 cloud-config입니다")
+)
+
+// vmPasswordLength - 새 VM 기본 계정의 초기 비밀번호 길이
+const vmPasswordLength = 16
+
+// 헷갈리기 쉬운 문자(0/O, 1/l/I)는 뺐다
+const passwordAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// ListSSHKeys - 사용자의 SSH 공개키 (새로 만드는 VM의 기본 계정에 등록된다)
+func (s *HostingService) ListSSHKeys(userID int64) ([]*SSHKey, error) {
+	keys, err := s.sshKeys.FindByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("SSH 키 목록 조회 실패: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *HostingService) AddSSHKey(userID int64, name, publicKey string) (*SSHKey, error) {
+	publicKey = strings.TrimSpace(publicKey)
+	if err := libvirt.ValidateSSHKey(publicKey); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSSHKey, err)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("%w: 키 이름이 필요합니다", ErrInvalidSSHKey)
+	}
+
+	key := &SSHKey{
+		UserID:      userID,
+		Name:        name,
+		PublicKey:   publicKey,
+		Fingerprint: sshFingerprint(publicKey),
+		CreatedAt:   time.Now(),
+	}
+	if err := s.sshKeys.Create(key); err != nil {
+		return nil, fmt.Errorf("SSH 키 저장 실패: %w", err)
+	}
+	return key, nil
+}
+
+// DeleteSSHKey - 이미 만든 VM의 authorized_keys에서는 지워지지 않는다
+func (s *HostingService) DeleteSSHKey(userID, keyID int64) error {
+	key, err := s.sshKeys.FindByID(keyID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && key.UserID != userID) {
+		return ErrSSHKeyNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("SSH 키 조회 실패: %w", err)
+	}
+	return s.sshKeys.Delete(key.ID)
+}
+
+// userSSHKeys - VM을 만들 때 기본 계정에 넣을 공개키
+func (s *HostingService) userSSHKeys(userID int64) ([]string, error) {
+	keys, err := s.sshKeys.FindByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("SSH 키 조회 실패: %w", err)
+	}
+	list := make([]string, 0, len(keys))
+	for _, k := range keys {
+		list = append(list, k.PublicKey)
+	}
+	return list, nil
+}
+
+// validateUserData - 작업을 큐에 넣기 전에 사용자 cloud-config를 확인한다
+func validateUserData(data string) error {
+	if data == "" {
+		return nil
+	}
+	if _, err := libvirt.ParseCloudConfig(data); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidUserData, err)
+	}
+	return nil
+}
+
+// newVMPassword - 초기 비밀번호와 cloud-init에 넘길 bcrypt 해시
+func newVMPassword() (string, string, error) {
+	password, err := randomPassword(vmPasswordLength)
+	if err != nil {
+		return "", "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", "", fmt.Errorf("비밀번호 해시 실패: %w", err)
+	}
+	return password, string(hash), nil
+}
+
+// randomPassword - passwordAlphabet에서 고른 n자 비밀번호
+func randomPassword(n int) (string, error) {
+	b := make([]byte, n)
+	max := big.NewInt(int64(len(passwordAlphabet)))
+	for i := range b {
+		k, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("비밀번호 생성 실패: %w", err)
+		}
+		b[i] = passwordAlphabet[k.Int64()]
+	}
+	return string(b), nil
+}
+
+// sshFingerprint - ssh-keygen -l 과 같은 SHA256 지문
+func sshFingerprint(publicKey string) string {
+	blob, _ := base64.StdEncoding.DecodeString(strings.Fields(publicKey)[1])
+	sum := sha256.Sum256(blob)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,103 @@
+package hosting_service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNoVNC - VNC 장치 없이 만든 VM (VNC 비밀번호 도입 전에 만든 VM)
+	ErrNoVNC     = errors.New("VNC를 쓸 수 없는 VM입니다")
+	ErrVNCTicket = errors.New("VNC 티켓이 없거나 만료되었습니다")
+)
+
+// vncPasswordLength - VNC 인증은 비밀번호 앞 8자만 쓴다
+const vncPasswordLength = 8
+
+// VNCTicket - VNC WebSocket 연결에 한 번만 쓸 수 있는 티켓.
+// Password는 noVNC가 RFB 인증에 쓰는 VM의 VNC 비밀번호다.
+type VNCTicket struct {
+	Ticket    string    `json:"ticket"`
+	HostingID int64     `json:"id"`
+	Name      string    `json:"name"`
+	Password  string    `json:"password"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	userID int64
+	vmName string
+}
+
+// vncTickets - 발급된 VNC 티켓 (유효 시간은 콘솔 티켓과 같다)
+type vncTickets struct {
+	mu      sync.Mutex
+	tickets map[string]*VNCTicket
+}
+
+// IssueVNCTicket - 실행 중인 VM의 VNC 티켓을 발급한다
+func (s *HostingService) IssueVNCTicket(userID int64, ref string) (*VNCTicket, error) {
+	h, err := s.findVM(userID, ref)
+	if err != nil {
+		return nil, err
+	}
+	if h.VNCPassword == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNoVNC, h.Name)
+	}
+	active, err := s.hv.IsActive(h.VMName)
+	if err != nil {
+		return nil, fmt.Errorf("VM 상태 조회 실패: %w", err)
+	}
+	if !active {
+		return nil, fmt.Errorf("%w: %s", ErrVMNotRunning, h.Name)
+	}
+
+	ticket, err := newTicket()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	t := &VNCTicket{
+		Ticket:    ticket,
+		HostingID: h.ID,
+		Name:      h.Name,
+		Password:  h.VNCPassword,
+		ExpiresAt: now.Add(consoleTicketTTL),
+		userID:    userID,
+		vmName:    h.VMName,
+	}
+
+	v := &s.vnc
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.tickets == nil {
+		v.tickets = make(map[string]*VNCTicket)
+	}
+	for k, old := range v.tickets {
+		if now.After(old.ExpiresAt) {
+			delete(v.tickets, k)
+		}
+	}
+	v.tickets[t.Ticket] = t
+	return t, nil
+}
+
+// ConnectVNC - 티켓으로 VM의 VNC 서버에 연결한다. 티켓은 성공 여부와 관계없이 한 번 쓰면 사라진다.
+// 콘솔과 달리 접속자마다 VNC 연결을 따로 연다 (QEMU가 여러 접속자를 받는다).
+func (s *HostingService) ConnectVNC(userID int64, ticket string) (io.ReadWriteCloser, error) {
+	v := &s.vnc
+	v.mu.Lock()
+	t, ok := v.tickets[ticket]
+	delete(v.tickets, ticket)
+	v.mu.Unlock()
+	if !ok || t.userID != userID || time.Now().After(t.ExpiresAt) {
+		return nil, ErrVNCTicket
+	}
+
+	conn, err := s.hv.OpenVNC(t.vmName)
+	if err != nil {
+		return nil, fmt.Errorf("VNC 연결 실패: %w", err)
+	}
+	return conn, nil
+}
@@ -0,0 +1,66 @@
+package cron_test
+
+import (
+	"testing"
+	"time"
+	"webhost-go/webhost-go/pkg/cron"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func at(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		expr string
+		from string
+		want string
+	}{
+		{"* * * * *", "2025-01-01 10:00", "2025-01-01 10:01"},
+		{"*/15 * * * *", "2025-01-01 10:07", "2025-01-01 10:15"},
+		{"30 2 * * *", "2025-01-01 03:00", "2025-01-02 02:30"},
+		{"@daily", "2025-12-31 12:00", "2026-01-01 00:00"},
+		{"0 0 * * 0", "2025-01-01 00:00", "2025-01-05 00:00"}, // 2025-01-01은 수요일
+		{"0 0 * * 7", "2025-01-01 00:00", "2025-01-05 00:00"},
+		{"0 9 * * 1-5", "2025-01-04 10:00", "2025-01-06 09:00"},
+		{"0 0 1,15 * *", "2025-01-02 00:00", "2025-01-15 00:00"},
+		{"0 0 31 * *", "2025-02-01 00:00", "2025-03-31 00:00"},
+		// 일과 요일이 모두 지정되면 둘 중 하나만 맞아도 된다
+		{"0 0 13 * 5", "2025-01-01 00:00", "2025-01-03 00:00"},
+	}
+	for _, tt := range tests {
+		s, err := cron.Parse(tt.expr)
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, at(tt.want), s.Next(at(tt.from)), tt.expr)
+	}
+}
+
+func TestNextNeverMatches(t *testing.T) {
+	s, err := cron.Parse("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, s.Next(at("2025-01-01 00:00")).IsZero())
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		_, err := cron.Parse(expr)
+		assert.Error(t, err, expr)
+	}
+}
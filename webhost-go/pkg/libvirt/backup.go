@@ -0,0 +1,237 @@
+package libvirt
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+)
+
+// DefaultBackupDir - 백업 파일 기본 위치 (backups/<vm-name>/<backup>.qcow2)
+const DefaultBackupDir = "/var/lib/libvirt/images/backups"
+
+// blockCommitPollInterval - 블록 커밋 진행 상황 확인 주기
+const blockCommitPollInterval = 500 * time.Millisecond
+
+// BackupPath - 백업 파일 경로
+func (m *LibvirtManager) BackupPath(domainName, backupName string) string {
+	return filepath.Join(m.backupDir(), domainName, backupName+".qcow2")
+}
+
+func (m *LibvirtManager) backupDir() string {
+	if m.BackupDir == "" {
+		return DefaultBackupDir
+	}
+	return m.BackupDir
+}
+
+// BackupDisk - 루트 디스크의 일관된 복사본을 백업 디렉토리에 만든다.
+// 실행 중인 도메인은 외부 스냅샷(overlay)으로 쓰기를 돌려 둔 채 원본을 복사하고 다시 커밋한다.
+// 외부 스냅샷을 만들 수 없으면 복사하는 동안 도메인을 일시 정지한다.
+// 백업 파일 경로와 크기(바이트)를 돌려준다.
+func (m *LibvirtManager) BackupDisk(domainName, backupName string) (string, int64, error) {
+	dom, err := m.conn.DomainLookupByName(domainName)
+	if err != nil {
+		return "", 0, fmt.Errorf("도메인 조회 실패: %w", err)
+	}
+	active, err := m.conn.DomainIsActive(dom)
+	if err != nil {
+		return "", 0, fmt.Errorf("도메인 상태 조회 실패: %w", err)
+	}
+
+	diskPath := InstanceDiskPath(domainName)
+	dst := m.BackupPath(domainName, backupName)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", 0, fmt.Errorf("백업 디렉토리 생성 실패: %w", err)
+	}
+
+	switch {
+	case active == 0:
+		err = copyDisk(diskPath, dst)
+	default:
+		err = m.backupLive(dom, diskPath, dst)
+	}
+	if err != nil {
+		os.Remove(dst)
+		return "", 0, err
+	}
+
+	info, err := os.Stat(dst)
+	if err != nil {
+		return "", 0, fmt.Errorf("백업 파일 확인 실패: %w", err)
+	}
+	return dst, info.Size(), nil
+}
+
+// diskOnlySnapshotXML - 루트 디스크만 외부 스냅샷(overlay)으로 돌리는 <domainsnapshot>
+type diskOnlySnapshotXML struct {
+	XMLName xml.Name          `xml:"domainsnapshot"`
+	Disks   []snapshotDiskXML `xml:"disks>disk"`
+}
+
+type snapshotDiskXML struct {
+	Name     string      `xml:"name,attr"`
+	Snapshot string      `xml:"snapshot,attr"`
+	Source   *DiskSource `xml:"source"`
+}
+
+func (m *LibvirtManager) backupLive(dom libvirt.Domain, diskPath, dst string) error {
+	overlay := diskPath + ".backup-overlay"
+	desc, err := m.conn.DomainGetXMLDesc(dom, 0)
+	if err != nil {
+		return fmt.Errorf("도메인 XML 조회 실패: %w", err)
+	}
+	def, err := ParseDomainXML(desc)
+	if err != nil {
+		return err
+	}
+	// seed(cdrom 또는 vfat disk)는 읽기 전용이라 스냅샷에서 뺀다
+	snap := diskOnlySnapshotXML{}
+	for _, disk := range def.Devices.Disks {
+		if disk.Target.Dev == rootDiskTarget {
+			snap.Disks = append(snap.Disks, snapshotDiskXML{
+				Name: rootDiskTarget, Snapshot: "external", Source: &DiskSource{File: overlay},
+			})
+			continue
+		}
+		snap.Disks = append(snap.Disks, snapshotDiskXML{Name: disk.Target.Dev, Snapshot: "no"})
+	}
+	snapXML, err := xml.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("스냅샷 XML 생성 실패: %w", err)
+	}
+	flags := libvirt.DomainSnapshotCreateDiskOnly | libvirt.DomainSnapshotCreateNoMetadata | libvirt.DomainSnapshotCreateAtomic
+
+	// overlay로 돌리는 순간만 게스트 파일시스템을 얼린다
+	thaw := m.freezeFilesystems(dom)
+	_, err = m.conn.DomainSnapshotCreateXML(dom, string(snapXML), uint32(flags))
+	thaw()
+	if err != nil {
+		// 내부 스냅샷이 있는 디스크 등 외부 스냅샷이 불가능하면 일시 정지 후 복사
+		return BackupWhilePaused(
+			func() error { return m.conn.DomainSuspend(dom) },
+			func() error { return m.conn.DomainResume(dom) },
+			diskPath, dst)
+	}
+
+	// overlay가 쓰기를 받는 동안 원본은 바뀌지 않는다
+	copyErr := copyDisk(diskPath, dst)
+	if err := m.commitOverlay(dom); err != nil {
+		return fmt.Errorf("overlay 커밋 실패 (%s 수동 정리 필요): %w", overlay, err)
+	}
+	if err := os.Remove(overlay); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("overlay 삭제 실패: %w", err)
+	}
+	return copyErr
+}
+
+// BackupWhilePaused - suspend로 도메인을 멈춘 채 diskPath를 dst로 복사하고 resume한다 (복사가 실패해도 재개한다).
+// 멈춘 QEMU도 이미지의 쓰기 잠금을 쥐고 있으므로 qemu-img는 -U(--force-share)로 연다.
+// 게스트가 멈춰 있는 동안에는 디스크가 바뀌지 않으므로 잠금 없이 읽어도 일관된다
+func BackupWhilePaused(suspend, resume func() error, diskPath, dst string) error {
+	if err := suspend(); err != nil {
+		return fmt.Errorf("도메인 일시 정지 실패: %w", err)
+	}
+	copyErr := copyDisk(diskPath, dst, "-U")
+	if err := resume(); err != nil {
+		return fmt.Errorf("도메인 재개 실패: %w", err)
+	}
+	return copyErr
+}
+
+// commitOverlay - 활성 overlay를 원본으로 병합하고 원본으로 되돌린다 (active block commit + pivot)
+func (m *LibvirtManager) commitOverlay(dom libvirt.Domain) error {
+	if err := m.conn.DomainBlockCommit(dom, rootDiskTarget, nil, nil, 0, libvirt.DomainBlockCommitActive); err != nil {
+		return err
+	}
+	for {
+		found, _, _, cur, end, err := m.conn.DomainGetBlockJobInfo(dom, rootDiskTarget, 0)
+		if err != nil {
+			return err
+		}
+		if found == 0 {
+			return fmt.Errorf("블록 커밋 작업이 사라졌습니다")
+		}
+		if end > 0 && cur == end {
+			break
+		}
+		time.Sleep(blockCommitPollInterval)
+	}
+	return m.conn.DomainBlockJobAbort(dom, rootDiskTarget, libvirt.DomainBlockJobAbortPivot)
+}
+
+// RestoreDisk - 중지된 도메인의 루트 디스크를 백업으로 교체한다.
+// 기존 디스크에 있던 내부 스냅샷은 사라지므로 스냅샷 메타데이터도 지운다.
+func (m *LibvirtManager) RestoreDisk(domainName, backupPath string) error {
+	if err := m.checkBackupPath(backupPath); err != nil {
+		return err
+	}
+	dom, err := m.conn.DomainLookupByName(domainName)
+	if err != nil {
+		return fmt.Errorf("도메인 조회 실패: %w", err)
+	}
+	active, err := m.conn.DomainIsActive(dom)
+	if err != nil {
+		return fmt.Errorf("도메인 상태 조회 실패: %w", err)
+	}
+	if active != 0 {
+		return fmt.Errorf("실행 중인 도메인의 디스크는 교체할 수 없습니다: %s", domainName)
+	}
+
+	snaps, _, err := m.conn.DomainListAllSnapshots(dom, 1, 0)
+	if err != nil {
+		return fmt.Errorf("스냅샷 목록 조회 실패: %w", err)
+	}
+	for _, snap := range snaps {
+		if err := m.conn.DomainSnapshotDelete(snap, libvirt.DomainSnapshotDeleteMetadataOnly); err != nil {
+			return fmt.Errorf("스냅샷 메타데이터 삭제 실패 (%s): %w", snap.Name, err)
+		}
+	}
+
+	diskPath := InstanceDiskPath(domainName)
+	tmp := diskPath + ".restore"
+	if err := copyDisk(backupPath, tmp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, diskPath); err != nil {
+		return fmt.Errorf("디스크 교체 실패: %w", err)
+	}
+	return nil
+}
+
+// DeleteBackup - 백업 파일 삭제 (백업 디렉토리 밖의 경로는 거부)
+func (m *LibvirtManager) DeleteBackup(backupPath string) error {
+	if err := m.checkBackupPath(backupPath); err != nil {
+		return err
+	}
+	if err := os.Remove(backupPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("백업 삭제 실패: %w", err)
+	}
+	return nil
+}
+
+func (m *LibvirtManager) checkBackupPath(path string) error {
+	rel, err := filepath.Rel(m.backupDir(), filepath.Clean(path))
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("백업 디렉토리 밖의 경로입니다: %s", path)
+	}
+	return nil
+}
+
+// copyDisk - qemu-img convert로 복사한다 (사용하지 않는 영역과 내부 스냅샷은 복사하지 않는다).
+// opts는 convert 옵션 (예: "-U")
+func copyDisk(src, dst string, opts ...string) error {
+	args := append([]string{"convert"}, opts...)
+	args = append(args, "-O", "qcow2", src, dst)
+	output, err := exec.Command("qemu-img", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("디스크 복사 실패: %w\n출력: %s", err, output)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package libvirt_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"webhost-go/webhost-go/pkg/libvirt"
+)
+
+// fakeQemuImg - PATH 앞에 qemu-img 대역을 둔다. 실행 중인 QEMU처럼 디스크 쓰기 잠금이 잡혀 있다고 보고
+// -U 없이 열면 실패하며, 받은 인자를 args 파일에 남긴다
+func fakeQemuImg(t *testing.T) (argsFile string) {
+	t.Helper()
+	dir := t.TempDir()
+	argsFile = filepath.Join(dir, "args")
+	script := `#!/bin/sh
+echo "$@" > "` + argsFile + `"
+if [ -n "$FAKE_QEMU_IMG_FAIL" ]; then
+	echo "qemu-img: simulated failure" >&2
+	exit 1
+fi
+case " $* " in
+*" -U "*) ;;
+*)
+	echo "qemu-img: Could not open '$3': Failed to get shared \"write\" lock" >&2
+	exit 1
+	;;
+esac
+for last; do :; done
+echo copied > "$last"
+`
+	if err := os.WriteFile(filepath.Join(dir, "qemu-img"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return argsFile
+}
+
+func TestBackupWhilePaused(t *testing.T) {
+	argsFile := fakeQemuImg(t)
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "disk.qcow2"), filepath.Join(dir, "backup.qcow2")
+
+	var calls []string
+	suspend := func() error { calls = append(calls, "suspend"); return nil }
+	resume := func() error { calls = append(calls, "resume"); return nil }
+
+	// 1. 멈춘 도메인의 디스크는 잠금을 공유해서 읽는다
+	if err := libvirt.BackupWhilePaused(suspend, resume, src, dst); err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+	if strings.Join(calls, ",") != "suspend,resume" {
+		t.Errorf("unexpected calls: %v", calls)
+	}
+	args, _ := os.ReadFile(argsFile)
+	if got := strings.TrimSpace(string(args)); got != "convert -U -O qcow2 "+src+" "+dst {
+		t.Errorf("unexpected qemu-img args: %s", got)
+	}
+	if _, err := os.Stat(dst); err != nil {
+		t.Errorf("backup file not written: %v", err)
+	}
+
+	// 2. 복사가 실패해도 도메인은 재개한다
+	calls = nil
+	t.Setenv("FAKE_QEMU_IMG_FAIL", "1")
+	if err := libvirt.BackupWhilePaused(suspend, resume, src, dst); err == nil || !strings.Contains(err.Error(), "디스크 복사 실패") {
+		t.Errorf("expected copy error, got %v", err)
+	}
+	if strings.Join(calls, ",") != "suspend,resume" {
+		t.Errorf("domain not resumed after a failed copy: %v", calls)
+	}
+
+	// 3. 일시 정지하지 못하면 복사하지 않는다
+	calls = nil
+	os.Remove(argsFile)
+	err := libvirt.BackupWhilePaused(func() error { return errors.New("operation failed") }, resume, src, dst)
+	if err == nil || !strings.Contains(err.Error(), "일시 정지 실패") {
+		t.Errorf("expected suspend error, got %v", err)
+	}
+	if len(calls) != 0 {
+		t.Errorf("unexpected calls: %v", calls)
+	}
+	if _, err := os.Stat(argsFile); !os.IsNotExist(err) {
+		t.Errorf("qemu-img ran without a paused domain")
+	}
+}
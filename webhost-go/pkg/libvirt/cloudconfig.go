@@ -0,0 +1,281 @@
+package libvirt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultCloudUser - CloudInit.User가 비어 있을 때 만드는 계정
+const DefaultCloudUser = "ubuntu"
+
+// MaxUserDataSize - 사용자가 넘기는 cloud-config 최대 크기 (NoCloud ISO에 그대로 들어간다)
+const MaxUserDataSize = 32 * 1024
+
+// ErrInvalidCloudConfig - 사용자 cloud-config 검증 실패
+var ErrInvalidCloudConfig = errors.New("잘못된 cloud-config입니다")
+
+// CloudInit - VM별 user-data 생성 입력
+type CloudInit struct {
+	Hostname     string   `json:"hostname,omitempty"`      // 비어 있으면 도메인 이름
+	User         string   `json:"user,omitempty"`          // 기본 로그인 계정 (이미지의 기본 사용자)
+	PasswordHash string   `json:"password_hash,omitempty"` // crypt 형식 (비어 있으면 비밀번호 로그인 불가)
+	SSHKeys      []string `json:"ssh_keys,omitempty"`
+	UserData     string   `json:"user_data,omitempty"`   // 사용자가 준 #cloud-config (baseline 위에 병합)
+	SeedFormat   string   `json:"seed_format,omitempty"` // seed 이미지 형식 (nocloud.FormatISO 또는 FormatVFAT, 비어 있으면 iso)
+}
+
+// CloudConfig - #cloud-config 문서 중 이 서비스가 만들거나 사용자에게 허용하는 키
+type CloudConfig struct {
+	Hostname          string      `yaml:"hostname,omitempty"`
+	ManageEtcHosts    bool        `yaml:"manage_etc_hosts,omitempty"`
+	Users             []CloudUser `yaml:"users,omitempty"`
+	Chpasswd          *Chpasswd   `yaml:"chpasswd,omitempty"`
+	SSHPwauth         *bool       `yaml:"ssh_pwauth,omitempty"`
+	DisableRoot       *bool       `yaml:"disable_root,omitempty"`
+	SSHAuthorizedKeys []string    `yaml:"ssh_authorized_keys,omitempty"`
+	PackageUpdate     bool        `yaml:"package_update,omitempty"`
+	PackageUpgrade    bool        `yaml:"package_upgrade,omitempty"`
+	Packages          []string    `yaml:"packages,omitempty"`
+	WriteFiles        []WriteFile `yaml:"write_files,omitempty"`
+	RunCmd            []string    `yaml:"runcmd,omitempty"`
+}
+
+type CloudUser struct {
+	Name              string   `yaml:"name"`
+	Shell             string   `yaml:"shell,omitempty"`
+	Sudo              string   `yaml:"sudo,omitempty"`
+	LockPasswd        *bool    `yaml:"lock_passwd,omitempty"`
+	Passwd            string   `yaml:"passwd,omitempty"`
+	SSHAuthorizedKeys []string `yaml:"ssh_authorized_keys,omitempty"`
+}
+
+type Chpasswd struct {
+	Expire bool `yaml:"expire"`
+}
+
+type WriteFile struct {
+	Path        string `yaml:"path"`
+	Content     string `yaml:"content"`
+	Permissions string `yaml:"permissions,omitempty"`
+	Owner       string `yaml:"owner,omitempty"`
+}
+
+var (
+	packagePattern     = regexp.MustCompile(`^[a-z0-9][a-z0-9+.-]*(=[A-Za-z0-9.:~+-]+)?$`)
+	permissionsPattern = regexp.MustCompile(`^0?[0-7]{3}$`)
+	ownerPattern       = regexp.MustCompile(`^[a-z_][a-z0-9_-]*(:[a-z_][a-z0-9_-]*)?$`)
+	cloudUserPattern   = regexp.MustCompile(`^[a-z_][a-z0-9_-]{0,31}$`)
+	hostnamePattern    = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+)
+
+// sshKeyTypes - 허용하는 공개키 종류
+var sshKeyTypes = map[string]bool{
+	"ssh-ed25519":                        true,
+	"ssh-rsa":                            true,
+	"ecdsa-sha2-nistp256":                true,
+	"ecdsa-sha2-nistp384":                true,
+	"ecdsa-sha2-nistp521":                true,
+	"sk-ssh-ed25519@openssh.com":         true,
+	"sk-ecdsa-sha2-nistp256@openssh.com": true,
+}
+
+// ValidateSSHKey - authorized_keys 한 줄 형식("<type> <base64> [comment]")인지 확인
+func ValidateSSHKey(key string) error {
+	if strings.ContainsAny(key, "\r\n") {
+		return errors.New("SSH 키는 한 줄이어야 합니다")
+	}
+	fields := strings.Fields(key)
+	if len(fields) < 2 {
+		return errors.New("SSH 키 형식은 '<종류> <키> [설명]'이어야 합니다")
+	}
+	if !sshKeyTypes[fields[0]] {
+		return fmt.Errorf("지원하지 않는 SSH 키 종류입니다: %s", fields[0])
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return errors.New("SSH 키 본문이 base64가 아닙니다")
+	}
+	// 키 본문은 길이가 붙은 종류 문자열로 시작한다
+	if len(blob) < 4 {
+		return errors.New("SSH 키 본문이 너무 짧습니다")
+	}
+	n := binary.BigEndian.Uint32(blob)
+	if uint64(n) > uint64(len(blob)-4) || string(blob[4:4+n]) != fields[0] {
+		return errors.New("SSH 키 본문과 종류가 일치하지 않습니다")
+	}
+	return nil
+}
+
+// BaselineCloudConfig - 모든 VM에 적용하는 안전한 기본 설정.
+// SSH 키가 있으면 비밀번호 SSH 로그인은 막고, root 로그인은 항상 막는다.
+func BaselineCloudConfig(ci CloudInit) *CloudConfig {
+	user := ci.User
+	if user == "" {
+		user = DefaultCloudUser
+	}
+	return &CloudConfig{
+		Hostname:       ci.Hostname,
+		ManageEtcHosts: true,
+		Users: []CloudUser{{
+			Name:              user,
+			Shell:             "/bin/bash",
+			Sudo:              "ALL=(ALL) NOPASSWD:ALL",
+			LockPasswd:        boolPtr(ci.PasswordHash == ""),
+			Passwd:            ci.PasswordHash,
+			SSHAuthorizedKeys: append([]string{}, ci.SSHKeys...),
+		}},
+		Chpasswd:       &Chpasswd{Expire: false},
+		DisableRoot:    boolPtr(true),
+		PackageUpdate:  true,
+		PackageUpgrade: true,
+		Packages:       []string{"nginx", "qemu-guest-agent"},
+		RunCmd: []string{
+			"systemctl enable nginx",
+			"systemctl start nginx",
+			// 도메인 XML의 org.qemu.guest_agent.0 채널로 IP 조회, fsfreeze, 비밀번호 변경에 쓴다
+			"systemctl start qemu-guest-agent",
+			fmt.Sprintf("ln -s /var/www/html /home/%s/www", user),
+			fmt.Sprintf("chown -R %s:%s /var/www/html", user, user),
+		},
+	}
+}
+
+// ParseCloudConfig - 사용자가 준 #cloud-config를 읽고 허용된 키만 쓰는지 확인한다.
+// 계정, 비밀번호, SSH 설정, 호스트 이름은 baseline이 정하므로 지정할 수 없다.
+func ParseCloudConfig(data string) (*CloudConfig, error) {
+	if len(data) > MaxUserDataSize {
+		return nil, fmt.Errorf("%w: %d바이트를 넘을 수 없습니다", ErrInvalidCloudConfig, MaxUserDataSize)
+	}
+	if !strings.HasPrefix(data, "#cloud-config\n") {
+		return nil, fmt.Errorf("%w: 첫 줄은 #cloud-config여야 합니다", ErrInvalidCloudConfig)
+	}
+
+	var cfg CloudConfig
+	dec := yaml.NewDecoder(strings.NewReader(data))
+	dec.KnownFields(true)
+	// 헤더만 있는 빈 문서는 그대로 허용
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCloudConfig, err)
+	}
+
+	switch {
+	case cfg.Hostname != "", cfg.ManageEtcHosts:
+		return nil, fmt.Errorf("%w: hostname은 지정할 수 없습니다", ErrInvalidCloudConfig)
+	case len(cfg.Users) > 0, cfg.Chpasswd != nil:
+		return nil, fmt.Errorf("%w: users/chpasswd는 지정할 수 없습니다", ErrInvalidCloudConfig)
+	case cfg.SSHPwauth != nil, cfg.DisableRoot != nil:
+		return nil, fmt.Errorf("%w: ssh_pwauth/disable_root는 지정할 수 없습니다", ErrInvalidCloudConfig)
+	}
+
+	for _, key := range cfg.SSHAuthorizedKeys {
+		if err := ValidateSSHKey(key); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCloudConfig, err)
+		}
+	}
+	for _, pkg := range cfg.Packages {
+		if !packagePattern.MatchString(pkg) {
+			return nil, fmt.Errorf("%w: 잘못된 패키지 이름입니다: %q", ErrInvalidCloudConfig, pkg)
+		}
+	}
+	for _, f := range cfg.WriteFiles {
+		if !path.IsAbs(f.Path) || path.Clean(f.Path) != f.Path {
+			return nil, fmt.Errorf("%w: write_files 경로는 절대 경로여야 합니다: %q", ErrInvalidCloudConfig, f.Path)
+		}
+		if f.Permissions != "" && !permissionsPattern.MatchString(f.Permissions) {
+			return nil, fmt.Errorf("%w: 잘못된 권한입니다: %q", ErrInvalidCloudConfig, f.Permissions)
+		}
+		if f.Owner != "" && !ownerPattern.MatchString(f.Owner) {
+			return nil, fmt.Errorf("%w: 잘못된 소유자입니다: %q", ErrInvalidCloudConfig, f.Owner)
+		}
+	}
+	for _, cmd := range cfg.RunCmd {
+		if strings.TrimSpace(cmd) == "" {
+			return nil, fmt.Errorf("%w: 빈 runcmd 항목이 있습니다", ErrInvalidCloudConfig)
+		}
+	}
+	return &cfg, nil
+}
+
+// Merge - 사용자 설정을 baseline 뒤에 덧붙인다 (패키지/파일/명령은 추가만 가능)
+func (c *CloudConfig) Merge(user *CloudConfig) {
+	c.PackageUpdate = c.PackageUpdate || user.PackageUpdate
+	c.PackageUpgrade = c.PackageUpgrade || user.PackageUpgrade
+	for _, pkg := range user.Packages {
+		if !containsString(c.Packages, pkg) {
+			c.Packages = append(c.Packages, pkg)
+		}
+	}
+	c.WriteFiles = append(c.WriteFiles, user.WriteFiles...)
+	c.RunCmd = append(c.RunCmd, user.RunCmd...)
+	if len(c.Users) > 0 {
+		for _, key := range user.SSHAuthorizedKeys {
+			if !containsString(c.Users[0].SSHAuthorizedKeys, key) {
+				c.Users[0].SSHAuthorizedKeys = append(c.Users[0].SSHAuthorizedKeys, key)
+			}
+		}
+	}
+}
+
+// Render - #cloud-config 헤더가 붙은 YAML
+func (c *CloudConfig) Render() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("#cloud-config\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return nil, fmt.Errorf("cloud-config 생성 실패: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("cloud-config 생성 실패: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// BuildUserData - baseline에 사용자 cloud-config를 병합한 VM별 user-data
+func BuildUserData(ci CloudInit) ([]byte, error) {
+	if ci.Hostname != "" && !hostnamePattern.MatchString(ci.Hostname) {
+		return nil, fmt.Errorf("%w: 잘못된 호스트 이름입니다: %q", ErrInvalidCloudConfig, ci.Hostname)
+	}
+	if ci.User != "" && !cloudUserPattern.MatchString(ci.User) {
+		return nil, fmt.Errorf("%w: 잘못된 사용자 이름입니다: %q", ErrInvalidCloudConfig, ci.User)
+	}
+	for _, key := range ci.SSHKeys {
+		if err := ValidateSSHKey(key); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCloudConfig, err)
+		}
+	}
+
+	cfg := BaselineCloudConfig(ci)
+	if ci.UserData != "" {
+		user, err := ParseCloudConfig(ci.UserData)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Merge(user)
+	}
+	// 키가 하나도 없을 때만 비밀번호 SSH 로그인을 허용한다
+	cfg.SSHPwauth = boolPtr(len(cfg.Users[0].SSHAuthorizedKeys) == 0 && ci.PasswordHash != "")
+	return cfg.Render()
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,265 @@
+package libvirt_test
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+	"webhost-go/webhost-go/pkg/libvirt"
+	"webhost-go/webhost-go/pkg/nocloud"
+)
+
+// testSSHKey - 형식만 맞는 ed25519 공개키
+func testSSHKey(seed byte, comment string) string {
+	var blob []byte
+	for _, part := range [][]byte{[]byte("ssh-ed25519"), make([]byte, 32)} {
+		n := make([]byte, 4)
+		binary.BigEndian.PutUint32(n, uint32(len(part)))
+		blob = append(blob, n...)
+		blob = append(blob, part...)
+	}
+	blob[len(blob)-1] = seed
+	return "ssh-ed25519 " + base64.StdEncoding.EncodeToString(blob) + " " + comment
+}
+
+func TestValidateSSHKey(t *testing.T) {
+	if err := libvirt.ValidateSSHKey(testSSHKey(1, "me@laptop")); err != nil {
+		t.Fatalf("valid key rejected: %v", err)
+	}
+
+	// 종류와 본문이 다른 키
+	mismatched := strings.Replace(testSSHKey(1, ""), "ssh-ed25519", "ssh-rsa", 1)
+	for _, key := range []string{
+		"",
+		"ssh-ed25519",
+		"ssh-dss AAAAB3NzaC1kc3MAAACBAP",
+		"ssh-ed25519 !!!notbase64",
+		mismatched,
+		testSSHKey(1, "a") + "\nssh-ed25519 AAAA",
+	} {
+		if err := libvirt.ValidateSSHKey(key); err == nil {
+			t.Errorf("invalid key accepted: %q", key)
+		}
+	}
+}
+
+func TestBuildUserData_Baseline(t *testing.T) {
+	key := testSSHKey(1, "me")
+	data, err := libvirt.BuildUserData(libvirt.CloudInit{
+		Hostname:     "web",
+		User:         "debian",
+		PasswordHash: "$2a$10$hash",
+		SSHKeys:      []string{key},
+	})
+	if err != nil {
+		t.Fatalf("BuildUserData failed: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "#cloud-config\n") {
+		t.Fatalf("missing #cloud-config header:\n%s", data)
+	}
+
+	var cfg libvirt.CloudConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("rendered user-data is not valid YAML: %v", err)
+	}
+	if cfg.Hostname != "web" || len(cfg.Users) != 1 {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+	u := cfg.Users[0]
+	if u.Name != "debian" || u.Passwd != "$2a$10$hash" || *u.LockPasswd {
+		t.Errorf("unexpected user: %+v", u)
+	}
+	if len(u.SSHAuthorizedKeys) != 1 || u.SSHAuthorizedKeys[0] != key {
+		t.Errorf("ssh key not set: %v", u.SSHAuthorizedKeys)
+	}
+	// 키가 있으면 비밀번호 SSH 로그인은 꺼진다
+	if cfg.SSHPwauth == nil || *cfg.SSHPwauth {
+		t.Errorf("ssh_pwauth should be false with keys")
+	}
+	if cfg.DisableRoot == nil || !*cfg.DisableRoot {
+		t.Errorf("disable_root should be true")
+	}
+	if strings.Contains(string(data), "plain_text_passwd") {
+		t.Errorf("plain text password in user-data:\n%s", data)
+	}
+}
+
+func TestBuildUserData_NoKeys(t *testing.T) {
+	data, err := libvirt.BuildUserData(libvirt.CloudInit{PasswordHash: "$2a$10$hash"})
+	if err != nil {
+		t.Fatalf("BuildUserData failed: %v", err)
+	}
+	var cfg libvirt.CloudConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Users[0].Name != libvirt.DefaultCloudUser {
+		t.Errorf("default user = %q", cfg.Users[0].Name)
+	}
+	if cfg.SSHPwauth == nil || !*cfg.SSHPwauth {
+		t.Errorf("ssh_pwauth should be true without keys")
+	}
+}
+
+func TestBuildUserData_MergeUserConfig(t *testing.T) {
+	extra := testSSHKey(2, "ci")
+	data, err := libvirt.BuildUserData(libvirt.CloudInit{
+		PasswordHash: "$2a$10$hash",
+		UserData: "#cloud-config\n" +
+			"packages: [nginx, git, python3=3.10.6-1]\n" +
+			"ssh_authorized_keys:\n  - " + extra + "\n" +
+			"write_files:\n  - path: /etc/motd\n    content: hello\n    permissions: '0644'\n" +
+			"runcmd:\n  - echo done\n",
+	})
+	if err != nil {
+		t.Fatalf("BuildUserData failed: %v", err)
+	}
+	var cfg libvirt.CloudConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(cfg.Packages, ",") != "nginx,qemu-guest-agent,git,python3=3.10.6-1" {
+		t.Errorf("packages = %v", cfg.Packages)
+	}
+	// baseline 명령 뒤에 사용자 명령
+	if last := cfg.RunCmd[len(cfg.RunCmd)-1]; last != "echo done" || cfg.RunCmd[0] != "systemctl enable nginx" {
+		t.Errorf("runcmd = %v", cfg.RunCmd)
+	}
+	if len(cfg.WriteFiles) != 1 || cfg.WriteFiles[0].Path != "/etc/motd" {
+		t.Errorf("write_files = %+v", cfg.WriteFiles)
+	}
+	if keys := cfg.Users[0].SSHAuthorizedKeys; len(keys) != 1 || keys[0] != extra {
+		t.Errorf("user keys = %v", keys)
+	}
+	if *cfg.SSHPwauth {
+		t.Errorf("ssh_pwauth should be false once user config adds a key")
+	}
+}
+
+func TestParseCloudConfig_Rejects(t *testing.T) {
+	for name, data := range map[string]string{
+		"no header":     "packages: [git]\n",
+		"unknown key":   "#cloud-config\nbootcmd: [reboot]\n",
+		"users":         "#cloud-config\nusers:\n  - name: root\n",
+		"chpasswd":      "#cloud-config\nchpasswd:\n  expire: true\n",
+		"ssh_pwauth":    "#cloud-config\nssh_pwauth: true\n",
+		"disable_root":  "#cloud-config\ndisable_root: false\n",
+		"hostname":      "#cloud-config\nhostname: other\n",
+		"bad package":   "#cloud-config\npackages: ['git; rm -rf /']\n",
+		"relative path": "#cloud-config\nwrite_files:\n  - path: etc/motd\n    content: x\n",
+		"dotdot path":   "#cloud-config\nwrite_files:\n  - path: /etc/../root/.ssh/x\n    content: x\n",
+		"bad key":       "#cloud-config\nssh_authorized_keys: ['ssh-ed25519 AAAA']\n",
+		"empty runcmd":  "#cloud-config\nruncmd: ['  ']\n",
+		"too large":     "#cloud-config\n" + strings.Repeat("#", libvirt.MaxUserDataSize),
+	} {
+		if _, err := libvirt.ParseCloudConfig(data); !errors.Is(err, libvirt.ErrInvalidCloudConfig) {
+			t.Errorf("%s: expected ErrInvalidCloudConfig, got %v", name, err)
+		}
+	}
+
+	if _, err := libvirt.ParseCloudConfig("#cloud-config\n"); err != nil {
+		t.Errorf("empty config rejected: %v", err)
+	}
+}
+
+func TestStaticNetworkConfig(t *testing.T) {
+	mac, err := libvirt.RandomMAC()
+	if err != nil {
+		t.Fatalf("RandomMAC failed: %v", err)
+	}
+	if !strings.HasPrefix(mac, "52:54:00:") || len(mac) != 17 {
+		t.Errorf("unexpected mac: %s", mac)
+	}
+
+	_, network, _ := net.ParseCIDR("192.168.122.0/24")
+	data := libvirt.StaticNetworkConfig(net.ParseIP("192.168.122.10"), network, net.ParseIP("192.168.122.1"), mac)
+
+	// NIC 이름은 머신 타입마다 다르므로(q35는 enp1s0) MAC으로 찾아야 한다
+	var cfg struct {
+		Ethernets map[string]struct {
+			Match     map[string]string `yaml:"match"`
+			Addresses []string          `yaml:"addresses"`
+			Gateway4  string            `yaml:"gateway4"`
+		} `yaml:"ethernets"`
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("network-config is not valid YAML: %v\n%s", err, data)
+	}
+	if len(cfg.Ethernets) != 1 {
+		t.Fatalf("expected one interface, got %v", cfg.Ethernets)
+	}
+	for name, eth := range cfg.Ethernets {
+		if strings.HasPrefix(name, "enp") {
+			t.Errorf("interface must not be matched by name: %s", name)
+		}
+		if eth.Match["macaddress"] != mac {
+			t.Errorf("unexpected match: %v", eth.Match)
+		}
+		if len(eth.Addresses) != 1 || eth.Addresses[0] != "192.168.122.10/24" || eth.Gateway4 != "192.168.122.1" {
+			t.Errorf("unexpected addressing: %+v", eth)
+		}
+	}
+}
+
+func TestReplaceSeedNetworkConfig(t *testing.T) {
+	_, network, _ := net.ParseCIDR("10.0.1.0/24")
+	config := libvirt.StaticNetworkConfig(net.ParseIP("10.0.1.7"), network, net.ParseIP("10.0.1.1"), "52:54:00:12:34:56")
+
+	// 1. netplan 파일은 network-config를 network: 아래에 둔다
+	var plan struct {
+		Network struct {
+			Version   int                    `yaml:"version"`
+			Ethernets map[string]interface{} `yaml:"ethernets"`
+		} `yaml:"network"`
+	}
+	if err := yaml.Unmarshal(libvirt.NetplanFile(config), &plan); err != nil {
+		t.Fatalf("netplan file is not valid YAML: %v", err)
+	}
+	if plan.Network.Version != 2 || len(plan.Network.Ethernets) != 1 {
+		t.Errorf("unexpected netplan file: %+v", plan)
+	}
+
+	// 2. seed는 meta-data와 user-data를 그대로 두고 network-config만 바꾼다
+	for _, format := range []nocloud.Format{nocloud.FormatISO, nocloud.FormatVFAT} {
+		path := filepath.Join(t.TempDir(), "seed")
+		seed := nocloud.Seed{
+			MetaData:      []byte("instance-id: vm-1\n"),
+			UserData:      []byte("#cloud-config\n"),
+			NetworkConfig: []byte("version: 2\n"),
+		}
+		if err := seed.WriteFile(path, format); err != nil {
+			t.Fatalf("%s: WriteFile failed: %v", format, err)
+		}
+		if err := libvirt.ReplaceSeedNetworkConfig(path, format, config); err != nil {
+			t.Fatalf("%s: ReplaceSeedNetworkConfig failed: %v", format, err)
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var vol *nocloud.Volume
+		if format == nocloud.FormatVFAT {
+			vol, err = nocloud.ReadVFAT(f)
+		} else {
+			vol, err = nocloud.ReadISO(f)
+		}
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: read failed: %v", format, err)
+		}
+		if string(vol.Files["meta-data"]) != "instance-id: vm-1\n" || string(vol.Files["user-data"]) != "#cloud-config\n" {
+			t.Errorf("%s: meta-data/user-data changed: %q %q", format, vol.Files["meta-data"], vol.Files["user-data"])
+		}
+		if string(vol.Files["network-config"]) != string(config) {
+			t.Errorf("%s: network-config not replaced: %q", format, vol.Files["network-config"])
+		}
+	}
+}
@@ -4,87 +4,86 @@ import (
 	"fmt"
 	"net"
 	"os"
-	"os/exec"
 	"path/filepath"
+
+	"webhost-go/webhost-go/pkg/nocloud"
 )
 
-func (m *LibvirtManager) CreateCloudInitISO(vmName string) (string, error) {
-	baseDir := filepath.Join("/var/lib/libvirt/images/instances", vmName)
-	if err := os.MkdirAll(baseDir, 0755); err != nil {
+// writeSeed - meta-data, user-data, network-config를 메모리에서 seed 이미지로 만든다.
+// 형식은 ci.SeedFormat (iso 또는 vfat), 경로는 baseDir/<name>.iso 또는 <name>.img
+func writeSeed(baseDir, name, vmName string, ci CloudInit, networkConfig []byte) (string, error) {
+	format, err := nocloud.ParseFormat(ci.SeedFormat)
+	if err != nil {
 		return "", err
 	}
-
-	metaPath := filepath.Join(baseDir, "meta-data")
-	userDataPath := "/var/lib/libvirt/images/cloud-init/share/user-data" // 공통
-	isoPath := filepath.Join(baseDir, "cloud-init.iso")
-
-	meta := fmt.Sprintf("instance-id: %s\nlocal-hostname: %s\n", vmName, vmName)
-	if err := os.WriteFile(metaPath, []byte(meta), 0644); err != nil {
-		return "", err
+	if ci.Hostname == "" {
+		ci.Hostname = vmName
 	}
-
-	cmd := exec.Command("genisoimage", "-output", isoPath, "-volid", "cidata", "-joliet", "-rock", userDataPath, metaPath)
-	if err := cmd.Run(); err != nil {
+	userData, err := BuildUserData(ci)
+	if err != nil {
 		return "", err
 	}
-	return isoPath, nil
-}
 
-func (m *LibvirtManager) CreateCloudInitISOWithStaticIP(vmName string, ip net.IP) (string, error) {
-	baseDir := filepath.Join("/var/lib/libvirt/images/instances", vmName)
-	if err := os.MkdirAll(baseDir, 0755); err != nil {
-		return "", fmt.Errorf("디렉터리 생성 실패: %w", err)
+	seed := nocloud.Seed{
+		MetaData:      []byte(fmt.Sprintf("instance-id: %s\nlocal-hostname: %s\n", vmName, ci.Hostname)),
+		UserData:      userData,
+		NetworkConfig: networkConfig,
 	}
+	path := seedPath(baseDir, name, format)
+	if err := seed.WriteFile(path, format); err != nil {
+		return "", fmt.Errorf("seed 이미지 생성 실패: %w", err)
+	}
+	return path, nil
+}
 
-	// 기본 네트워크 설정에서 gateway, CIDR 가져오기
-	netConf, err := m.GetDefaultNetworkConfig()
-	if err != nil {
-		return "", fmt.Errorf("네트워크 설정 조회 실패: %w", err)
+// seedPath - baseDir/<name>.iso 또는 <name>.img (vfat)
+func seedPath(baseDir, name string, format nocloud.Format) string {
+	ext := ".iso"
+	if format == nocloud.FormatVFAT {
+		ext = ".img"
 	}
-	maskSize, _ := netConf.CIDR.Mask.Size()
+	return filepath.Join(baseDir, name+ext)
+}
 
-	// 1. meta-data 생성
-	metaPath := filepath.Join(baseDir, "meta-data")
-	meta := fmt.Sprintf("instance-id: %s\nlocal-hostname: %s\n", vmName, vmName)
-	if err := os.WriteFile(metaPath, []byte(meta), 0644); err != nil {
-		return "", fmt.Errorf("meta-data 작성 실패: %w", err)
+func (m *LibvirtManager) CreateCloudInitISO(vmName string) (string, error) {
+	baseDir := filepath.Join("/var/lib/libvirt/images/instances", vmName)
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return "", err
 	}
+	return writeSeed(baseDir, "cloud-init", vmName, CloudInit{}, nil)
+}
 
-	// 2. network-config 생성
-	networkPath := filepath.Join(baseDir, "network-config")
-	networkYaml := fmt.Sprintf(`version: 2
+// StaticNetworkConfig - mac 주소의 NIC에 static IP를 주는 netplan v2 network-config.
+// NIC 이름은 머신 타입에 따라 다르므로(i440fx는 enp0s2, q35는 enp1s0) 이름 대신 MAC으로 찾는다
+func StaticNetworkConfig(ip net.IP, network *net.IPNet, gateway net.IP, mac string) []byte {
+	maskSize, _ := network.Mask.Size()
+	return []byte(fmt.Sprintf(`version: 2
 ethernets:
-  enp0s2:
+  primary:
+    match:
+      macaddress: "%s"
     dhcp4: false
     addresses: [%s/%d]
     gateway4: %s
     nameservers:
       addresses: [8.8.8.8, 1.1.1.1]
-`, ip.String(), maskSize, netConf.Gateway.String())
-	if err := os.WriteFile(networkPath, []byte(networkYaml), 0644); err != nil {
-		return "", fmt.Errorf("network-config 작성 실패: %w", err)
-	}
+`, mac, ip.String(), maskSize, gateway.String()))
+}
 
-	// 3. user-data는 공유 경로에서
-	userDataPath := "/var/lib/libvirt/images/cloud-init/share/user-data"
-	if _, err := os.Stat(userDataPath); err != nil {
-		return "", fmt.Errorf("user-data 경로 확인 실패: %w", err)
+// CreateCloudInitISOWithStaticIP - static IP network-config가 들어간 seed 이미지를 만든다.
+// mac은 도메인 XML의 NIC MAC과 같아야 한다 (VMConfig.MAC).
+// ci.SeedFormat이 vfat이면 ISO 대신 FAT 이미지를 만든다 (도메인에는 disk로 연결).
+func (m *LibvirtManager) CreateCloudInitISOWithStaticIP(vmName string, ip net.IP, mac string, ci CloudInit) (string, error) {
+	baseDir := filepath.Join("/var/lib/libvirt/images/instances", vmName)
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return "", fmt.Errorf("디렉터리 생성 실패: %w", err)
 	}
 
-	// 4. ISO 생성
-	isoPath := filepath.Join(baseDir, vmName+".iso")
-	cmd := exec.Command("genisoimage",
-		"-output", isoPath,
-		"-volid", "cidata",
-		"-joliet", "-rock",
-		userDataPath, metaPath, networkPath,
-	)
-
-	var output []byte
-	output, err = cmd.CombinedOutput()
+	// 기본 네트워크 설정에서 gateway, CIDR 가져오기
+	netConf, err := m.GetDefaultNetworkConfig()
 	if err != nil {
-		return "", fmt.Errorf("ISO 생성 실패: %w\n명령 출력: %s", err, string(output))
+		return "", fmt.Errorf("네트워크 설정 조회 실패: %w", err)
 	}
-	fmt.Println(string(output))
-	return isoPath, nil
+
+	return writeSeed(baseDir, vmName, vmName, ci, StaticNetworkConfig(ip, netConf.CIDR, netConf.Gateway, mac))
 }
@@ -0,0 +1,104 @@
+package libvirt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/digitalocean/go-libvirt"
+)
+
+// ErrNoConsole - 실행 중인 도메인 XML에 pty 콘솔이 없다
+var ErrNoConsole = errors.New("도메인에 pty 시리얼 콘솔이 없습니다")
+
+// ConsoleStream - 도메인 시리얼 콘솔 스트림. Read는 게스트 출력, Write는 게스트 입력이다.
+//
+// go-libvirt의 DomainOpenConsole은 받는 스트림만 지원하므로 입력은 콘솔 pty(/dev/pts/N)에
+// 직접 쓴다. 그래서 libvirtd와 같은 호스트(libvirt-agent)에서만 열 수 있고,
+// 원격 URI로 연결한 매니저에서는 ErrRemoteHost를 돌려준다.
+type ConsoleStream struct {
+	out  *io.PipeReader
+	pty  *os.File
+	once sync.Once
+}
+
+// OpenConsole - 실행 중인 도메인의 시리얼 콘솔을 연다.
+// 이전 스트림이 남아 있으면 빼앗아 온다 (DomainConsoleForce).
+func (m *LibvirtManager) OpenConsole(name string) (io.ReadWriteCloser, error) {
+	if m.remote {
+		return nil, fmt.Errorf("콘솔: %w", ErrRemoteHost)
+	}
+	dom, err := m.conn.DomainLookupByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("도메인 조회 실패: %w", err)
+	}
+	active, err := m.conn.DomainIsActive(dom)
+	if err != nil {
+		return nil, fmt.Errorf("도메인 상태 조회 실패: %w", err)
+	}
+	if active == 0 {
+		return nil, fmt.Errorf("실행 중인 도메인이 아닙니다: %s", name)
+	}
+
+	desc, err := m.conn.DomainGetXMLDesc(dom, 0)
+	if err != nil {
+		return nil, fmt.Errorf("도메인 XML 가져오기 실패: %w", err)
+	}
+	d, err := ParseDomainXML(desc)
+	if err != nil {
+		return nil, err
+	}
+	path := ConsolePTY(d)
+	if path == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNoConsole, name)
+	}
+	pty, err := os.OpenFile(path, os.O_WRONLY|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("콘솔 pty 열기 실패: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		// 스트림이 끝나거나 Close 후 다음 출력이 쓰일 때 반환된다
+		err := m.conn.DomainOpenConsole(dom, nil, pw, uint32(libvirt.DomainConsoleForce))
+		if err == nil {
+			err = io.EOF
+		}
+		pw.CloseWithError(err)
+	}()
+	return &ConsoleStream{out: pr, pty: pty}, nil
+}
+
+func (c *ConsoleStream) Read(p []byte) (int, error) {
+	return c.out.Read(p)
+}
+
+func (c *ConsoleStream) Write(p []byte) (int, error) {
+	return c.pty.Write(p)
+}
+
+func (c *ConsoleStream) Close() error {
+	var err error
+	c.once.Do(func() {
+		c.out.Close()
+		err = c.pty.Close()
+	})
+	return err
+}
+
+// ConsolePTY - 도메인의 첫 pty 콘솔 경로 (정의만 된 도메인이면 빈 문자열)
+func ConsolePTY(d *Domain) string {
+	for _, con := range d.Devices.Consoles {
+		if con.Type != "pty" {
+			continue
+		}
+		if con.Source != nil && con.Source.Path != "" {
+			return con.Source.Path
+		}
+		return con.TTY
+	}
+	return ""
+}
@@ -0,0 +1,334 @@
+package libvirt
+
+import (
+	"crypto/rand"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net"
+
+	"webhost-go/webhost-go/pkg/nocloud"
+)
+
+// 도메인의 디스크 target. seed는 형식에 따라 cdrom(sda) 또는 virtio disk(vdb)로 붙는다.
+const (
+	rootDiskTarget     = "vda"
+	seedCDROMTarget    = "sda"
+	seedDiskTarget     = "vdb"
+	defaultNetworkName = "default"
+)
+
+// cpuSharesPerVCPU - vCPU 하나당 cputune shares (큰 플랜이 경쟁 시 비례해서 더 받는다)
+const cpuSharesPerVCPU = 1024
+
+// cpuQuotaPeriod - CPUQuota 계산에 쓰는 cputune period (마이크로초)
+const cpuQuotaPeriod = 100000
+
+// VNC는 호스트 localhost에만 열고 libvirt-agent가 중계한다. VNC 인증은 비밀번호 8자까지만 쓴다.
+const (
+	vncListen         = "127.0.0.1"
+	maxVNCPasswordLen = 8
+)
+
+// Domain - libvirt 도메인 XML (<domain>) 중 이 서비스가 만들고 읽는 요소
+type Domain struct {
+	XMLName  xml.Name  `xml:"domain"`
+	Type     string    `xml:"type,attr"`
+	Name     string    `xml:"name"`
+	UUID     string    `xml:"uuid,omitempty"`
+	Memory   Memory    `xml:"memory"`
+	VCPU     int       `xml:"vcpu"`
+	CPUTune  *CPUTune  `xml:"cputune"`
+	MemTune  *MemTune  `xml:"memtune"`
+	OS       DomainOS  `xml:"os"`
+	Features *Features `xml:"features"`
+	Devices  Devices   `xml:"devices"`
+}
+
+type Memory struct {
+	Unit  string `xml:"unit,attr"`
+	Value int    `xml:",chardata"`
+}
+
+type CPUTune struct {
+	Shares int `xml:"shares,omitempty"`
+	Period int `xml:"period,omitempty"`
+	Quota  int `xml:"quota,omitempty"`
+}
+
+type MemTune struct {
+	HardLimit *Memory `xml:"hard_limit"`
+	SoftLimit *Memory `xml:"soft_limit"`
+}
+
+type DomainOS struct {
+	Firmware string `xml:"firmware,attr,omitempty"` // "efi"
+	Type     OSType `xml:"type"`
+	// Loader - NewDomain은 쓰지 않고 libvirt가 firmware="efi"를 풀어 채운다 (pflash면 UEFI)
+	Loader *Loader `xml:"loader"`
+	Boot   []Boot  `xml:"boot"`
+}
+
+type Loader struct {
+	Type string `xml:"type,attr,omitempty"` // "pflash", "rom"
+	Path string `xml:",chardata"`
+}
+
+type OSType struct {
+	Arch    string `xml:"arch,attr"`
+	Machine string `xml:"machine,attr,omitempty"`
+	Value   string `xml:",chardata"`
+}
+
+type Boot struct {
+	Dev string `xml:"dev,attr"`
+}
+
+// Features - ACPI가 없으면 DomainShutdown(전원 버튼)을 게스트가 받지 못한다
+type Features struct {
+	ACPI *struct{} `xml:"acpi"`
+	APIC *struct{} `xml:"apic"`
+}
+
+type Devices struct {
+	Disks      []Disk      `xml:"disk"`
+	Interfaces []Interface `xml:"interface"`
+	Consoles   []Console   `xml:"console"`
+	Channels   []Channel   `xml:"channel"`
+	Graphics   []Graphics  `xml:"graphics"`
+	RNGs       []RNG       `xml:"rng"`
+}
+
+type Disk struct {
+	Type     string     `xml:"type,attr"`   // "file"
+	Device   string     `xml:"device,attr"` // "disk", "cdrom"
+	Driver   DiskDriver `xml:"driver"`
+	Source   DiskSource `xml:"source"`
+	Target   DiskTarget `xml:"target"`
+	ReadOnly *struct{}  `xml:"readonly"`
+}
+
+type DiskDriver struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"` // "qcow2", "raw"
+}
+
+type DiskSource struct {
+	File string `xml:"file,attr,omitempty"`
+}
+
+type DiskTarget struct {
+	Dev string `xml:"dev,attr"`
+	Bus string `xml:"bus,attr"`
+}
+
+type Interface struct {
+	Type   string          `xml:"type,attr"` // "network"
+	MAC    *InterfaceMAC   `xml:"mac"`
+	Source InterfaceSource `xml:"source"`
+	Model  InterfaceModel  `xml:"model"`
+}
+
+type InterfaceMAC struct {
+	Address string `xml:"address,attr"`
+}
+
+type InterfaceSource struct {
+	Network string `xml:"network,attr,omitempty"`
+}
+
+type InterfaceModel struct {
+	Type string `xml:"type,attr"`
+}
+
+type Console struct {
+	Type   string         `xml:"type,attr"`          // "pty"
+	TTY    string         `xml:"tty,attr,omitempty"` // 실행 중인 도메인에서 libvirt가 채운다
+	Source *ConsoleSource `xml:"source"`
+	Target ConsoleTarget  `xml:"target"`
+}
+
+type ConsoleSource struct {
+	Path string `xml:"path,attr"` // 호스트의 pty (/dev/pts/N)
+}
+
+type ConsoleTarget struct {
+	Type string `xml:"type,attr"` // "serial"
+	Port int    `xml:"port,attr"`
+}
+
+// Channel - 게스트 에이전트용 virtio-serial 채널 (소켓 경로는 libvirt가 정한다)
+type Channel struct {
+	Type   string         `xml:"type,attr"` // "unix"
+	Source *ChannelSource `xml:"source"`
+	Target ChannelTarget  `xml:"target"`
+}
+
+type ChannelSource struct {
+	Mode string `xml:"mode,attr,omitempty"`
+	Path string `xml:"path,attr,omitempty"`
+}
+
+type ChannelTarget struct {
+	Type  string `xml:"type,attr"`            // "virtio"
+	Name  string `xml:"name,attr"`            // GuestAgentChannel
+	State string `xml:"state,attr,omitempty"` // 실행 중인 도메인에서 "connected"/"disconnected"
+}
+
+type Graphics struct {
+	Type     string `xml:"type,attr"`           // "vnc"
+	Port     int    `xml:"port,attr,omitempty"` // autoport면 정의 시 -1, 실행 중에는 실제 포트
+	AutoPort string `xml:"autoport,attr,omitempty"`
+	Listen   string `xml:"listen,attr,omitempty"`
+	Passwd   string `xml:"passwd,attr,omitempty"` // libvirt는 VIR_DOMAIN_XML_SECURE 없이는 돌려주지 않는다
+}
+
+type RNG struct {
+	Model   string     `xml:"model,attr"` // "virtio"
+	Backend RNGBackend `xml:"backend"`
+}
+
+type RNGBackend struct {
+	Model string `xml:"model,attr"` // "random"
+	Path  string `xml:",chardata"`
+}
+
+// NewDomain - VMConfig로 도메인 정의를 만든다 (루트 디스크, seed, default 네트워크, 시리얼 콘솔,
+// 게스트 에이전트 채널, VNCPassword가 있으면 localhost VNC)
+func NewDomain(cfg VMConfig) (*Domain, error) {
+	if cfg.Name == "" {
+		return nil, errors.New("도메인 이름이 필요합니다")
+	}
+	if cfg.VCPUs < 1 || cfg.MemoryMB < 1 {
+		return nil, fmt.Errorf("vCPU와 메모리는 1 이상이어야 합니다 (vcpus=%d, memory=%dMB)", cfg.VCPUs, cfg.MemoryMB)
+	}
+	if cfg.DiskPath == "" {
+		return nil, errors.New("루트 디스크 경로가 필요합니다")
+	}
+	if cfg.CPUQuota < 0 || cfg.CPUQuota > 100 {
+		return nil, fmt.Errorf("CPU 할당률은 0~100%%여야 합니다: %d", cfg.CPUQuota)
+	}
+	if len(cfg.VNCPassword) > maxVNCPasswordLen {
+		return nil, fmt.Errorf("VNC 비밀번호는 %d자 이하여야 합니다", maxVNCPasswordLen)
+	}
+	if cfg.MAC != "" {
+		if _, err := net.ParseMAC(cfg.MAC); err != nil {
+			return nil, fmt.Errorf("잘못된 MAC 주소입니다: %s", cfg.MAC)
+		}
+	}
+
+	d := &Domain{
+		Type:   "kvm",
+		Name:   cfg.Name,
+		Memory: Memory{Unit: "MiB", Value: cfg.MemoryMB},
+		VCPU:   cfg.VCPUs,
+		CPUTune: &CPUTune{
+			Shares: cfg.VCPUs * cpuSharesPerVCPU,
+		},
+		OS: DomainOS{
+			Type: OSType{Arch: "x86_64", Value: "hvm"},
+			Boot: []Boot{{Dev: "hd"}},
+		},
+		Features: &Features{ACPI: &struct{}{}, APIC: &struct{}{}},
+	}
+	if cfg.CPUQuota > 0 {
+		d.CPUTune.Period = cpuQuotaPeriod
+		d.CPUTune.Quota = cpuQuotaPeriod * cfg.CPUQuota / 100
+	}
+	if cfg.UEFI {
+		d.OS.Firmware = "efi"
+		d.OS.Type.Machine = "q35"
+	}
+
+	d.Devices.Disks = append(d.Devices.Disks, Disk{
+		Type:   "file",
+		Device: "disk",
+		Driver: DiskDriver{Name: "qemu", Type: "qcow2"},
+		Source: DiskSource{File: cfg.DiskPath},
+		Target: DiskTarget{Dev: rootDiskTarget, Bus: "virtio"},
+	})
+	if cfg.ISOPath != "" {
+		seed := Disk{
+			Type:     "file",
+			Device:   "cdrom",
+			Driver:   DiskDriver{Name: "qemu", Type: "raw"},
+			Source:   DiskSource{File: cfg.ISOPath},
+			Target:   DiskTarget{Dev: seedCDROMTarget, Bus: "sata"},
+			ReadOnly: &struct{}{},
+		}
+		if cfg.CloudInit.SeedFormat == string(nocloud.FormatVFAT) {
+			seed.Device = "disk"
+			seed.Target = DiskTarget{Dev: seedDiskTarget, Bus: "virtio"}
+		}
+		d.Devices.Disks = append(d.Devices.Disks, seed)
+	}
+
+	d.Devices.Interfaces = []Interface{{
+		Type:   "network",
+		Source: InterfaceSource{Network: defaultNetworkName},
+		Model:  InterfaceModel{Type: "virtio"},
+	}}
+	if cfg.MAC != "" {
+		d.Devices.Interfaces[0].MAC = &InterfaceMAC{Address: cfg.MAC}
+	}
+	d.Devices.Consoles = []Console{{
+		Type:   "pty",
+		Target: ConsoleTarget{Type: "serial", Port: 0},
+	}}
+	d.Devices.Channels = []Channel{{
+		Type:   "unix",
+		Target: ChannelTarget{Type: "virtio", Name: GuestAgentChannel},
+	}}
+	if cfg.VNCPassword != "" {
+		d.Devices.Graphics = []Graphics{{
+			Type:     "vnc",
+			Port:     -1,
+			AutoPort: "yes",
+			Listen:   vncListen,
+			Passwd:   cfg.VNCPassword,
+		}}
+	}
+	if cfg.VirtioRNG {
+		d.Devices.RNGs = []RNG{{
+			Model:   "virtio",
+			Backend: RNGBackend{Model: "random", Path: "/dev/urandom"},
+		}}
+	}
+	return d, nil
+}
+
+// RandomMAC - QEMU/KVM OUI(52:54:00)의 임의 MAC 주소
+func RandomMAC() (string, error) {
+	var b [3]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("MAC 주소 생성 실패: %w", err)
+	}
+	return fmt.Sprintf("52:54:00:%02x:%02x:%02x", b[0], b[1], b[2]), nil
+}
+
+// XML - libvirt DomainDefineXML에 넘길 문자열
+func (d *Domain) XML() (string, error) {
+	data, err := xml.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("도메인 XML 생성 실패: %w", err)
+	}
+	return string(data) + "\n", nil
+}
+
+// BuildDomainXML - NewDomain + XML
+func BuildDomainXML(cfg VMConfig) (string, error) {
+	d, err := NewDomain(cfg)
+	if err != nil {
+		return "", err
+	}
+	return d.XML()
+}
+
+// ParseDomainXML - DomainGetXMLDesc 결과에서 Domain에 있는 요소만 읽는다
+func ParseDomainXML(desc string) (*Domain, error) {
+	var d Domain
+	if err := xml.Unmarshal([]byte(desc), &d); err != nil {
+		return nil, fmt.Errorf("도메인 XML 해석 실패: %w", err)
+	}
+	return &d, nil
+}
@@ -0,0 +1,252 @@
+package libvirt_test
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"webhost-go/webhost-go/pkg/libvirt"
+)
+
+// go test ./pkg/libvirt -run TestBuildDomainXML -update 로 골든 파일을 다시 만든다
+var update = flag.Bool("update", false, "update golden files")
+
+func TestBuildDomainXML(t *testing.T) {
+	base := libvirt.VMConfig{
+		Name:     "vm-1a2b3c4d",
+		VCPUs:    2,
+		MemoryMB: 2048,
+		DiskPath: "/var/lib/libvirt/images/instances/vm-1a2b3c4d/disk.qcow2",
+		ISOPath:  "/var/lib/libvirt/images/instances/vm-1a2b3c4d/vm-1a2b3c4d.iso",
+	}
+
+	vfat := base
+	vfat.ISOPath = "/var/lib/libvirt/images/instances/vm-1a2b3c4d/vm-1a2b3c4d.img"
+	vfat.CloudInit.SeedFormat = "vfat"
+
+	features := base
+	features.UEFI, features.VirtioRNG, features.CPUQuota = true, true, 50
+
+	noSeed := base
+	noSeed.ISOPath = ""
+
+	vnc := base
+	vnc.VNCPassword = "Xk3pQ9wz"
+
+	for name, cfg := range map[string]libvirt.VMConfig{
+		"domain_basic":    base,
+		"domain_vfat":     vfat,
+		"domain_features": features,
+		"domain_no_seed":  noSeed,
+		"domain_vnc":      vnc,
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := libvirt.BuildDomainXML(cfg)
+			if err != nil {
+				t.Fatalf("BuildDomainXML failed: %v", err)
+			}
+
+			golden := filepath.Join("testdata", name+".xml")
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("golden file missing (run with -update): %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("domain XML mismatch for %s\n--- got\n%s\n--- want\n%s", name, got, want)
+			}
+
+			// 만든 XML을 다시 읽으면 같은 정의가 나온다
+			built, _ := libvirt.NewDomain(cfg)
+			parsed, err := libvirt.ParseDomainXML(got)
+			if err != nil {
+				t.Fatalf("ParseDomainXML failed: %v", err)
+			}
+			parsed.XMLName = built.XMLName
+			if !reflect.DeepEqual(built, parsed) {
+				t.Errorf("round trip mismatch\nbuilt:  %+v\nparsed: %+v", built, parsed)
+			}
+		})
+	}
+}
+
+func TestBuildDomainXML_Invalid(t *testing.T) {
+	valid := libvirt.VMConfig{Name: "vm", VCPUs: 1, MemoryMB: 512, DiskPath: "/disk.qcow2"}
+	if _, err := libvirt.BuildDomainXML(valid); err != nil {
+		t.Fatalf("valid config rejected: %v", err)
+	}
+
+	for name, mutate := range map[string]func(*libvirt.VMConfig){
+		"no name":      func(c *libvirt.VMConfig) { c.Name = "" },
+		"no vcpus":     func(c *libvirt.VMConfig) { c.VCPUs = 0 },
+		"no memory":    func(c *libvirt.VMConfig) { c.MemoryMB = 0 },
+		"no disk":      func(c *libvirt.VMConfig) { c.DiskPath = "" },
+		"quota > 100%": func(c *libvirt.VMConfig) { c.CPUQuota = 150 },
+		"vnc password": func(c *libvirt.VMConfig) { c.VNCPassword = "longer-than-8" },
+		"bad mac":      func(c *libvirt.VMConfig) { c.MAC = "52:54:00:zz" },
+	} {
+		cfg := valid
+		mutate(&cfg)
+		if _, err := libvirt.BuildDomainXML(cfg); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestParseDomainXML_LibvirtOutput(t *testing.T) {
+	// libvirt가 돌려주는 XML에는 모르는 요소가 많다
+	desc := `<domain type='kvm' id='3'>
+  <name>vm-1</name>
+  <uuid>8f7c1e02-0000-4000-8000-000000000001</uuid>
+  <memory unit='KiB'>1048576</memory>
+  <vcpu placement='static'>1</vcpu>
+  <os><type arch='x86_64' machine='pc-i440fx-jammy'>hvm</type><boot dev='hd'/></os>
+  <devices>
+    <emulator>/usr/bin/qemu-system-x86_64</emulator>
+    <disk type='file' device='disk'>
+      <driver name='qemu' type='qcow2'/>
+      <source file='/var/lib/libvirt/images/instances/vm-1/disk.qcow2' index='2'/>
+      <backingStore type='file' index='3'><format type='qcow2'/></backingStore>
+      <target dev='vda' bus='virtio'/>
+    </disk>
+    <interface type='network'>
+      <mac address='52:54:00:12:34:56'/>
+      <source network='default' portid='x' bridge='virbr0'/>
+      <model type='virtio'/>
+    </interface>
+    <console type='pty' tty='/dev/pts/3'>
+      <source path='/dev/pts/3'/>
+      <target type='serial' port='0'/>
+      <alias name='serial0'/>
+    </console>
+    <channel type='unix'>
+      <source mode='bind' path='/run/libvirt/qemu/channel/3-vm-1/org.qemu.guest_agent.0'/>
+      <target type='virtio' name='org.qemu.guest_agent.0' state='connected'/>
+      <alias name='channel0'/>
+    </channel>
+    <graphics type='vnc' port='5901' autoport='yes' listen='127.0.0.1'/>
+  </devices>
+</domain>`
+	d, err := libvirt.ParseDomainXML(desc)
+	if err != nil {
+		t.Fatalf("ParseDomainXML failed: %v", err)
+	}
+	if d.Name != "vm-1" || d.Memory.Value != 1048576 || d.Memory.Unit != "KiB" {
+		t.Errorf("unexpected domain: %+v", d)
+	}
+	if len(d.Devices.Disks) != 1 || d.Devices.Disks[0].Source.File != "/var/lib/libvirt/images/instances/vm-1/disk.qcow2" {
+		t.Errorf("unexpected disks: %+v", d.Devices.Disks)
+	}
+	if len(d.Devices.Interfaces) != 1 || d.Devices.Interfaces[0].MAC.Address != "52:54:00:12:34:56" {
+		t.Errorf("unexpected interfaces: %+v", d.Devices.Interfaces)
+	}
+	if port, err := libvirt.VNCAddress(d); err != nil || port != "127.0.0.1:5901" {
+		t.Errorf("VNCAddress = %q, %v", port, err)
+	}
+	if len(d.Devices.Graphics) != 1 || d.Devices.Graphics[0].Port != 5901 {
+		t.Errorf("unexpected graphics: %+v", d.Devices.Graphics)
+	}
+	if len(d.Devices.Channels) != 1 || d.Devices.Channels[0].Target.Name != libvirt.GuestAgentChannel || d.Devices.Channels[0].Target.State != "connected" {
+		t.Errorf("unexpected channels: %+v", d.Devices.Channels)
+	}
+	if got := libvirt.ConsolePTY(d); got != "/dev/pts/3" {
+		t.Errorf("ConsolePTY = %q, want /dev/pts/3", got)
+	}
+
+	// 정의만 된 도메인(NewDomain)에는 pty 경로가 없다
+	built, _ := libvirt.NewDomain(libvirt.VMConfig{Name: "vm", VCPUs: 1, MemoryMB: 512, DiskPath: "/disk.qcow2"})
+	if got := libvirt.ConsolePTY(built); got != "" {
+		t.Errorf("ConsolePTY of new domain = %q, want empty", got)
+	}
+}
+
+func TestSnapshotDisks(t *testing.T) {
+	cfg := libvirt.VMConfig{Name: "vm", VCPUs: 1, MemoryMB: 512, DiskPath: "/disk.qcow2", ISOPath: "/seed.img"}
+
+	// 1. raw seed(cdrom, vfat disk)는 내부 스냅샷에서 뺀다
+	for _, format := range []string{"iso", "vfat"} {
+		c := cfg
+		c.CloudInit.SeedFormat = format
+		d, err := libvirt.NewDomain(c)
+		if err != nil {
+			t.Fatalf("%s: NewDomain failed: %v", format, err)
+		}
+		disks, err := libvirt.SnapshotDisks(d)
+		if err != nil {
+			t.Fatalf("%s: SnapshotDisks failed: %v", format, err)
+		}
+		seed := "sda"
+		if format == "vfat" {
+			seed = "vdb"
+		}
+		if want := map[string]string{"vda": "internal", seed: "no"}; !reflect.DeepEqual(disks, want) {
+			t.Errorf("%s: unexpected disks: %v", format, disks)
+		}
+	}
+
+	// 2. UEFI 도메인은 정의한 XML이든 libvirt가 loader를 채운 XML이든 거부한다
+	c := cfg
+	c.UEFI = true
+	d, err := libvirt.NewDomain(c)
+	if err != nil {
+		t.Fatalf("NewDomain failed: %v", err)
+	}
+	if _, err := libvirt.SnapshotDisks(d); !errors.Is(err, libvirt.ErrSnapshotUnsupported) {
+		t.Errorf("expected ErrSnapshotUnsupported, got %v", err)
+	}
+	d, err = libvirt.ParseDomainXML(`<domain type='kvm'><name>vm</name><os>
+  <type arch='x86_64' machine='pc-q35-8.2'>hvm</type>
+  <loader readonly='yes' type='pflash'>/usr/share/OVMF/OVMF_CODE_4M.fd</loader>
+  <nvram>/var/lib/libvirt/qemu/nvram/vm_VARS.fd</nvram>
+</os></domain>`)
+	if err != nil {
+		t.Fatalf("ParseDomainXML failed: %v", err)
+	}
+	if _, err := libvirt.SnapshotDisks(d); !errors.Is(err, libvirt.ErrSnapshotUnsupported) {
+		t.Errorf("expected ErrSnapshotUnsupported for pflash loader, got %v", err)
+	}
+}
+
+func TestRebuildDomain(t *testing.T) {
+	// 다른 노드에서 받은 정의에 호스트 장치와 엉뚱한 디스크가 들어 있어도 MAC만 가져온다
+	archived, err := libvirt.ParseDomainXML(`<domain type='kvm'><name>vm-1</name>
+  <memory unit='KiB'>67108864</memory><vcpu>64</vcpu>
+  <devices>
+    <disk type='file' device='disk'><source file='/etc/shadow'/><target dev='vda' bus='virtio'/></disk>
+    <hostdev mode='subsystem' type='pci'><source><address domain='0' bus='1' slot='0' function='0'/></source></hostdev>
+    <interface type='network'><mac address='52:54:00:12:34:56'/><source network='default'/></interface>
+  </devices>
+</domain>`)
+	if err != nil {
+		t.Fatalf("ParseDomainXML failed: %v", err)
+	}
+	cfg := libvirt.VMConfig{
+		Name:        "vm-1",
+		VCPUs:       2,
+		MemoryMB:    2048,
+		DiskPath:    "/var/lib/libvirt/images/instances/vm-1/disk.qcow2",
+		ISOPath:     "/var/lib/libvirt/images/instances/vm-1/vm-1.iso",
+		VNCPassword: "Xk3pQ9wz",
+	}
+	d, err := libvirt.RebuildDomain(cfg, archived)
+	if err != nil {
+		t.Fatalf("RebuildDomain failed: %v", err)
+	}
+	cfg.MAC = "52:54:00:12:34:56"
+	want, _ := libvirt.NewDomain(cfg)
+	if !reflect.DeepEqual(d, want) {
+		t.Errorf("rebuilt domain mismatch\ngot:  %+v\nwant: %+v", d, want)
+	}
+
+	cfg.Name = "vm-2"
+	if _, err := libvirt.RebuildDomain(cfg, archived); err == nil {
+		t.Error("expected error for mismatched name")
+	}
+}
@@ -0,0 +1,81 @@
+package libvirt
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+)
+
+// 도메인 라이프사이클 이벤트 종류
+const (
+	EventDefined     = "defined"
+	EventUndefined   = "undefined"
+	EventStarted     = "started"
+	EventSuspended   = "suspended"
+	EventResumed     = "resumed"
+	EventStopped     = "stopped"
+	EventShutdown    = "shutdown"
+	EventPMSuspended = "pmsuspended"
+	EventCrashed     = "crashed"
+)
+
+// LifecycleEvent - libvirt DomainEventLifecycle 콜백을 변환한 이벤트
+type LifecycleEvent struct {
+	Domain string    `json:"domain"`
+	Type   string    `json:"type"`
+	Time   time.Time `json:"time"`
+}
+
+// SubscribeLifecycle - 모든 도메인의 라이프사이클 이벤트를 받는다.
+// ctx가 끝나거나 libvirt 연결이 끊기면 채널이 닫힌다.
+func (m *LibvirtManager) SubscribeLifecycle(ctx context.Context) (<-chan LifecycleEvent, error) {
+	raw, err := m.conn.LifecycleEvents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("라이프사이클 이벤트 구독 실패: %w", err)
+	}
+
+	ch := make(chan LifecycleEvent, 64)
+	go func() {
+		defer close(ch)
+		for msg := range raw {
+			ev := LifecycleEvent{
+				Domain: msg.Dom.Name,
+				Type:   lifecycleEventType(libvirt.DomainEventType(msg.Event)),
+				Time:   time.Now(),
+			}
+			select {
+			case ch <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func lifecycleEventType(t libvirt.DomainEventType) string {
+	switch t {
+	case libvirt.DomainEventDefined:
+		return EventDefined
+	case libvirt.DomainEventUndefined:
+		return EventUndefined
+	case libvirt.DomainEventStarted:
+		return EventStarted
+	case libvirt.DomainEventSuspended:
+		return EventSuspended
+	case libvirt.DomainEventResumed:
+		return EventResumed
+	case libvirt.DomainEventStopped:
+		return EventStopped
+	case libvirt.DomainEventShutdown:
+		return EventShutdown
+	case libvirt.DomainEventPmsuspended:
+		return EventPMSuspended
+	case libvirt.DomainEventCrashed:
+		return EventCrashed
+	default:
+		return fmt.Sprintf("unknown(%d)", t)
+	}
+}
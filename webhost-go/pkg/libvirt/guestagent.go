@@ -0,0 +1,142 @@
+package libvirt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/digitalocean/go-libvirt"
+)
+
+// GuestAgentChannel - 도메인 XML의 qemu-guest-agent virtio-serial 채널 이름
+const GuestAgentChannel = "org.qemu.guest_agent.0"
+
+// ErrGuestAgentUnavailable - 게스트 에이전트가 없거나 응답하지 않는다
+// (채널 없이 만든 도메인, 에이전트 설치 전, 부팅 중, 꺼진 도메인)
+var ErrGuestAgentUnavailable = errors.New("게스트 에이전트를 사용할 수 없습니다")
+
+// GuestInterface - 게스트 안에서 본 네트워크 인터페이스
+type GuestInterface struct {
+	Name      string   `json:"name"`
+	MAC       string   `json:"mac,omitempty"`
+	Addresses []string `json:"addresses"` // CIDR 형식 (192.168.122.10/24)
+}
+
+// GuestInfo - 게스트 에이전트가 보고한 OS 정보
+type GuestInfo struct {
+	Hostname      string `json:"hostname,omitempty"`
+	OSID          string `json:"os_id,omitempty"` // "ubuntu"
+	OSName        string `json:"os_name,omitempty"`
+	OSPrettyName  string `json:"os_pretty_name,omitempty"`
+	OSVersionID   string `json:"os_version_id,omitempty"`
+	KernelRelease string `json:"kernel_release,omitempty"`
+	Machine       string `json:"machine,omitempty"`
+}
+
+// GuestInterfaceAddresses - 게스트 에이전트로 읽은 인터페이스 주소 (loopback 제외)
+func (m *LibvirtManager) GuestInterfaceAddresses(name string) ([]GuestInterface, error) {
+	dom, err := m.conn.DomainLookupByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("도메인 조회 실패: %w", err)
+	}
+	ifaces, err := m.conn.DomainInterfaceAddresses(dom, uint32(libvirt.DomainInterfaceAddressesSrcAgent), 0)
+	if err != nil {
+		return nil, guestAgentError(err)
+	}
+
+	list := make([]GuestInterface, 0, len(ifaces))
+	for _, iface := range ifaces {
+		if iface.Name == "lo" {
+			continue
+		}
+		gi := GuestInterface{Name: iface.Name, Addresses: []string{}}
+		if len(iface.Hwaddr) > 0 {
+			gi.MAC = iface.Hwaddr[0]
+		}
+		for _, addr := range iface.Addrs {
+			gi.Addresses = append(gi.Addresses, fmt.Sprintf("%s/%d", addr.Addr, addr.Prefix))
+		}
+		list = append(list, gi)
+	}
+	return list, nil
+}
+
+// GetGuestInfo - 게스트 에이전트로 읽은 호스트명과 OS 정보
+func (m *LibvirtManager) GetGuestInfo(name string) (*GuestInfo, error) {
+	dom, err := m.conn.DomainLookupByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("도메인 조회 실패: %w", err)
+	}
+	params, err := m.conn.DomainGetGuestInfo(dom, uint32(libvirt.DomainGuestInfoOs|libvirt.DomainGuestInfoHostname), 0)
+	if err != nil {
+		return nil, guestAgentError(err)
+	}
+	info := ParseGuestInfo(params)
+	return &info, nil
+}
+
+// ParseGuestInfo - DomainGetGuestInfo의 typed parameter 중 os.*, hostname
+func ParseGuestInfo(params []libvirt.TypedParam) GuestInfo {
+	var info GuestInfo
+	for _, p := range params {
+		v, ok := p.Value.I.(string)
+		if !ok {
+			continue
+		}
+		switch p.Field {
+		case "hostname":
+			info.Hostname = v
+		case "os.id":
+			info.OSID = v
+		case "os.name":
+			info.OSName = v
+		case "os.pretty-name":
+			info.OSPrettyName = v
+		case "os.version-id":
+			info.OSVersionID = v
+		case "os.kernel-release":
+			info.KernelRelease = v
+		case "os.machine":
+			info.Machine = v
+		}
+	}
+	return info
+}
+
+// SetUserPassword - 게스트 계정의 비밀번호를 바꾼다 (password는 평문, 에이전트가 게스트 안에서 chpasswd)
+func (m *LibvirtManager) SetUserPassword(name, user, password string) error {
+	dom, err := m.conn.DomainLookupByName(name)
+	if err != nil {
+		return fmt.Errorf("도메인 조회 실패: %w", err)
+	}
+	if err := m.conn.DomainSetUserPassword(dom, libvirt.OptString{user}, libvirt.OptString{password}, 0); err != nil {
+		return guestAgentError(err)
+	}
+	return nil
+}
+
+// freezeFilesystems - 실행 중인 게스트의 파일시스템을 얼리고, 녹이는 함수를 돌려준다.
+// 에이전트가 없거나 꺼진 도메인이면 얼리지 않는다 (crash-consistent로 진행).
+func (m *LibvirtManager) freezeFilesystems(dom libvirt.Domain) (thaw func()) {
+	if _, err := m.conn.DomainFsfreeze(dom, nil, 0); err != nil {
+		return func() {}
+	}
+	return func() { m.thawFilesystems(dom) }
+}
+
+// thawFilesystems - 얼어 있지 않아도 에러 없이 끝난다
+func (m *LibvirtManager) thawFilesystems(dom libvirt.Domain) {
+	_, _ = m.conn.DomainFsthaw(dom, nil, 0)
+}
+
+// guestAgentError - 에이전트가 없거나 응답하지 않는 libvirt 오류를 ErrGuestAgentUnavailable로 바꾼다
+func guestAgentError(err error) error {
+	var e libvirt.Error
+	if errors.As(err, &e) {
+		switch libvirt.ErrorNumber(e.Code) {
+		case libvirt.ErrAgentUnresponsive, libvirt.ErrAgentUnsynced, libvirt.ErrArgumentUnsupported, libvirt.ErrOperationInvalid:
+			return fmt.Errorf("%w: %s", ErrGuestAgentUnavailable, strings.TrimSpace(e.Message))
+		}
+	}
+	return fmt.Errorf("게스트 에이전트 요청 실패: %w", err)
+}
@@ -0,0 +1,192 @@
+package libvirt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+
+	"webhost-go/webhost-go/pkg/nocloud"
+)
+
+// guestNetplanPath - cloud-init이 network-config로 만드는 netplan 파일
+const guestNetplanPath = "/etc/netplan/50-cloud-init.yaml"
+
+// guestExecTimeout - 게스트에서 실행한 명령(netplan apply)이 끝나기를 기다리는 시간
+const guestExecTimeout = 30 * time.Second
+
+// SetGuestNetwork - 실행 중인 도메인의 고정 IP를 ip로 바꾼다. ip는 이 노드 네트워크(GetDefaultNetworkConfig)의 주소여야 한다.
+// 게스트 에이전트로 netplan 파일을 쓰고 netplan apply를 실행한 뒤, 인스턴스 디렉토리의 seed 이미지
+// network-config도 같은 내용으로 바꾼다 (cloud-init은 첫 부팅 때만 적용하므로 게스트에는 에이전트로 적용한다)
+func (m *LibvirtManager) SetGuestNetwork(name string, ip net.IP) error {
+	dom, err := m.conn.DomainLookupByName(name)
+	if err != nil {
+		return fmt.Errorf("도메인 조회 실패: %w", err)
+	}
+	desc, err := m.conn.DomainGetXMLDesc(dom, 0)
+	if err != nil {
+		return fmt.Errorf("도메인 XML 가져오기 실패: %w", err)
+	}
+	d, err := ParseDomainXML(desc)
+	if err != nil {
+		return err
+	}
+	var mac string
+	for _, iface := range d.Devices.Interfaces {
+		if iface.MAC != nil {
+			mac = iface.MAC.Address
+			break
+		}
+	}
+	if mac == "" {
+		return fmt.Errorf("도메인에 NIC MAC 주소가 없습니다: %s", name)
+	}
+
+	netConf, err := m.GetDefaultNetworkConfig()
+	if err != nil {
+		return fmt.Errorf("네트워크 설정 조회 실패: %w", err)
+	}
+	if !netConf.CIDR.Contains(ip) {
+		return fmt.Errorf("이 노드의 네트워크(%s)에 없는 주소입니다: %s", netConf.CIDR, ip)
+	}
+	config := StaticNetworkConfig(ip, netConf.CIDR, netConf.Gateway, mac)
+
+	if err := m.guestWriteFile(dom, guestNetplanPath, NetplanFile(config)); err != nil {
+		return err
+	}
+	if err := m.guestExec(dom, "/usr/sbin/netplan", "apply"); err != nil {
+		return err
+	}
+
+	for _, format := range []nocloud.Format{nocloud.FormatISO, nocloud.FormatVFAT} {
+		if path := seedPath(instanceDir(name), name, format); fileExists(path) {
+			return ReplaceSeedNetworkConfig(path, format, config)
+		}
+	}
+	return nil
+}
+
+// NetplanFile - network-config(version 2)를 /etc/netplan 파일 형식(network: 아래)으로 바꾼다
+func NetplanFile(networkConfig []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("network:\n")
+	for _, line := range strings.SplitAfter(string(networkConfig), "\n") {
+		if strings.TrimSpace(line) != "" {
+			b.WriteString("  ")
+		}
+		b.WriteString(line)
+	}
+	return b.Bytes()
+}
+
+// ReplaceSeedNetworkConfig - seed 이미지의 meta-data, user-data는 그대로 두고 network-config만 바꾼다
+func ReplaceSeedNetworkConfig(path string, format nocloud.Format, networkConfig []byte) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("seed 이미지 열기 실패: %w", err)
+	}
+	var vol *nocloud.Volume
+	if format == nocloud.FormatVFAT {
+		vol, err = nocloud.ReadVFAT(f)
+	} else {
+		vol, err = nocloud.ReadISO(f)
+	}
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("seed 이미지 읽기 실패: %w", err)
+	}
+
+	seed := nocloud.Seed{
+		MetaData:      vol.Files["meta-data"],
+		UserData:      vol.Files["user-data"],
+		NetworkConfig: networkConfig,
+	}
+	if err := seed.WriteFile(path, format); err != nil {
+		return fmt.Errorf("seed 이미지 생성 실패: %w", err)
+	}
+	return nil
+}
+
+// agentCommand - qemu-guest-agent 명령을 보내고 "return" 값을 out에 읽는다
+func (m *LibvirtManager) agentCommand(dom libvirt.Domain, command string, args interface{}, out interface{}) error {
+	req, err := json.Marshal(map[string]interface{}{"execute": command, "arguments": args})
+	if err != nil {
+		return err
+	}
+	res, err := m.conn.QEMUDomainAgentCommand(dom, string(req), int32(libvirt.DomainAgentResponseTimeoutDefault), 0)
+	if err != nil {
+		return guestAgentError(err)
+	}
+	if out == nil || len(res) == 0 {
+		return nil
+	}
+	var resp struct {
+		Return json.RawMessage `json:"return"`
+	}
+	if err := json.Unmarshal([]byte(res[0]), &resp); err != nil {
+		return fmt.Errorf("게스트 에이전트 응답 해석 실패 (%s): %w", command, err)
+	}
+	return json.Unmarshal(resp.Return, out)
+}
+
+// guestWriteFile - 게스트 안의 path를 data로 덮어쓴다
+func (m *LibvirtManager) guestWriteFile(dom libvirt.Domain, path string, data []byte) error {
+	var handle int64
+	if err := m.agentCommand(dom, "guest-file-open", map[string]string{"path": path, "mode": "w"}, &handle); err != nil {
+		return fmt.Errorf("게스트 파일 열기 실패 (%s): %w", path, err)
+	}
+	werr := m.agentCommand(dom, "guest-file-write", map[string]interface{}{
+		"handle":  handle,
+		"buf-b64": base64.StdEncoding.EncodeToString(data),
+	}, nil)
+	cerr := m.agentCommand(dom, "guest-file-close", map[string]int64{"handle": handle}, nil)
+	if werr != nil {
+		return fmt.Errorf("게스트 파일 쓰기 실패 (%s): %w", path, werr)
+	}
+	if cerr != nil {
+		return fmt.Errorf("게스트 파일 닫기 실패 (%s): %w", path, cerr)
+	}
+	return nil
+}
+
+// guestExec - 게스트에서 명령을 실행하고 끝날 때까지 기다린다 (종료 코드가 0이 아니면 에러)
+func (m *LibvirtManager) guestExec(dom libvirt.Domain, path string, args ...string) error {
+	var started struct {
+		PID int64 `json:"pid"`
+	}
+	if err := m.agentCommand(dom, "guest-exec", map[string]interface{}{
+		"path":           path,
+		"arg":            args,
+		"capture-output": true,
+	}, &started); err != nil {
+		return fmt.Errorf("게스트 명령 실행 실패 (%s): %w", path, err)
+	}
+
+	deadline := time.Now().Add(guestExecTimeout)
+	for {
+		var status struct {
+			Exited   bool   `json:"exited"`
+			ExitCode int    `json:"exitcode"`
+			ErrData  []byte `json:"err-data"`
+		}
+		if err := m.agentCommand(dom, "guest-exec-status", map[string]int64{"pid": started.PID}, &status); err != nil {
+			return fmt.Errorf("게스트 명령 상태 조회 실패 (%s): %w", path, err)
+		}
+		if status.Exited {
+			if status.ExitCode != 0 {
+				return fmt.Errorf("게스트 명령 실패 (%s, 종료 코드 %d): %s", path, status.ExitCode, strings.TrimSpace(string(status.ErrData)))
+			}
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("게스트 명령이 %s 안에 끝나지 않았습니다: %s", guestExecTimeout, path)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
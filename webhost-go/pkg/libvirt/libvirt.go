@@ -2,22 +2,31 @@
 package libvirt
 
 import (
-	"bytes"
-	"encoding/xml"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strings"
-	"text/template"
 	"time"
 
 	"github.com/digitalocean/go-libvirt"
 	"net"
 )
 
+// ErrRemoteHost - 원격 libvirt URI(qemu+tcp, qemu+ssh)로 연결한 노드에서는 쓸 수 없는 기능.
+// 콘솔 pty와 VNC 포트는 libvirtd 호스트에만 있으므로 그 호스트의 libvirt-agent를 거쳐야 한다
+var ErrRemoteHost = errors.New("원격 libvirt URI로 연결한 노드에서는 쓸 수 없습니다 (libvirt-agent로 등록하세요)")
+
+// ErrDomainNotFound - libvirtd에 그 이름의 도메인이 없다
+var ErrDomainNotFound = errors.New("도메인이 없습니다")
+
 type LibvirtManager struct {
 	conn *libvirt.Libvirt
+	// remote - libvirtd가 다른 호스트에 있다 (NewLibvirtManagerURI에 호스트가 있는 URI)
+	remote bool
+	// BackupDir - 백업 파일을 저장할 디렉토리 (비어 있으면 DefaultBackupDir)
+	BackupDir string
 }
 
 type DomainInfo struct {
@@ -40,6 +49,26 @@ func NewLibvirtManager() (*LibvirtManager, error) {
 	return &LibvirtManager{conn: l}, nil
 }
 
+// Close - libvirtd 연결을 끊는다
+func (l *LibvirtManager) Close() error {
+	return l.conn.Disconnect()
+}
+
+// NewLibvirtManagerURI - libvirt URI로 연결한다 ("qemu:///system", "qemu+tcp://host/system", "qemu+ssh://user@host/system").
+// 디스크/cloud-init/백업 파일은 이 프로세스가 로컬 경로에 만들므로, 원격 libvirtd를 쓸 때는
+// 이미지/백업 디렉토리가 양쪽에서 같은 경로로 보이는 공유 저장소여야 한다
+func NewLibvirtManagerURI(uri string) (*LibvirtManager, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("libvirt URI 파싱 실패: %w", err)
+	}
+	l, err := libvirt.ConnectToURI(u)
+	if err != nil {
+		return nil, err
+	}
+	return &LibvirtManager{conn: l, remote: u.Host != ""}, nil
+}
+
 func (m *LibvirtManager) CreateDisk(path string, sizeGB int) error {
 	if _, err := os.Stat(path); err == nil {
 		return nil // already exists
@@ -48,16 +77,6 @@ func (m *LibvirtManager) CreateDisk(path string, sizeGB int) error {
 	return cmd.Run()
 }
 
-func (m *LibvirtManager) LoadDomainXML(tmplPath string, cfg VMConfig) (string, error) {
-	tmpl, err := template.ParseFiles(tmplPath)
-	if err != nil {
-		return "", err
-	}
-	var buf bytes.Buffer
-	err = tmpl.Execute(&buf, cfg)
-	return buf.String(), err
-}
-
 func (m *LibvirtManager) DefineAndStartVM(xmlStr string) error {
 	dom, err := m.conn.DomainDefineXML(xmlStr)
 	if err != nil {
@@ -66,21 +85,15 @@ func (m *LibvirtManager) DefineAndStartVM(xmlStr string) error {
 	return m.conn.DomainCreate(dom)
 }
 
-func (m *LibvirtManager) EnableSerialConsole(xmlTemplate string) string {
-	// This would add a <console> tag, assuming template does not have it already
-	// You should customize your XML template to include it instead if needed
-	return strings.Replace(xmlTemplate, "</devices>", `
-		<console type="pty">
-		  <target type="serial" port="0"/>
-		</console>
-	</devices>`, 1)
-}
-
+// Shutdown - 게스트 에이전트로 OS 종료를 요청하고, 에이전트가 없으면 ACPI 전원 버튼으로 보낸다
 func (m *LibvirtManager) Shutdown(name string) error {
 	dom, err := m.conn.DomainLookupByName(name)
 	if err != nil {
 		return err
 	}
+	if err := m.conn.DomainShutdownFlags(dom, libvirt.DomainShutdownGuestAgent); err == nil {
+		return nil
+	}
 	return m.conn.DomainShutdown(dom)
 }
 
@@ -114,8 +127,12 @@ func (m *LibvirtManager) DeleteDomain(name string, withDisks bool) error {
 		}
 	}
 
-	// 정의 제거
-	if err := m.conn.DomainUndefine(dom); err != nil {
+	// 정의 제거 (디스크까지 지우는 경우 스냅샷 메타데이터도 함께 삭제)
+	var flags libvirt.DomainUndefineFlagsValues
+	if withDisks {
+		flags |= libvirt.DomainUndefineSnapshotsMetadata
+	}
+	if err := m.conn.DomainUndefineFlags(dom, flags); err != nil {
 		return fmt.Errorf("도메인 정의 삭제 실패: %w", err)
 	}
 
@@ -159,9 +176,30 @@ func (l *LibvirtManager) DomainIsActive(name string) (bool, error) {
 	return rState == uint8(libvirt.DomainRunning), nil
 }
 
+// ListDomains - 정의된 모든 도메인(실행 중이 아닌 것 포함)의 이름과 상태
+func (l *LibvirtManager) ListDomains() ([]DomainSummary, error) {
+	domains, _, err := l.conn.ConnectListAllDomains(1, 0)
+	if err != nil {
+		return nil, fmt.Errorf("도메인 목록 조회 실패: %w", err)
+	}
+
+	list := make([]DomainSummary, 0, len(domains))
+	for _, dom := range domains {
+		state, _, err := l.conn.DomainGetState(dom, 0)
+		if err != nil {
+			return nil, fmt.Errorf("도메인 상태 조회 실패 (%s): %w", dom.Name, err)
+		}
+		list = append(list, DomainSummary{Name: dom.Name, State: uint8(state)})
+	}
+	return list, nil
+}
+
 func (l *LibvirtManager) GetDomainInfoByName(name string) (*DomainInfo, error) {
 	// 도메인 조회
 	domain, err := l.conn.DomainLookupByName(name)
+	if libvirt.IsNotFound(err) {
+		return nil, fmt.Errorf("%w: %s", ErrDomainNotFound, name)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("도메인 조회 실패: %w", err)
 	}
@@ -183,22 +221,14 @@ func (l *LibvirtManager) GetDomainInfoByName(name string) (*DomainInfo, error) {
 	return info, nil
 }
 
-type domainDiskXML struct {
-	Disks []struct {
-		Source struct {
-			File string `xml:"file,attr"`
-		} `xml:"source"`
-	} `xml:"devices>disk"`
-}
-
 func extractDiskPaths(xmlDesc string) []string {
-	var parsed domainDiskXML
-	if err := xml.Unmarshal([]byte(xmlDesc), &parsed); err != nil {
+	parsed, err := ParseDomainXML(xmlDesc)
+	if err != nil {
 		return nil
 	}
 
 	var paths []string
-	for _, disk := range parsed.Disks {
+	for _, disk := range parsed.Devices.Disks {
 		if disk.Source.File != "" {
 			paths = append(paths, disk.Source.File)
 		}
@@ -206,26 +236,23 @@ func extractDiskPaths(xmlDesc string) []string {
 	return paths
 }
 
-func (m *LibvirtManager) CopyTemplateDisk(dstPath string) error {
-	srcPath := "/var/lib/libvirt/images/templates/ubuntu-22.04-server-cloudimg-amd64.img"
-	cmd := exec.Command("cp", "-f", srcPath, dstPath)
-	return cmd.Run()
+// InstanceDiskPath - VM 루트 디스크 경로 (instances/<vm-name>/disk.qcow2)
+func InstanceDiskPath(vmName string) string {
+	return filepath.Join("/var/lib/libvirt/images/instances", vmName, "disk.qcow2")
 }
 
 func (m *LibvirtManager) StartUbuntuVM(vmName string) error {
 	baseDir := filepath.Join("/var/lib/libvirt/images/instances", vmName)
-	diskPath := filepath.Join(baseDir, "disk.qcow2")
-	isoPath := filepath.Join(baseDir, "cloud-init.iso")
-	xmlTemplatePath := "/etc/libvirt/templates/domain_template.xml"
+	diskPath := InstanceDiskPath(vmName)
 
 	// 1. 디렉토리 생성
 	if err := os.MkdirAll(baseDir, 0755); err != nil {
 		return fmt.Errorf("VM 디렉토리 생성 실패: %w", err)
 	}
 
-	// 2. 루트 디스크 복사 (템플릿에서)
-	if err := m.CopyTemplateDisk(diskPath); err != nil {
-		return fmt.Errorf("디스크 복사 실패: %w", err)
+	// 2. 루트 디스크 생성 (템플릿 위의 overlay)
+	if err := m.CreateOverlayDisk(diskPath, DefaultTemplate); err != nil {
+		return fmt.Errorf("디스크 생성 실패: %w", err)
 	}
 
 	// 3. cloud-init ISO 생성
@@ -234,7 +261,7 @@ func (m *LibvirtManager) StartUbuntuVM(vmName string) error {
 		return fmt.Errorf("cloud-init ISO 생성 실패: %w", err)
 	}
 
-	// 4. 도메인 XML 로드 및 구성
+	// 4. 도메인 XML 생성 (시리얼 콘솔 포함)
 	cfg := VMConfig{
 		Name:     vmName,
 		MemoryMB: 1024, // 메모리 설정 (1GB)
@@ -242,15 +269,12 @@ func (m *LibvirtManager) StartUbuntuVM(vmName string) error {
 		DiskPath: diskPath,
 		ISOPath:  isoPath,
 	}
-	xmlStr, err := m.LoadDomainXML(xmlTemplatePath, cfg)
+	xmlStr, err := BuildDomainXML(cfg)
 	if err != nil {
-		return fmt.Errorf("도메인 XML 로드 실패: %w", err)
+		return err
 	}
 
-	// 5. 시리얼 콘솔 추가
-	xmlStr = m.EnableSerialConsole(xmlStr)
-
-	// 6. 도메인 정의 및 시작
+	// 5. 도메인 정의 및 시작
 	if err := m.DefineAndStartVM(xmlStr); err != nil {
 		return fmt.Errorf("VM 시작 실패: %w", err)
 	}
@@ -258,54 +282,143 @@ func (m *LibvirtManager) StartUbuntuVM(vmName string) error {
 	return nil
 }
 
-func (m *LibvirtManager) StartUbuntuVMWithStaticIP(vmName string, staticIP net.IP) error {
+// StartUbuntuVMWithStaticIP - 템플릿 디스크와 static IP cloud-init으로 VM을 만들고 부팅한다.
+// cfg의 Name, VCPUs, MemoryMB, DiskGB를 사용하며 DiskPath/ISOPath는 여기서 채운다.
+// progress가 nil이 아니면 각 단계(StepCopyingDisk 등)를 시작할 때 호출된다.
+func (m *LibvirtManager) StartUbuntuVMWithStaticIP(cfg VMConfig, staticIP net.IP, progress func(step string)) error {
+	report := func(step string) {
+		if progress != nil {
+			progress(step)
+		}
+	}
+
+	vmName := cfg.Name
 	baseDir := filepath.Join("/var/lib/libvirt/images/instances", vmName)
-	diskPath := filepath.Join(baseDir, "disk.qcow2")
-	isoPath := filepath.Join(baseDir, "cloud-init.iso")
-	xmlTemplatePath := "/etc/libvirt/templates/domain_template.xml"
+	diskPath := InstanceDiskPath(vmName)
 
 	// 1. 디렉토리 생성
 	if err := os.MkdirAll(baseDir, 0755); err != nil {
 		return fmt.Errorf("VM 디렉토리 생성 실패: %w", err)
 	}
 
-	// 2. 템플릿 디스크 복사
-	if err := m.CopyTemplateDisk(diskPath); err != nil {
-		return fmt.Errorf("디스크 복사 실패: %w", err)
+	// 2. 템플릿 위에 overlay 디스크 생성 (SourceDisk가 있으면 백업에서 복사)
+	report(StepCopyingDisk)
+	if cfg.SourceDisk != "" {
+		if err := m.checkBackupPath(cfg.SourceDisk); err != nil {
+			return err
+		}
+		if err := copyDisk(cfg.SourceDisk, diskPath); err != nil {
+			return err
+		}
+	} else if err := m.CreateOverlayDisk(diskPath, cfg.templateName()); err != nil {
+		return fmt.Errorf("디스크 생성 실패: %w", err)
 	}
 
-	// 디스크 크기 조절 (예: 10GB로 확장)
-	resizeCmd := exec.Command("qemu-img", "resize", diskPath, "10G")
+	// 디스크 크기 조절 (플랜의 DiskGB로 확장)
+	report(StepResizingDisk)
+	resizeCmd := exec.Command("qemu-img", "resize", diskPath, fmt.Sprintf("%dG", cfg.DiskGB))
 	if output, err := resizeCmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("디스크 크기 조절 실패: %w\n출력: %s", err, output)
 	}
 
-	// 3. Static IP 기반 cloud-init ISO 생성
-	isoPath, err := m.CreateCloudInitISOWithStaticIP(vmName, staticIP)
+	// 3. Static IP 기반 cloud-init ISO 생성 (NIC는 MAC으로 찾으므로 도메인보다 먼저 정한다)
+	report(StepBuildingCloudInit)
+	if cfg.MAC == "" {
+		mac, err := RandomMAC()
+		if err != nil {
+			return err
+		}
+		cfg.MAC = mac
+	}
+	isoPath, err := m.CreateCloudInitISOWithStaticIP(vmName, staticIP, cfg.MAC, cfg.CloudInit)
 	if err != nil {
 		return fmt.Errorf("cloud-init ISO 생성 실패: %w", err)
 	}
 
-	// 4. 도메인 XML 생성
-	cfg := VMConfig{
-		Name:     vmName,
-		MemoryMB: 1024,
-		VCPUs:    1,
-		DiskPath: diskPath,
-		ISOPath:  isoPath,
-	}
-	xmlStr, err := m.LoadDomainXML(xmlTemplatePath, cfg)
+	// 4. 도메인 XML 생성 (시리얼 콘솔과 플랜/이미지의 선택 기능 포함)
+	cfg.DiskPath = diskPath
+	cfg.ISOPath = isoPath
+	xmlStr, err := BuildDomainXML(cfg)
 	if err != nil {
-		return fmt.Errorf("도메인 XML 로드 실패: %w", err)
+		return err
 	}
 
-	// 5. 시리얼 콘솔 추가
-	xmlStr = m.EnableSerialConsole(xmlStr)
-
-	// 6. VM 정의 및 시작
+	// 5. VM 정의 및 시작
+	report(StepBooting)
 	if err := m.DefineAndStartVM(xmlStr); err != nil {
 		return fmt.Errorf("VM 시작 실패: %w", err)
 	}
 
 	return nil
 }
+
+// ResizeDomain - vCPU/메모리를 바꾸고 루트 디스크를 diskGB로 늘린다.
+// 도메인 설정에는 항상 반영하고, 실행 중이면 live 적용을 시도한다.
+// live 적용이 불가능하면(최대치 초과 등) 다음 부팅부터 적용되며 live=false를 돌려준다.
+// 디스크 축소는 허용하지 않는다.
+func (m *LibvirtManager) ResizeDomain(name string, vcpus, memoryMB, diskGB int) (live bool, err error) {
+	dom, err := m.conn.DomainLookupByName(name)
+	if err != nil {
+		return false, fmt.Errorf("도메인 조회 실패: %w", err)
+	}
+	active, err := m.conn.DomainIsActive(dom)
+	if err != nil {
+		return false, fmt.Errorf("도메인 상태 조회 실패: %w", err)
+	}
+
+	// 1. 디스크 확장 (축소 거부)
+	diskPath := InstanceDiskPath(name)
+	_, capacity, _, err := m.conn.DomainGetBlockInfo(dom, diskPath, 0)
+	if err != nil {
+		return false, fmt.Errorf("디스크 정보 조회 실패: %w", err)
+	}
+	size := uint64(diskGB) << 30
+	if size < capacity {
+		return false, fmt.Errorf("디스크는 줄일 수 없습니다 (현재 %dGB, 요청 %dGB)", capacity>>30, diskGB)
+	}
+	if size > capacity {
+		if active != 0 {
+			// 실행 중인 디스크는 qemu가 잠그고 있으므로 libvirt를 통해 늘린다
+			err = m.conn.DomainBlockResize(dom, diskPath, size, libvirt.DomainBlockResizeBytes)
+		} else {
+			var output []byte
+			output, err = exec.Command("qemu-img", "resize", diskPath, fmt.Sprintf("%dG", diskGB)).CombinedOutput()
+			if err != nil {
+				err = fmt.Errorf("%w\n출력: %s", err, output)
+			}
+		}
+		if err != nil {
+			return false, fmt.Errorf("디스크 크기 조절 실패: %w", err)
+		}
+	}
+
+	// 2. 설정 반영 (최대치 → 현재값 순서)
+	config := uint32(libvirt.DomainAffectConfig)
+	if err := m.conn.DomainSetVcpusFlags(dom, uint32(vcpus), config|uint32(libvirt.DomainVCPUMaximum)); err != nil {
+		return false, fmt.Errorf("최대 vCPU 설정 실패: %w", err)
+	}
+	if err := m.conn.DomainSetVcpusFlags(dom, uint32(vcpus), config); err != nil {
+		return false, fmt.Errorf("vCPU 설정 실패: %w", err)
+	}
+	memKB := uint64(memoryMB) * 1024
+	if err := m.conn.DomainSetMemoryFlags(dom, memKB, config|uint32(libvirt.DomainMemMaximum)); err != nil {
+		return false, fmt.Errorf("최대 메모리 설정 실패: %w", err)
+	}
+	if err := m.conn.DomainSetMemoryFlags(dom, memKB, config); err != nil {
+		return false, fmt.Errorf("메모리 설정 실패: %w", err)
+	}
+
+	if active == 0 {
+		return false, nil
+	}
+
+	// 3. 실행 중인 도메인에 live 적용 시도 (실패해도 설정은 이미 반영됨)
+	liveFlag := uint32(libvirt.DomainAffectLive)
+	if err := m.conn.DomainSetVcpusFlags(dom, uint32(vcpus), liveFlag); err != nil {
+		return false, nil
+	}
+	if err := m.conn.DomainSetMemoryFlags(dom, memKB, liveFlag); err != nil {
+		return false, nil
+	}
+	return true, nil
+}
@@ -0,0 +1,371 @@
+package libvirt
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/digitalocean/go-libvirt"
+
+	"webhost-go/webhost-go/pkg/nocloud"
+)
+
+// 인스턴스 아카이브 내용 (ExportInstance). 아카이브는 노드 사이에 VM을 옮길 때 쓰는 tar 스트림이다
+const (
+	// ArchiveFull - 도메인 정의와 인스턴스 디렉토리 전체 (꺼진 VM, 저장소를 공유하지 않는 노드)
+	ArchiveFull = "full"
+	// ArchiveSkeleton - 도메인 정의와 루트 디스크를 뺀 파일(seed 등), 루트 디스크의 크기와 backing file.
+	// 받는 쪽은 같은 크기의 빈 디스크를 만들고 내용은 live migration이 복사한다
+	ArchiveSkeleton = "skeleton"
+	// ArchiveDefinition - 도메인 정의만 (인스턴스 디렉토리를 공유하는 노드)
+	ArchiveDefinition = "definition"
+)
+
+const (
+	archiveDomainXML   = "domain.xml"
+	archiveDiskInfo    = "disk.json"
+	archiveFilesPrefix = "files/"
+)
+
+// ErrDomainActive - 꺼진 도메인에만 할 수 있는 작업
+var ErrDomainActive = errors.New("실행 중인 도메인입니다")
+
+// archiveDisk - ArchiveSkeleton에서 루트 디스크 대신 보내는 정보
+type archiveDisk struct {
+	VirtualSize int64  `json:"virtual_size"`
+	BackingFile string `json:"backing_file,omitempty"`
+}
+
+func instanceDir(vmName string) string {
+	return filepath.Join(instancesDir, vmName)
+}
+
+// ExportInstance - 도메인 정의(migratable XML)와 인스턴스 파일을 tar 스트림으로 내보낸다.
+// ArchiveFull은 꺼진 도메인만 가능하다. 스트림을 다 읽거나 닫아야 한다
+func (m *LibvirtManager) ExportInstance(name, content string) (io.ReadCloser, error) {
+	switch content {
+	case ArchiveFull, ArchiveSkeleton, ArchiveDefinition:
+	default:
+		return nil, fmt.Errorf("알 수 없는 아카이브 내용입니다: %q", content)
+	}
+
+	dom, err := m.conn.DomainLookupByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("도메인 조회 실패: %w", err)
+	}
+	if content == ArchiveFull {
+		active, err := m.conn.DomainIsActive(dom)
+		if err != nil {
+			return nil, fmt.Errorf("도메인 상태 조회 실패: %w", err)
+		}
+		if active != 0 {
+			return nil, fmt.Errorf("%w: 디스크를 복사하려면 도메인을 꺼야 합니다", ErrDomainActive)
+		}
+	}
+	xmlDesc, err := m.conn.DomainGetXMLDesc(dom, libvirt.DomainXMLInactive|libvirt.DomainXMLMigratable)
+	if err != nil {
+		return nil, fmt.Errorf("도메인 XML 가져오기 실패: %w", err)
+	}
+
+	var disk *archiveDisk
+	if content == ArchiveSkeleton {
+		info, err := diskImageInfo(InstanceDiskPath(name))
+		if err != nil {
+			return nil, err
+		}
+		disk = &archiveDisk{VirtualSize: info.VirtualSize, BackingFile: info.FullBackingFile}
+		if disk.BackingFile == "" {
+			disk.BackingFile = info.BackingFile
+		}
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeInstanceArchive(pw, name, content, xmlDesc, disk))
+	}()
+	return pr, nil
+}
+
+func writeInstanceArchive(w io.Writer, name, content, xmlDesc string, disk *archiveDisk) error {
+	tw := tar.NewWriter(w)
+	if err := writeTarEntry(tw, archiveDomainXML, int64(len(xmlDesc)), strings.NewReader(xmlDesc)); err != nil {
+		return err
+	}
+	if disk != nil {
+		data, err := json.Marshal(disk)
+		if err != nil {
+			return err
+		}
+		if err := writeTarEntry(tw, archiveDiskInfo, int64(len(data)), bytes.NewReader(data)); err != nil {
+			return err
+		}
+	}
+
+	if content != ArchiveDefinition {
+		entries, err := os.ReadDir(instanceDir(name))
+		if err != nil {
+			return fmt.Errorf("인스턴스 디렉토리 읽기 실패: %w", err)
+		}
+		for _, e := range entries {
+			path := filepath.Join(instanceDir(name), e.Name())
+			if !e.Type().IsRegular() || (disk != nil && path == InstanceDiskPath(name)) {
+				continue
+			}
+			if err := writeTarFile(tw, path); err != nil {
+				return err
+			}
+		}
+	}
+	return tw.Close()
+}
+
+func writeTarFile(tw *tar.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("파일 열기 실패 (%s): %w", path, err)
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("파일 정보 조회 실패 (%s): %w", path, err)
+	}
+	return writeTarEntry(tw, archiveFilesPrefix+filepath.Base(path), st.Size(), f)
+}
+
+func writeTarEntry(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: size, Typeflag: tar.TypeReg}); err != nil {
+		return fmt.Errorf("아카이브 쓰기 실패 (%s): %w", name, err)
+	}
+	if _, err := io.Copy(tw, r); err != nil {
+		return fmt.Errorf("아카이브 쓰기 실패 (%s): %w", name, err)
+	}
+	return nil
+}
+
+// ImportInstance - ExportInstance 스트림을 인스턴스 디렉토리에 풀고, define이면 도메인을 정의한다 (꺼진 상태).
+// 정의는 아카이브의 XML이 아니라 cfg(사양, 선택 기능, seed 형식, VNC 비밀번호)와 인스턴스 디렉토리 경로로
+// 다시 만든다 (RebuildDomain). 파일이 든 아카이브는 인스턴스 디렉토리가 없어야 하며, 실패하면 만든 디렉토리를 지운다
+func (m *LibvirtManager) ImportInstance(cfg VMConfig, r io.Reader, define bool) (err error) {
+	name := cfg.Name
+	dir := instanceDir(name)
+	created := false
+	defer func() {
+		if err != nil && created {
+			os.RemoveAll(dir)
+		}
+	}()
+	mkdir := func() error {
+		if created {
+			return nil
+		}
+		if _, err := os.Stat(dir); err == nil {
+			return fmt.Errorf("인스턴스 디렉토리가 이미 있습니다: %s", dir)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("디렉터리 생성 실패: %w", err)
+		}
+		created = true
+		return nil
+	}
+
+	var xmlDesc string
+	var disk *archiveDisk
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("아카이브 읽기 실패: %w", err)
+		}
+
+		switch {
+		case hdr.Name == archiveDomainXML:
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return fmt.Errorf("아카이브 읽기 실패: %w", err)
+			}
+			xmlDesc = string(data)
+		case hdr.Name == archiveDiskInfo:
+			disk = &archiveDisk{}
+			if err := json.NewDecoder(tr).Decode(disk); err != nil {
+				return fmt.Errorf("디스크 정보 파싱 실패: %w", err)
+			}
+		case strings.HasPrefix(hdr.Name, archiveFilesPrefix):
+			file := strings.TrimPrefix(hdr.Name, archiveFilesPrefix)
+			if file == "" || file != filepath.Base(file) || file == "." || file == ".." {
+				return fmt.Errorf("잘못된 아카이브 항목입니다: %q", hdr.Name)
+			}
+			if err := mkdir(); err != nil {
+				return err
+			}
+			if err := writeFileFrom(filepath.Join(dir, file), tr); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("잘못된 아카이브 항목입니다: %q", hdr.Name)
+		}
+	}
+
+	if xmlDesc == "" {
+		return errors.New("아카이브에 도메인 정의가 없습니다")
+	}
+	d, err := ParseDomainXML(xmlDesc)
+	if err != nil {
+		return err
+	}
+	if d.Name != name {
+		return fmt.Errorf("아카이브의 도메인 이름이 다릅니다: %s", d.Name)
+	}
+
+	// live migration은 대상에 같은 크기의 디스크가 있어야 내용을 복사한다
+	if disk != nil {
+		if err := mkdir(); err != nil {
+			return err
+		}
+		args := []string{"create", "-f", "qcow2"}
+		if disk.BackingFile != "" {
+			if _, err := os.Stat(disk.BackingFile); err != nil {
+				return fmt.Errorf("backing file 확인 실패: %w", err)
+			}
+			args = append(args, "-b", disk.BackingFile, "-F", "qcow2")
+		}
+		args = append(args, InstanceDiskPath(name), strconv.FormatInt(disk.VirtualSize, 10))
+		if output, err := exec.Command("qemu-img", args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("디스크 생성 실패: %w\n출력: %s", err, output)
+		}
+	}
+
+	if define {
+		format, err := nocloud.ParseFormat(cfg.CloudInit.SeedFormat)
+		if err != nil {
+			return err
+		}
+		cfg.DiskPath, cfg.ISOPath = InstanceDiskPath(name), ""
+		if seed := seedPath(dir, name, format); fileExists(seed) {
+			cfg.ISOPath = seed
+		}
+		rebuilt, err := RebuildDomain(cfg, d)
+		if err != nil {
+			return err
+		}
+		out, err := rebuilt.XML()
+		if err != nil {
+			return err
+		}
+		if _, err := m.conn.DomainDefineXMLFlags(out, 0); err != nil {
+			return fmt.Errorf("도메인 정의 실패: %w", err)
+		}
+	}
+	return nil
+}
+
+// RebuildDomain - 다른 노드에서 받은 도메인 정의 대신 cfg로 새로 만든 정의.
+// 받은 정의에서는 게스트 네트워크 설정(MAC으로 NIC를 찾는다)이 맞도록 첫 NIC의 MAC만 가져온다.
+// 디스크 경로, 장치, 사양은 받은 값을 쓰지 않는다
+func RebuildDomain(cfg VMConfig, archived *Domain) (*Domain, error) {
+	if archived.Name != cfg.Name {
+		return nil, fmt.Errorf("아카이브의 도메인 이름이 다릅니다: %s", archived.Name)
+	}
+	if cfg.MAC == "" {
+		for _, iface := range archived.Devices.Interfaces {
+			if iface.MAC != nil {
+				cfg.MAC = iface.MAC.Address
+				break
+			}
+		}
+	}
+	return NewDomain(cfg)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func writeFileFrom(path string, r io.Reader) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("파일 생성 실패 (%s): %w", path, err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("파일 쓰기 실패 (%s): %w", path, err)
+	}
+	return f.Close()
+}
+
+// MigrateDomain - 실행 중인(또는 일시 정지된) 도메인을 destURI의 libvirtd로 live migration 한다.
+// 원본 libvirtd가 대상에 직접 연결하며(peer-to-peer), 끝나면 대상에 정의가 남고 원본의 정의는 지워진다.
+// copyStorage면 루트 디스크 내용도 복사한다 (대상에 ImportInstance(ArchiveSkeleton)로 디스크를 먼저 만들어 둔다).
+// 실패하면 도메인은 원본에서 계속 실행된다
+func (m *LibvirtManager) MigrateDomain(name, destURI string, copyStorage bool) error {
+	dom, err := m.conn.DomainLookupByName(name)
+	if err != nil {
+		return fmt.Errorf("도메인 조회 실패: %w", err)
+	}
+
+	flags := libvirt.MigrateLive | libvirt.MigratePeer2peer | libvirt.MigratePersistDest |
+		libvirt.MigrateUndefineSource | libvirt.MigrateAutoConverge
+	var params []libvirt.TypedParam
+	if copyStorage {
+		backing, err := DiskBackingFile(InstanceDiskPath(name))
+		if err != nil {
+			return err
+		}
+		// overlay면 템플릿(backing file)은 대상에도 있으므로 overlay만 복사한다
+		if backing != "" {
+			flags |= libvirt.MigrateNonSharedInc
+		} else {
+			flags |= libvirt.MigrateNonSharedDisk
+		}
+		// seed 이미지는 ImportInstance가 미리 옮겨 둔다
+		params = append(params, libvirt.TypedParam{
+			Field: libvirt.MigrateParamMigrateDisks,
+			Value: *libvirt.NewTypedParamValueString(rootDiskTarget),
+		})
+	}
+
+	if _, err := m.conn.DomainMigratePerform3Params(dom, libvirt.OptString{destURI}, params, nil, flags); err != nil {
+		return fmt.Errorf("도메인 이전 실패: %w", err)
+	}
+	return nil
+}
+
+// DiscardInstance - 꺼진 도메인의 정의를 지우고, withFiles면 인스턴스 디렉토리도 지운다.
+// 도메인이 없으면 파일만 정리한다 (live migration 뒤의 원본, 이전 실패 뒤의 대상)
+func (m *LibvirtManager) DiscardInstance(name string, withFiles bool) error {
+	dom, err := m.conn.DomainLookupByName(name)
+	switch {
+	case err == nil:
+		active, err := m.conn.DomainIsActive(dom)
+		if err != nil {
+			return fmt.Errorf("도메인 상태 조회 실패: %w", err)
+		}
+		if active != 0 {
+			return fmt.Errorf("%w: 정리하려면 도메인을 꺼야 합니다", ErrDomainActive)
+		}
+		flags := libvirt.DomainUndefineSnapshotsMetadata | libvirt.DomainUndefineNvram
+		if err := m.conn.DomainUndefineFlags(dom, flags); err != nil {
+			return fmt.Errorf("도메인 정의 삭제 실패: %w", err)
+		}
+	case !libvirt.IsNotFound(err):
+		return fmt.Errorf("도메인 조회 실패: %w", err)
+	}
+
+	if withFiles {
+		if err := os.RemoveAll(instanceDir(name)); err != nil {
+			return fmt.Errorf("인스턴스 디렉토리 삭제 실패: %w", err)
+		}
+	}
+	return nil
+}
@@ -45,7 +45,7 @@ func TestGetUsableIPs(t *testing.T) {
 	fmt.Println("🌐 Gateway:", netConf.Gateway.String())
 
 	// 사용 가능한 IP 목록 가져오기
-	usableIPs, err := manager.GetUsableIPs()
+	usableIPs, err := manager.GetUsableIPs(nil)
 	if err != nil {
 		t.Fatalf("사용 가능한 IP 조회 실패: %v", err)
 	}
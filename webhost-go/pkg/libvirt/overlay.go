@@ -0,0 +1,242 @@
+package libvirt
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// TemplateDir - 부팅 디스크 템플릿 위치. 인스턴스 디스크는 템플릿을 backing file로 쓰는 overlay다.
+	TemplateDir     = "/var/lib/libvirt/images/templates"
+	DefaultTemplate = "ubuntu-22.04-server-cloudimg-amd64.img"
+
+	instancesDir = "/var/lib/libvirt/images/instances"
+)
+
+// ErrTemplateInUse - overlay가 참조 중인 템플릿 삭제 시도
+var ErrTemplateInUse = errors.New("템플릿을 사용 중인 VM이 있습니다")
+
+// TemplateSummary - 템플릿과 이를 backing file로 쓰는 도메인 목록
+type TemplateSummary struct {
+	Name      string   `json:"name"`
+	SizeBytes int64    `json:"size_bytes"`
+	Users     []string `json:"users"`
+}
+
+// TemplatePath - 템플릿 이름을 경로로 바꾼다 (디렉토리 이동은 허용하지 않는다)
+func TemplatePath(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return "", fmt.Errorf("잘못된 템플릿 이름입니다: %q", name)
+	}
+	return filepath.Join(TemplateDir, name), nil
+}
+
+func (cfg VMConfig) templateName() string {
+	if cfg.Template == "" {
+		return DefaultTemplate
+	}
+	return cfg.Template
+}
+
+// CreateOverlayDisk - 템플릿을 backing file로 하는 qcow2 overlay를 만든다 (복사 없이 즉시 생성)
+func (m *LibvirtManager) CreateOverlayDisk(dstPath, templateName string) error {
+	tpl, err := TemplatePath(templateName)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(tpl); err != nil {
+		return fmt.Errorf("템플릿 확인 실패: %w", err)
+	}
+	output, err := exec.Command("qemu-img", "create", "-f", "qcow2", "-b", tpl, "-F", "qcow2", dstPath).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("overlay 디스크 생성 실패: %w\n출력: %s", err, output)
+	}
+	return nil
+}
+
+// DiskBackingFile - qcow2 디스크의 backing file 절대 경로 (독립 디스크면 빈 문자열)
+func DiskBackingFile(path string) (string, error) {
+	info, err := diskImageInfo(path)
+	if err != nil {
+		return "", err
+	}
+	if info.FullBackingFile != "" {
+		return info.FullBackingFile, nil
+	}
+	return info.BackingFile, nil
+}
+
+// qemuImgInfo - qemu-img info 결과 중 필요한 값
+type qemuImgInfo struct {
+	VirtualSize     int64  `json:"virtual-size"`
+	BackingFile     string `json:"backing-filename"`
+	FullBackingFile string `json:"full-backing-filename"`
+}
+
+func diskImageInfo(path string) (*qemuImgInfo, error) {
+	output, err := exec.Command("qemu-img", "info", "-U", "--output=json", path).Output()
+	if err != nil {
+		return nil, fmt.Errorf("디스크 정보 조회 실패 (%s): %w", path, err)
+	}
+	var info qemuImgInfo
+	if err := json.Unmarshal(output, &info); err != nil {
+		return nil, fmt.Errorf("디스크 정보 파싱 실패 (%s): %w", path, err)
+	}
+	return &info, nil
+}
+
+// FlattenDisk - 템플릿 데이터를 인스턴스 디스크로 모두 가져와 backing file 의존을 없앤다.
+// 실행 중이면 block pull, 중지 상태면 qemu-img rebase로 처리한다.
+func (m *LibvirtManager) FlattenDisk(domainName string) error {
+	dom, err := m.conn.DomainLookupByName(domainName)
+	if err != nil {
+		return fmt.Errorf("도메인 조회 실패: %w", err)
+	}
+	active, err := m.conn.DomainIsActive(dom)
+	if err != nil {
+		return fmt.Errorf("도메인 상태 조회 실패: %w", err)
+	}
+
+	diskPath := InstanceDiskPath(domainName)
+	backing, err := DiskBackingFile(diskPath)
+	if err != nil {
+		return err
+	}
+	if backing == "" {
+		return nil // 이미 독립 디스크
+	}
+
+	if active == 0 {
+		output, err := exec.Command("qemu-img", "rebase", "-f", "qcow2", "-b", "", diskPath).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("디스크 평탄화 실패: %w\n출력: %s", err, output)
+		}
+		return nil
+	}
+
+	if err := m.conn.DomainBlockPull(dom, rootDiskTarget, 0, 0); err != nil {
+		return fmt.Errorf("block pull 시작 실패: %w", err)
+	}
+	// block pull은 끝나면 작업이 자동으로 사라진다
+	for {
+		found, _, _, _, _, err := m.conn.DomainGetBlockJobInfo(dom, rootDiskTarget, 0)
+		if err != nil {
+			return fmt.Errorf("block pull 진행 상황 조회 실패: %w", err)
+		}
+		if found == 0 {
+			return nil
+		}
+		time.Sleep(blockCommitPollInterval)
+	}
+}
+
+// TemplateChecksum - 템플릿 파일의 sha256 (hex)
+func (m *LibvirtManager) TemplateChecksum(name string) (string, error) {
+	path, err := TemplatePath(name)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("템플릿 열기 실패: %w", err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", fmt.Errorf("템플릿 읽기 실패: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// ListTemplates - 템플릿 목록과 각 템플릿을 쓰는 도메인
+func (m *LibvirtManager) ListTemplates() ([]TemplateSummary, error) {
+	entries, err := os.ReadDir(TemplateDir)
+	if err != nil {
+		return nil, fmt.Errorf("템플릿 디렉토리 조회 실패: %w", err)
+	}
+	users, err := templateUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	list := []TemplateSummary{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, fmt.Errorf("템플릿 정보 조회 실패 (%s): %w", e.Name(), err)
+		}
+		path := filepath.Join(TemplateDir, e.Name())
+		list = append(list, TemplateSummary{
+			Name:      e.Name(),
+			SizeBytes: info.Size(),
+			Users:     append([]string{}, users[path]...),
+		})
+	}
+	return list, nil
+}
+
+// DeleteTemplate - overlay가 아직 참조하는 템플릿은 지울 수 없다 (먼저 flatten 필요)
+func (m *LibvirtManager) DeleteTemplate(name string) error {
+	path, err := TemplatePath(name)
+	if err != nil {
+		return err
+	}
+	users, err := templateUsers()
+	if err != nil {
+		return err
+	}
+	if len(users[path]) > 0 {
+		return fmt.Errorf("%w: %v", ErrTemplateInUse, users[path])
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("템플릿 삭제 실패: %w", err)
+	}
+	return nil
+}
+
+// templateUsers - backing file 경로 → 그 파일을 쓰는 인스턴스(도메인) 이름
+func templateUsers() (map[string][]string, error) {
+	entries, err := os.ReadDir(instancesDir)
+	if os.IsNotExist(err) {
+		return map[string][]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("인스턴스 디렉토리 조회 실패: %w", err)
+	}
+
+	users := map[string][]string{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		disk := InstanceDiskPath(e.Name())
+		if _, err := os.Stat(disk); err != nil {
+			continue
+		}
+		backing, err := DiskBackingFile(disk)
+		if err != nil {
+			return nil, err
+		}
+		if backing != "" {
+			backing = filepath.Clean(backing)
+			users[backing] = append(users[backing], e.Name())
+		}
+	}
+	for _, names := range users {
+		sort.Strings(names)
+	}
+	return users, nil
+}
@@ -0,0 +1,89 @@
+package libvirt
+
+import (
+	"fmt"
+
+	"github.com/digitalocean/go-libvirt"
+)
+
+// Start - 꺼진 도메인은 부팅하고(DomainCreate), 일시 정지된 도메인은 재개한다. 이미 실행 중이면 그대로 둔다
+func (m *LibvirtManager) Start(name string) error {
+	dom, err := m.conn.DomainLookupByName(name)
+	if err != nil {
+		return fmt.Errorf("도메인 조회 실패: %w", err)
+	}
+	state, _, err := m.conn.DomainGetState(dom, 0)
+	if err != nil {
+		return fmt.Errorf("도메인 상태 조회 실패: %w", err)
+	}
+
+	switch libvirt.DomainState(state) {
+	case libvirt.DomainRunning, libvirt.DomainBlocked:
+		return nil
+	case libvirt.DomainPaused:
+		if err := m.conn.DomainResume(dom); err != nil {
+			return fmt.Errorf("도메인 재개 실패: %w", err)
+		}
+	default:
+		if err := m.conn.DomainCreate(dom); err != nil {
+			return fmt.Errorf("도메인 부팅 실패: %w", err)
+		}
+	}
+	return nil
+}
+
+// Reboot - 게스트 에이전트로 재부팅을 요청하고, 에이전트가 없으면 ACPI로 보낸다.
+// 게스트가 요청을 따르는지는 확인하지 않는다
+func (m *LibvirtManager) Reboot(name string) error {
+	dom, err := m.conn.DomainLookupByName(name)
+	if err != nil {
+		return fmt.Errorf("도메인 조회 실패: %w", err)
+	}
+	if err := m.conn.DomainReboot(dom, libvirt.DomainRebootGuestAgent); err == nil {
+		return nil
+	}
+	if err := m.conn.DomainReboot(dom, libvirt.DomainRebootAcpiPowerBtn); err != nil {
+		return fmt.Errorf("도메인 재부팅 실패: %w", err)
+	}
+	return nil
+}
+
+// Reset - 게스트 OS를 거치지 않는 하드 리셋 (전원 리셋 버튼)
+func (m *LibvirtManager) Reset(name string) error {
+	dom, err := m.conn.DomainLookupByName(name)
+	if err != nil {
+		return fmt.Errorf("도메인 조회 실패: %w", err)
+	}
+	if err := m.conn.DomainReset(dom, 0); err != nil {
+		return fmt.Errorf("도메인 리셋 실패: %w", err)
+	}
+	return nil
+}
+
+// SetAutostart - libvirtd가 시작될 때(호스트 부팅) 도메인을 자동으로 부팅할지 정한다
+func (m *LibvirtManager) SetAutostart(name string, enabled bool) error {
+	dom, err := m.conn.DomainLookupByName(name)
+	if err != nil {
+		return fmt.Errorf("도메인 조회 실패: %w", err)
+	}
+	var flag int32
+	if enabled {
+		flag = 1
+	}
+	if err := m.conn.DomainSetAutostart(dom, flag); err != nil {
+		return fmt.Errorf("자동 시작 설정 실패: %w", err)
+	}
+	return nil
+}
+
+// Suspend - vCPU를 멈춘다 (메모리는 유지, Resume으로 재개)
+func (m *LibvirtManager) Suspend(name string) error {
+	dom, err := m.conn.DomainLookupByName(name)
+	if err != nil {
+		return fmt.Errorf("도메인 조회 실패: %w", err)
+	}
+	if err := m.conn.DomainSuspend(dom); err != nil {
+		return fmt.Errorf("도메인 일시 정지 실패: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package libvirt
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+)
+
+// SnapshotSummary - 도메인 스냅샷 목록 조회 결과
+type SnapshotSummary struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	State       string    `json:"state"` // 스냅샷 시점의 도메인 상태 (running, shutoff 등)
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ErrSnapshotUnsupported - 내부 스냅샷을 만들 수 없는 도메인 (UEFI NVRAM은 pflash라 qcow2 내부 스냅샷에 담기지 않는다)
+var ErrSnapshotUnsupported = errors.New("이 VM은 스냅샷을 지원하지 않습니다")
+
+// domainSnapshotXML - <domainsnapshot> 중 사용하는 필드만
+type domainSnapshotXML struct {
+	XMLName      xml.Name       `xml:"domainsnapshot"`
+	Name         string         `xml:"name"`
+	Description  string         `xml:"description,omitempty"`
+	State        string         `xml:"state,omitempty"`
+	CreationTime int64          `xml:"creationTime,omitempty"`
+	Disks        *snapshotDisks `xml:"disks"`
+}
+
+type snapshotDisks struct {
+	Disks []snapshotDisk `xml:"disk"`
+}
+
+type snapshotDisk struct {
+	Name     string `xml:"name,attr"`
+	Snapshot string `xml:"snapshot,attr"` // "internal", "no"
+}
+
+// SnapshotDisks - 도메인의 어떤 디스크를 내부 스냅샷에 담을지 정한다. qcow2 디스크만 담고
+// raw seed(cdrom, vfat disk)는 snapshot="no"로 뺀다. UEFI(pflash) 도메인은 ErrSnapshotUnsupported
+func SnapshotDisks(d *Domain) (map[string]string, error) {
+	if d.OS.Firmware == "efi" || (d.OS.Loader != nil && d.OS.Loader.Type == "pflash") {
+		return nil, fmt.Errorf("%w: UEFI 펌웨어로 부팅하는 VM입니다", ErrSnapshotUnsupported)
+	}
+	disks := make(map[string]string, len(d.Devices.Disks))
+	for _, disk := range d.Devices.Disks {
+		if disk.Driver.Type == "qcow2" {
+			disks[disk.Target.Dev] = "internal"
+		} else {
+			disks[disk.Target.Dev] = "no"
+		}
+	}
+	return disks, nil
+}
+
+// CreateSnapshot - qcow2 내부 스냅샷 생성. 실행 중인 도메인은 메모리 상태까지 저장된다.
+func (m *LibvirtManager) CreateSnapshot(domainName, snapshotName, description string) error {
+	dom, err := m.conn.DomainLookupByName(domainName)
+	if err != nil {
+		return fmt.Errorf("도메인 조회 실패: %w", err)
+	}
+
+	desc, err := m.conn.DomainGetXMLDesc(dom, 0)
+	if err != nil {
+		return fmt.Errorf("도메인 XML 조회 실패: %w", err)
+	}
+	d, err := ParseDomainXML(desc)
+	if err != nil {
+		return err
+	}
+	disks, err := SnapshotDisks(d)
+	if err != nil {
+		return err
+	}
+	x := domainSnapshotXML{Name: snapshotName, Description: description, Disks: &snapshotDisks{}}
+	for _, disk := range d.Devices.Disks {
+		x.Disks.Disks = append(x.Disks.Disks, snapshotDisk{Name: disk.Target.Dev, Snapshot: disks[disk.Target.Dev]})
+	}
+
+	data, err := xml.Marshal(x)
+	if err != nil {
+		return fmt.Errorf("스냅샷 XML 생성 실패: %w", err)
+	}
+
+	// 메모리 상태에는 얼린 상태가 함께 저장되므로 복원 후에도 녹인다 (RevertSnapshot)
+	thaw := m.freezeFilesystems(dom)
+	_, err = m.conn.DomainSnapshotCreateXML(dom, string(data), uint32(libvirt.DomainSnapshotCreateAtomic))
+	thaw()
+	if err != nil {
+		return fmt.Errorf("스냅샷 생성 실패: %w", err)
+	}
+	return nil
+}
+
+// ListSnapshots - 도메인의 스냅샷 목록 (생성 순)
+func (m *LibvirtManager) ListSnapshots(domainName string) ([]SnapshotSummary, error) {
+	dom, err := m.conn.DomainLookupByName(domainName)
+	if err != nil {
+		return nil, fmt.Errorf("도메인 조회 실패: %w", err)
+	}
+
+	snaps, _, err := m.conn.DomainListAllSnapshots(dom, 1, 0)
+	if err != nil {
+		return nil, fmt.Errorf("스냅샷 목록 조회 실패: %w", err)
+	}
+
+	list := make([]SnapshotSummary, 0, len(snaps))
+	for _, snap := range snaps {
+		desc, err := m.conn.DomainSnapshotGetXMLDesc(snap, 0)
+		if err != nil {
+			return nil, fmt.Errorf("스냅샷 XML 조회 실패 (%s): %w", snap.Name, err)
+		}
+		var x domainSnapshotXML
+		if err := xml.Unmarshal([]byte(desc), &x); err != nil {
+			return nil, fmt.Errorf("스냅샷 XML 파싱 실패 (%s): %w", snap.Name, err)
+		}
+		list = append(list, SnapshotSummary{
+			Name:        snap.Name,
+			Description: x.Description,
+			State:       x.State,
+			CreatedAt:   time.Unix(x.CreationTime, 0),
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list, nil
+}
+
+// RevertSnapshot - 스냅샷 시점으로 되돌린다. 도메인 상태도 스냅샷 시점의 상태가 된다.
+func (m *LibvirtManager) RevertSnapshot(domainName, snapshotName string) error {
+	snap, err := m.lookupSnapshot(domainName, snapshotName)
+	if err != nil {
+		return err
+	}
+	if err := m.conn.DomainRevertToSnapshot(snap, 0); err != nil {
+		return fmt.Errorf("스냅샷 복원 실패: %w", err)
+	}
+	// 얼린 채 찍은 메모리 상태로 돌아왔을 수 있다
+	if active, err := m.conn.DomainIsActive(snap.Dom); err == nil && active == 1 {
+		m.thawFilesystems(snap.Dom)
+	}
+	return nil
+}
+
+// DeleteSnapshot - 스냅샷 삭제 (자식 스냅샷은 부모 쪽으로 병합된다)
+func (m *LibvirtManager) DeleteSnapshot(domainName, snapshotName string) error {
+	snap, err := m.lookupSnapshot(domainName, snapshotName)
+	if err != nil {
+		return err
+	}
+	if err := m.conn.DomainSnapshotDelete(snap, 0); err != nil {
+		return fmt.Errorf("스냅샷 삭제 실패: %w", err)
+	}
+	return nil
+}
+
+func (m *LibvirtManager) lookupSnapshot(domainName, snapshotName string) (libvirt.DomainSnapshot, error) {
+	dom, err := m.conn.DomainLookupByName(domainName)
+	if err != nil {
+		return libvirt.DomainSnapshot{}, fmt.Errorf("도메인 조회 실패: %w", err)
+	}
+	snap, err := m.conn.DomainSnapshotLookupByName(dom, snapshotName, 0)
+	if err != nil {
+		return libvirt.DomainSnapshot{}, fmt.Errorf("스냅샷 조회 실패: %w", err)
+	}
+	return snap, nil
+}
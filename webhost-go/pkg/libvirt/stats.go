@@ -0,0 +1,90 @@
+package libvirt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/digitalocean/go-libvirt"
+)
+
+// DomainStats - ConnectGetAllDomainStats로 읽은 도메인 하나의 누적 카운터.
+// 값은 부팅 이후 누적이므로 사용률은 두 샘플의 차이로 계산한다.
+type DomainStats struct {
+	Name string `json:"name"`
+	// VCPUs - 현재 vCPU 수 (vcpu.current)
+	VCPUs int `json:"vcpus"`
+	// CPUTime - 모든 vCPU가 쓴 CPU 시간 (ns, cpu.time)
+	CPUTime uint64 `json:"cpu_time"`
+	// 메모리 (KiB). Unused는 게스트 balloon 드라이버가 보고할 때만 0이 아니다
+	MemoryKB       uint64 `json:"memory_kb"`
+	MemoryUnusedKB uint64 `json:"memory_unused_kb"`
+	// 모든 디스크/인터페이스의 합계 (바이트)
+	BlockReadBytes  uint64 `json:"block_read_bytes"`
+	BlockWriteBytes uint64 `json:"block_write_bytes"`
+	NetRxBytes      uint64 `json:"net_rx_bytes"`
+	NetTxBytes      uint64 `json:"net_tx_bytes"`
+}
+
+// domainStatsTypes - 수집하는 통계 그룹
+const domainStatsTypes = libvirt.DomainStatsCPUTotal | libvirt.DomainStatsBalloon | libvirt.DomainStatsVCPU |
+	libvirt.DomainStatsInterface | libvirt.DomainStatsBlock
+
+// GetAllDomainStats - 실행 중인 모든 도메인의 통계를 한 번에 읽는다
+func (l *LibvirtManager) GetAllDomainStats() ([]DomainStats, error) {
+	records, err := l.conn.ConnectGetAllDomainStats(nil, uint32(domainStatsTypes), uint32(libvirt.ConnectGetAllDomainsStatsActive))
+	if err != nil {
+		return nil, fmt.Errorf("도메인 통계 조회 실패: %w", err)
+	}
+
+	list := make([]DomainStats, 0, len(records))
+	for _, rec := range records {
+		list = append(list, ParseDomainStats(rec.Dom.Name, rec.Params))
+	}
+	return list, nil
+}
+
+// ParseDomainStats - 통계 레코드의 typed parameter를 DomainStats로 모은다.
+// block.<n>.*, net.<n>.* 는 모든 장치를 더한다.
+func ParseDomainStats(name string, params []libvirt.TypedParam) DomainStats {
+	st := DomainStats{Name: name}
+	for _, p := range params {
+		v, ok := paramUint(p.Value)
+		if !ok {
+			continue
+		}
+		switch field := p.Field; {
+		case field == "cpu.time":
+			st.CPUTime = v
+		case field == "vcpu.current":
+			st.VCPUs = int(v)
+		case field == "balloon.current":
+			st.MemoryKB = v
+		case field == "balloon.unused":
+			st.MemoryUnusedKB = v
+		case strings.HasPrefix(field, "block.") && strings.HasSuffix(field, ".rd.bytes"):
+			st.BlockReadBytes += v
+		case strings.HasPrefix(field, "block.") && strings.HasSuffix(field, ".wr.bytes"):
+			st.BlockWriteBytes += v
+		case strings.HasPrefix(field, "net.") && strings.HasSuffix(field, ".rx.bytes"):
+			st.NetRxBytes += v
+		case strings.HasPrefix(field, "net.") && strings.HasSuffix(field, ".tx.bytes"):
+			st.NetTxBytes += v
+		}
+	}
+	return st
+}
+
+// paramUint - 정수형 typed parameter 값 (문자열, 실수, bool은 false)
+func paramUint(v libvirt.TypedParamValue) (uint64, bool) {
+	switch n := v.I.(type) {
+	case int32:
+		return uint64(n), n >= 0
+	case uint32:
+		return uint64(n), true
+	case int64:
+		return uint64(n), n >= 0
+	case uint64:
+		return n, true
+	}
+	return 0, false
+}
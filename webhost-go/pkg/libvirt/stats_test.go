@@ -0,0 +1,71 @@
+package libvirt_test
+
+import (
+	"testing"
+
+	golibvirt "github.com/digitalocean/go-libvirt"
+	"webhost-go/webhost-go/pkg/libvirt"
+)
+
+func TestParseDomainStats(t *testing.T) {
+	params := []golibvirt.TypedParam{
+		{Field: "cpu.time", Value: *golibvirt.NewTypedParamValueUllong(12_000_000_000)},
+		{Field: "cpu.user", Value: *golibvirt.NewTypedParamValueUllong(9_000_000_000)},
+		{Field: "vcpu.current", Value: *golibvirt.NewTypedParamValueUint(2)},
+		{Field: "balloon.current", Value: *golibvirt.NewTypedParamValueUllong(2097152)},
+		{Field: "balloon.unused", Value: *golibvirt.NewTypedParamValueUllong(1048576)},
+		{Field: "block.count", Value: *golibvirt.NewTypedParamValueUint(2)},
+		{Field: "block.0.name", Value: *golibvirt.NewTypedParamValueString("vda")},
+		{Field: "block.0.rd.bytes", Value: *golibvirt.NewTypedParamValueUllong(1000)},
+		{Field: "block.0.wr.bytes", Value: *golibvirt.NewTypedParamValueUllong(2000)},
+		{Field: "block.0.rd.reqs", Value: *golibvirt.NewTypedParamValueUllong(7)},
+		{Field: "block.1.rd.bytes", Value: *golibvirt.NewTypedParamValueUllong(500)},
+		{Field: "net.count", Value: *golibvirt.NewTypedParamValueUint(1)},
+		{Field: "net.0.rx.bytes", Value: *golibvirt.NewTypedParamValueUllong(3000)},
+		{Field: "net.0.tx.bytes", Value: *golibvirt.NewTypedParamValueUllong(4000)},
+		{Field: "net.0.rx.pkts", Value: *golibvirt.NewTypedParamValueUllong(30)},
+	}
+
+	got := libvirt.ParseDomainStats("vm-1", params)
+	want := libvirt.DomainStats{
+		Name:            "vm-1",
+		VCPUs:           2,
+		CPUTime:         12_000_000_000,
+		MemoryKB:        2097152,
+		MemoryUnusedKB:  1048576,
+		BlockReadBytes:  1500,
+		BlockWriteBytes: 2000,
+		NetRxBytes:      3000,
+		NetTxBytes:      4000,
+	}
+	if got != want {
+		t.Errorf("ParseDomainStats\n got: %+v\nwant: %+v", got, want)
+	}
+}
+
+func TestParseGuestInfo(t *testing.T) {
+	params := []golibvirt.TypedParam{
+		{Field: "hostname", Value: *golibvirt.NewTypedParamValueString("web")},
+		{Field: "os.id", Value: *golibvirt.NewTypedParamValueString("ubuntu")},
+		{Field: "os.name", Value: *golibvirt.NewTypedParamValueString("Ubuntu")},
+		{Field: "os.pretty-name", Value: *golibvirt.NewTypedParamValueString("Ubuntu 22.04.4 LTS")},
+		{Field: "os.version-id", Value: *golibvirt.NewTypedParamValueString("22.04")},
+		{Field: "os.kernel-release", Value: *golibvirt.NewTypedParamValueString("5.15.0-105-generic")},
+		{Field: "os.machine", Value: *golibvirt.NewTypedParamValueString("x86_64")},
+		{Field: "user.count", Value: *golibvirt.NewTypedParamValueUint(1)},
+	}
+
+	got := libvirt.ParseGuestInfo(params)
+	want := libvirt.GuestInfo{
+		Hostname:      "web",
+		OSID:          "ubuntu",
+		OSName:        "Ubuntu",
+		OSPrettyName:  "Ubuntu 22.04.4 LTS",
+		OSVersionID:   "22.04",
+		KernelRelease: "5.15.0-105-generic",
+		Machine:       "x86_64",
+	}
+	if got != want {
+		t.Errorf("ParseGuestInfo\n got: %+v\nwant: %+v", got, want)
+	}
+}
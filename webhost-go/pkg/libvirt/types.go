@@ -4,8 +4,30 @@ type VMConfig struct {
 	Name     string
 	MemoryMB int
 	VCPUs    int
+	DiskGB   int // 루트 디스크 크기 (템플릿 복사 후 이 크기로 확장)
 	DiskPath string
-	ISOPath  string
+	ISOPath  string // cloud-init seed 이미지 (CloudInit.SeedFormat이 vfat이면 disk, 아니면 cdrom으로 연결)
+	// Template - TemplateDir 안의 부팅 디스크 템플릿 (비어 있으면 DefaultTemplate)
+	Template string
+	// SourceDisk - 템플릿 대신 복사할 디스크 (백업에서 새 VM을 만들 때)
+	SourceDisk string
+	// CloudInit - VM 전용 user-data 입력
+	CloudInit CloudInit
+	// 플랜/이미지가 켜는 선택 기능 (hosting_service.DomainFeatures를 하이퍼바이저 쪽에서 옮겨 담는다)
+	UEFI      bool // OVMF 펌웨어로 부팅 (q35 머신)
+	VirtioRNG bool // 호스트 /dev/urandom을 게스트 엔트로피로 제공
+	// CPUQuota - vCPU 하나가 쓸 수 있는 호스트 CPU 비율 (%, 0이면 제한 없음)
+	CPUQuota int
+	// VNCPassword - localhost에만 열리는 VNC 장치의 비밀번호 (비어 있으면 VNC 없음, 최대 8자)
+	VNCPassword string
+	// MAC - NIC의 MAC 주소 (비어 있으면 libvirt가 정한다). network-config는 이 MAC으로 NIC를 찾는다
+	MAC string
+}
+
+// DomainSummary - 도메인 목록 조회 결과 (State는 virDomainState 값)
+type DomainSummary struct {
+	Name  string `json:"name"`
+	State uint8  `json:"state"`
 }
 
 type VMInfo struct {
@@ -15,3 +37,11 @@ type VMInfo struct {
 	VCPU   uint
 	UUID   string
 }
+
+// VM 생성 진행 단계
+const (
+	StepCopyingDisk       = "copying_disk"
+	StepResizingDisk      = "resizing_disk"
+	StepBuildingCloudInit = "building_cloud_init"
+	StepBooting           = "booting"
+)
@@ -18,7 +18,6 @@ func TestLibvirtVMCreation(t *testing.T) {
 	vmName := "testvm-" + time.Now().Format("150405")
 	diskPath := filepath.Join("/var/lib/libvirt/images/", vmName+".qcow2")
 	isoPath := filepath.Join("/var/lib/libvirt/images/cloud-init", vmName+".iso")
-	xmlTemplate := "/etc/libvirt/templates/domain_template.xml" // 실제 경로 맞게 수정
 
 	// Clean up after test
 	t.Cleanup(func() {
@@ -39,7 +38,7 @@ func TestLibvirtVMCreation(t *testing.T) {
 		t.Fatalf("failed to create cloud-init ISO: %v", err)
 	}
 
-	// Step 3: Load and render XML
+	// Step 3: Build domain XML
 	cfg := libvirt.VMConfig{
 		Name:     vmName,
 		MemoryMB: 2028,
@@ -48,11 +47,10 @@ func TestLibvirtVMCreation(t *testing.T) {
 		ISOPath:  isoPath,
 	}
 
-	xmlStr, err := manager.LoadDomainXML(xmlTemplate, cfg)
+	xmlStr, err := libvirt.BuildDomainXML(cfg)
 	if err != nil {
-		t.Fatalf("failed to load domain XML: %v", err)
+		t.Fatalf("failed to build domain XML: %v", err)
 	}
-	xmlStr = manager.EnableSerialConsole(xmlStr)
 
 	// Step 4: Define and start VM
 	err = manager.DefineAndStartVM(xmlStr)
@@ -95,7 +93,7 @@ func TestStartUbuntuVMWithStaticIP(t *testing.T) {
 	}
 
 	// 사용 가능한 IP 목록 조회
-	ips, err := manager.GetUsableIPs()
+	ips, err := manager.GetUsableIPs(nil)
 	if err != nil || len(ips) == 0 {
 		t.Fatalf("사용 가능한 IP 조회 실패 또는 없음: %v", err)
 	}
@@ -103,7 +101,8 @@ func TestStartUbuntuVMWithStaticIP(t *testing.T) {
 	vmName := "testvm-static-" + time.Now().Format("150405")
 	ip := ips[0]
 
-	err = manager.StartUbuntuVMWithStaticIP(vmName, ip)
+	cfg := libvirt.VMConfig{Name: vmName, MemoryMB: 1024, VCPUs: 1, DiskGB: 10}
+	err = manager.StartUbuntuVMWithStaticIP(cfg, ip, nil)
 	if err != nil {
 		t.Fatalf("Static IP 기반 VM 생성 실패: %v", err)
 	}
@@ -0,0 +1,61 @@
+package libvirt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// ErrNoVNC - 실행 중인 도메인에 VNC 장치가 없다 (VNCPassword 없이 만든 도메인)
+var ErrNoVNC = errors.New("도메인에 VNC 장치가 없습니다")
+
+// OpenVNC - 실행 중인 도메인의 VNC 서버(localhost)에 TCP로 연결한다.
+// RFB 핸드셰이크와 VNC 인증은 호출한 쪽(noVNC)이 한다.
+// VNC 서버는 libvirtd 호스트의 127.0.0.1에만 열리므로 원격 URI로 연결한 매니저에서는 ErrRemoteHost를 돌려준다.
+func (m *LibvirtManager) OpenVNC(name string) (io.ReadWriteCloser, error) {
+	if m.remote {
+		return nil, fmt.Errorf("VNC: %w", ErrRemoteHost)
+	}
+	dom, err := m.conn.DomainLookupByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("도메인 조회 실패: %w", err)
+	}
+	desc, err := m.conn.DomainGetXMLDesc(dom, 0)
+	if err != nil {
+		return nil, fmt.Errorf("도메인 XML 가져오기 실패: %w", err)
+	}
+	d, err := ParseDomainXML(desc)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := VNCAddress(d)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, name)
+	}
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("VNC 연결 실패: %w", err)
+	}
+	return conn, nil
+}
+
+// VNCAddress - 실행 중인 도메인 XML의 VNC 주소 (정의만 된 도메인은 포트가 정해지지 않아 에러)
+func VNCAddress(d *Domain) (string, error) {
+	for _, g := range d.Devices.Graphics {
+		if g.Type != "vnc" {
+			continue
+		}
+		if g.Port <= 0 {
+			return "", errors.New("VNC 포트가 아직 할당되지 않았습니다 (실행 중인 도메인이 아님)")
+		}
+		listen := g.Listen
+		if listen == "" {
+			listen = vncListen
+		}
+		return net.JoinHostPort(listen, strconv.Itoa(g.Port)), nil
+	}
+	return "", ErrNoVNC
+}
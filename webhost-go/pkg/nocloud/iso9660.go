@@ -0,0 +1,403 @@
+package nocloud
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+const isoSectorSize = 2048
+
+// ISO9660 레이아웃 (섹터 번호). 0~15는 system area라 비워 둔다.
+const (
+	pvdSector        = 16 // primary volume descriptor
+	svdSector        = 17 // Joliet supplementary volume descriptor
+	terminatorSector = 18
+	pathTableLSector = 19 // primary path table (little endian)
+	pathTableMSector = 20 // primary path table (big endian)
+	jolietLSector    = 21
+	jolietMSector    = 22
+	rootDirSector    = 23 // primary 루트 디렉토리, 이어서 Joliet 루트, CE 영역, 파일 데이터
+)
+
+// Rock Ridge 확장 식별 정보 (RRIP 1991A, mkisofs와 같은 값)
+const (
+	rrExtID  = "RRIP_1991A"
+	rrExtDes = "THE ROCK RIDGE INTERCHANGE PROTOCOL PROVIDES SUPPORT FOR POSIX FILE SYSTEM SEMANTICS"
+	rrExtSrc = "PLEASE CONTACT DISC PUBLISHER FOR SPECIFICATION SOURCE.  SEE PUBLISHER IDENTIFIER IN PRIMARY VOLUME DESCRIPTOR FOR CONTACT INFORMATION."
+)
+
+// jolietEscape - UCS-2 Level 3
+var jolietEscape = []byte("%/E")
+
+// dirEntry - 디렉토리 레코드 하나. file은 files의 인덱스 (디렉토리면 -1)
+type dirEntry struct {
+	ident  []byte
+	file   int
+	extent uint32
+	size   uint32
+	su     []byte // system use 영역 (Rock Ridge)
+}
+
+func (e dirEntry) length() int {
+	n := 33 + len(e.ident)
+	if len(e.ident)%2 == 0 {
+		n++
+	}
+	n += len(e.su)
+	if n%2 == 1 {
+		n++
+	}
+	return n
+}
+
+func (e dirEntry) record(t time.Time) []byte {
+	rec := make([]byte, e.length())
+	rec[0] = byte(len(rec))
+	putBoth32(rec[2:], e.extent)
+	putBoth32(rec[10:], e.size)
+	putRecordTime(rec[18:], t)
+	if e.file < 0 {
+		rec[25] = 0x02 // directory
+	}
+	putBoth16(rec[28:], 1) // volume sequence number
+	rec[32] = byte(len(e.ident))
+	copy(rec[33:], e.ident)
+	suStart := 33 + len(e.ident)
+	if len(e.ident)%2 == 0 {
+		suStart++
+	}
+	copy(rec[suStart:], e.su)
+	return rec
+}
+
+// WriteISO - files를 루트에 둔 ISO9660 이미지를 w에 쓴다.
+// 기본 이름(8.3 대문자)에 Joliet(UCS-2)과 Rock Ridge(POSIX 이름과 권한)를 함께 기록하므로
+// genisoimage -joliet -rock 결과처럼 어느 쪽으로 마운트해도 원래 이름이 보인다.
+func WriteISO(w io.Writer, label string, files []File, modTime time.Time) error {
+	if err := validateFiles(files); err != nil {
+		return err
+	}
+	if label == "" || len(label) > 16 {
+		return fmt.Errorf("볼륨 이름은 1~16자여야 합니다: %q", label)
+	}
+	modTime = modTime.UTC()
+
+	// 1. 두 트리의 루트 디렉토리 레코드 (위치는 크기를 계산한 뒤 채운다)
+	isoIdents := primaryIdents(files)
+	primary := []dirEntry{
+		{ident: []byte{0}, file: -1, su: concatBytes(rrSP(), rrCE(0, 0, 0), rrPX(0o40555, 2))},
+		{ident: []byte{1}, file: -1, su: rrPX(0o40555, 2)},
+	}
+	joliet := []dirEntry{
+		{ident: []byte{0}, file: -1},
+		{ident: []byte{1}, file: -1},
+	}
+	for i, f := range files {
+		primary = append(primary, dirEntry{
+			ident: []byte(isoIdents[i]),
+			file:  i,
+			su:    concatBytes(rrPX(0o100444, 1), rrNM(f.Name)),
+		})
+		joliet = append(joliet, dirEntry{ident: ucs2(f.Name), file: i})
+	}
+	sortEntries(primary[2:])
+	sortEntries(joliet[2:])
+
+	// 2. 레이아웃: 루트 디렉토리 두 개, Rock Ridge ER 항목을 담는 continuation area,
+	// 파일 데이터 순서. 순차로 읽는 reader(libarchive)는 CE가 디렉토리 뒤에 있어야 한다.
+	primarySize := dirSize(primary)
+	jolietSize := dirSize(joliet)
+	primaryLoc := uint32(rootDirSector)
+	jolietLoc := primaryLoc + sectors(primarySize, isoSectorSize)
+	ceLoc := jolietLoc + sectors(jolietSize, isoSectorSize)
+	next := ceLoc + 1
+	primary[0].su = concatBytes(rrSP(), rrCE(ceLoc, 0, uint32(len(rrER()))), rrPX(0o40555, 2))
+	extents := make([]uint32, len(files))
+	for i, f := range files {
+		extents[i] = next
+		next += sectors(len(f.Data), isoSectorSize)
+	}
+	total := next
+
+	fill := func(entries []dirEntry, loc uint32, size int) {
+		for i := range entries {
+			if entries[i].file < 0 {
+				// 루트의 ".."는 루트 자신
+				entries[i].extent, entries[i].size = loc, uint32(size)
+				continue
+			}
+			entries[i].extent = extents[entries[i].file]
+			entries[i].size = uint32(len(files[entries[i].file].Data))
+		}
+	}
+	fill(primary, primaryLoc, primarySize)
+	fill(joliet, jolietLoc, jolietSize)
+
+	// 3. 이미지 조립
+	img := make([]byte, int(total)*isoSectorSize)
+	sector := func(n uint32) []byte {
+		return img[int(n)*isoSectorSize : int(n+1)*isoSectorSize]
+	}
+
+	// volume descriptor 안의 루트 레코드는 34바이트 고정이라 system use 영역을 뺀다
+	rootRecord := func(e dirEntry) []byte {
+		e.su = nil
+		return e.record(modTime)
+	}
+	copy(sector(pvdSector), volumeDescriptor(1, label, total, rootRecord(primary[0]),
+		pathTableLSector, pathTableMSector, modTime))
+	copy(sector(svdSector), volumeDescriptor(2, label, total, rootRecord(joliet[0]),
+		jolietLSector, jolietMSector, modTime))
+	term := sector(terminatorSector)
+	term[0] = 255
+	copy(term[1:], "CD001")
+	term[6] = 1
+
+	copy(sector(pathTableLSector), pathTable(primaryLoc, binary.LittleEndian))
+	copy(sector(pathTableMSector), pathTable(primaryLoc, binary.BigEndian))
+	copy(sector(jolietLSector), pathTable(jolietLoc, binary.LittleEndian))
+	copy(sector(jolietMSector), pathTable(jolietLoc, binary.BigEndian))
+	copy(sector(ceLoc), rrER())
+
+	packDir(img[int(primaryLoc)*isoSectorSize:], primary, modTime)
+	packDir(img[int(jolietLoc)*isoSectorSize:], joliet, modTime)
+	for i, f := range files {
+		copy(img[int(extents[i])*isoSectorSize:], f.Data)
+	}
+
+	_, err := w.Write(img)
+	return err
+}
+
+// primaryIdents - ISO9660 level 1 이름 ("USER_DAT.;1"). 겹치면 뒤에 숫자를 붙인다.
+func primaryIdents(files []File) []string {
+	clean := func(s string, n int) string {
+		var b strings.Builder
+		for _, r := range strings.ToUpper(s) {
+			if b.Len() == n {
+				break
+			}
+			if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+				b.WriteRune(r)
+			} else {
+				b.WriteByte('_')
+			}
+		}
+		return b.String()
+	}
+
+	used := make(map[string]bool, len(files))
+	idents := make([]string, len(files))
+	for i, f := range files {
+		base, ext := f.Name, ""
+		if dot := strings.LastIndexByte(f.Name, '.'); dot > 0 {
+			base, ext = f.Name[:dot], f.Name[dot+1:]
+		}
+		base, ext = clean(base, 8), clean(ext, 3)
+		ident := base + "." + ext + ";1"
+		for n := 1; used[ident]; n++ {
+			suffix := strconv.Itoa(n)
+			b := base
+			if len(b) > 8-len(suffix) {
+				b = b[:8-len(suffix)]
+			}
+			ident = b + suffix + "." + ext + ";1"
+		}
+		used[ident] = true
+		idents[i] = ident
+	}
+	return idents
+}
+
+func sortEntries(entries []dirEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].ident, entries[j].ident) < 0
+	})
+}
+
+// dirSize - 레코드는 섹터 경계를 넘을 수 없으므로 남은 공간이 부족하면 다음 섹터에서 시작한다
+func dirSize(entries []dirEntry) int {
+	size := 0
+	for _, e := range entries {
+		n := e.length()
+		if size%isoSectorSize+n > isoSectorSize {
+			size += isoSectorSize - size%isoSectorSize
+		}
+		size += n
+	}
+	return int(sectors(size, isoSectorSize)) * isoSectorSize
+}
+
+func packDir(dst []byte, entries []dirEntry, t time.Time) {
+	off := 0
+	for _, e := range entries {
+		rec := e.record(t)
+		if off%isoSectorSize+len(rec) > isoSectorSize {
+			off += isoSectorSize - off%isoSectorSize
+		}
+		copy(dst[off:], rec)
+		off += len(rec)
+	}
+}
+
+// pathTable - 루트 디렉토리 하나만 있는 path table
+func pathTable(rootLoc uint32, order binary.ByteOrder) []byte {
+	rec := make([]byte, 10)
+	rec[0] = 1 // 식별자 길이
+	order.PutUint32(rec[2:], rootLoc)
+	order.PutUint16(rec[6:], 1) // 부모 디렉토리 번호
+	return rec
+}
+
+// volumeDescriptor - typ 1은 primary, 2는 Joliet supplementary
+func volumeDescriptor(typ byte, label string, total uint32, rootRecord []byte, ptL, ptM uint32, t time.Time) []byte {
+	vd := make([]byte, isoSectorSize)
+	joliet := typ == 2
+	str := func(dst []byte, s string) {
+		if joliet {
+			putUCS2Padded(dst, s)
+			return
+		}
+		for i := range dst {
+			dst[i] = ' '
+		}
+		copy(dst, s)
+	}
+
+	vd[0] = typ
+	copy(vd[1:], "CD001")
+	vd[6] = 1
+	str(vd[8:40], "LINUX")
+	str(vd[40:72], label)
+	putBoth32(vd[80:], total)
+	if joliet {
+		copy(vd[88:], jolietEscape)
+	}
+	putBoth16(vd[120:], 1)             // volume set size
+	putBoth16(vd[124:], 1)             // volume sequence number
+	putBoth16(vd[128:], isoSectorSize) // logical block size
+	putBoth32(vd[132:], 10)            // path table size
+	binary.LittleEndian.PutUint32(vd[140:], ptL)
+	binary.BigEndian.PutUint32(vd[148:], ptM)
+	copy(vd[156:190], rootRecord)
+	str(vd[190:318], "")                   // volume set
+	str(vd[318:446], "")                   // publisher
+	str(vd[446:574], "")                   // data preparer
+	str(vd[574:702], "WEBHOST-GO NOCLOUD") // application
+	str(vd[702:739], "")
+	str(vd[739:776], "")
+	str(vd[776:813], "")
+	putVolumeTime(vd[813:], t)           // 생성
+	putVolumeTime(vd[830:], t)           // 수정
+	putVolumeTime(vd[847:], time.Time{}) // 만료 (지정 안 함)
+	putVolumeTime(vd[864:], time.Time{}) // 유효 (지정 안 함)
+	vd[881] = 1                          // file structure version
+	return vd
+}
+
+// Rock Ridge / SUSP 항목
+
+func rrSP() []byte {
+	return []byte{'S', 'P', 7, 1, 0xBE, 0xEF, 0}
+}
+
+func rrCE(block, offset, length uint32) []byte {
+	b := make([]byte, 28)
+	copy(b, "CE")
+	b[2], b[3] = 28, 1
+	putBoth32(b[4:], block)
+	putBoth32(b[12:], offset)
+	putBoth32(b[20:], length)
+	return b
+}
+
+func rrPX(mode, nlink uint32) []byte {
+	b := make([]byte, 36)
+	copy(b, "PX")
+	b[2], b[3] = 36, 1
+	putBoth32(b[4:], mode)
+	putBoth32(b[12:], nlink)
+	return b
+}
+
+func rrNM(name string) []byte {
+	b := []byte{'N', 'M', byte(5 + len(name)), 1, 0}
+	return append(b, name...)
+}
+
+func rrER() []byte {
+	b := []byte{'E', 'R', 0, 1, byte(len(rrExtID)), byte(len(rrExtDes)), byte(len(rrExtSrc)), 1}
+	b = append(b, rrExtID...)
+	b = append(b, rrExtDes...)
+	b = append(b, rrExtSrc...)
+	b[2] = byte(len(b))
+	return b
+}
+
+// 인코딩 도우미
+
+func putBoth16(b []byte, v uint16) {
+	binary.LittleEndian.PutUint16(b, v)
+	binary.BigEndian.PutUint16(b[2:], v)
+}
+
+func putBoth32(b []byte, v uint32) {
+	binary.LittleEndian.PutUint32(b, v)
+	binary.BigEndian.PutUint32(b[4:], v)
+}
+
+func putRecordTime(b []byte, t time.Time) {
+	b[0] = byte(t.Year() - 1900)
+	b[1] = byte(t.Month())
+	b[2] = byte(t.Day())
+	b[3] = byte(t.Hour())
+	b[4] = byte(t.Minute())
+	b[5] = byte(t.Second())
+	b[6] = 0 // UTC
+}
+
+// putVolumeTime - "YYYYMMDDHHMMSScc" + GMT offset. 0이면 모두 '0'
+func putVolumeTime(b []byte, t time.Time) {
+	if t.IsZero() {
+		copy(b, "0000000000000000")
+		b[16] = 0
+		return
+	}
+	copy(b, t.Format("20060102150405")+fmt.Sprintf("%02d", t.Nanosecond()/1e7))
+	b[16] = 0
+}
+
+func ucs2(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(units))
+	for i, u := range units {
+		binary.BigEndian.PutUint16(b[2*i:], u)
+	}
+	return b
+}
+
+func putUCS2Padded(dst []byte, s string) {
+	for i := 0; i+1 < len(dst); i += 2 {
+		dst[i], dst[i+1] = 0, ' '
+	}
+	copy(dst, ucs2(s))
+}
+
+func sectors(n, size int) uint32 {
+	return uint32((n + size - 1) / size)
+}
+
+func concatBytes(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
@@ -0,0 +1,125 @@
+// Package nocloud builds cloud-init NoCloud seed images (ISO9660 or vfat) in memory
+package nocloud
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Label - cloud-init NoCloud가 찾는 볼륨 이름
+const Label = "cidata"
+
+// Format - seed 이미지 형식
+type Format string
+
+const (
+	FormatISO  Format = "iso"  // ISO9660 + Joliet + Rock Ridge (cdrom으로 연결)
+	FormatVFAT Format = "vfat" // FAT12 (disk로 연결, iso를 읽지 못하는 이미지용)
+)
+
+var ErrUnknownFormat = errors.New("알 수 없는 seed 형식")
+
+// ParseFormat - 빈 문자열은 FormatISO
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case "", FormatISO:
+		return FormatISO, nil
+	case FormatVFAT:
+		return FormatVFAT, nil
+	}
+	return "", fmt.Errorf("%w: %q (iso, vfat 중 하나)", ErrUnknownFormat, s)
+}
+
+// File - seed 볼륨 루트에 들어가는 파일
+type File struct {
+	Name string
+	Data []byte
+}
+
+// Seed - NoCloud 데이터. NetworkConfig가 비어 있으면 network-config 파일을 넣지 않는다.
+type Seed struct {
+	MetaData      []byte
+	UserData      []byte
+	NetworkConfig []byte
+	// ModTime - 파일과 볼륨의 시각 (0이면 현재 시각)
+	ModTime time.Time
+}
+
+// Files - 볼륨에 들어갈 파일 목록
+func (s Seed) Files() []File {
+	files := []File{
+		{Name: "meta-data", Data: s.MetaData},
+		{Name: "user-data", Data: s.UserData},
+	}
+	if len(s.NetworkConfig) > 0 {
+		files = append(files, File{Name: "network-config", Data: s.NetworkConfig})
+	}
+	return files
+}
+
+// Write - format 형식의 seed 이미지를 w에 쓴다
+func (s Seed) Write(w io.Writer, format Format) error {
+	modTime := s.ModTime
+	if modTime.IsZero() {
+		modTime = time.Now()
+	}
+	switch format {
+	case FormatISO:
+		return WriteISO(w, Label, s.Files(), modTime)
+	case FormatVFAT:
+		return WriteVFAT(w, Label, s.Files(), modTime)
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
+
+// WriteFile - seed 이미지를 path에 만든다. user-data에 비밀번호 해시가 들어가므로 0600이며,
+// 임시 파일에 다 쓴 뒤 rename하므로 실패해도 기존 파일이 반쯤 덮이지 않는다.
+func (s Seed) WriteFile(path string, format Format) error {
+	var buf bytes.Buffer
+	if err := s.Write(&buf, format); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("seed 임시 파일 생성 실패: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("seed 이미지 쓰기 실패: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("seed 이미지 쓰기 실패: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("seed 이미지 저장 실패: %w", err)
+	}
+	return nil
+}
+
+// validateFiles - 볼륨 루트에 둘 수 있는 이름인지, 중복이 없는지 확인한다
+func validateFiles(files []File) error {
+	seen := make(map[string]bool, len(files))
+	for _, f := range files {
+		if f.Name == "" || f.Name == "." || f.Name == ".." || len(f.Name) > 64 {
+			return fmt.Errorf("잘못된 파일 이름: %q", f.Name)
+		}
+		for _, r := range f.Name {
+			if r == '/' || r == '\\' || r < 0x20 || r > 0xFFFF {
+				return fmt.Errorf("잘못된 파일 이름: %q", f.Name)
+			}
+		}
+		if seen[f.Name] {
+			return fmt.Errorf("중복된 파일 이름: %q", f.Name)
+		}
+		seen[f.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package nocloud_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"webhost-go/webhost-go/pkg/nocloud"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var modTime = time.Date(2025, 3, 1, 12, 30, 0, 0, time.UTC)
+
+func testSeed() nocloud.Seed {
+	return nocloud.Seed{
+		MetaData:      []byte("instance-id: web1\nlocal-hostname: web1\n"),
+		UserData:      []byte("#cloud-config\nhostname: web1\n"),
+		NetworkConfig: []byte("version: 2\n"),
+		ModTime:       modTime,
+	}
+}
+
+func TestWriteISO_RoundTrip(t *testing.T) {
+	seed := testSeed()
+	var buf bytes.Buffer
+	require.NoError(t, seed.Write(&buf, nocloud.FormatISO))
+	assert.Zero(t, buf.Len()%2048, "ISO는 2048바이트 섹터 단위")
+
+	vol, err := nocloud.ReadISO(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, "cidata", vol.Label)
+	assert.True(t, vol.RockRidge)
+
+	want := map[string][]byte{
+		"meta-data":      seed.MetaData,
+		"user-data":      seed.UserData,
+		"network-config": seed.NetworkConfig,
+	}
+	assert.Equal(t, want, vol.Files)
+	assert.Equal(t, want, vol.Joliet)
+	assert.Equal(t, map[string][]byte{
+		"META_DAT.;1": seed.MetaData,
+		"USER_DAT.;1": seed.UserData,
+		"NETWORK_.;1": seed.NetworkConfig,
+	}, vol.ISONames)
+
+	// 같은 입력이면 같은 이미지
+	var again bytes.Buffer
+	require.NoError(t, seed.Write(&again, nocloud.FormatISO))
+	assert.Equal(t, buf.Bytes(), again.Bytes())
+}
+
+func TestWriteISO_LargeAndEmptyFiles(t *testing.T) {
+	big := bytes.Repeat([]byte("0123456789abcdef"), 10000) // 여러 섹터
+	files := []nocloud.File{
+		{Name: "user-data", Data: big},
+		{Name: "vendor-data"},
+		{Name: "user-data.bak", Data: []byte("old")}, // 8.3 이름이 겹친다
+	}
+	var buf bytes.Buffer
+	require.NoError(t, nocloud.WriteISO(&buf, "cidata", files, modTime))
+
+	vol, err := nocloud.ReadISO(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, big, vol.Files["user-data"])
+	assert.Empty(t, vol.Files["vendor-data"])
+	assert.Equal(t, []byte("old"), vol.Files["user-data.bak"])
+	assert.Len(t, vol.ISONames, 3)
+}
+
+func TestWriteVFAT_RoundTrip(t *testing.T) {
+	seed := testSeed()
+	seed.UserData = bytes.Repeat([]byte("x"), 1300) // 여러 클러스터
+	var buf bytes.Buffer
+	require.NoError(t, seed.Write(&buf, nocloud.FormatVFAT))
+
+	vol, err := nocloud.ReadVFAT(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, "CIDATA", vol.Label)
+	assert.Equal(t, map[string][]byte{
+		"meta-data":      seed.MetaData,
+		"user-data":      seed.UserData,
+		"network-config": seed.NetworkConfig,
+	}, vol.Files)
+}
+
+func TestWriteVFAT_LargeFile(t *testing.T) {
+	// 기본 클러스터(512B) 수를 넘으면 클러스터 크기를 늘린다
+	big := bytes.Repeat([]byte{0xAB}, 2*1024*1024)
+	var buf bytes.Buffer
+	require.NoError(t, nocloud.WriteVFAT(&buf, "cidata", []nocloud.File{{Name: "user-data", Data: big}}, modTime))
+
+	vol, err := nocloud.ReadVFAT(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, big, vol.Files["user-data"])
+}
+
+func TestWrite_InvalidFiles(t *testing.T) {
+	for _, files := range [][]nocloud.File{
+		{{Name: ""}},
+		{{Name: "dir/user-data"}},
+		{{Name: strings.Repeat("a", 65)}},
+		{{Name: "user-data"}, {Name: "user-data"}},
+	} {
+		assert.Error(t, nocloud.WriteISO(&bytes.Buffer{}, "cidata", files, modTime), "%v", files)
+		assert.Error(t, nocloud.WriteVFAT(&bytes.Buffer{}, "cidata", files, modTime), "%v", files)
+	}
+	assert.Error(t, nocloud.WriteISO(&bytes.Buffer{}, strings.Repeat("x", 17), nil, modTime))
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := nocloud.ParseFormat("")
+	require.NoError(t, err)
+	assert.Equal(t, nocloud.FormatISO, f)
+
+	f, err = nocloud.ParseFormat("vfat")
+	require.NoError(t, err)
+	assert.Equal(t, nocloud.FormatVFAT, f)
+
+	_, err = nocloud.ParseFormat("udf")
+	assert.ErrorIs(t, err, nocloud.ErrUnknownFormat)
+}
+
+func TestSeed_WriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seed.iso")
+	require.NoError(t, testSeed().WriteFile(path, nocloud.FormatISO))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	vol, err := nocloud.ReadISO(f)
+	require.NoError(t, err)
+	assert.Equal(t, testSeed().UserData, vol.Files["user-data"])
+
+	// 임시 파일이 남지 않는다
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
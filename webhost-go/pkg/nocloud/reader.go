@@ -0,0 +1,372 @@
+package nocloud
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// Volume - seed 이미지에서 읽은 루트 디렉토리 (점검과 테스트용으로 하위 디렉토리는 읽지 않는다)
+type Volume struct {
+	Label string
+	// Files - 게스트에서 보이는 이름(Rock Ridge 또는 vfat 긴 이름)별 내용
+	Files map[string][]byte
+	// ISONames, Joliet - ISO 이미지의 기본 이름(8.3)과 Joliet 이름별 내용
+	ISONames map[string][]byte
+	Joliet   map[string][]byte
+	// RockRidge - 루트에 SP/ER 항목이 있어 Rock Ridge로 인식되는지
+	RockRidge bool
+}
+
+var ErrInvalidImage = errors.New("seed 이미지 형식이 올바르지 않습니다")
+
+// ReadISO - WriteISO가 만든 (또는 genisoimage가 만든) ISO9660 이미지를 읽는다
+func ReadISO(r io.ReaderAt) (*Volume, error) {
+	var pvd, svd []byte
+	for n := int64(pvdSector); n < pvdSector+32; n++ {
+		vd, err := readAt(r, n*isoSectorSize, isoSectorSize)
+		if err != nil {
+			return nil, err
+		}
+		if string(vd[1:6]) != "CD001" {
+			return nil, fmt.Errorf("%w: volume descriptor %d", ErrInvalidImage, n)
+		}
+		switch vd[0] {
+		case 1:
+			pvd = vd
+		case 2:
+			if bytes.HasPrefix(vd[88:], []byte("%/@")) || bytes.HasPrefix(vd[88:], []byte("%/C")) ||
+				bytes.HasPrefix(vd[88:], jolietEscape) {
+				svd = vd
+			}
+		}
+		if vd[0] == 255 {
+			break
+		}
+	}
+	if pvd == nil {
+		return nil, fmt.Errorf("%w: primary volume descriptor 없음", ErrInvalidImage)
+	}
+
+	vol := &Volume{
+		Label:    strings.TrimRight(string(pvd[40:72]), " "),
+		Files:    map[string][]byte{},
+		ISONames: map[string][]byte{},
+		Joliet:   map[string][]byte{},
+	}
+
+	// 1. primary 트리 (Rock Ridge 이름 포함)
+	entries, err := readISODir(r, pvd[156:190])
+	if err != nil {
+		return nil, err
+	}
+	for i, e := range entries {
+		if i == 0 {
+			// "."의 SP 항목이 Rock Ridge(SUSP) 사용 표시
+			_, er, err := parseSUSP(r, e.su)
+			if err != nil {
+				return nil, err
+			}
+			vol.RockRidge = bytes.HasPrefix(e.su, []byte{'S', 'P', 7, 1, 0xBE, 0xEF}) && er
+			continue
+		}
+		if e.dir {
+			continue
+		}
+		data, err := readAt(r, int64(e.extent)*isoSectorSize, int(e.size))
+		if err != nil {
+			return nil, err
+		}
+		vol.ISONames[string(e.ident)] = data
+
+		name := strings.TrimSuffix(strings.TrimSuffix(string(e.ident), ";1"), ".")
+		if vol.RockRidge {
+			nm, _, err := parseSUSP(r, e.su)
+			if err != nil {
+				return nil, err
+			}
+			if nm != "" {
+				name = nm
+			}
+		}
+		vol.Files[name] = data
+	}
+
+	// 2. Joliet 트리
+	if svd != nil {
+		entries, err := readISODir(r, svd[156:190])
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.dir {
+				continue
+			}
+			data, err := readAt(r, int64(e.extent)*isoSectorSize, int(e.size))
+			if err != nil {
+				return nil, err
+			}
+			vol.Joliet[strings.TrimSuffix(decodeUCS2(e.ident), ";1")] = data
+		}
+		if !vol.RockRidge {
+			vol.Files = vol.Joliet
+		}
+	}
+	return vol, nil
+}
+
+type isoRecord struct {
+	ident  []byte
+	dir    bool
+	extent uint32
+	size   uint32
+	su     []byte
+}
+
+func parseISORecord(rec []byte) (isoRecord, error) {
+	if len(rec) < 34 || int(rec[0]) > len(rec) || 33+int(rec[32]) > int(rec[0]) {
+		return isoRecord{}, fmt.Errorf("%w: 디렉토리 레코드", ErrInvalidImage)
+	}
+	n := int(rec[32])
+	suStart := 33 + n
+	if n%2 == 0 {
+		suStart++
+	}
+	e := isoRecord{
+		ident:  rec[33 : 33+n],
+		dir:    rec[25]&0x02 != 0,
+		extent: binary.LittleEndian.Uint32(rec[2:]),
+		size:   binary.LittleEndian.Uint32(rec[10:]),
+	}
+	if suStart < int(rec[0]) {
+		e.su = rec[suStart:rec[0]]
+	}
+	return e, nil
+}
+
+// readISODir - root 레코드가 가리키는 디렉토리의 레코드 (".", ".." 포함)
+func readISODir(r io.ReaderAt, rootRecord []byte) ([]isoRecord, error) {
+	root, err := parseISORecord(rootRecord)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readAt(r, int64(root.extent)*isoSectorSize, int(root.size))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []isoRecord
+	for off := 0; off < len(data); {
+		if data[off] == 0 {
+			// 섹터의 나머지는 비어 있다
+			off += isoSectorSize - off%isoSectorSize
+			continue
+		}
+		end := off + int(data[off])
+		if end > len(data) {
+			return nil, fmt.Errorf("%w: 디렉토리 레코드가 범위를 벗어남", ErrInvalidImage)
+		}
+		e, err := parseISORecord(data[off:end])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+		off = end
+	}
+	return entries, nil
+}
+
+// parseSUSP - system use 영역에서 NM 이름과 ER 존재 여부를 찾는다 (CE는 따라간다)
+func parseSUSP(r io.ReaderAt, su []byte) (name string, er bool, err error) {
+	for depth := 0; len(su) >= 4 && depth < 16; {
+		sig, n := string(su[:2]), int(su[2])
+		if n < 4 || n > len(su) {
+			break
+		}
+		switch sig {
+		case "NM":
+			if n > 5 {
+				name += string(su[5:n])
+			}
+		case "ER":
+			er = true
+		case "ST":
+			return name, er, nil
+		case "CE":
+			if n >= 28 {
+				block := binary.LittleEndian.Uint32(su[4:])
+				offset := binary.LittleEndian.Uint32(su[12:])
+				length := binary.LittleEndian.Uint32(su[20:])
+				rest, err := readAt(r, int64(block)*isoSectorSize+int64(offset), int(length))
+				if err != nil {
+					return "", false, err
+				}
+				// 남은 항목을 처리한 뒤 continuation area로 넘어간다
+				more, moreER, err := parseSUSP(r, su[n:])
+				if err != nil {
+					return "", false, err
+				}
+				name += more
+				er = er || moreER
+				su, n = rest, 0
+				depth++
+				continue
+			}
+		}
+		su = su[n:]
+	}
+	return name, er, nil
+}
+
+func decodeUCS2(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = binary.BigEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// ReadVFAT - WriteVFAT가 만든 FAT12 이미지를 읽는다
+func ReadVFAT(r io.ReaderAt) (*Volume, error) {
+	boot, err := readAt(r, 0, fatSectorSize)
+	if err != nil {
+		return nil, err
+	}
+	if boot[510] != 0x55 || boot[511] != 0xAA {
+		return nil, fmt.Errorf("%w: 부트 섹터 서명 없음", ErrInvalidImage)
+	}
+	bps := int(binary.LittleEndian.Uint16(boot[11:]))
+	perCluster := int(boot[13])
+	reserved := int(binary.LittleEndian.Uint16(boot[14:]))
+	fats := int(boot[16])
+	rootEntries := int(binary.LittleEndian.Uint16(boot[17:]))
+	total := int(binary.LittleEndian.Uint16(boot[19:]))
+	if total == 0 {
+		total = int(binary.LittleEndian.Uint32(boot[32:]))
+	}
+	fatSectors := int(binary.LittleEndian.Uint16(boot[22:]))
+	if bps == 0 || perCluster == 0 || fatSectors == 0 {
+		return nil, fmt.Errorf("%w: BPB", ErrInvalidImage)
+	}
+
+	rootStart := reserved + fats*fatSectors
+	rootSectors := (rootEntries*fatDirEntrySize + bps - 1) / bps
+	dataStart := rootStart + rootSectors
+	if (total-dataStart)/perCluster >= 4085 {
+		return nil, fmt.Errorf("%w: FAT12만 읽을 수 있습니다", ErrInvalidImage)
+	}
+
+	fat, err := readAt(r, int64(reserved*bps), fatSectors*bps)
+	if err != nil {
+		return nil, err
+	}
+	root, err := readAt(r, int64(rootStart*bps), rootEntries*fatDirEntrySize)
+	if err != nil {
+		return nil, err
+	}
+
+	vol := &Volume{
+		Label: strings.TrimRight(string(boot[43:54]), " "),
+		Files: map[string][]byte{},
+	}
+	var long []uint16
+	var longSum byte
+	for off := 0; off+fatDirEntrySize <= len(root); off += fatDirEntrySize {
+		e := root[off : off+fatDirEntrySize]
+		if e[0] == 0 {
+			break
+		}
+		if e[0] == 0xE5 {
+			long = nil
+			continue
+		}
+		attr := e[11]
+		if attr == attrLFN {
+			if e[0]&0x40 != 0 {
+				long = make([]uint16, int(e[0]&0x1F)*fatLFNChars)
+				longSum = e[13]
+			}
+			seq := int(e[0] & 0x1F)
+			if long == nil || seq == 0 || seq*fatLFNChars > len(long) {
+				long = nil
+				continue
+			}
+			chars := long[(seq-1)*fatLFNChars:]
+			for j := 0; j < fatLFNChars; j++ {
+				var p int
+				switch {
+				case j < 5:
+					p = 1 + 2*j
+				case j < 11:
+					p = 14 + 2*(j-5)
+				default:
+					p = 28 + 2*(j-11)
+				}
+				chars[j] = binary.LittleEndian.Uint16(e[p:])
+			}
+			continue
+		}
+		if attr&attrVolumeID != 0 {
+			vol.Label = strings.TrimRight(string(e[:11]), " ")
+			long = nil
+			continue
+		}
+		if attr&attrDir != 0 {
+			long = nil
+			continue
+		}
+
+		name := shortDisplayName(e[:11])
+		if long != nil && longSum == lfnChecksum(e[:11]) {
+			end := len(long)
+			for i, u := range long {
+				if u == 0 {
+					end = i
+					break
+				}
+			}
+			name = string(utf16.Decode(long[:end]))
+		}
+		long = nil
+
+		size := int(binary.LittleEndian.Uint32(e[28:]))
+		data := make([]byte, 0, size)
+		clusterSize := perCluster * bps
+		for c := int(binary.LittleEndian.Uint16(e[26:])); c >= 2 && c < 0xFF8 && len(data) < size; c = int(getFAT12(fat, c)) {
+			chunk, err := readAt(r, int64((dataStart+(c-2)*perCluster)*bps), clusterSize)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, chunk...)
+		}
+		if len(data) < size {
+			return nil, fmt.Errorf("%w: %s의 클러스터 체인이 짧습니다", ErrInvalidImage, name)
+		}
+		vol.Files[name] = data[:size]
+	}
+	return vol, nil
+}
+
+func shortDisplayName(short []byte) string {
+	base := strings.TrimRight(string(short[:8]), " ")
+	ext := strings.TrimRight(string(short[8:11]), " ")
+	if ext == "" {
+		return base
+	}
+	return base + "." + ext
+}
+
+func readAt(r io.ReaderAt, off int64, n int) ([]byte, error) {
+	b := make([]byte, n)
+	if n == 0 {
+		return b, nil
+	}
+	if _, err := r.ReadAt(b, off); err != nil {
+		return nil, fmt.Errorf("%w: %d바이트 위치 읽기 실패: %v", ErrInvalidImage, off, err)
+	}
+	return b, nil
+}
@@ -0,0 +1,302 @@
+package nocloud
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// FAT12 레이아웃. 클러스터 수를 1.44MB 플로피와 같게 고정하고
+// 파일이 크면 클러스터 크기를 늘린다 (4085개 미만이어야 FAT12로 인식된다).
+const (
+	fatSectorSize    = 512
+	fatClusters      = 2847
+	fatRootEntries   = 224
+	fatReserved      = 1
+	fatCount         = 2
+	fatMaxPerCluster = 128
+	fatMedia         = 0xF8
+	fatDirEntrySize  = 32
+	fatLFNChars      = 13
+)
+
+const (
+	attrReadOnly = 0x01
+	attrVolumeID = 0x08
+	attrDir      = 0x10
+	attrArchive  = 0x20
+	attrLFN      = 0x0F
+)
+
+// WriteVFAT - files를 루트에 둔 FAT12 이미지를 w에 쓴다. 모든 파일에 긴 이름(VFAT LFN)을 붙인다.
+// 라벨은 FAT 관례대로 대문자로 기록된다 (cloud-init은 대소문자 모두 찾는다).
+func WriteVFAT(w io.Writer, label string, files []File, modTime time.Time) error {
+	if err := validateFiles(files); err != nil {
+		return err
+	}
+	if label == "" || len(label) > 11 {
+		return fmt.Errorf("볼륨 이름은 1~11자여야 합니다: %q", label)
+	}
+
+	// 1. 클러스터 크기 결정
+	perCluster := 1
+	for ; ; perCluster *= 2 {
+		if perCluster > fatMaxPerCluster {
+			return fmt.Errorf("seed 파일이 너무 커서 FAT12 볼륨에 들어가지 않습니다")
+		}
+		if clustersNeeded(files, perCluster*fatSectorSize) <= fatClusters {
+			break
+		}
+	}
+	clusterSize := perCluster * fatSectorSize
+
+	// 2. 루트 디렉토리 항목 (볼륨 라벨 + 파일마다 LFN 항목과 8.3 항목)
+	shortNames := shortNames(files)
+	used := 1
+	for _, f := range files {
+		used += 1 + lfnEntries(f.Name)
+	}
+	if used > fatRootEntries {
+		return fmt.Errorf("루트 디렉토리 항목이 부족합니다 (%d개 필요, 최대 %d개)", used, fatRootEntries)
+	}
+
+	fatSectors := int(sectors((fatClusters+2)*3/2+1, fatSectorSize))
+	rootSectors := fatRootEntries * fatDirEntrySize / fatSectorSize
+	dataStart := fatReserved + fatCount*fatSectors + rootSectors
+	total := dataStart + fatClusters*perCluster
+
+	img := make([]byte, total*fatSectorSize)
+	volLabel := fatLabel(label)
+
+	// 3. 부트 섹터 (BPB)
+	boot := img[:fatSectorSize]
+	copy(boot, []byte{0xEB, 0x3C, 0x90})
+	copy(boot[3:], "WEBHOST ")
+	binary.LittleEndian.PutUint16(boot[11:], fatSectorSize)
+	boot[13] = byte(perCluster)
+	binary.LittleEndian.PutUint16(boot[14:], fatReserved)
+	boot[16] = fatCount
+	binary.LittleEndian.PutUint16(boot[17:], fatRootEntries)
+	if total < 0x10000 {
+		binary.LittleEndian.PutUint16(boot[19:], uint16(total))
+	} else {
+		binary.LittleEndian.PutUint32(boot[32:], uint32(total))
+	}
+	boot[21] = fatMedia
+	binary.LittleEndian.PutUint16(boot[22:], uint16(fatSectors))
+	binary.LittleEndian.PutUint16(boot[24:], 32) // sectors per track
+	binary.LittleEndian.PutUint16(boot[26:], 64) // heads
+	boot[36] = 0x80
+	boot[38] = 0x29
+	binary.LittleEndian.PutUint32(boot[39:], uint32(modTime.Unix()))
+	copy(boot[43:54], volLabel)
+	copy(boot[54:62], "FAT12   ")
+	boot[510], boot[511] = 0x55, 0xAA
+
+	// 4. 파일 데이터와 FAT (클러스터 2부터 연속 배치)
+	fat := make([]byte, fatSectors*fatSectorSize)
+	setFAT12(fat, 0, 0xF00|fatMedia)
+	setFAT12(fat, 1, 0xFFF)
+	startClusters := make([]int, len(files))
+	next := 2
+	for i, f := range files {
+		n := int(sectors(len(f.Data), clusterSize))
+		if n == 0 {
+			continue // 빈 파일은 클러스터 0
+		}
+		startClusters[i] = next
+		for c := next; c < next+n; c++ {
+			if c == next+n-1 {
+				setFAT12(fat, c, 0xFFF)
+			} else {
+				setFAT12(fat, c, uint16(c+1))
+			}
+		}
+		copy(img[(dataStart+(next-2)*perCluster)*fatSectorSize:], f.Data)
+		next += n
+	}
+	for i := 0; i < fatCount; i++ {
+		copy(img[(fatReserved+i*fatSectors)*fatSectorSize:], fat)
+	}
+
+	// 5. 루트 디렉토리
+	root := img[(fatReserved+fatCount*fatSectors)*fatSectorSize:]
+	dosDate, dosTime := dosDateTime(modTime)
+	entry := func(name []byte, attr byte, cluster int, size int) []byte {
+		e := make([]byte, fatDirEntrySize)
+		copy(e, name)
+		e[11] = attr
+		binary.LittleEndian.PutUint16(e[14:], dosTime)
+		binary.LittleEndian.PutUint16(e[16:], dosDate)
+		binary.LittleEndian.PutUint16(e[18:], dosDate)
+		binary.LittleEndian.PutUint16(e[22:], dosTime)
+		binary.LittleEndian.PutUint16(e[24:], dosDate)
+		binary.LittleEndian.PutUint16(e[26:], uint16(cluster))
+		binary.LittleEndian.PutUint32(e[28:], uint32(size))
+		return e
+	}
+
+	off := 0
+	off += copy(root[off:], entry(volLabel, attrVolumeID, 0, 0))
+	for i, f := range files {
+		for _, e := range lfn(f.Name, shortNames[i]) {
+			off += copy(root[off:], e)
+		}
+		off += copy(root[off:], entry(shortNames[i], attrReadOnly|attrArchive, startClusters[i], len(f.Data)))
+	}
+
+	_, err := w.Write(img)
+	return err
+}
+
+func clustersNeeded(files []File, clusterSize int) int {
+	n := 0
+	for _, f := range files {
+		n += int(sectors(len(f.Data), clusterSize))
+	}
+	return n
+}
+
+// setFAT12 - 12비트 항목 두 개가 3바이트를 나눠 쓴다
+func setFAT12(fat []byte, cluster int, v uint16) {
+	off := cluster + cluster/2
+	if cluster%2 == 0 {
+		fat[off] = byte(v)
+		fat[off+1] = fat[off+1]&0xF0 | byte(v>>8)&0x0F
+	} else {
+		fat[off] = fat[off]&0x0F | byte(v<<4)
+		fat[off+1] = byte(v >> 4)
+	}
+}
+
+func getFAT12(fat []byte, cluster int) uint16 {
+	off := cluster + cluster/2
+	v := binary.LittleEndian.Uint16(fat[off:])
+	if cluster%2 == 0 {
+		return v & 0x0FFF
+	}
+	return v >> 4
+}
+
+func fatLabel(label string) []byte {
+	b := []byte(strings.ToUpper(label) + strings.Repeat(" ", 11))
+	return b[:11]
+}
+
+// shortNames - 8.3 이름 ("USER-D~1   "). 실제 이름은 LFN 항목에 있다.
+func shortNames(files []File) [][]byte {
+	clean := func(s string) string {
+		var b strings.Builder
+		for _, r := range strings.ToUpper(s) {
+			switch {
+			case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("!#$%&'()-@^_`{}~", r):
+				b.WriteRune(r)
+			case r == ' ' || r == '.':
+			default:
+				b.WriteByte('_')
+			}
+		}
+		return b.String()
+	}
+
+	used := make(map[string]bool, len(files))
+	names := make([][]byte, len(files))
+	for i, f := range files {
+		base, ext := f.Name, ""
+		if dot := strings.LastIndexByte(f.Name, '.'); dot > 0 {
+			base, ext = f.Name[:dot], f.Name[dot+1:]
+		}
+		base, ext = clean(base), clean(ext)
+		if base == "" {
+			base = "_"
+		}
+		if len(ext) > 3 {
+			ext = ext[:3]
+		}
+		var name string
+		for n := 1; ; n++ {
+			tail := "~" + strconv.Itoa(n)
+			b := base
+			if len(b) > 8-len(tail) {
+				b = b[:8-len(tail)]
+			}
+			name = fmt.Sprintf("%-8s%-3s", b+tail, ext)
+			if !used[name] {
+				break
+			}
+		}
+		used[name] = true
+		names[i] = []byte(name)
+	}
+	return names
+}
+
+func lfnEntries(name string) int {
+	return (len(utf16.Encode([]rune(name))) + fatLFNChars - 1) / fatLFNChars
+}
+
+// lfn - 긴 이름 항목들 (디스크 순서: 마지막 조각이 먼저)
+func lfn(name string, short []byte) [][]byte {
+	units := utf16.Encode([]rune(name))
+	count := lfnEntries(name)
+	// 0x0000으로 끝내고 나머지는 0xFFFF로 채운다
+	padded := make([]uint16, count*fatLFNChars)
+	for i := range padded {
+		switch {
+		case i < len(units):
+			padded[i] = units[i]
+		case i == len(units):
+			padded[i] = 0
+		default:
+			padded[i] = 0xFFFF
+		}
+	}
+
+	sum := lfnChecksum(short)
+	entries := make([][]byte, 0, count)
+	for seq := count; seq >= 1; seq-- {
+		e := make([]byte, fatDirEntrySize)
+		e[0] = byte(seq)
+		if seq == count {
+			e[0] |= 0x40
+		}
+		e[11] = attrLFN
+		e[13] = sum
+		chars := padded[(seq-1)*fatLFNChars : seq*fatLFNChars]
+		for j, u := range chars {
+			var off int
+			switch {
+			case j < 5:
+				off = 1 + 2*j
+			case j < 11:
+				off = 14 + 2*(j-5)
+			default:
+				off = 28 + 2*(j-11)
+			}
+			binary.LittleEndian.PutUint16(e[off:], u)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func lfnChecksum(short []byte) byte {
+	var sum byte
+	for _, c := range short[:11] {
+		sum = (sum&1)<<7 + sum>>1 + c
+	}
+	return sum
+}
+
+func dosDateTime(t time.Time) (date, clock uint16) {
+	if t.Year() < 1980 {
+		t = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	date = uint16(t.Year()-1980)<<9 | uint16(t.Month())<<5 | uint16(t.Day())
+	clock = uint16(t.Hour())<<11 | uint16(t.Minute())<<5 | uint16(t.Second()/2)
+	return date, clock
+}